@@ -1,6 +1,10 @@
 package internal
 
-import "regexp"
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
 
 type Name string
 
@@ -13,39 +17,304 @@ func NewName(name string) (Name, error) {
 	return Name(name), nil
 }
 
+// AccountStatus is the lifecycle state of an account. Active accounts can
+// move money, frozen ones are blocked until they're unfrozen and closed ones
+// are blocked for good:
+//
+//	active ⇄ frozen
+//	active → closed (only with a zero balance)
+//
+// An empty status, as in accounts stored before statuses existed, behaves as
+// active.
+type AccountStatus string
+
+const (
+	AccountActive AccountStatus = "active"
+	AccountFrozen AccountStatus = "frozen"
+	AccountClosed AccountStatus = "closed"
+)
+
 type Account struct {
-	ID      string  `json:"id"`
-	Owner   Name    `json:"owner"`
-	Balance float32 `json:"balance"`
+	ID       string        `json:"id"`
+	Owner    Name          `json:"owner"`
+	Currency Currency      `json:"currency"`
+	Balance  Money         `json:"balance"`
+	Status   AccountStatus `json:"status"`
+	// OverdraftLimit is how far below zero the balance may go.
+	OverdraftLimit Money `json:"overdraft_limit"`
+	// Held is the amount reserved by active holds, which can't be spent.
+	Held Money       `json:"held"`
+	Type AccountType `json:"type"`
+	AccountInterest
+	// OpenedAt is when the account was opened. It's nil for accounts stored
+	// before opening times were kept.
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	// Version is increased on every update of the account, so concurrent
+	// updates can be detected.
+	Version int64 `json:"version"`
 }
 
-func NewAccount(id, owner string, balance float32) (Account, error) {
-	if balance < 0 {
+func NewAccount(id, owner, currency string, balance Money) (Account, error) {
+	if balance.IsNegative() {
 		return Account{}, ErrInvalidValue{Msg: "the initial balance should be greater than 0"}
 	}
 
+	accountCurrency, err := NewCurrency(currency)
+	if err != nil {
+		return Account{}, err
+	}
+
+	balance, err = balance.In(accountCurrency)
+	if err != nil {
+		return Account{}, err
+	}
+
 	ownerName, err := NewName(owner)
 	if err != nil {
 		return Account{}, err
 	}
 
 	return Account{
-		ID:      id,
-		Owner:   ownerName,
-		Balance: balance,
+		ID:             id,
+		Owner:          ownerName,
+		Currency:       accountCurrency,
+		Balance:        balance,
+		Status:         AccountActive,
+		OverdraftLimit: NewMoney(0, accountCurrency),
+		Held:           NewMoney(0, accountCurrency),
+		Type:           AccountChecking,
 	}, nil
 }
 
-func (a *Account) Deposit(amount float32) {
-	a.Balance += amount
+func (a *Account) Deposit(amount Money, currency Currency) error {
+	if !amount.IsPositive() {
+		return ErrInvalidValue{Msg: "the amount to deposit should be greater than 0"}
+	}
+
+	if err := a.checkActive("deposit into"); err != nil {
+		return err
+	}
+
+	if err := a.checkCurrency(currency); err != nil {
+		return err
+	}
+
+	balance, err := a.Balance.Add(amount)
+	if err != nil {
+		return err
+	}
+
+	a.Balance = balance
+
+	return nil
 }
 
-func (a *Account) Withdraw(amount float32) error {
-	if a.Balance-amount < 0 {
-		return ErrInsufficientBalance{AccountID: a.ID}
+func (a *Account) Withdraw(amount Money, currency Currency) error {
+	if !amount.IsPositive() {
+		return ErrInvalidValue{Msg: "the amount to withdraw should be greater than 0"}
+	}
+
+	if err := a.checkActive("withdraw from"); err != nil {
+		return err
+	}
+
+	if err := a.checkCurrency(currency); err != nil {
+		return err
+	}
+
+	available, err := a.AvailableBalance()
+	if err != nil {
+		return err
+	}
+
+	if available.Cmp(amount) < 0 {
+		return ErrInsufficientBalance{AccountID: a.ID, Available: available, Requested: amount}
+	}
+
+	balance, err := a.Balance.Sub(amount)
+	if err != nil {
+		return err
+	}
+
+	a.Balance = balance
+
+	return nil
+}
+
+// Adjust corrects the balance by the signed amount. Unlike deposits and
+// withdrawals it goes through whatever the status and the available balance of
+// the account, since it fixes what's already wrong rather than moving money.
+func (a *Account) Adjust(amount Money, currency Currency) error {
+	if err := a.checkCurrency(currency); err != nil {
+		return err
+	}
+
+	balance, err := a.Balance.Add(amount)
+	if err != nil {
+		return err
+	}
+
+	a.Balance = balance
+
+	return nil
+}
+
+// AvailableBalance is the amount that can be withdrawn: the balance plus the
+// overdraft limit, minus the amount held.
+func (a *Account) AvailableBalance() (Money, error) {
+	available, err := a.Balance.Add(a.OverdraftLimit)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return available.Sub(a.Held)
+}
+
+// PlaceHold reserves the amount so it can't be spent until the hold is
+// released.
+func (a *Account) PlaceHold(amount Money, currency Currency) error {
+	if err := a.checkActive("place a hold on"); err != nil {
+		return err
+	}
+
+	if err := a.checkCurrency(currency); err != nil {
+		return err
+	}
+
+	available, err := a.AvailableBalance()
+	if err != nil {
+		return err
+	}
+
+	if available.Cmp(amount) < 0 {
+		return ErrInsufficientBalance{AccountID: a.ID, Available: available, Requested: amount}
+	}
+
+	held, err := a.Held.Add(amount)
+	if err != nil {
+		return err
 	}
 
-	a.Balance -= amount
+	a.Held = held
+
+	return nil
+}
+
+// ReleaseHold makes the amount of a hold available again.
+func (a *Account) ReleaseHold(amount Money, currency Currency) error {
+	if err := a.checkCurrency(currency); err != nil {
+		return err
+	}
+
+	held, err := a.Held.Sub(amount)
+	if err != nil {
+		return err
+	}
+
+	a.Held = held
+
+	return nil
+}
+
+// SetOverdraftLimit lets the balance go down to -limit. The limit can't be
+// lowered below the current overdraft.
+func (a *Account) SetOverdraftLimit(limit Money) error {
+	if a.status() == AccountClosed {
+		return ErrAccountStatus{AccountID: a.ID, Status: a.status(), Operation: "change the overdraft limit of"}
+	}
+
+	if limit.IsNegative() {
+		return ErrInvalidValue{Msg: "the overdraft limit can't be negative"}
+	}
+
+	limit, err := limit.In(a.Currency)
+	if err != nil {
+		return err
+	}
+
+	if a.Balance.Cmp(limit.Neg()) < 0 {
+		return ErrInvalidValue{Msg: fmt.Sprintf("the balance %s is overdrawn beyond the overdraft limit %s", a.Balance, limit)}
+	}
+
+	a.OverdraftLimit = limit
+
+	return nil
+}
+
+// Freeze blocks every movement of money of an active account.
+func (a *Account) Freeze() error {
+	if err := a.checkActive("freeze"); err != nil {
+		return err
+	}
+
+	a.Status = AccountFrozen
+
+	return nil
+}
+
+// Unfreeze makes a frozen account active again.
+func (a *Account) Unfreeze() error {
+	if a.Status != AccountFrozen {
+		return ErrAccountStatus{AccountID: a.ID, Status: a.status(), Operation: "unfreeze"}
+	}
+
+	a.Status = AccountActive
+
+	return nil
+}
+
+// Close closes an active account for good. Its balance has to be zero and it
+// can't have active holds, so no money is left behind.
+func (a *Account) Close() error {
+	if err := a.checkActive("close"); err != nil {
+		return err
+	}
+
+	if !a.Balance.IsZero() || !a.Held.IsZero() {
+		return ErrAccountNotEmpty{AccountID: a.ID, Balance: a.Balance, Held: a.Held}
+	}
+
+	a.Status = AccountClosed
+
+	return nil
+}
+
+// OpenedBy reports whether the account was already open at the given time.
+// Accounts without an opening time are taken as opened long before.
+func (a *Account) OpenedBy(t time.Time) bool {
+	return a.OpenedAt == nil || !a.OpenedAt.After(t)
+}
+
+// checkActive returns ErrAccountStatus when the account isn't active.
+func (a *Account) checkActive(operation string) error {
+	if a.status() != AccountActive {
+		return ErrAccountStatus{AccountID: a.ID, Status: a.status(), Operation: operation}
+	}
+
+	return nil
+}
+
+func (a *Account) status() AccountStatus {
+	if a.Status == "" {
+		return AccountActive
+	}
+
+	return a.Status
+}
+
+// accountType treats accounts stored before types existed as checking ones.
+func (a *Account) accountType() AccountType {
+	if a.Type == "" {
+		return AccountChecking
+	}
+
+	return a.Type
+}
+
+func (a *Account) checkCurrency(currency Currency) error {
+	if currency != a.Currency {
+		return ErrCurrencyMismatch{Expected: a.Currency, Actual: currency}
+	}
 
 	return nil
 }
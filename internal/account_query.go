@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// DefaultAccountsLimit is the size of the pages of accounts when none is
+	// given.
+	DefaultAccountsLimit = 100
+	MaxAccountsLimit     = 1000
+)
+
+// AccountQuery selects accounts in creation order. Every filter left empty
+// matches all of them.
+type AccountQuery struct {
+	// OwnerPrefix selects the accounts whose owner starts with it, ignoring
+	// case.
+	OwnerPrefix string
+	// MinBalance and MaxBalance bound the balance, both included, whatever
+	// the currency of the account.
+	MinBalance *Money
+	MaxBalance *Money
+	Statuses   []AccountStatus
+	// After is the ID of the account the page starts after.
+	After string
+	// Limit is the maximum number of accounts returned, with 0 meaning all of
+	// them.
+	Limit int
+}
+
+func (q AccountQuery) Validate() error {
+	for _, status := range q.Statuses {
+		switch status {
+		case AccountActive, AccountFrozen, AccountClosed:
+		default:
+			return ErrInvalidValue{Msg: fmt.Sprintf("invalid account status %q", status)}
+		}
+	}
+
+	if q.MinBalance != nil && q.MaxBalance != nil && q.MinBalance.Cmp(*q.MaxBalance) > 0 {
+		return ErrInvalidValue{Msg: "the minimum balance is above the maximum"}
+	}
+
+	if q.Limit < 0 || q.Limit > MaxAccountsLimit {
+		return ErrInvalidValue{Msg: fmt.Sprintf("the limit can't be negative or above %d", MaxAccountsLimit)}
+	}
+
+	return nil
+}
+
+// Matches reports whether the account passes the query's filters, leaving
+// aside its position and the limit.
+func (q AccountQuery) Matches(account Account) bool {
+	switch {
+	case q.OwnerPrefix != "" && !strings.HasPrefix(strings.ToLower(string(account.Owner)), strings.ToLower(q.OwnerPrefix)),
+		q.MinBalance != nil && account.Balance.Cmp(*q.MinBalance) < 0,
+		q.MaxBalance != nil && account.Balance.Cmp(*q.MaxBalance) > 0,
+		len(q.Statuses) > 0 && !slices.Contains(q.Statuses, account.status()):
+		return false
+	default:
+		return true
+	}
+}
+
+// AccountPage is a page of the accounts selected by a query. NextCursor is
+// empty on the last page.
+type AccountPage struct {
+	Accounts   []Account
+	NextCursor string
+}
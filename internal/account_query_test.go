@@ -0,0 +1,48 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountQuery_Matches(t *testing.T) {
+	var (
+		ten     = internal.MustParseMoney("10")
+		account = internal.Account{Owner: "Alice Smith", Balance: internal.MustParseMoney("20.00")}
+	)
+
+	testCases := map[string]struct {
+		query    internal.AccountQuery
+		expected bool
+	}{
+		"Empty":                   {query: internal.AccountQuery{}, expected: true},
+		"Owner prefix":            {query: internal.AccountQuery{OwnerPrefix: "ALICE s"}, expected: true},
+		"Other owner":             {query: internal.AccountQuery{OwnerPrefix: "Bob"}, expected: false},
+		"Balance above minimum":   {query: internal.AccountQuery{MinBalance: &ten}, expected: true},
+		"Balance above maximum":   {query: internal.AccountQuery{MaxBalance: &ten}, expected: false},
+		"Accounts without status": {query: internal.AccountQuery{Statuses: []internal.AccountStatus{internal.AccountActive}}, expected: true},
+		"Other status":            {query: internal.AccountQuery{Statuses: []internal.AccountStatus{internal.AccountFrozen}}, expected: false},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			require.NoError(t, tc.query.Validate())
+			assert.Equal(t, tc.expected, tc.query.Matches(account))
+		})
+	}
+}
+
+func TestAccountQuery_Validate(t *testing.T) {
+	var (
+		ten  = internal.MustParseMoney("10")
+		five = internal.MustParseMoney("5")
+	)
+
+	require.NoError(t, internal.AccountQuery{MinBalance: &five, MaxBalance: &ten, Limit: internal.MaxAccountsLimit}.Validate())
+	require.ErrorAs(t, internal.AccountQuery{MinBalance: &ten, MaxBalance: &five}.Validate(), &internal.ErrInvalidValue{})
+	require.ErrorAs(t, internal.AccountQuery{Statuses: []internal.AccountStatus{"deleted"}}.Validate(), &internal.ErrInvalidValue{})
+	require.ErrorAs(t, internal.AccountQuery{Limit: -1}.Validate(), &internal.ErrInvalidValue{})
+}
@@ -3,6 +3,7 @@ package internal_test
 import (
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/bxcodec/faker/v3"
 	"github.com/google/uuid"
@@ -15,10 +16,10 @@ func TestAccount_New_OK(t *testing.T) {
 	var (
 		id     = uuid.NewString()
 		owner  = faker.Name()
-		amount = rand.Float32() * 100
+		amount = internal.NewMoney(rand.Int63n(10000), internal.DefaultCurrency)
 	)
 
-	_, err := internal.NewAccount(id, owner, amount)
+	_, err := internal.NewAccount(id, owner, "EUR", amount)
 	require.NoError(t, err)
 }
 
@@ -26,10 +27,10 @@ func TestAccount_New_InvalidOwner(t *testing.T) {
 	var (
 		id     = uuid.NewString()
 		owner  = "12345"
-		amount = rand.Float32()
+		amount = internal.NewMoney(rand.Int63n(100), internal.DefaultCurrency)
 	)
 
-	_, err := internal.NewAccount(id, owner, amount)
+	_, err := internal.NewAccount(id, owner, "EUR", amount)
 	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
 }
 
@@ -37,10 +38,35 @@ func TestAccount_New_InvalidAmount(t *testing.T) {
 	var (
 		id     = uuid.NewString()
 		owner  = faker.Name()
-		amount = -rand.Float32()
+		amount = internal.NewMoney(-rand.Int63n(100)-1, internal.DefaultCurrency)
 	)
 
-	_, err := internal.NewAccount(id, owner, amount)
+	_, err := internal.NewAccount(id, owner, "EUR", amount)
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+}
+
+func TestAccount_New_InvalidCurrency(t *testing.T) {
+	var (
+		id     = uuid.NewString()
+		owner  = faker.Name()
+		amount = internal.MustParseMoney("10")
+	)
+
+	_, err := internal.NewAccount(id, owner, "XYZ", amount)
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+}
+
+func TestAccount_New_ZeroDecimalCurrency(t *testing.T) {
+	var (
+		id    = uuid.NewString()
+		owner = faker.Name()
+	)
+
+	account, err := internal.NewAccount(id, owner, "JPY", internal.MustParseMoney("1000"))
+	require.NoError(t, err)
+	assert.Equal(t, internal.Currency("JPY"), account.Currency)
+
+	_, err = internal.NewAccount(id, owner, "JPY", internal.MustParseMoney("10.5"))
 	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
 }
 
@@ -48,43 +74,344 @@ func TestAccount_Deposit(t *testing.T) {
 	var (
 		id     = uuid.NewString()
 		owner  = "Test User"
-		amount = 100
+		amount = internal.MustParseMoney("100")
 	)
 
-	account, err := internal.NewAccount(id, owner, float32(amount))
+	account, err := internal.NewAccount(id, owner, "EUR", amount)
 	require.NoError(t, err)
 
-	account.Deposit(100)
-	assert.Equal(t, float32(200), account.Balance)
+	err = account.Deposit(internal.MustParseMoney("100"), "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("200.00"), account.Balance)
+}
+
+func TestAccount_NonPositiveAmounts(t *testing.T) {
+	testCases := map[string]string{
+		"Zero":     "0",
+		"Negative": "-10",
+	}
+
+	for testName, amount := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			account, err := internal.NewAccount(uuid.NewString(), "Test User", "EUR", internal.MustParseMoney("100"))
+			require.NoError(t, err)
+
+			err = account.Deposit(internal.MustParseMoney(amount), "EUR")
+			require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+
+			err = account.Withdraw(internal.MustParseMoney(amount), "EUR")
+			require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+			assert.Equal(t, internal.MustParseMoney("100.00"), account.Balance)
+
+			_, err = internal.NewTransaction(uuid.NewString(), account.ID, internal.TxDeposit, internal.MustParseMoney(amount), "EUR", time.Now())
+			require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+		})
+	}
 }
 
 func TestAccount_Withdrawal_OK(t *testing.T) {
 	var (
 		id     = uuid.NewString()
 		owner  = "Test User"
-		amount = 100
+		amount = internal.MustParseMoney("100")
 	)
 
-	account, err := internal.NewAccount(id, owner, float32(amount))
+	account, err := internal.NewAccount(id, owner, "EUR", amount)
 	require.NoError(t, err)
 
-	err = account.Withdraw(20)
+	err = account.Withdraw(internal.MustParseMoney("20"), "EUR")
 
 	require.NoError(t, err)
-	assert.Equal(t, float32(80), account.Balance)
+	assert.Equal(t, internal.MustParseMoney("80.00"), account.Balance)
 }
 
 func TestAccount_Withdrawal_InsufficentBalance(t *testing.T) {
 	var (
 		id     = uuid.NewString()
 		owner  = "Test User"
-		amount = 100
+		amount = internal.MustParseMoney("100")
 	)
 
-	account, err := internal.NewAccount(id, owner, float32(amount))
+	account, err := internal.NewAccount(id, owner, "EUR", amount)
 	require.NoError(t, err)
 
-	err = account.Withdraw(200)
+	err = account.Withdraw(internal.MustParseMoney("200"), "EUR")
 	assert.ErrorAs(t, err, &internal.ErrInsufficientBalance{})
-	assert.Equal(t, float32(100), account.Balance)
+	assert.Equal(t, internal.MustParseMoney("100.00"), account.Balance)
+}
+
+func TestAccount_New_InvalidPrecision(t *testing.T) {
+	var (
+		id     = uuid.NewString()
+		owner  = faker.Name()
+		amount = internal.MustParseMoney("20.305")
+	)
+
+	_, err := internal.NewAccount(id, owner, "EUR", amount)
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+}
+
+func TestAccount_CurrencyMismatch(t *testing.T) {
+	var (
+		id     = uuid.NewString()
+		owner  = "Test User"
+		amount = internal.MustParseMoney("100")
+	)
+
+	account, err := internal.NewAccount(id, owner, "EUR", amount)
+	require.NoError(t, err)
+
+	err = account.Deposit(internal.MustParseMoney("10"), "USD")
+	require.ErrorAs(t, err, &internal.ErrCurrencyMismatch{})
+
+	err = account.Withdraw(internal.MustParseMoney("10"), "USD")
+	require.ErrorAs(t, err, &internal.ErrCurrencyMismatch{})
+
+	assert.Equal(t, internal.MustParseMoney("100.00"), account.Balance)
+}
+
+func TestAccount_StatusTransitions(t *testing.T) {
+	transitions := map[string]func(*internal.Account) error{
+		"freeze":   (*internal.Account).Freeze,
+		"unfreeze": (*internal.Account).Unfreeze,
+		"close":    (*internal.Account).Close,
+	}
+
+	testCases := map[string]struct {
+		status         internal.AccountStatus
+		balance        string
+		transition     string
+		expectedStatus internal.AccountStatus
+		expectedError  error
+	}{
+		"Freeze active account": {
+			status:         internal.AccountActive,
+			balance:        "10",
+			transition:     "freeze",
+			expectedStatus: internal.AccountFrozen,
+		},
+		"Freeze frozen account": {
+			status:        internal.AccountFrozen,
+			balance:       "10",
+			transition:    "freeze",
+			expectedError: &internal.ErrAccountStatus{},
+		},
+		"Unfreeze frozen account": {
+			status:         internal.AccountFrozen,
+			balance:        "10",
+			transition:     "unfreeze",
+			expectedStatus: internal.AccountActive,
+		},
+		"Unfreeze active account": {
+			status:        internal.AccountActive,
+			balance:       "10",
+			transition:    "unfreeze",
+			expectedError: &internal.ErrAccountStatus{},
+		},
+		"Close empty account": {
+			status:         internal.AccountActive,
+			balance:        "0",
+			transition:     "close",
+			expectedStatus: internal.AccountClosed,
+		},
+		"Close account with balance": {
+			status:        internal.AccountActive,
+			balance:       "10",
+			transition:    "close",
+			expectedError: &internal.ErrAccountNotEmpty{},
+		},
+		"Close frozen account": {
+			status:        internal.AccountFrozen,
+			balance:       "0",
+			transition:    "close",
+			expectedError: &internal.ErrAccountStatus{},
+		},
+		"Unfreeze closed account": {
+			status:        internal.AccountClosed,
+			balance:       "0",
+			transition:    "unfreeze",
+			expectedError: &internal.ErrAccountStatus{},
+		},
+		"Freeze account without status": {
+			balance:        "10",
+			transition:     "freeze",
+			expectedStatus: internal.AccountFrozen,
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			account := internal.Account{
+				ID:       uuid.NewString(),
+				Currency: internal.DefaultCurrency,
+				Balance:  internal.MustParseMoney(tc.balance),
+				Status:   tc.status,
+			}
+
+			err := transitions[tc.transition](&account)
+			if tc.expectedError != nil {
+				require.ErrorAs(t, err, tc.expectedError)
+				assert.Equal(t, tc.status, account.Status)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, account.Status)
+		})
+	}
+}
+
+func TestAccount_InactiveAccountsDontMoveMoney(t *testing.T) {
+	for _, status := range []internal.AccountStatus{internal.AccountFrozen, internal.AccountClosed} {
+		t.Run(string(status), func(t *testing.T) {
+			account, err := internal.NewAccount(uuid.NewString(), "Test User", "EUR", internal.MustParseMoney("100"))
+			require.NoError(t, err)
+			account.Status = status
+
+			err = account.Deposit(internal.MustParseMoney("10"), "EUR")
+			require.ErrorAs(t, err, &internal.ErrAccountStatus{})
+
+			err = account.Withdraw(internal.MustParseMoney("10"), "EUR")
+			require.ErrorAs(t, err, &internal.ErrAccountStatus{})
+
+			assert.Equal(t, internal.MustParseMoney("100.00"), account.Balance)
+		})
+	}
+}
+
+func TestAccount_Withdrawal_Overdraft(t *testing.T) {
+	account, err := internal.NewAccount(uuid.NewString(), "Test User", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+	require.NoError(t, account.SetOverdraftLimit(internal.MustParseMoney("50")))
+
+	require.NoError(t, account.Withdraw(internal.MustParseMoney("130"), "EUR"))
+	assert.Equal(t, internal.MustParseMoney("-30.00"), account.Balance)
+	available, err := account.AvailableBalance()
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("20.00"), available)
+
+	err = account.Withdraw(internal.MustParseMoney("20.01"), "EUR")
+	var insufficient internal.ErrInsufficientBalance
+	require.ErrorAs(t, err, &insufficient)
+	assert.Equal(t, internal.MustParseMoney("20.00"), insufficient.Available)
+	assert.Equal(t, internal.MustParseMoney("20.01"), insufficient.Requested)
+	assert.Equal(t, internal.MustParseMoney("-30.00"), account.Balance)
+
+	require.NoError(t, account.Withdraw(internal.MustParseMoney("20"), "EUR"))
+	assert.Equal(t, internal.MustParseMoney("-50.00"), account.Balance)
+}
+
+func TestAccount_SetOverdraftLimit(t *testing.T) {
+	testCases := map[string]struct {
+		balance       string
+		status        internal.AccountStatus
+		limit         string
+		expectedError error
+	}{
+		"Limit set": {
+			balance: "10",
+			status:  internal.AccountActive,
+			limit:   "500",
+		},
+		"Limit removed": {
+			balance: "10",
+			status:  internal.AccountActive,
+			limit:   "0",
+		},
+		"Limit of frozen account": {
+			balance: "10",
+			status:  internal.AccountFrozen,
+			limit:   "500",
+		},
+		"Limit covering the overdraft": {
+			balance: "-100",
+			status:  internal.AccountActive,
+			limit:   "100",
+		},
+		"Limit below the overdraft": {
+			balance:       "-100",
+			status:        internal.AccountActive,
+			limit:         "99.99",
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Negative limit": {
+			balance:       "10",
+			status:        internal.AccountActive,
+			limit:         "-1",
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Invalid precision": {
+			balance:       "10",
+			status:        internal.AccountActive,
+			limit:         "1.001",
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Closed account": {
+			balance:       "0",
+			status:        internal.AccountClosed,
+			limit:         "500",
+			expectedError: &internal.ErrAccountStatus{},
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			account := internal.Account{
+				ID:             uuid.NewString(),
+				Currency:       internal.DefaultCurrency,
+				Balance:        internal.MustParseMoney(tc.balance),
+				Status:         tc.status,
+				OverdraftLimit: internal.MustParseMoney("200.00"),
+			}
+
+			err := account.SetOverdraftLimit(internal.MustParseMoney(tc.limit))
+			if tc.expectedError != nil {
+				require.ErrorAs(t, err, tc.expectedError)
+				assert.Equal(t, internal.MustParseMoney("200.00"), account.OverdraftLimit)
+				return
+			}
+
+			require.NoError(t, err)
+			expected, err := internal.MustParseMoney(tc.limit).Round(internal.DefaultCurrency)
+			require.NoError(t, err)
+			assert.Equal(t, expected, account.OverdraftLimit)
+		})
+	}
+}
+
+func TestAccount_Holds(t *testing.T) {
+	account, err := internal.NewAccount(uuid.NewString(), "Test User", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+
+	require.NoError(t, account.PlaceHold(internal.MustParseMoney("60.00"), "EUR"))
+	assert.Equal(t, internal.MustParseMoney("100.00"), account.Balance)
+	available, err := account.AvailableBalance()
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("40.00"), available)
+
+	err = account.Withdraw(internal.MustParseMoney("40.01"), "EUR")
+	require.ErrorAs(t, err, &internal.ErrInsufficientBalance{})
+
+	err = account.PlaceHold(internal.MustParseMoney("40.01"), "EUR")
+	require.ErrorAs(t, err, &internal.ErrInsufficientBalance{})
+
+	err = account.PlaceHold(internal.MustParseMoney("1.00"), "USD")
+	require.ErrorAs(t, err, &internal.ErrCurrencyMismatch{})
+
+	require.NoError(t, account.Freeze())
+	err = account.PlaceHold(internal.MustParseMoney("1.00"), "EUR")
+	require.ErrorAs(t, err, &internal.ErrAccountStatus{})
+	require.NoError(t, account.Unfreeze())
+
+	require.NoError(t, account.Withdraw(internal.MustParseMoney("40"), "EUR"))
+	available, err = account.AvailableBalance()
+	require.NoError(t, err)
+	assert.True(t, available.IsZero())
+	err = account.Close()
+	require.ErrorAs(t, err, &internal.ErrAccountNotEmpty{})
+
+	require.NoError(t, account.ReleaseHold(internal.MustParseMoney("60.00"), "EUR"))
+	available, err = account.AvailableBalance()
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("60.00"), available)
 }
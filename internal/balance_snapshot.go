@@ -0,0 +1,96 @@
+package internal
+
+import "time"
+
+// BalanceSnapshot is the balance an account had at a point in time, that is
+// what the transactions made before At added up to. Snapshots bound how many
+// transactions have to be replayed to know the balance at any other time.
+type BalanceSnapshot struct {
+	AccountID string    `json:"account_id"`
+	At        time.Time `json:"at"`
+	Balance   Money     `json:"balance"`
+	Currency  Currency  `json:"currency"`
+}
+
+// BalanceSnapshotTime returns when the snapshot of the account taken now
+// should be: at the start of the day, once it's an hour old so the
+// transactions stamped before it have all been recorded. Interest is stamped
+// at the end of the day it's for but posted later, so the snapshot is never
+// past the interest the account still has to post.
+func BalanceSnapshotTime(account Account, now time.Time) time.Time {
+	at := Date(now.Add(-time.Hour))
+	if account.InterestAccruedThrough != nil {
+		if posted := account.InterestAccruedThrough.AddDate(0, 0, 1); posted.Before(at) {
+			at = posted
+		}
+	}
+
+	return at
+}
+
+// BalanceChanges returns how much the transactions changed the balance of
+// their account.
+func BalanceChanges(transactions []Transaction) (Money, error) {
+	var total Money
+	for _, transaction := range transactions {
+		var err error
+		if total, err = total.Add(transaction.BalanceChange()); err != nil {
+			return Money{}, err
+		}
+	}
+
+	return total, nil
+}
+
+// ReplayBalance returns the balance of an account at the given time, after
+// the transactions made at it, by replaying its transactions between two
+// known balances: from, the latest snapshot before the time if there's one,
+// and to, the next snapshot or the current balance. The transactions are the
+// ones made from from.At, or after the time when there's no snapshot before
+// it, up to to.At, excluded.
+//
+// Replaying the transactions from one known balance must lead to the other,
+// otherwise the history doesn't reconcile and ErrHistoryMismatch is returned.
+// Without a snapshot before the time, the balance is worked out back from the
+// next known one and there's nothing to check.
+func ReplayBalance(at time.Time, from *BalanceSnapshot, to BalanceSnapshot, transactions []Transaction) (Money, error) {
+	if from == nil {
+		balance := to.Balance
+		for _, transaction := range transactions {
+			if !transaction.Timestamp.After(at) {
+				continue
+			}
+
+			var err error
+			if balance, err = balance.Sub(transaction.BalanceChange()); err != nil {
+				return Money{}, err
+			}
+		}
+
+		return balance, nil
+	}
+
+	balance, replayed := from.Balance, from.Balance
+	for _, transaction := range transactions {
+		var err error
+		if replayed, err = replayed.Add(transaction.BalanceChange()); err != nil {
+			return Money{}, err
+		}
+
+		if !transaction.Timestamp.After(at) {
+			balance = replayed
+		}
+	}
+
+	if replayed.Cmp(to.Balance) != 0 {
+		return Money{}, ErrHistoryMismatch{
+			AccountID: to.AccountID,
+			From:      from.At,
+			To:        to.At,
+			Expected:  to.Balance,
+			Replayed:  replayed,
+		}
+	}
+
+	return balance, nil
+}
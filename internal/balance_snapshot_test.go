@@ -0,0 +1,118 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceSnapshotTime(t *testing.T) {
+	var (
+		now       = time.Date(2024, 12, 1, 9, 30, 0, 0, time.UTC)
+		today     = time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+		yesterday = today.AddDate(0, 0, -1)
+		lastWeek  = today.AddDate(0, 0, -7)
+	)
+
+	testCases := map[string]struct {
+		now            time.Time
+		accruedThrough *time.Time
+		expected       time.Time
+	}{
+		"Start of the day":       {now: now, expected: today},
+		"Day just started":       {now: today.Add(30 * time.Minute), expected: yesterday},
+		"Interest accrued":       {now: now, accruedThrough: &yesterday, expected: today},
+		"Interest to be accrued": {now: now, accruedThrough: &lastWeek, expected: lastWeek.AddDate(0, 0, 1)},
+		"Other offset than UTC":  {now: now.In(time.FixedZone("UTC-10", -10*60*60)), expected: today},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			account := internal.Account{ID: uuid.NewString()}
+			account.InterestAccruedThrough = tc.accruedThrough
+
+			assert.Equal(t, tc.expected, internal.BalanceSnapshotTime(account, tc.now))
+		})
+	}
+}
+
+func TestReplayBalance(t *testing.T) {
+	var (
+		accountID = uuid.NewString()
+		start     = time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+		end       = start.AddDate(0, 0, 1)
+		from      = internal.BalanceSnapshot{AccountID: accountID, At: start, Balance: internal.MustParseMoney("100.00"), Currency: "EUR"}
+		to        = internal.BalanceSnapshot{AccountID: accountID, At: end, Balance: internal.MustParseMoney("125.00"), Currency: "EUR"}
+	)
+
+	newTransaction := func(txType string, amount string, timestamp time.Time) internal.Transaction {
+		return internal.Transaction{
+			ID:        uuid.NewString(),
+			AccountID: accountID,
+			Type:      internal.TransactionType(txType),
+			Amount:    internal.MustParseMoney(amount),
+			Currency:  "EUR",
+			Timestamp: timestamp,
+		}
+	}
+
+	transactions := []internal.Transaction{
+		newTransaction(internal.TxDeposit, "50.00", start),
+		newTransaction(internal.TxWithdrawal, "20.00", start.Add(time.Hour)),
+		newTransaction(internal.TxFee, "5.00", start.Add(time.Hour)),
+	}
+
+	testCases := map[string]struct {
+		at       time.Time
+		from     *internal.BalanceSnapshot
+		expected string
+	}{
+		"At the snapshot":      {at: start, from: &from, expected: "150.00"},
+		"Transactions at once": {at: start.Add(time.Hour), from: &from, expected: "125.00"},
+		"Between transactions": {at: start.Add(time.Minute), from: &from, expected: "150.00"},
+		"Back from the next":   {at: start.Add(time.Minute), expected: "150.00"},
+		"Back before all":      {at: start.Add(-time.Minute), expected: "100.00"},
+		"Back after all":       {at: start.Add(2 * time.Hour), expected: "125.00"},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			var replayed []internal.Transaction
+			for _, transaction := range transactions {
+				if tc.from != nil || transaction.Timestamp.After(tc.at) {
+					replayed = append(replayed, transaction)
+				}
+			}
+
+			balance, err := internal.ReplayBalance(tc.at, tc.from, to, replayed)
+			require.NoError(t, err)
+			assert.Zero(t, internal.MustParseMoney(tc.expected).Cmp(balance), "expected %s, got %s", tc.expected, balance)
+		})
+	}
+
+	t.Run("History mismatch", func(t *testing.T) {
+		_, err := internal.ReplayBalance(start, &from, to, transactions[:2])
+
+		var mismatch internal.ErrHistoryMismatch
+		require.ErrorAs(t, err, &mismatch)
+		assert.Equal(t, accountID, mismatch.AccountID)
+		assert.Equal(t, start, mismatch.From)
+		assert.Equal(t, end, mismatch.To)
+		assert.Equal(t, "125.00", mismatch.Expected.String())
+		assert.Equal(t, "130.00", mismatch.Replayed.String())
+	})
+
+	t.Run("Balance changes", func(t *testing.T) {
+		changes, err := internal.BalanceChanges(transactions)
+		require.NoError(t, err)
+		assert.Equal(t, "25.00", changes.String())
+
+		changes, err = internal.BalanceChanges(nil)
+		require.NoError(t, err)
+		assert.True(t, changes.IsZero())
+	})
+}
@@ -0,0 +1,16 @@
+package internal
+
+import "time"
+
+// Clock tells the current time. Services that act on their own at given times
+// take a Clock, so tests can control the time.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock of the system's time.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
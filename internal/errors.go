@@ -3,6 +3,7 @@ package internal
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // var (
@@ -25,14 +26,197 @@ func (e ErrAccountNotFound) Error() string {
 	return fmt.Sprintf("account with id %q not found", e.AccountID)
 }
 
+type ErrTransferNotFound struct {
+	TransferID string
+}
+
+func (e ErrTransferNotFound) Error() string {
+	return fmt.Sprintf("transfer with id %q not found", e.TransferID)
+}
+
+// ErrTransferExists is returned when saving a transaction of a transfer that
+// already has one of the same type on the same account, which happens when
+// the transfer was already made.
+type ErrTransferExists struct {
+	TransferID string
+}
+
+func (e ErrTransferExists) Error() string {
+	return fmt.Sprintf("transfer with id %q already exists", e.TransferID)
+}
+
+type ErrTransactionNotFound struct {
+	TransactionID string
+}
+
+func (e ErrTransactionNotFound) Error() string {
+	return fmt.Sprintf("transaction with id %q not found", e.TransactionID)
+}
+
 type ErrInsufficientBalance struct {
 	AccountID string
+	// Available is the balance plus the overdraft limit of the account, minus
+	// the amount held.
+	Available Money
+	Requested Money
 }
 
 func (e ErrInsufficientBalance) Error() string {
-	return fmt.Sprintf("balance for account with id %q is insufficient", e.AccountID)
+	return fmt.Sprintf(
+		"balance for account with id %q is insufficient: %s available, %s requested",
+		e.AccountID, e.Available, e.Requested,
+	)
 }
 
 var (
 	ErrAccountAlreadyExists = errors.New("account already exists")
 )
+
+type ErrCurrencyMismatch struct {
+	Expected Currency
+	Actual   Currency
+}
+
+func (e ErrCurrencyMismatch) Error() string {
+	return fmt.Sprintf("currency mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+type ErrExchangeRateNotFound struct {
+	From Currency
+	To   Currency
+}
+
+func (e ErrExchangeRateNotFound) Error() string {
+	return fmt.Sprintf("exchange rate from %s to %s not found", e.From, e.To)
+}
+
+type ErrBalanceMismatch struct {
+	AccountID     string
+	Balance       Money
+	LedgerBalance Money
+}
+
+func (e ErrBalanceMismatch) Error() string {
+	return fmt.Sprintf(
+		"balance %s of account with id %q doesn't match the ledger balance %s",
+		e.Balance, e.AccountID, e.LedgerBalance,
+	)
+}
+
+// ErrHistoryMismatch is returned when the transactions of an account made
+// between two of its known balances don't add up from one to the other.
+type ErrHistoryMismatch struct {
+	AccountID string
+	From      time.Time
+	To        time.Time
+	Expected  Money
+	Replayed  Money
+}
+
+func (e ErrHistoryMismatch) Error() string {
+	return fmt.Sprintf(
+		"the history of account with id %q doesn't reconcile: its transactions from %s to %s lead to a balance of %s instead of %s",
+		e.AccountID,
+		e.From.Format(time.RFC3339),
+		e.To.Format(time.RFC3339),
+		e.Replayed,
+		e.Expected,
+	)
+}
+
+type ErrVersionConflict struct {
+	AccountID       string
+	ExpectedVersion int64
+}
+
+func (e ErrVersionConflict) Error() string {
+	return fmt.Sprintf("account with id %q is no longer at version %d", e.AccountID, e.ExpectedVersion)
+}
+
+type ErrAccountStatus struct {
+	AccountID string
+	Status    AccountStatus
+	Operation string
+}
+
+func (e ErrAccountStatus) Error() string {
+	return fmt.Sprintf("can't %s account with id %q because it's %s", e.Operation, e.AccountID, e.Status)
+}
+
+type ErrAccountNotEmpty struct {
+	AccountID string
+	Balance   Money
+	Held      Money
+}
+
+func (e ErrAccountNotEmpty) Error() string {
+	return fmt.Sprintf(
+		"account with id %q can't be closed with a balance of %s and %s held",
+		e.AccountID, e.Balance, e.Held,
+	)
+}
+
+type ErrHoldNotFound struct {
+	HoldID string
+}
+
+func (e ErrHoldNotFound) Error() string {
+	return fmt.Sprintf("hold with id %q not found", e.HoldID)
+}
+
+type ErrHoldNotActive struct {
+	HoldID string
+	Status HoldStatus
+}
+
+func (e ErrHoldNotActive) Error() string {
+	return fmt.Sprintf("hold with id %q is %s", e.HoldID, e.Status)
+}
+
+type ErrScheduledTransferNotFound struct {
+	ScheduledTransferID string
+}
+
+func (e ErrScheduledTransferNotFound) Error() string {
+	return fmt.Sprintf("scheduled transfer with id %q not found", e.ScheduledTransferID)
+}
+
+type ErrScheduledTransferNotActive struct {
+	ScheduledTransferID string
+	Status              ScheduledTransferStatus
+}
+
+func (e ErrScheduledTransferNotActive) Error() string {
+	return fmt.Sprintf("scheduled transfer with id %q is %s", e.ScheduledTransferID, e.Status)
+}
+
+// ErrReversalExceeded is returned when reversing more than what remains of a
+// transaction after its previous reversals.
+type ErrReversalExceeded struct {
+	TransactionID string
+	Remaining     Money
+	Requested     Money
+}
+
+func (e ErrReversalExceeded) Error() string {
+	if e.Remaining.IsZero() {
+		return fmt.Sprintf("transaction with id %q is already reversed", e.TransactionID)
+	}
+
+	return fmt.Sprintf(
+		"can't reverse %s of transaction with id %q, only %s remain",
+		e.Requested, e.TransactionID, e.Remaining,
+	)
+}
+
+var (
+	ErrTxDone = errors.New("transaction has already been committed or rolled back")
+)
+
+type ErrIdempotencyKeyReused struct {
+	Key string
+}
+
+func (e ErrIdempotencyKeyReused) Error() string {
+	return fmt.Sprintf("idempotency key %q was already used for a different request", e.Key)
+}
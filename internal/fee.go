@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Percentage is an exact percentage between 0 and 100, such as 1.5 for 1.5%.
+type Percentage struct {
+	value Money
+}
+
+func ParsePercentage(s string) (Percentage, error) {
+	value, err := ParseMoney(s)
+	if err != nil || value.IsNegative() || value.Cmp(MustParseMoney("100")) > 0 {
+		return Percentage{}, ErrInvalidValue{Msg: fmt.Sprintf("invalid percentage %q, it should be between 0 and 100", s)}
+	}
+
+	return Percentage{value: value.normalize()}, nil
+}
+
+func MustParsePercentage(s string) Percentage {
+	percentage, err := ParsePercentage(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return percentage
+}
+
+func (p Percentage) String() string {
+	return p.value.String()
+}
+
+func (p Percentage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+func (p *Percentage) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return ErrInvalidValue{Msg: "percentages must be encoded as strings"}
+	}
+
+	parsed, err := ParsePercentage(s)
+	if err != nil {
+		return err
+	}
+
+	*p = parsed
+
+	return nil
+}
+
+// rat returns the percentage as a fraction of one.
+func (p Percentage) rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(p.value.units), big.NewInt(100*pow10(p.value.scale)))
+}
+
+// FeeRule is the fee of an operation: a flat amount plus a percentage of the
+// operation's amount, kept within the minimum and the maximum when they're
+// given. Amounts are in the currency of the account charged.
+type FeeRule struct {
+	Flat       Money      `json:"flat"`
+	Percentage Percentage `json:"percentage"`
+	Min        *Money     `json:"min,omitempty"`
+	Max        *Money     `json:"max,omitempty"`
+}
+
+func (r FeeRule) Validate() error {
+	for _, amount := range []*Money{&r.Flat, r.Min, r.Max} {
+		if amount != nil && amount.IsNegative() {
+			return ErrInvalidValue{Msg: fmt.Sprintf("invalid fee amount %s, it can't be negative", amount)}
+		}
+	}
+
+	if r.Min != nil && r.Max != nil && r.Min.Cmp(*r.Max) > 0 {
+		return ErrInvalidValue{Msg: fmt.Sprintf("the minimum fee %s is above the maximum %s", r.Min, r.Max)}
+	}
+
+	return nil
+}
+
+// Fee returns the fee of an operation of the amount, rounded half-even to the
+// currency's minor units.
+func (r FeeRule) Fee(amount Money, currency Currency) (Money, error) {
+	percentage, err := amount.mulRat(r.Percentage.rat(), currency.MinorUnits())
+	if err != nil {
+		return Money{}, err
+	}
+
+	fee, err := r.Flat.Add(percentage)
+	if err != nil {
+		return Money{}, err
+	}
+
+	if r.Min != nil && fee.Cmp(*r.Min) < 0 {
+		fee = *r.Min
+	}
+
+	if r.Max != nil && fee.Cmp(*r.Max) > 0 {
+		fee = *r.Max
+	}
+
+	return fee.Round(currency)
+}
+
+// FeeSchedule is the fees charged to the accounts of a type.
+type FeeSchedule struct {
+	Withdrawal FeeRule `json:"withdrawal"`
+	// Transfer is charged to the source account, on the amount sent.
+	Transfer FeeRule `json:"transfer"`
+	// Maintenance is charged at the end of each month.
+	Maintenance Money `json:"maintenance"`
+}
+
+func (s FeeSchedule) Validate() error {
+	if err := s.Withdrawal.Validate(); err != nil {
+		return fmt.Errorf("withdrawal fee: %w", err)
+	}
+
+	if err := s.Transfer.Validate(); err != nil {
+		return fmt.Errorf("transfer fee: %w", err)
+	}
+
+	if s.Maintenance.IsNegative() {
+		return ErrInvalidValue{Msg: fmt.Sprintf("invalid maintenance fee %s, it can't be negative", s.Maintenance)}
+	}
+
+	return nil
+}
+
+// FeeSchedules are the fee schedules of each account type. The accounts of
+// types without a schedule aren't charged any fee.
+type FeeSchedules map[AccountType]FeeSchedule
+
+func (fs FeeSchedules) Validate() error {
+	for accountType, schedule := range fs {
+		if _, err := NewAccountType(string(accountType)); err != nil {
+			return err
+		}
+
+		if err := schedule.Validate(); err != nil {
+			return fmt.Errorf("fees of %s accounts: %w", accountType, err)
+		}
+	}
+
+	return nil
+}
+
+// WithdrawalFee returns the fee of withdrawing the amount from the account.
+func (fs FeeSchedules) WithdrawalFee(account Account, amount Money) (Money, error) {
+	return fs[account.accountType()].Withdrawal.Fee(amount, account.Currency)
+}
+
+// TransferFee returns the fee of sending the amount from the account.
+func (fs FeeSchedules) TransferFee(account Account, amount Money) (Money, error) {
+	return fs[account.accountType()].Transfer.Fee(amount, account.Currency)
+}
+
+// MaintenanceFee returns the monthly fee of keeping the account.
+func (fs FeeSchedules) MaintenanceFee(account Account) (Money, error) {
+	return fs[account.accountType()].Maintenance.Round(account.Currency)
+}
+
+// NewFeeTransaction returns the transaction that charges the fee to the
+// account. feeFor is the transaction the fee is charged for, if any.
+func NewFeeTransaction(id string, account Account, fee Money, feeFor string, timestamp time.Time) Transaction {
+	return Transaction{
+		ID:        id,
+		AccountID: account.ID,
+		Type:      TxFee,
+		Amount:    fee,
+		Currency:  account.Currency,
+		Timestamp: timestamp,
+		FeeFor:    feeFor,
+	}
+}
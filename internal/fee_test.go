@@ -0,0 +1,94 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeRule_Fee(t *testing.T) {
+	var (
+		one = internal.MustParseMoney("1")
+		ten = internal.MustParseMoney("10")
+	)
+
+	testCases := map[string]struct {
+		rule     internal.FeeRule
+		amount   string
+		currency internal.Currency
+		expected string
+	}{
+		"No fee":              {rule: internal.FeeRule{}, amount: "100", currency: "EUR", expected: "0.00"},
+		"Flat":                {rule: internal.FeeRule{Flat: internal.MustParseMoney("0.5")}, amount: "100", currency: "EUR", expected: "0.50"},
+		"Percentage":          {rule: internal.FeeRule{Percentage: internal.MustParsePercentage("1.5")}, amount: "200", currency: "EUR", expected: "3.00"},
+		"Percentage rounded":  {rule: internal.FeeRule{Percentage: internal.MustParsePercentage("1")}, amount: "12.25", currency: "EUR", expected: "0.12"},
+		"Flat and percentage": {rule: internal.FeeRule{Flat: one, Percentage: internal.MustParsePercentage("2")}, amount: "50", currency: "EUR", expected: "2.00"},
+		"Below minimum":       {rule: internal.FeeRule{Percentage: internal.MustParsePercentage("1"), Min: &one, Max: &ten}, amount: "20", currency: "EUR", expected: "1.00"},
+		"Above maximum":       {rule: internal.FeeRule{Percentage: internal.MustParsePercentage("1"), Min: &one, Max: &ten}, amount: "5000", currency: "EUR", expected: "10.00"},
+		"Within caps":         {rule: internal.FeeRule{Percentage: internal.MustParsePercentage("1"), Min: &one, Max: &ten}, amount: "500", currency: "EUR", expected: "5.00"},
+		"Zero-decimal":        {rule: internal.FeeRule{Percentage: internal.MustParsePercentage("0.5")}, amount: "1500", currency: "JPY", expected: "8"},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			fee, err := tc.rule.Fee(internal.MustParseMoney(tc.amount), tc.currency)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(tc.expected), fee)
+		})
+	}
+}
+
+func TestFeeSchedules(t *testing.T) {
+	schedules := internal.FeeSchedules{
+		internal.AccountChecking: {
+			Withdrawal:  internal.FeeRule{Flat: internal.MustParseMoney("0.5")},
+			Maintenance: internal.MustParseMoney("3"),
+		},
+	}
+
+	var (
+		checking = internal.Account{ID: uuid.NewString(), Currency: "EUR", Type: internal.AccountChecking}
+		untyped  = internal.Account{ID: uuid.NewString(), Currency: "EUR"}
+		savings  = internal.Account{ID: uuid.NewString(), Currency: "EUR", Type: internal.AccountSavings}
+	)
+
+	fee, err := schedules.WithdrawalFee(checking, internal.MustParseMoney("10"))
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("0.50"), fee)
+
+	fee, err = schedules.WithdrawalFee(untyped, internal.MustParseMoney("10"))
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("0.50"), fee, "accounts without type are checking ones")
+
+	fee, err = schedules.WithdrawalFee(savings, internal.MustParseMoney("10"))
+	require.NoError(t, err)
+	assert.True(t, fee.IsZero())
+
+	fee, err = schedules.MaintenanceFee(checking)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("3.00"), fee)
+
+	fee, err = internal.FeeSchedules(nil).TransferFee(checking, internal.MustParseMoney("10"))
+	require.NoError(t, err)
+	assert.True(t, fee.IsZero())
+}
+
+func TestNewFeeTransaction(t *testing.T) {
+	var (
+		account   = internal.Account{ID: uuid.NewString(), Currency: "EUR"}
+		timestamp = time.Date(2024, 11, 24, 3, 26, 51, 0, time.UTC)
+	)
+
+	fee := internal.NewFeeTransaction(uuid.NewString(), account, internal.MustParseMoney("0.50"), "withdrawal", timestamp)
+	assert.Equal(t, internal.TransactionType(internal.TxFee), fee.Type)
+	assert.Equal(t, "withdrawal", fee.FeeFor)
+
+	entry, err := internal.NewTransactionEntry(uuid.NewString(), fee)
+	require.NoError(t, err)
+	assertBalanceOf(t, "-0.50", []internal.JournalEntry{entry}, account.ID, "EUR")
+	assertBalanceOf(t, "0.50", []internal.JournalEntry{entry}, internal.SystemAccountRevenue, "EUR")
+}
@@ -0,0 +1,44 @@
+package fees
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+// LoadFile reads the fee schedules of each account type from a JSON file such
+// as
+//
+//	{
+//		"checking": {
+//			"withdrawal": {"flat": "0.50"},
+//			"transfer": {"percentage": "1", "min": "1", "max": "10"},
+//			"maintenance": "3"
+//		}
+//	}
+//
+// Fees left out are zero.
+func LoadFile(path string) (internal.FeeSchedules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading fees file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Misspelled fees would be silently zero otherwise.
+	decoder.DisallowUnknownFields()
+
+	var schedules internal.FeeSchedules
+	if err := decoder.Decode(&schedules); err != nil {
+		return nil, fmt.Errorf("decoding fees file: %w", err)
+	}
+
+	if err := schedules.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid fees file: %w", err)
+	}
+
+	return schedules, nil
+}
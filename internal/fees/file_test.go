@@ -0,0 +1,71 @@
+package fees_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fees"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFile(t *testing.T) {
+	testCases := map[string]struct {
+		content       string
+		expectedError bool
+	}{
+		"Schedules": {
+			content: `{
+				"checking": {
+					"withdrawal": {"flat": "0.50"},
+					"transfer": {"percentage": "1", "min": "1", "max": "10"},
+					"maintenance": "3"
+				},
+				"savings": {"withdrawal": {"flat": "2"}}
+			}`,
+		},
+		"Unknown account type": {content: `{"brokerage": {"maintenance": "3"}}`, expectedError: true},
+		"Unknown fee":          {content: `{"checking": {"deposit": {"flat": "1"}}}`, expectedError: true},
+		"Negative fee":         {content: `{"checking": {"withdrawal": {"flat": "-1"}}}`, expectedError: true},
+		"Minimum above max":    {content: `{"checking": {"transfer": {"min": "10", "max": "1"}}}`, expectedError: true},
+		"Invalid percentage":   {content: `{"checking": {"transfer": {"percentage": "150"}}}`, expectedError: true},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fees.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			schedules, err := fees.LoadFile(path)
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			checking := internal.Account{ID: "checking", Currency: "EUR", Type: internal.AccountChecking}
+			fee, err := schedules.WithdrawalFee(checking, internal.MustParseMoney("100.00"))
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney("0.50"), fee)
+
+			fee, err = schedules.MaintenanceFee(checking)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney("3.00"), fee)
+
+			savings := internal.Account{ID: "savings", Currency: "EUR", Type: internal.AccountSavings}
+			fee, err = schedules.WithdrawalFee(savings, internal.MustParseMoney("100.00"))
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney("2.00"), fee)
+
+			fee, err = schedules.TransferFee(savings, internal.MustParseMoney("100.00"))
+			require.NoError(t, err)
+			assert.True(t, fee.IsZero())
+		})
+	}
+
+	_, err := fees.LoadFile(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// rateDecimals is the precision used to print rates that are not finite
+// decimals, like the inverse of a configured rate.
+const rateDecimals = 10
+
+// Rate is an exact exchange rate.
+type Rate struct {
+	value *big.Rat
+}
+
+func ParseRate(s string) (Rate, error) {
+	value, ok := new(big.Rat).SetString(s)
+	if !ok || value.Sign() <= 0 || strings.ContainsAny(s, "/eE") {
+		return Rate{}, ErrInvalidValue{Msg: fmt.Sprintf("invalid exchange rate %q", s)}
+	}
+
+	return Rate{value: value}, nil
+}
+
+func MustParseRate(s string) Rate {
+	rate, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return rate
+}
+
+// IdentityRate is the rate between a currency and itself.
+func IdentityRate() Rate {
+	return Rate{value: big.NewRat(1, 1)}
+}
+
+// Inverse returns the rate for the opposite direction. The zero Rate has no
+// inverse and stays zero.
+func (r Rate) Inverse() Rate {
+	if !r.IsPositive() {
+		return Rate{}
+	}
+
+	return Rate{value: new(big.Rat).Inv(r.rat())}
+}
+
+// IsPositive tells whether the rate can convert amounts, which the zero Rate
+// can't.
+func (r Rate) IsPositive() bool {
+	return r.rat().Sign() > 0
+}
+
+func (r Rate) Cmp(other Rate) int {
+	return r.rat().Cmp(other.rat())
+}
+
+func (r Rate) String() string {
+	s := r.rat().FloatString(rateDecimals)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return ErrInvalidValue{Msg: "rates must be encoded as strings"}
+	}
+
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+
+	*r = parsed
+
+	return nil
+}
+
+// rat returns the value of the rate, 0 for the zero Rate.
+func (r Rate) rat() *big.Rat {
+	if r.value == nil {
+		return new(big.Rat)
+	}
+
+	return r.value
+}
+
+// ExchangeRate is the amount of To currency that one unit of From buys at a
+// given moment.
+type ExchangeRate struct {
+	From      Currency  `json:"from"`
+	To        Currency  `json:"to"`
+	Rate      Rate      `json:"rate"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Convert converts an amount of the From currency into the To currency,
+// rounding half-even to the To currency's minor units.
+func (er ExchangeRate) Convert(amount Money) (Money, error) {
+	if !er.Rate.IsPositive() {
+		return Money{}, ErrInvalidValue{Msg: fmt.Sprintf("invalid exchange rate %s from %s to %s", er.Rate, er.From, er.To)}
+	}
+
+	return amount.mulRat(er.Rate.rat(), er.To.MinorUnits())
+}
+
+type FXRateProvider interface {
+	Rate(ctx context.Context, from, to Currency) (ExchangeRate, error)
+}
+
+// Quote is the breakdown of a transfer between two accounts, possibly in
+// different currencies.
+type Quote struct {
+	SourceAmount        Money     `json:"source_amount"`
+	SourceCurrency      Currency  `json:"source_currency"`
+	DestinationAmount   Money     `json:"destination_amount"`
+	DestinationCurrency Currency  `json:"destination_currency"`
+	Rate                Rate      `json:"rate"`
+	RateTimestamp       time.Time `json:"rate_timestamp"`
+	// Fee is charged to the source account on top of the source amount.
+	Fee Money `json:"fee"`
+}
+
+// NewQuote returns the quote of converting the amount at the rate, without
+// fees.
+func NewQuote(amount Money, rate ExchangeRate) (Quote, error) {
+	converted, err := rate.Convert(amount)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	return Quote{
+		SourceAmount:        amount,
+		SourceCurrency:      rate.From,
+		DestinationAmount:   converted,
+		DestinationCurrency: rate.To,
+		Rate:                rate.Rate,
+		RateTimestamp:       rate.Timestamp,
+		Fee:                 NewMoney(0, rate.From),
+	}, nil
+}
@@ -0,0 +1,116 @@
+package fxrates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+type pair struct {
+	from internal.Currency
+	to   internal.Currency
+}
+
+// StaticProvider serves a fixed set of exchange rates. Inverse rates are
+// derived when only the opposite direction is configured.
+type StaticProvider struct {
+	rates map[pair]internal.ExchangeRate
+	mutex *sync.RWMutex
+}
+
+var _ internal.FXRateProvider = (*StaticProvider)(nil)
+
+func NewStaticProvider(rates ...internal.ExchangeRate) *StaticProvider {
+	provider := &StaticProvider{
+		rates: make(map[pair]internal.ExchangeRate, len(rates)),
+		mutex: &sync.RWMutex{},
+	}
+
+	for _, rate := range rates {
+		provider.Set(rate)
+	}
+
+	return provider
+}
+
+// LoadFile reads a JSON array of exchange rates such as
+// [{"from": "EUR", "to": "USD", "rate": "1.0834", "timestamp": "2024-11-24T00:00:00Z"}].
+// Rates without timestamp take the file's modification time.
+func LoadFile(path string) (*StaticProvider, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rates file: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rates file: %w", err)
+	}
+
+	var rates []internal.ExchangeRate
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("decoding rates file: %w", err)
+	}
+
+	for i, rate := range rates {
+		if _, err := internal.NewCurrency(string(rate.From)); err != nil {
+			return nil, err
+		}
+
+		if _, err := internal.NewCurrency(string(rate.To)); err != nil {
+			return nil, err
+		}
+
+		if !rate.Rate.IsPositive() {
+			return nil, internal.ErrInvalidValue{Msg: fmt.Sprintf("missing rate from %s to %s", rate.From, rate.To)}
+		}
+
+		if rate.Timestamp.IsZero() {
+			rates[i].Timestamp = info.ModTime().UTC()
+		}
+	}
+
+	return NewStaticProvider(rates...), nil
+}
+
+// Set adds or replaces the rate for its currency pair.
+func (p *StaticProvider) Set(rate internal.ExchangeRate) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.rates[pair{from: rate.From, to: rate.To}] = rate
+}
+
+func (p *StaticProvider) Rate(_ context.Context, from, to internal.Currency) (internal.ExchangeRate, error) {
+	if from == to {
+		return internal.ExchangeRate{
+			From:      from,
+			To:        to,
+			Rate:      internal.IdentityRate(),
+			Timestamp: time.Now(),
+		}, nil
+	}
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if rate, ok := p.rates[pair{from: from, to: to}]; ok {
+		return rate, nil
+	}
+
+	if rate, ok := p.rates[pair{from: to, to: from}]; ok {
+		return internal.ExchangeRate{
+			From:      from,
+			To:        to,
+			Rate:      rate.Rate.Inverse(),
+			Timestamp: rate.Timestamp,
+		}, nil
+	}
+
+	return internal.ExchangeRate{}, internal.ErrExchangeRateNotFound{From: from, To: to}
+}
@@ -0,0 +1,85 @@
+package fxrates_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fxrates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticProvider_LoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	content := `[
+		{"from": "EUR", "to": "USD", "rate": "1.0834", "timestamp": "2024-11-24T00:00:00Z"},
+		{"from": "EUR", "to": "JPY", "rate": "161.2"}
+	]`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	provider, err := fxrates.LoadFile(path)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	rate, err := provider.Rate(ctx, "EUR", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0834", rate.Rate.String())
+	assert.Equal(t, "2024-11-24T00:00:00Z", rate.Timestamp.Format("2006-01-02T15:04:05Z07:00"))
+
+	rate, err = provider.Rate(ctx, "JPY", "EUR")
+	require.NoError(t, err)
+	assert.False(t, rate.Timestamp.IsZero())
+	converted, err := rate.Convert(internal.MustParseMoney("1612"))
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("10.00"), converted)
+
+	rate, err = provider.Rate(ctx, "USD", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "1", rate.Rate.String())
+
+	_, err = provider.Rate(ctx, "USD", "JPY")
+	require.ErrorAs(t, err, &internal.ErrExchangeRateNotFound{})
+}
+
+func TestStaticProvider_LoadFile_Invalid(t *testing.T) {
+	testCases := map[string]string{
+		"Invalid currency": `[{"from": "EUR", "to": "XXX", "rate": "2"}]`,
+		"Missing rate":     `[{"from": "EUR", "to": "USD"}]`,
+		"Zero rate":        `[{"from": "EUR", "to": "USD", "rate": "0"}]`,
+		"Negative rate":    `[{"from": "EUR", "to": "USD", "rate": "-1.08"}]`,
+	}
+
+	for testName, content := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rates.json")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			_, err := fxrates.LoadFile(path)
+			require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+		})
+	}
+}
+
+func TestExchangeRate_Convert_ZeroRate(t *testing.T) {
+	rate := internal.ExchangeRate{From: "EUR", To: "USD"}
+
+	_, err := rate.Convert(internal.MustParseMoney("10"))
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+	assert.False(t, rate.Rate.Inverse().IsPositive())
+}
+
+func TestExchangeRate_Convert_RoundsHalfEven(t *testing.T) {
+	rate := internal.ExchangeRate{From: "EUR", To: "USD", Rate: internal.MustParseRate("0.5")}
+
+	converted, err := rate.Convert(internal.MustParseMoney("0.05"))
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("0.02"), converted)
+
+	converted, err = rate.Convert(internal.MustParseMoney("0.07"))
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("0.04"), converted)
+}
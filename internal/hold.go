@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"time"
+)
+
+// HoldStatus is the state of a hold. Holds are created active and end up
+// captured, released or expired.
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "active"
+	HoldCaptured HoldStatus = "captured"
+	HoldReleased HoldStatus = "released"
+	HoldExpired  HoldStatus = "expired"
+)
+
+// Hold reserves an amount of an account, as card authorizations do, until it
+// is captured, released or it expires. While active, the amount is included
+// in the account's Held amount, so it can't be spent, but the balance doesn't
+// change until the hold is captured.
+type Hold struct {
+	ID        string     `json:"id"`
+	AccountID string     `json:"accountId"`
+	Amount    Money      `json:"amount"`
+	Currency  Currency   `json:"currency"`
+	Status    HoldStatus `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+
+	// Only set for captured holds
+	CapturedAmount *Money `json:"capturedAmount,omitempty"`
+	TransactionID  string `json:"transactionId,omitempty"`
+}
+
+func NewHold(
+	id,
+	accountID string,
+	amount Money,
+	currency Currency,
+	createdAt,
+	expiresAt time.Time,
+) (Hold, error) {
+	if _, err := NewCurrency(string(currency)); err != nil {
+		return Hold{}, err
+	}
+
+	amount, err := amount.In(currency)
+	if err != nil {
+		return Hold{}, err
+	}
+
+	if !amount.IsPositive() {
+		return Hold{}, ErrInvalidValue{Msg: "the amount to hold should be greater than 0"}
+	}
+
+	if !expiresAt.After(createdAt) {
+		return Hold{}, ErrInvalidValue{Msg: "the hold should expire in the future"}
+	}
+
+	return Hold{
+		ID:        id,
+		AccountID: accountID,
+		Amount:    amount,
+		Currency:  currency,
+		Status:    HoldActive,
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Capture takes up to the held amount from the account through the given
+// transaction. The rest of the amount is released.
+func (h *Hold) Capture(amount Money, transactionID string, now time.Time) error {
+	if err := h.checkActive(now); err != nil {
+		return err
+	}
+
+	amount, err := amount.In(h.Currency)
+	if err != nil {
+		return err
+	}
+
+	if !amount.IsPositive() {
+		return ErrInvalidValue{Msg: "the amount to capture should be greater than 0"}
+	}
+
+	if amount.Cmp(h.Amount) > 0 {
+		return ErrInvalidValue{Msg: "the amount to capture can't be greater than the held amount"}
+	}
+
+	h.Status = HoldCaptured
+	h.CapturedAmount = &amount
+	h.TransactionID = transactionID
+
+	return nil
+}
+
+// Release gives the held amount back to the account.
+func (h *Hold) Release() error {
+	if h.Status != HoldActive {
+		return ErrHoldNotActive{HoldID: h.ID, Status: h.Status}
+	}
+
+	h.Status = HoldReleased
+
+	return nil
+}
+
+// Expire releases a hold that outlived its expiry time.
+func (h *Hold) Expire(now time.Time) error {
+	if h.Status != HoldActive {
+		return ErrHoldNotActive{HoldID: h.ID, Status: h.Status}
+	}
+
+	if now.Before(h.ExpiresAt) {
+		return ErrInvalidValue{Msg: "the hold hasn't expired yet"}
+	}
+
+	h.Status = HoldExpired
+
+	return nil
+}
+
+// checkActive returns ErrHoldNotActive when the hold can't be captured
+// anymore, including when it has expired but wasn't marked as expired yet.
+func (h *Hold) checkActive(now time.Time) error {
+	if h.Status != HoldActive {
+		return ErrHoldNotActive{HoldID: h.ID, Status: h.Status}
+	}
+
+	if !now.Before(h.ExpiresAt) {
+		return ErrHoldNotActive{HoldID: h.ID, Status: HoldExpired}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHold_New(t *testing.T) {
+	now := time.Now()
+
+	testCases := map[string]struct {
+		amount        string
+		currency      internal.Currency
+		expiresAt     time.Time
+		expectedError error
+	}{
+		"Hold created": {
+			amount:    "10",
+			currency:  "EUR",
+			expiresAt: now.Add(time.Hour),
+		},
+		"Zero amount": {
+			amount:        "0",
+			currency:      "EUR",
+			expiresAt:     now.Add(time.Hour),
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Invalid precision": {
+			amount:        "1.001",
+			currency:      "EUR",
+			expiresAt:     now.Add(time.Hour),
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Invalid currency": {
+			amount:        "10",
+			currency:      "XYZ",
+			expiresAt:     now.Add(time.Hour),
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Already expired": {
+			amount:        "10",
+			currency:      "EUR",
+			expiresAt:     now,
+			expectedError: &internal.ErrInvalidValue{},
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			hold, err := internal.NewHold(uuid.NewString(), uuid.NewString(), internal.MustParseMoney(tc.amount), tc.currency, now, tc.expiresAt)
+			if tc.expectedError != nil {
+				require.ErrorAs(t, err, tc.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, internal.HoldActive, hold.Status)
+			assert.Equal(t, internal.MustParseMoney("10.00"), hold.Amount)
+		})
+	}
+}
+
+func TestHold_Transitions(t *testing.T) {
+	var (
+		now       = time.Now()
+		expiresAt = now.Add(time.Hour)
+	)
+
+	newHold := func(t *testing.T) internal.Hold {
+		t.Helper()
+
+		hold, err := internal.NewHold(uuid.NewString(), uuid.NewString(), internal.MustParseMoney("10"), "EUR", now, expiresAt)
+		require.NoError(t, err)
+
+		return hold
+	}
+
+	t.Run("Capture", func(t *testing.T) {
+		hold := newHold(t)
+
+		err := hold.Capture(internal.MustParseMoney("10.01"), "tx", now)
+		require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+
+		require.NoError(t, hold.Capture(internal.MustParseMoney("7.5"), "tx", now))
+		assert.Equal(t, internal.HoldCaptured, hold.Status)
+		assert.Equal(t, internal.MustParseMoney("7.50"), *hold.CapturedAmount)
+		assert.Equal(t, "tx", hold.TransactionID)
+
+		require.ErrorAs(t, hold.Release(), &internal.ErrHoldNotActive{})
+	})
+
+	t.Run("Capture after expiry", func(t *testing.T) {
+		hold := newHold(t)
+
+		var notActive internal.ErrHoldNotActive
+		require.ErrorAs(t, hold.Capture(internal.MustParseMoney("1"), "tx", expiresAt), &notActive)
+		assert.Equal(t, internal.HoldExpired, notActive.Status)
+		assert.Equal(t, internal.HoldActive, hold.Status)
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		hold := newHold(t)
+
+		require.NoError(t, hold.Release())
+		assert.Equal(t, internal.HoldReleased, hold.Status)
+
+		require.ErrorAs(t, hold.Capture(internal.MustParseMoney("1"), "tx", now), &internal.ErrHoldNotActive{})
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		hold := newHold(t)
+
+		require.ErrorAs(t, hold.Expire(now), &internal.ErrInvalidValue{})
+
+		require.NoError(t, hold.Expire(expiresAt))
+		assert.Equal(t, internal.HoldExpired, hold.Status)
+
+		require.ErrorAs(t, hold.Expire(expiresAt), &internal.ErrHoldNotActive{})
+	})
+}
@@ -0,0 +1,27 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotentResponse is the response to a request sent with an idempotency
+// key. It's replayed when the request is retried with the same key.
+type IdempotentResponse struct {
+	// Fingerprint identifies the request that produced the response, so a key
+	// reused for a different request can be told apart from a retry.
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type IdempotencyStore interface {
+	// Lock blocks until no other request holds the key and returns the
+	// function that releases it.
+	Lock(ctx context.Context, key string) (unlock func(), err error)
+	// Get returns the response stored for the key, if it hasn't expired.
+	Get(ctx context.Context, key string) (IdempotentResponse, bool, error)
+	// Save stores the response for the key during the given time.
+	Save(ctx context.Context, key string, response IdempotentResponse, ttl time.Duration) error
+}
@@ -0,0 +1,319 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// AccountType is the product of an account. Only savings accounts earn
+// interest. An empty type, as in accounts stored before types existed,
+// behaves as checking.
+type AccountType string
+
+const (
+	AccountChecking AccountType = "checking"
+	AccountSavings  AccountType = "savings"
+)
+
+func NewAccountType(accountType string) (AccountType, error) {
+	switch AccountType(accountType) {
+	case AccountChecking, AccountSavings:
+		return AccountType(accountType), nil
+	}
+
+	return "", ErrInvalidValue{Msg: fmt.Sprintf("invalid account type %q", accountType)}
+}
+
+// accruedInterestDecimals is the precision of the interest accrued but not
+// posted yet, so the daily interest of small balances isn't lost.
+const accruedInterestDecimals = 6
+
+// InterestRate is an exact annual interest rate, such as 0.025 for 2.5%.
+type InterestRate struct {
+	value Money
+}
+
+func ParseInterestRate(s string) (InterestRate, error) {
+	value, err := ParseMoney(s)
+	if err != nil || value.IsNegative() || value.Cmp(MustParseMoney("1")) > 0 {
+		return InterestRate{}, ErrInvalidValue{Msg: fmt.Sprintf("invalid interest rate %q, it should be between 0 and 1", s)}
+	}
+
+	return InterestRate{value: value.normalize()}, nil
+}
+
+func MustParseInterestRate(s string) InterestRate {
+	rate, err := ParseInterestRate(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return rate
+}
+
+func (r InterestRate) IsZero() bool {
+	return r.value.IsZero()
+}
+
+func (r InterestRate) String() string {
+	return r.value.String()
+}
+
+func (r InterestRate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *InterestRate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return ErrInvalidValue{Msg: "interest rates must be encoded as strings"}
+	}
+
+	parsed, err := ParseInterestRate(s)
+	if err != nil {
+		return err
+	}
+
+	*r = parsed
+
+	return nil
+}
+
+func (r InterestRate) rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(r.value.units), big.NewInt(pow10(r.value.scale)))
+}
+
+// DayCount is the convention that tells the fraction of the year each day is
+// worth when accruing interest.
+type DayCount string
+
+const (
+	// DayCountActual365 counts every year as 365 days.
+	DayCountActual365 DayCount = "ACT/365"
+	// DayCountActual360 counts every year as 360 days, as money markets do.
+	DayCountActual360 DayCount = "ACT/360"
+	// DayCountActualActual counts the actual days of each year, so days of
+	// leap years are worth 1/366.
+	DayCountActualActual DayCount = "ACT/ACT"
+)
+
+func ParseDayCount(convention string) (DayCount, error) {
+	switch DayCount(convention) {
+	case DayCountActual365, DayCountActual360, DayCountActualActual:
+		return DayCount(convention), nil
+	}
+
+	return "", ErrInvalidValue{Msg: fmt.Sprintf("invalid day count convention %q", convention)}
+}
+
+// dayFraction returns the fraction of the year the day is worth.
+func (dc DayCount) dayFraction(day time.Time) *big.Rat {
+	switch dc {
+	case DayCountActual360:
+		return big.NewRat(1, 360)
+	case DayCountActualActual:
+		year := day.Year()
+		days := time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC).Sub(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC))
+		return big.NewRat(1, int64(days/(24*time.Hour)))
+	default:
+		return big.NewRat(1, 365)
+	}
+}
+
+// AccountInterest is how an account earns interest. Interest accrues daily on
+// the balance at the end of each day, and is posted to the account at the end
+// of each month.
+type AccountInterest struct {
+	InterestRate InterestRate `json:"interest_rate"`
+	// AccruedInterest is the interest earned but not posted yet.
+	AccruedInterest Money `json:"accrued_interest"`
+	// InterestAccruedThrough is the last day whose interest has been accrued.
+	// It's only set once the account has had interest terms.
+	InterestAccruedThrough *time.Time `json:"interest_accrued_through,omitempty"`
+}
+
+func (i AccountInterest) Equal(other AccountInterest) bool {
+	if (i.InterestAccruedThrough == nil) != (other.InterestAccruedThrough == nil) {
+		return false
+	}
+
+	if i.InterestAccruedThrough != nil && !i.InterestAccruedThrough.Equal(*other.InterestAccruedThrough) {
+		return false
+	}
+
+	return i.InterestRate == other.InterestRate && i.AccruedInterest == other.AccruedInterest
+}
+
+// InterestPosting is the interest to post to an account at the end of a day.
+type InterestPosting struct {
+	Date   time.Time
+	Amount Money
+}
+
+// SetInterestTerms changes the type and the interest rate of the account from
+// the given day on. Interest up to the day before must have been accrued with
+// the previous terms. Only savings accounts can have an interest rate.
+func (a *Account) SetInterestTerms(accountType AccountType, rate InterestRate, today time.Time) error {
+	if a.status() == AccountClosed {
+		return ErrAccountStatus{AccountID: a.ID, Status: a.status(), Operation: "change the interest terms of"}
+	}
+
+	if !rate.IsZero() && accountType != AccountSavings {
+		return ErrInvalidValue{Msg: "only savings accounts can earn interest"}
+	}
+
+	yesterday := Date(today).AddDate(0, 0, -1)
+	if through := a.InterestAccruedThrough; through != nil && through.Before(yesterday) {
+		return ErrInvalidValue{Msg: fmt.Sprintf("the interest of account %q is only accrued through %s", a.ID, through.Format(time.DateOnly))}
+	}
+
+	a.Type = accountType
+	a.InterestRate = rate
+	if a.InterestAccruedThrough == nil {
+		a.InterestAccruedThrough = &yesterday
+	}
+
+	if a.AccruedInterest == (Money{}) {
+		a.AccruedInterest = Money{scale: accruedInterestDecimals}
+	}
+
+	return nil
+}
+
+// AccruesInterestThrough reports whether the interest of the account still has
+// to be accrued through the given day.
+func (a *Account) AccruesInterestThrough(day time.Time) bool {
+	return a.InterestAccruedThrough != nil && a.InterestAccruedThrough.Before(Date(day))
+}
+
+// InterestAccrualStart returns the first day whose interest is still to be
+// accrued, or nil when the account doesn't accrue interest.
+func (a *Account) InterestAccrualStart() *time.Time {
+	if a.InterestAccruedThrough == nil {
+		return nil
+	}
+
+	start := a.InterestAccruedThrough.AddDate(0, 0, 1)
+
+	return &start
+}
+
+// AccrueInterest accrues the interest of every day after the last accrued one
+// through the given day, and returns the interest to post at the end of the
+// months in between. Each day earns on the balance at its end, worked out
+// back from the current one with the transactions of the account made since
+// the start of the accrual. Interest is posted in whole minor units, and the
+// rest stays accrued. Frozen accounts keep accruing interest, but it's only
+// posted once they're active again. The balance of the account isn't changed,
+// as posting the interest does it.
+func (a *Account) AccrueInterest(through time.Time, dayCount DayCount, transactions []Transaction) ([]InterestPosting, error) {
+	if !a.AccruesInterestThrough(through) {
+		return nil, nil
+	}
+
+	start := *a.InterestAccrualStart()
+	changes, balance, err := dailyBalanceChanges(a.Balance, start, transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		postings    []InterestPosting
+		earns       = a.Type == AccountSavings && !a.InterestRate.IsZero() && a.status() != AccountClosed
+		annualRate  = a.InterestRate.rat()
+		lastAccrued = Date(through)
+	)
+
+	for day := start; !day.After(lastAccrued); day = day.AddDate(0, 0, 1) {
+		if change, ok := changes[day]; ok {
+			if balance, err = balance.Add(change); err != nil {
+				return nil, err
+			}
+		}
+
+		if earns && balance.IsPositive() {
+			factor := new(big.Rat).Mul(annualRate, dayCount.dayFraction(day))
+			interest, err := balance.mulRat(factor, accruedInterestDecimals)
+			if err != nil {
+				return nil, err
+			}
+
+			if a.AccruedInterest, err = a.AccruedInterest.Add(interest); err != nil {
+				return nil, err
+			}
+		}
+
+		if day.AddDate(0, 0, 1).Day() != 1 || a.status() != AccountActive {
+			continue
+		}
+
+		amount, err := a.AccruedInterest.truncate(a.Currency.MinorUnits())
+		if err != nil {
+			return nil, err
+		}
+
+		if amount.IsPositive() {
+			postings = append(postings, InterestPosting{Date: day, Amount: amount})
+			if a.AccruedInterest, err = a.AccruedInterest.Sub(amount); err != nil {
+				return nil, err
+			}
+
+			if balance, err = balance.Add(amount); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	a.InterestAccruedThrough = &lastAccrued
+
+	return postings, nil
+}
+
+// dailyBalanceChanges adds up the balance changes of the transactions made
+// since start by day, and returns them with the balance at the start, taking
+// them back from the current balance.
+func dailyBalanceChanges(
+	balance Money,
+	start time.Time,
+	transactions []Transaction,
+) (map[time.Time]Money, Money, error) {
+	changes := make(map[time.Time]Money)
+	for _, transaction := range transactions {
+		if transaction.Timestamp.Before(start) {
+			continue
+		}
+
+		var err error
+		day := Date(transaction.Timestamp)
+		if changes[day], err = changes[day].Add(transaction.BalanceChange()); err != nil {
+			return nil, Money{}, err
+		}
+
+		if balance, err = balance.Sub(transaction.BalanceChange()); err != nil {
+			return nil, Money{}, err
+		}
+	}
+
+	return changes, balance, nil
+}
+
+// NewInterestTransaction returns the transaction that posts the interest to
+// the account, at the end of its day.
+func NewInterestTransaction(id string, account Account, posting InterestPosting) Transaction {
+	return Transaction{
+		ID:        id,
+		AccountID: account.ID,
+		Type:      TxInterest,
+		Amount:    posting.Amount,
+		Currency:  account.Currency,
+		Timestamp: posting.Date.AddDate(0, 0, 1).Add(-time.Second),
+	}
+}
+
+// Date returns the day of the time in UTC, at midnight.
+func Date(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
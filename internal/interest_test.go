@@ -0,0 +1,250 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterestRate_Parse(t *testing.T) {
+	testCases := map[string]struct {
+		rate          string
+		expected      string
+		expectedError error
+	}{
+		"Rate":         {rate: "0.025", expected: "0.025"},
+		"Zero":         {rate: "0", expected: "0"},
+		"Trailing 0s":  {rate: "0.0500", expected: "0.05"},
+		"Whole":        {rate: "1", expected: "1"},
+		"Negative":     {rate: "-0.01", expectedError: &internal.ErrInvalidValue{}},
+		"Above 100%":   {rate: "1.01", expectedError: &internal.ErrInvalidValue{}},
+		"Not a number": {rate: "2.5%", expectedError: &internal.ErrInvalidValue{}},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			rate, err := internal.ParseInterestRate(tc.rate)
+			if tc.expectedError != nil {
+				require.ErrorAs(t, err, tc.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, rate.String())
+		})
+	}
+}
+
+func TestAccount_SetInterestTerms(t *testing.T) {
+	today := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		accountType   internal.AccountType
+		rate          string
+		status        internal.AccountStatus
+		expectedError error
+	}{
+		"Savings":            {accountType: internal.AccountSavings, rate: "0.02"},
+		"Checking":           {accountType: internal.AccountChecking, rate: "0"},
+		"Frozen":             {accountType: internal.AccountSavings, rate: "0.02", status: internal.AccountFrozen},
+		"Checking with rate": {accountType: internal.AccountChecking, rate: "0.02", expectedError: &internal.ErrInvalidValue{}},
+		"Closed":             {accountType: internal.AccountSavings, rate: "0.02", status: internal.AccountClosed, expectedError: &internal.ErrAccountStatus{}},
+		"Savings at 0%":      {accountType: internal.AccountSavings, rate: "0"},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			account := newInterestAccount(t, "100")
+			account.Status = tc.status
+
+			err := account.SetInterestTerms(tc.accountType, internal.MustParseInterestRate(tc.rate), today)
+			if tc.expectedError != nil {
+				require.ErrorAs(t, err, tc.expectedError)
+				assert.Nil(t, account.InterestAccruedThrough)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.accountType, account.Type)
+			assert.Equal(t, tc.rate, account.InterestRate.String())
+			require.NotNil(t, account.InterestAccruedThrough)
+			assert.Equal(t, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), *account.InterestAccruedThrough)
+		})
+	}
+
+	t.Run("Interest not accrued", func(t *testing.T) {
+		account := newInterestAccount(t, "100")
+		require.NoError(t, account.SetInterestTerms(internal.AccountSavings, internal.MustParseInterestRate("0.02"), today))
+
+		err := account.SetInterestTerms(internal.AccountChecking, internal.InterestRate{}, today.AddDate(0, 0, 2))
+		require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+		assert.Equal(t, internal.AccountSavings, account.Type)
+	})
+}
+
+func TestAccount_AccrueInterest(t *testing.T) {
+	var (
+		today     = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+		endOfJan  = time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+		endOfFeb  = time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+		secondFeb = time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)
+	)
+
+	// The rates make every day of 2024 earn 0.10 on 1000.00 with each
+	// convention.
+	testCases := map[string]struct {
+		dayCount internal.DayCount
+		rate     string
+	}{
+		"ACT/365": {dayCount: internal.DayCountActual365, rate: "0.0365"},
+		"ACT/360": {dayCount: internal.DayCountActual360, rate: "0.036"},
+		"ACT/ACT": {dayCount: internal.DayCountActualActual, rate: "0.0366"},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			account := newInterestAccount(t, "1000")
+			require.NoError(t, account.SetInterestTerms(internal.AccountSavings, internal.MustParseInterestRate(tc.rate), today))
+
+			postings := accrueInterest(t, account, endOfJan.Add(12*time.Hour), tc.dayCount)
+			require.Len(t, postings, 1)
+			assert.Equal(t, endOfJan, postings[0].Date)
+			assert.Equal(t, internal.MustParseMoney("3.10"), postings[0].Amount)
+			assert.True(t, account.AccruedInterest.IsZero())
+			assert.Equal(t, endOfJan, *account.InterestAccruedThrough)
+			assert.Empty(t, accrueInterest(t, account, endOfJan, tc.dayCount), "accruing a day twice must do nothing")
+
+			// The posted interest earns interest from the next day on.
+			balance, err := account.Balance.Add(postings[0].Amount)
+			require.NoError(t, err)
+			account.Balance = balance
+			assert.Empty(t, accrueInterest(t, account, secondFeb, tc.dayCount))
+			assert.Zero(t, internal.MustParseMoney("0.20062").Cmp(account.AccruedInterest), account.AccruedInterest.String())
+		})
+	}
+
+	t.Run("Rest of the minor units carried", func(t *testing.T) {
+		account := newInterestAccount(t, "1000")
+		require.NoError(t, account.SetInterestTerms(internal.AccountSavings, internal.MustParseInterestRate("0.01"), today))
+
+		// Each day earns 0.027397, rounded to the accrued decimals.
+		postings := accrueInterest(t, account, endOfJan, internal.DayCountActual365)
+		require.Len(t, postings, 1)
+		assert.Equal(t, internal.MustParseMoney("0.84"), postings[0].Amount)
+		assert.Zero(t, internal.MustParseMoney("0.009307").Cmp(account.AccruedInterest), account.AccruedInterest.String())
+	})
+
+	t.Run("Frozen accounts post once active", func(t *testing.T) {
+		account := newInterestAccount(t, "1000")
+		require.NoError(t, account.SetInterestTerms(internal.AccountSavings, internal.MustParseInterestRate("0.0365"), today))
+		require.NoError(t, account.Freeze())
+
+		assert.Empty(t, accrueInterest(t, account, endOfJan, internal.DayCountActual365))
+		assert.Zero(t, internal.MustParseMoney("3.1").Cmp(account.AccruedInterest), account.AccruedInterest.String())
+
+		require.NoError(t, account.Unfreeze())
+		postings := accrueInterest(t, account, endOfFeb, internal.DayCountActual365)
+		require.Len(t, postings, 1)
+		assert.Equal(t, internal.MustParseMoney("6.00"), postings[0].Amount)
+	})
+
+	t.Run("Months ended in between", func(t *testing.T) {
+		account := newInterestAccount(t, "1000")
+		require.NoError(t, account.SetInterestTerms(internal.AccountSavings, internal.MustParseInterestRate("0.0365"), today))
+
+		postings := accrueInterest(t, account, endOfFeb, internal.DayCountActual365)
+		require.Len(t, postings, 2)
+		assert.Equal(t, endOfJan, postings[0].Date)
+		assert.Equal(t, endOfFeb, postings[1].Date)
+		// February earns 0.10031 a day on 1003.10.
+		assert.Equal(t, internal.MustParseMoney("2.90"), postings[1].Amount)
+		assert.Zero(t, internal.MustParseMoney("0.00899").Cmp(account.AccruedInterest), account.AccruedInterest.String())
+	})
+
+	t.Run("Balance changed within the accrual", func(t *testing.T) {
+		account := newInterestAccount(t, "1500")
+		require.NoError(t, account.SetInterestTerms(internal.AccountSavings, internal.MustParseInterestRate("0.0365"), today))
+
+		transaction := func(txType internal.TransactionType, amount string, timestamp time.Time) internal.Transaction {
+			return internal.Transaction{
+				ID:        uuid.NewString(),
+				AccountID: account.ID,
+				Type:      txType,
+				Amount:    internal.MustParseMoney(amount),
+				Currency:  account.Currency,
+				Timestamp: timestamp,
+			}
+		}
+
+		// The balance was 1000.00 through the 15th of January and 2000.00
+		// since, until the withdrawal after the accrued days.
+		postings := accrueInterest(t, account, endOfJan, internal.DayCountActual365,
+			transaction(internal.TxDeposit, "1000", time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC)),
+			transaction(internal.TxWithdrawal, "500", secondFeb.Add(9*time.Hour)),
+		)
+		require.Len(t, postings, 1)
+		assert.Equal(t, internal.MustParseMoney("4.70"), postings[0].Amount)
+		assert.True(t, account.AccruedInterest.IsZero())
+	})
+
+	t.Run("Checking accounts", func(t *testing.T) {
+		account := newInterestAccount(t, "1000")
+		require.NoError(t, account.SetInterestTerms(internal.AccountChecking, internal.InterestRate{}, today))
+
+		assert.Empty(t, accrueInterest(t, account, endOfFeb, internal.DayCountActual365))
+		assert.True(t, account.AccruedInterest.IsZero())
+		assert.Equal(t, endOfFeb, *account.InterestAccruedThrough)
+	})
+
+	t.Run("Without interest terms", func(t *testing.T) {
+		account := newInterestAccount(t, "1000")
+
+		assert.False(t, account.AccruesInterestThrough(endOfJan))
+		assert.Empty(t, accrueInterest(t, account, endOfJan, internal.DayCountActual365))
+		assert.Nil(t, account.InterestAccruedThrough)
+	})
+}
+
+func TestNewInterestTransaction(t *testing.T) {
+	account := newInterestAccount(t, "1000")
+	posting := internal.InterestPosting{Date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), Amount: internal.MustParseMoney("3.10")}
+
+	transaction := internal.NewInterestTransaction(uuid.NewString(), *account, posting)
+	assert.Equal(t, internal.TransactionType(internal.TxInterest), transaction.Type)
+	assert.Equal(t, time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC), transaction.Timestamp)
+
+	entry, err := internal.NewTransactionEntry(uuid.NewString(), transaction)
+	require.NoError(t, err)
+	assertBalanceOf(t, "3.10", []internal.JournalEntry{entry}, account.ID, "EUR")
+	assertBalanceOf(t, "-3.10", []internal.JournalEntry{entry}, internal.SystemAccountInterest, "EUR")
+}
+
+// accrueInterest accrues the interest of the account through the given day,
+// given its transactions made since the start of the accrual.
+func accrueInterest(
+	t *testing.T,
+	account *internal.Account,
+	through time.Time,
+	dayCount internal.DayCount,
+	transactions ...internal.Transaction,
+) []internal.InterestPosting {
+	t.Helper()
+
+	postings, err := account.AccrueInterest(through, dayCount, transactions)
+	require.NoError(t, err)
+
+	return postings
+}
+
+func newInterestAccount(t *testing.T, balance string) *internal.Account {
+	t.Helper()
+
+	account, err := internal.NewAccount(uuid.NewString(), "Test User", "EUR", internal.MustParseMoney(balance))
+	require.NoError(t, err)
+
+	return &account
+}
@@ -0,0 +1,95 @@
+// Package keylock serializes work by key within the process, such as the
+// operations on an account or the requests with the same idempotency key.
+package keylock
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Locks holds a mutex per key. Waiting for one can be abandoned when the
+// context is done, and it's removed once nobody holds nor waits for it.
+type Locks struct {
+	locks map[string]*lock
+	mutex *sync.Mutex
+}
+
+type lock struct {
+	held chan struct{}
+	refs int
+}
+
+func New() *Locks {
+	return &Locks{
+		locks: make(map[string]*lock),
+		mutex: &sync.Mutex{},
+	}
+}
+
+// Lock blocks until it holds the locks of all the keys, or the context is
+// done. The returned function releases them and can be called more than once.
+// Keys are locked in ascending order, which keeps two callers locking the same
+// keys in opposite orders from deadlocking.
+func (l *Locks) Lock(ctx context.Context, keys ...string) (func(), error) {
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	held := make([]string, 0, len(keys))
+	unlock := func() {
+		// Released in reverse order, although any order is deadlock free.
+		for i := len(held) - 1; i >= 0; i-- {
+			l.unlock(held[i])
+		}
+	}
+
+	for _, key := range keys {
+		if err := l.lock(ctx, key); err != nil {
+			unlock()
+			return nil, err
+		}
+		held = append(held, key)
+	}
+
+	var once sync.Once
+	return func() { once.Do(unlock) }, nil
+}
+
+func (l *Locks) lock(ctx context.Context, key string) error {
+	l.mutex.Lock()
+	lk, ok := l.locks[key]
+	if !ok {
+		lk = &lock{held: make(chan struct{}, 1)}
+		l.locks[key] = lk
+	}
+	lk.refs++
+	l.mutex.Unlock()
+
+	select {
+	case lk.held <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		l.release(key, lk)
+		return ctx.Err()
+	}
+}
+
+func (l *Locks) unlock(key string) {
+	l.mutex.Lock()
+	lk := l.locks[key]
+	l.mutex.Unlock()
+
+	<-lk.held
+	l.release(key, lk)
+}
+
+func (l *Locks) release(key string, lk *lock) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	lk.refs--
+	if lk.refs == 0 {
+		delete(l.locks, key)
+	}
+}
@@ -0,0 +1,85 @@
+package keylock_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jyisus/bank-server/internal/keylock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocks_Lock(t *testing.T) {
+	var (
+		locks = keylock.New()
+		ctx   = context.Background()
+	)
+
+	unlock, err := locks.Lock(ctx, "a", "b")
+	require.NoError(t, err)
+
+	otherUnlock, err := locks.Lock(ctx, "c")
+	require.NoError(t, err, "different keys should not block each other")
+	otherUnlock()
+
+	tests := map[string][]string{
+		"Same keys":    {"a", "b"},
+		"One held key": {"b", "c"},
+	}
+
+	for name, keys := range tests {
+		t.Run(name, func(t *testing.T) {
+			timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+
+			_, err := locks.Lock(timeoutCtx, keys...)
+			require.ErrorIs(t, err, context.DeadlineExceeded, "a held key should block")
+		})
+	}
+
+	unlock()
+	unlock()
+
+	unlock, err = locks.Lock(ctx, "c", "b", "a", "a")
+	require.NoError(t, err, "abandoned and released keys should be free")
+	unlock()
+}
+
+func TestLocks_Lock_OppositeOrders(t *testing.T) {
+	var (
+		locks = keylock.New()
+		ctx   = context.Background()
+		wg    sync.WaitGroup
+	)
+
+	for i := 0; i < 100; i++ {
+		keys := []string{"a", "b"}
+		if i%2 == 1 {
+			keys = []string{"b", "a"}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := locks.Lock(ctx, keys...)
+			if assert.NoError(t, err) {
+				unlock()
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("locking the same keys in opposite orders should not deadlock")
+	}
+}
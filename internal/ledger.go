@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// System accounts are the counterparts of the money entering, leaving or
+// being exchanged by the bank. They are not stored in the accounts repository.
+const (
+	SystemAccountCashIn  = "system:cash-in"
+	SystemAccountCashOut = "system:cash-out"
+	SystemAccountFX      = "system:fx"
+	// SystemAccountInterest pays the interest earned by the accounts.
+	SystemAccountInterest = "system:interest"
+	// SystemAccountRevenue collects the fees charged to the accounts.
+	SystemAccountRevenue = "system:revenue"
+	// SystemAccountSuspense holds the differences corrected reconciling the
+	// balances of the accounts with their history, until they're explained.
+	SystemAccountSuspense = "system:suspense"
+)
+
+func IsSystemAccount(accountID string) bool {
+	return strings.HasPrefix(accountID, "system:")
+}
+
+// Posting is one side of a journal entry. Amounts are signed from the account
+// holder's point of view: positive postings increase the account's balance and
+// negative ones decrease it.
+type Posting struct {
+	AccountID string   `json:"account_id"`
+	Amount    Money    `json:"amount"`
+	Currency  Currency `json:"currency"`
+}
+
+// JournalEntry is a balanced set of postings: for every currency the postings
+// add up to zero, so money is never created nor destroyed.
+type JournalEntry struct {
+	ID        string    `json:"id"`
+	Reference string    `json:"reference"`
+	Timestamp time.Time `json:"timestamp"`
+	Postings  []Posting `json:"postings"`
+}
+
+func NewJournalEntry(id, reference string, timestamp time.Time, postings ...Posting) (JournalEntry, error) {
+	entry := JournalEntry{
+		ID:        id,
+		Reference: reference,
+		Timestamp: timestamp,
+		Postings:  postings,
+	}
+
+	if err := entry.Validate(); err != nil {
+		return JournalEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// NewTransactionEntry returns the entry that books a deposit or a withdrawal
+// against the cash system accounts, or interest against the interest one.
+// Reversals are booked against the system account of the transaction they
+// reverse.
+func NewTransactionEntry(id string, transaction Transaction) (JournalEntry, error) {
+	amount := transaction.Amount
+	counterpart, reversedCounterpart := SystemAccountCashIn, SystemAccountCashOut
+	switch transaction.Type {
+	case TxWithdrawal:
+		amount = amount.Neg()
+		counterpart, reversedCounterpart = reversedCounterpart, counterpart
+	case TxInterest:
+		counterpart = SystemAccountInterest
+	case TxFee:
+		amount = amount.Neg()
+		counterpart = SystemAccountRevenue
+	}
+
+	if transaction.ReversalOf != "" {
+		counterpart = reversedCounterpart
+	}
+
+	return NewJournalEntry(
+		id,
+		transaction.ID,
+		transaction.Timestamp,
+		Posting{AccountID: transaction.AccountID, Amount: amount, Currency: transaction.Currency},
+		Posting{AccountID: counterpart, Amount: amount.Neg(), Currency: transaction.Currency},
+	)
+}
+
+// NewTransferEntry returns the entry that books a transfer. Cross-currency
+// transfers go through the FX system account so each currency stays balanced.
+func NewTransferEntry(id string, transfer Transfer) (JournalEntry, error) {
+	postings := []Posting{
+		{AccountID: transfer.SourceAccountID, Amount: transfer.SourceAmount.Neg(), Currency: transfer.SourceCurrency},
+		{AccountID: transfer.DestinationAccountID, Amount: transfer.DestinationAmount, Currency: transfer.DestinationCurrency},
+	}
+
+	if transfer.SourceCurrency != transfer.DestinationCurrency {
+		postings = append(postings,
+			Posting{AccountID: SystemAccountFX, Amount: transfer.SourceAmount, Currency: transfer.SourceCurrency},
+			Posting{AccountID: SystemAccountFX, Amount: transfer.DestinationAmount.Neg(), Currency: transfer.DestinationCurrency},
+		)
+	}
+
+	return NewJournalEntry(id, transfer.ID, transfer.Timestamp, postings...)
+}
+
+// NewOpeningEntry returns the entry that books the initial balance of an account.
+func NewOpeningEntry(id string, account Account, timestamp time.Time) (JournalEntry, error) {
+	return NewJournalEntry(
+		id,
+		account.ID,
+		timestamp,
+		Posting{AccountID: account.ID, Amount: account.Balance, Currency: account.Currency},
+		Posting{AccountID: SystemAccountCashIn, Amount: account.Balance.Neg(), Currency: account.Currency},
+	)
+}
+
+// NewAdjustmentEntry returns the entry that corrects the balance of the
+// account of the discrepancy to the one recomputed from its history, against
+// the suspense system account.
+func NewAdjustmentEntry(id, reference string, discrepancy Discrepancy, timestamp time.Time) (JournalEntry, error) {
+	return NewJournalEntry(
+		id,
+		reference,
+		timestamp,
+		Posting{AccountID: discrepancy.AccountID, Amount: discrepancy.Difference.Neg(), Currency: discrepancy.Currency},
+		Posting{AccountID: SystemAccountSuspense, Amount: discrepancy.Difference, Currency: discrepancy.Currency},
+	)
+}
+
+// Validate checks that the entry has postings and that debits equal credits
+// for every currency.
+func (e JournalEntry) Validate() error {
+	if len(e.Postings) < 2 {
+		return ErrInvalidValue{Msg: "journal entries need at least two postings"}
+	}
+
+	totals := make(map[Currency]Money)
+	for _, posting := range e.Postings {
+		if posting.AccountID == "" {
+			return ErrInvalidValue{Msg: "postings need an account"}
+		}
+
+		total, err := totals[posting.Currency].Add(posting.Amount)
+		if err != nil {
+			return err
+		}
+
+		totals[posting.Currency] = total
+	}
+
+	for currency, total := range totals {
+		if !total.IsZero() {
+			return ErrInvalidValue{Msg: fmt.Sprintf("journal entry %q is unbalanced by %s %s", e.ID, total, currency)}
+		}
+	}
+
+	return nil
+}
+
+// BalanceOf adds up the postings of the given account in the given currency.
+func BalanceOf(entries []JournalEntry, accountID string, currency Currency) (Money, error) {
+	balance := NewMoney(0, currency)
+	for _, entry := range entries {
+		for _, posting := range entry.Postings {
+			if posting.AccountID != accountID || posting.Currency != currency {
+				continue
+			}
+
+			var err error
+			if balance, err = balance.Add(posting.Amount); err != nil {
+				return Money{}, err
+			}
+		}
+	}
+
+	return balance, nil
+}
+
+// OpeningBalance returns the initial balance of the account booked by its
+// opening entry, the one referencing the account itself. Accounts without one
+// were opened before the ledger and are taken as opened empty.
+func OpeningBalance(entries []JournalEntry, accountID string, currency Currency) (Money, error) {
+	var opening []JournalEntry
+	for _, entry := range entries {
+		if entry.Reference == accountID {
+			opening = append(opening, entry)
+		}
+	}
+
+	return BalanceOf(opening, accountID, currency)
+}
@@ -0,0 +1,92 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalEntry_Validate(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		postings      []internal.Posting
+		expectedError error
+	}{
+		"Balanced": {
+			postings: []internal.Posting{
+				{AccountID: "a", Amount: internal.MustParseMoney("10.00"), Currency: "EUR"},
+				{AccountID: "b", Amount: internal.MustParseMoney("-10"), Currency: "EUR"},
+			},
+		},
+		"Unbalanced": {
+			postings: []internal.Posting{
+				{AccountID: "a", Amount: internal.MustParseMoney("10.00"), Currency: "EUR"},
+				{AccountID: "b", Amount: internal.MustParseMoney("-9.99"), Currency: "EUR"},
+			},
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Balanced total but mixed currencies": {
+			postings: []internal.Posting{
+				{AccountID: "a", Amount: internal.MustParseMoney("10.00"), Currency: "EUR"},
+				{AccountID: "b", Amount: internal.MustParseMoney("-10.00"), Currency: "USD"},
+			},
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Single posting": {
+			postings: []internal.Posting{
+				{AccountID: "a", Amount: internal.MustParseMoney("0"), Currency: "EUR"},
+			},
+			expectedError: &internal.ErrInvalidValue{},
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			_, err := internal.NewJournalEntry(uuid.NewString(), "ref", time.Now(), tc.postings...)
+			if tc.expectedError != nil {
+				require.ErrorAs(t, err, tc.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestJournalEntry_Transfer_CrossCurrency(t *testing.T) {
+	transfer := internal.Transfer{
+		ID:                   uuid.NewString(),
+		SourceAccountID:      "source",
+		DestinationAccountID: "destination",
+		Quote: internal.Quote{
+			SourceAmount:        internal.MustParseMoney("10.00"),
+			SourceCurrency:      "EUR",
+			DestinationAmount:   internal.MustParseMoney("10.83"),
+			DestinationCurrency: "USD",
+		},
+	}
+
+	entry, err := internal.NewTransferEntry(uuid.NewString(), transfer)
+	require.NoError(t, err)
+
+	entries := []internal.JournalEntry{entry}
+	assertBalanceOf(t, "-10.00", entries, "source", "EUR")
+	assertBalanceOf(t, "10.83", entries, "destination", "USD")
+	assertBalanceOf(t, "10.00", entries, internal.SystemAccountFX, "EUR")
+	assertBalanceOf(t, "-10.83", entries, internal.SystemAccountFX, "USD")
+}
+
+// assertBalanceOf checks that the postings of the account in the currency add
+// up to the expected amount.
+func assertBalanceOf(t *testing.T, expected string, entries []internal.JournalEntry, accountID string, currency internal.Currency) {
+	t.Helper()
+
+	balance, err := internal.BalanceOf(entries, accountID, currency)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney(expected), balance)
+}
@@ -2,7 +2,7 @@ package memrepo
 
 import (
 	"context"
-	"errors"
+	"slices"
 	"sync"
 
 	"github.com/jyisus/bank-server/internal"
@@ -10,7 +10,10 @@ import (
 
 type AccountsRepository struct {
 	memAccounts map[string]internal.Account
-	mutex       *sync.Mutex
+	// ids keeps the creation order of the accounts.
+	ids   []string
+	mutex *sync.Mutex
+	wal   *WAL
 }
 
 var _ internal.AccountsRepository = (*AccountsRepository)(nil)
@@ -29,11 +32,21 @@ func (ar *AccountsRepository) Create(_ context.Context, account internal.Account
 	if _, ok := ar.memAccounts[account.ID]; ok {
 		return internal.ErrAccountAlreadyExists
 	}
-	ar.memAccounts[account.ID] = account
+
+	if err := ar.wal.write(createAccountOperation(account)); err != nil {
+		return err
+	}
+	ar.create(account)
 
 	return nil
 }
 
+// create stores a new account. The caller must hold the mutex.
+func (ar *AccountsRepository) create(account internal.Account) {
+	ar.memAccounts[account.ID] = account
+	ar.ids = append(ar.ids, account.ID)
+}
+
 func (ar *AccountsRepository) Get(_ context.Context, id string) (*internal.Account, error) {
 	ar.mutex.Lock()
 	defer ar.mutex.Unlock()
@@ -50,24 +63,131 @@ func (ar *AccountsRepository) List(_ context.Context) ([]internal.Account, error
 	ar.mutex.Lock()
 	defer ar.mutex.Unlock()
 
-	accounts := make([]internal.Account, 0, len(ar.memAccounts))
-	for _, account := range ar.memAccounts {
-		accounts = append(accounts, account)
+	accounts := make([]internal.Account, 0, len(ar.ids))
+	for _, id := range ar.ids {
+		accounts = append(accounts, ar.memAccounts[id])
 	}
 
 	return accounts, nil
 }
 
-func (ar *AccountsRepository) UpdateBalance(_ context.Context, accountID string, newBalance float32) error {
-	account, ok := ar.memAccounts[accountID]
-	if !ok {
-		return errors.New("account with given ID not found")
+func (ar *AccountsRepository) Find(ctx context.Context, query internal.AccountQuery) ([]internal.Account, error) {
+	accounts, err := ar.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return findAccounts(accounts, query), nil
+}
+
+// findAccounts returns the accounts selected by the query out of all of them,
+// in creation order.
+func findAccounts(accounts []internal.Account, query internal.AccountQuery) []internal.Account {
+	if query.After != "" {
+		i := slices.IndexFunc(accounts, func(account internal.Account) bool { return account.ID == query.After })
+		if i < 0 {
+			return []internal.Account{}
+		}
+		accounts = accounts[i+1:]
 	}
 
+	found := make([]internal.Account, 0)
+	for _, account := range accounts {
+		if query.Limit > 0 && len(found) == query.Limit {
+			break
+		}
+
+		if query.Matches(account) {
+			found = append(found, account)
+		}
+	}
+
+	return found
+}
+
+func (ar *AccountsRepository) UpdateBalance(
+	_ context.Context,
+	accountID string,
+	expectedVersion int64,
+	newBalance internal.Money,
+) error {
+	return ar.update(accountID, expectedVersion, updateBalanceOperation, func(account *internal.Account) {
+		account.Balance = newBalance
+	})
+}
+
+func (ar *AccountsRepository) UpdateStatus(
+	_ context.Context,
+	accountID string,
+	expectedVersion int64,
+	status internal.AccountStatus,
+) error {
+	return ar.update(accountID, expectedVersion, updateStatusOperation, func(account *internal.Account) {
+		account.Status = status
+	})
+}
+
+func (ar *AccountsRepository) UpdateOverdraftLimit(
+	_ context.Context,
+	accountID string,
+	expectedVersion int64,
+	limit internal.Money,
+) error {
+	return ar.update(accountID, expectedVersion, updateOverdraftLimitOperation, func(account *internal.Account) {
+		account.OverdraftLimit = limit
+	})
+}
+
+func (ar *AccountsRepository) UpdateHeld(
+	_ context.Context,
+	accountID string,
+	expectedVersion int64,
+	held internal.Money,
+) error {
+	return ar.update(accountID, expectedVersion, updateHeldOperation, func(account *internal.Account) {
+		account.Held = held
+	})
+}
+
+func (ar *AccountsRepository) UpdateInterest(
+	_ context.Context,
+	accountID string,
+	expectedVersion int64,
+	accountType internal.AccountType,
+	interest internal.AccountInterest,
+) error {
+	return ar.update(accountID, expectedVersion, updateInterestOperation, func(account *internal.Account) {
+		account.Type = accountType
+		account.AccountInterest = interest
+	})
+}
+
+// update changes the account as long as it's at the expected version, and
+// logs the change with the given operation.
+func (ar *AccountsRepository) update(
+	accountID string,
+	expectedVersion int64,
+	toOperation func(internal.Account) operation,
+	change func(*internal.Account),
+) error {
 	ar.mutex.Lock()
 	defer ar.mutex.Unlock()
 
-	account.Balance = newBalance
+	account, ok := ar.memAccounts[accountID]
+	if !ok {
+		return internal.ErrAccountNotFound{AccountID: accountID}
+	}
+
+	if account.Version != expectedVersion {
+		return internal.ErrVersionConflict{AccountID: accountID, ExpectedVersion: expectedVersion}
+	}
+
+	change(&account)
+	account.Version++
+
+	if err := ar.wal.write(toOperation(account)); err != nil {
+		return err
+	}
 	ar.memAccounts[accountID] = account
 
 	return nil
@@ -0,0 +1,101 @@
+package memrepo
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+type BalanceSnapshotsRepository struct {
+	// snapshots holds the snapshots of each account ordered by time.
+	snapshots map[string][]internal.BalanceSnapshot
+	mutex     *sync.RWMutex
+	wal       *WAL
+}
+
+var _ internal.BalanceSnapshotsRepository = (*BalanceSnapshotsRepository)(nil)
+
+func NewBalanceSnapshotsRepository() *BalanceSnapshotsRepository {
+	return &BalanceSnapshotsRepository{
+		snapshots: make(map[string][]internal.BalanceSnapshot),
+		mutex:     &sync.RWMutex{},
+	}
+}
+
+func (br *BalanceSnapshotsRepository) Save(_ context.Context, snapshot internal.BalanceSnapshot) error {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+
+	if err := br.wal.write(saveBalanceSnapshotOperation(snapshot)); err != nil {
+		return err
+	}
+	br.save(snapshot)
+
+	return nil
+}
+
+// save stores the snapshot in its place. The caller must hold the mutex.
+func (br *BalanceSnapshotsRepository) save(snapshot internal.BalanceSnapshot) {
+	snapshots := br.snapshots[snapshot.AccountID]
+	i, found := slices.BinarySearchFunc(snapshots, snapshot.At, compareSnapshotTime)
+	if found {
+		snapshots[i] = snapshot
+		return
+	}
+
+	br.snapshots[snapshot.AccountID] = slices.Insert(snapshots, i, snapshot)
+}
+
+func (br *BalanceSnapshotsRepository) FindLatest(
+	_ context.Context,
+	accountID string,
+	at time.Time,
+) (*internal.BalanceSnapshot, error) {
+	br.mutex.RLock()
+	defer br.mutex.RUnlock()
+
+	snapshots := br.snapshots[accountID]
+	i, found := slices.BinarySearchFunc(snapshots, at, compareSnapshotTime)
+	if found {
+		snapshot := snapshots[i]
+		return &snapshot, nil
+	}
+
+	if i == 0 {
+		return nil, nil
+	}
+
+	snapshot := snapshots[i-1]
+
+	return &snapshot, nil
+}
+
+func (br *BalanceSnapshotsRepository) FindNext(
+	_ context.Context,
+	accountID string,
+	after time.Time,
+) (*internal.BalanceSnapshot, error) {
+	br.mutex.RLock()
+	defer br.mutex.RUnlock()
+
+	snapshots := br.snapshots[accountID]
+	i, found := slices.BinarySearchFunc(snapshots, after, compareSnapshotTime)
+	if found {
+		i++
+	}
+
+	if i == len(snapshots) {
+		return nil, nil
+	}
+
+	snapshot := snapshots[i]
+
+	return &snapshot, nil
+}
+
+func compareSnapshotTime(snapshot internal.BalanceSnapshot, t time.Time) int {
+	return snapshot.At.Compare(t)
+}
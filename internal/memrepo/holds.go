@@ -0,0 +1,169 @@
+package memrepo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+type HoldsRepository struct {
+	holds map[string]internal.Hold
+	mutex *sync.RWMutex
+	wal   *WAL
+}
+
+var _ internal.HoldsRepository = (*HoldsRepository)(nil)
+
+func NewHoldsRepository() *HoldsRepository {
+	return &HoldsRepository{
+		holds: make(map[string]internal.Hold),
+		mutex: &sync.RWMutex{},
+	}
+}
+
+func (hr *HoldsRepository) Create(_ context.Context, hold internal.Hold) error {
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+
+	if _, ok := hr.holds[hold.ID]; ok {
+		return errors.New("hold with given ID already exists")
+	}
+
+	if err := hr.wal.write(saveHoldOperation(hold)); err != nil {
+		return err
+	}
+	hr.holds[hold.ID] = hold
+
+	return nil
+}
+
+func (hr *HoldsRepository) Get(_ context.Context, id string) (internal.Hold, error) {
+	hr.mutex.RLock()
+	defer hr.mutex.RUnlock()
+
+	hold, ok := hr.holds[id]
+	if !ok {
+		return internal.Hold{}, internal.ErrHoldNotFound{HoldID: id}
+	}
+
+	return hold, nil
+}
+
+func (hr *HoldsRepository) Update(_ context.Context, hold internal.Hold) error {
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+
+	if _, ok := hr.holds[hold.ID]; !ok {
+		return internal.ErrHoldNotFound{HoldID: hold.ID}
+	}
+
+	if err := hr.wal.write(saveHoldOperation(hold)); err != nil {
+		return err
+	}
+	hr.holds[hold.ID] = hold
+
+	return nil
+}
+
+func (hr *HoldsRepository) FindExpired(_ context.Context, asOf time.Time) ([]internal.Hold, error) {
+	hr.mutex.RLock()
+	defer hr.mutex.RUnlock()
+
+	holds := make([]internal.Hold, 0)
+	for _, hold := range hr.holds {
+		if isExpired(hold, asOf) {
+			holds = append(holds, hold)
+		}
+	}
+	slices.SortFunc(holds, byExpiry)
+
+	return holds, nil
+}
+
+func isExpired(hold internal.Hold, asOf time.Time) bool {
+	return hold.Status == internal.HoldActive && !hold.ExpiresAt.After(asOf)
+}
+
+// byExpiry orders holds by expiry time, breaking ties by ID.
+func byExpiry(a, b internal.Hold) int {
+	if c := a.ExpiresAt.Compare(b.ExpiresAt); c != 0 {
+		return c
+	}
+
+	return cmp.Compare(a.ID, b.ID)
+}
+
+type txHolds struct {
+	base    *HoldsRepository
+	pending map[string]internal.Hold
+}
+
+func (th *txHolds) Create(ctx context.Context, hold internal.Hold) error {
+	if _, err := th.Get(ctx, hold.ID); err == nil {
+		return errors.New("hold with given ID already exists")
+	}
+
+	th.pending[hold.ID] = hold
+
+	return nil
+}
+
+func (th *txHolds) Get(ctx context.Context, id string) (internal.Hold, error) {
+	if hold, ok := th.pending[id]; ok {
+		return hold, nil
+	}
+
+	return th.base.Get(ctx, id)
+}
+
+func (th *txHolds) Update(ctx context.Context, hold internal.Hold) error {
+	if _, err := th.Get(ctx, hold.ID); err != nil {
+		return err
+	}
+
+	th.pending[hold.ID] = hold
+
+	return nil
+}
+
+func (th *txHolds) FindExpired(ctx context.Context, asOf time.Time) ([]internal.Hold, error) {
+	holds, err := th.base.FindExpired(ctx, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pending changes replace the stored holds.
+	holds = slices.DeleteFunc(holds, func(hold internal.Hold) bool {
+		_, ok := th.pending[hold.ID]
+		return ok
+	})
+	for _, hold := range th.pending {
+		if isExpired(hold, asOf) {
+			holds = append(holds, hold)
+		}
+	}
+	slices.SortFunc(holds, byExpiry)
+
+	return holds, nil
+}
+
+func (th *txHolds) operations() []operation {
+	operations := make([]operation, 0, len(th.pending))
+	for _, hold := range th.pending {
+		operations = append(operations, saveHoldOperation(hold))
+	}
+
+	return operations
+}
+
+// commit applies the changes. The caller must hold the repository's mutex.
+func (th *txHolds) commit() {
+	for id, hold := range th.pending {
+		th.base.holds[id] = hold
+	}
+}
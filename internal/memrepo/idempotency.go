@@ -0,0 +1,75 @@
+package memrepo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/keylock"
+)
+
+// sweepInterval is how often expired responses are removed.
+const sweepInterval = time.Minute
+
+type IdempotencyStore struct {
+	responses map[string]storedResponse
+	locks     *keylock.Locks
+	nextSweep time.Time
+	mutex     *sync.Mutex
+}
+
+var _ internal.IdempotencyStore = (*IdempotencyStore)(nil)
+
+type storedResponse struct {
+	response  internal.IdempotentResponse
+	expiresAt time.Time
+}
+
+func NewIdempotencyStore() *IdempotencyStore {
+	return &IdempotencyStore{
+		responses: make(map[string]storedResponse),
+		locks:     keylock.New(),
+		mutex:     &sync.Mutex{},
+	}
+}
+
+func (s *IdempotencyStore) Lock(ctx context.Context, key string) (func(), error) {
+	return s.locks.Lock(ctx, key)
+}
+
+func (s *IdempotencyStore) Get(_ context.Context, key string) (internal.IdempotentResponse, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.responses[key]
+	if !ok {
+		return internal.IdempotentResponse{}, false, nil
+	}
+
+	if !time.Now().Before(stored.expiresAt) {
+		delete(s.responses, key)
+		return internal.IdempotentResponse{}, false, nil
+	}
+
+	return stored.response, true, nil
+}
+
+func (s *IdempotencyStore) Save(_ context.Context, key string, response internal.IdempotentResponse, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for k, stored := range s.responses {
+			if !now.Before(stored.expiresAt) {
+				delete(s.responses, k)
+			}
+		}
+		s.nextSweep = now.Add(sweepInterval)
+	}
+
+	s.responses[key] = storedResponse{response: response, expiresAt: now.Add(ttl)}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package memrepo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/memrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyStore_Expiration(t *testing.T) {
+	var (
+		store    = memrepo.NewIdempotencyStore()
+		ctx      = context.Background()
+		response = internal.IdempotentResponse{Fingerprint: "fingerprint", StatusCode: 201, Body: []byte("{}")}
+	)
+
+	_, ok, err := store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Save(ctx, "key", response, 50*time.Millisecond))
+
+	stored, ok, err := store.Get(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, response, stored)
+
+	time.Sleep(60 * time.Millisecond)
+
+	_, ok, err = store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok, "expired responses should not be returned")
+}
+
+func TestIdempotencyStore_Lock(t *testing.T) {
+	var (
+		store = memrepo.NewIdempotencyStore()
+		ctx   = context.Background()
+	)
+
+	unlock, err := store.Lock(ctx, "key")
+	require.NoError(t, err)
+
+	otherUnlock, err := store.Lock(ctx, "other-key")
+	require.NoError(t, err, "different keys should not block each other")
+	otherUnlock()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	_, err = store.Lock(timeoutCtx, "key")
+	require.ErrorIs(t, err, context.DeadlineExceeded, "a held key should block")
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock, err := store.Lock(ctx, "key")
+		assert.NoError(t, err)
+		close(acquired)
+		unlock()
+	}()
+
+	unlock()
+	unlock()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("the key should be acquired once released")
+	}
+}
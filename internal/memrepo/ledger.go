@@ -0,0 +1,72 @@
+package memrepo
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+type LedgerRepository struct {
+	entries   []internal.JournalEntry
+	entryIDs  map[string]struct{}
+	byAccount map[string][]int
+	mutex     *sync.RWMutex
+	wal       *WAL
+}
+
+var _ internal.LedgerRepository = (*LedgerRepository)(nil)
+
+func NewLedgerRepository() *LedgerRepository {
+	return &LedgerRepository{
+		entryIDs:  make(map[string]struct{}),
+		byAccount: make(map[string][]int),
+		mutex:     &sync.RWMutex{},
+	}
+}
+
+func (lr *LedgerRepository) Append(_ context.Context, entry internal.JournalEntry) error {
+	lr.mutex.Lock()
+	defer lr.mutex.Unlock()
+
+	if _, ok := lr.entryIDs[entry.ID]; ok {
+		return errors.New("journal entry with given ID already exists")
+	}
+
+	if err := lr.wal.write(appendEntryOperation(entry)); err != nil {
+		return err
+	}
+	lr.append(entry)
+
+	return nil
+}
+
+// append stores the entry. The caller must hold the mutex.
+func (lr *LedgerRepository) append(entry internal.JournalEntry) {
+	lr.entries = append(lr.entries, entry)
+	lr.entryIDs[entry.ID] = struct{}{}
+
+	index := len(lr.entries) - 1
+	indexed := make(map[string]bool, len(entry.Postings))
+	for _, posting := range entry.Postings {
+		if !indexed[posting.AccountID] {
+			lr.byAccount[posting.AccountID] = append(lr.byAccount[posting.AccountID], index)
+			indexed[posting.AccountID] = true
+		}
+	}
+}
+
+// FindAllByAccount returns the entries with postings for the account in the
+// order they were appended.
+func (lr *LedgerRepository) FindAllByAccount(_ context.Context, accountID string) ([]internal.JournalEntry, error) {
+	lr.mutex.RLock()
+	defer lr.mutex.RUnlock()
+
+	entries := make([]internal.JournalEntry, 0, len(lr.byAccount[accountID]))
+	for _, index := range lr.byAccount[accountID] {
+		entries = append(entries, lr.entries[index])
+	}
+
+	return entries, nil
+}
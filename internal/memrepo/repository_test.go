@@ -0,0 +1,39 @@
+package memrepo_test
+
+import (
+	"testing"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/memrepo"
+	"github.com/jyisus/bank-server/internal/repotest"
+)
+
+func TestAccountsRepository(t *testing.T) {
+	repotest.TestAccountsRepository(t, func(t *testing.T) internal.AccountsRepository {
+		return memrepo.NewAccountsRepository()
+	})
+}
+
+func TestTransactionsRepository(t *testing.T) {
+	repotest.TestTransactionsRepository(t, func(t *testing.T) internal.TransactionsRepository {
+		return memrepo.NewTransactionsRepository()
+	})
+}
+
+func TestHoldsRepository(t *testing.T) {
+	repotest.TestHoldsRepository(t, func(t *testing.T) internal.HoldsRepository {
+		return memrepo.NewHoldsRepository()
+	})
+}
+
+func TestScheduledTransfersRepository(t *testing.T) {
+	repotest.TestScheduledTransfersRepository(t, func(t *testing.T) internal.ScheduledTransfersRepository {
+		return memrepo.NewScheduledTransfersRepository()
+	})
+}
+
+func TestBalanceSnapshotsRepository(t *testing.T) {
+	repotest.TestBalanceSnapshotsRepository(t, func(t *testing.T) internal.BalanceSnapshotsRepository {
+		return memrepo.NewBalanceSnapshotsRepository()
+	})
+}
@@ -0,0 +1,106 @@
+package memrepo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+type ScheduledTransfersRepository struct {
+	scheduledTransfers map[string]internal.ScheduledTransfer
+	mutex              *sync.RWMutex
+	wal                *WAL
+}
+
+var _ internal.ScheduledTransfersRepository = (*ScheduledTransfersRepository)(nil)
+
+func NewScheduledTransfersRepository() *ScheduledTransfersRepository {
+	return &ScheduledTransfersRepository{
+		scheduledTransfers: make(map[string]internal.ScheduledTransfer),
+		mutex:              &sync.RWMutex{},
+	}
+}
+
+func (sr *ScheduledTransfersRepository) Create(_ context.Context, scheduledTransfer internal.ScheduledTransfer) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if _, ok := sr.scheduledTransfers[scheduledTransfer.ID]; ok {
+		return errors.New("scheduled transfer with given ID already exists")
+	}
+
+	if err := sr.wal.write(saveScheduledTransferOperation(scheduledTransfer)); err != nil {
+		return err
+	}
+	sr.scheduledTransfers[scheduledTransfer.ID] = scheduledTransfer
+
+	return nil
+}
+
+func (sr *ScheduledTransfersRepository) Get(_ context.Context, id string) (internal.ScheduledTransfer, error) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	scheduledTransfer, ok := sr.scheduledTransfers[id]
+	if !ok {
+		return internal.ScheduledTransfer{}, internal.ErrScheduledTransferNotFound{ScheduledTransferID: id}
+	}
+
+	return scheduledTransfer, nil
+}
+
+func (sr *ScheduledTransfersRepository) Update(_ context.Context, scheduledTransfer internal.ScheduledTransfer) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if _, ok := sr.scheduledTransfers[scheduledTransfer.ID]; !ok {
+		return internal.ErrScheduledTransferNotFound{ScheduledTransferID: scheduledTransfer.ID}
+	}
+
+	if err := sr.wal.write(saveScheduledTransferOperation(scheduledTransfer)); err != nil {
+		return err
+	}
+	sr.scheduledTransfers[scheduledTransfer.ID] = scheduledTransfer
+
+	return nil
+}
+
+func (sr *ScheduledTransfersRepository) FindAllByAccount(_ context.Context, accountID string) ([]internal.ScheduledTransfer, error) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	scheduledTransfers := make([]internal.ScheduledTransfer, 0)
+	for _, scheduledTransfer := range sr.scheduledTransfers {
+		if scheduledTransfer.SourceAccountID == accountID {
+			scheduledTransfers = append(scheduledTransfers, scheduledTransfer)
+		}
+	}
+	slices.SortFunc(scheduledTransfers, func(a, b internal.ScheduledTransfer) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+
+	return scheduledTransfers, nil
+}
+
+func (sr *ScheduledTransfersRepository) FindDue(_ context.Context, asOf time.Time) ([]internal.ScheduledTransfer, error) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	scheduledTransfers := make([]internal.ScheduledTransfer, 0)
+	for _, scheduledTransfer := range sr.scheduledTransfers {
+		if scheduledTransfer.IsDue(asOf) {
+			scheduledTransfers = append(scheduledTransfers, scheduledTransfer)
+		}
+	}
+	slices.SortFunc(scheduledTransfers, func(a, b internal.ScheduledTransfer) int {
+		return cmp.Or(a.DueAt().Compare(b.DueAt()), strings.Compare(a.ID, b.ID))
+	})
+
+	return scheduledTransfers, nil
+}
@@ -1,14 +1,20 @@
 package memrepo
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/jyisus/bank-server/internal"
 )
 
 type TransactionsReposiory struct {
 	transactions map[string]internal.Transaction
+	mutex        *sync.RWMutex
+	wal          *WAL
 }
 
 var _ internal.TransactionsRepository = (*TransactionsReposiory)(nil)
@@ -16,35 +22,115 @@ var _ internal.TransactionsRepository = (*TransactionsReposiory)(nil)
 func NewTransactionsRepository() *TransactionsReposiory {
 	return &TransactionsReposiory{
 		transactions: make(map[string]internal.Transaction),
+		mutex:        &sync.RWMutex{},
 	}
 }
 
 func (tr *TransactionsReposiory) Save(_ context.Context, transaction internal.Transaction) error {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+
 	if _, ok := tr.transactions[transaction.ID]; ok {
 		return errors.New("transaction with given ID already exists")
 	}
 
+	if transaction.TransferID != "" {
+		for _, saved := range tr.transactions {
+			if sameTransferLeg(saved, transaction) {
+				return internal.ErrTransferExists{TransferID: transaction.TransferID}
+			}
+		}
+	}
+
+	if err := tr.wal.write(saveTransactionOperation(transaction)); err != nil {
+		return err
+	}
 	tr.transactions[transaction.ID] = transaction
 
 	return nil
 }
 
 func (tr *TransactionsReposiory) Get(_ context.Context, id string) (internal.Transaction, error) {
+	tr.mutex.RLock()
+	defer tr.mutex.RUnlock()
+
 	transaction, ok := tr.transactions[id]
 	if !ok {
-		return internal.Transaction{}, errors.New("transaction not found")
+		return internal.Transaction{}, internal.ErrTransactionNotFound{TransactionID: id}
 	}
 
 	return transaction, nil
 }
 
 func (tr *TransactionsReposiory) FindAllByAccount(_ context.Context, accountID string) ([]internal.Transaction, error) {
+	tr.mutex.RLock()
+	defer tr.mutex.RUnlock()
+
 	transactions := make([]internal.Transaction, 0, len(tr.transactions))
 	for _, transaction := range tr.transactions {
 		if transaction.AccountID == accountID {
 			transactions = append(transactions, transaction)
 		}
 	}
+	slices.SortFunc(transactions, chronologically)
 
 	return transactions, nil
 }
+
+func (tr *TransactionsReposiory) Find(_ context.Context, query internal.TransactionQuery) ([]internal.Transaction, error) {
+	tr.mutex.RLock()
+	defer tr.mutex.RUnlock()
+
+	transactions := make([]internal.Transaction, 0)
+	for _, transaction := range tr.transactions {
+		if query.Matches(transaction) {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	return sortAndLimit(transactions, query), nil
+}
+
+// sameTransferLeg reports whether both transactions move the same transfer
+// in the same direction on the same account.
+func sameTransferLeg(a, b internal.Transaction) bool {
+	return a.TransferID != "" && a.TransferID == b.TransferID && a.AccountID == b.AccountID && a.Type == b.Type
+}
+
+func (tr *TransactionsReposiory) FindAllByTransfer(_ context.Context, transferID string) ([]internal.Transaction, error) {
+	tr.mutex.RLock()
+	defer tr.mutex.RUnlock()
+
+	var transactions []internal.Transaction
+	for _, transaction := range tr.transactions {
+		if transaction.TransferID == transferID {
+			transactions = append(transactions, transaction)
+		}
+	}
+	slices.SortFunc(transactions, byID)
+
+	return transactions, nil
+}
+
+// chronologically orders transactions by timestamp, breaking ties by ID.
+func chronologically(a, b internal.Transaction) int {
+	return cmp.Or(a.Timestamp.Compare(b.Timestamp), strings.Compare(a.ID, b.ID))
+}
+
+// sortAndLimit orders the transactions as the query does and keeps the first
+// ones up to its limit.
+func sortAndLimit(transactions []internal.Transaction, query internal.TransactionQuery) []internal.Transaction {
+	slices.SortFunc(transactions, func(a, b internal.Transaction) int {
+		return query.Compare(internal.CursorOf(a), internal.CursorOf(b))
+	})
+
+	if query.Limit > 0 && len(transactions) > query.Limit {
+		transactions = transactions[:query.Limit]
+	}
+
+	return transactions
+}
+
+func byID(a, b internal.Transaction) int {
+	return strings.Compare(a.ID, b.ID)
+}
@@ -0,0 +1,496 @@
+package memrepo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+// UnitOfWork runs transactions over the in-memory repositories. Changes made
+// through a transaction are buffered and only applied to the repositories on
+// commit. Transactions are serialized, so one never sees another's changes
+// before they are committed.
+type UnitOfWork struct {
+	accounts     *AccountsRepository
+	transactions *TransactionsReposiory
+	ledger       *LedgerRepository
+	holds        *HoldsRepository
+	mutex        *sync.Mutex
+}
+
+var _ internal.UnitOfWork = (*UnitOfWork)(nil)
+
+func NewUnitOfWork(
+	accounts *AccountsRepository,
+	transactions *TransactionsReposiory,
+	ledger *LedgerRepository,
+	holds *HoldsRepository,
+) *UnitOfWork {
+	return &UnitOfWork{
+		accounts:     accounts,
+		transactions: transactions,
+		ledger:       ledger,
+		holds:        holds,
+		mutex:        &sync.Mutex{},
+	}
+}
+
+func (u *UnitOfWork) Begin(_ context.Context) (internal.Tx, error) {
+	u.mutex.Lock()
+
+	return &tx{
+		uow: u,
+		accounts: &txAccounts{
+			base:         u.accounts,
+			pending:      make(map[string]internal.Account),
+			baseVersions: make(map[string]int64),
+		},
+		transactions: &txTransactions{
+			base:    u.transactions,
+			pending: make(map[string]internal.Transaction),
+		},
+		ledger: &txLedger{
+			base:     u.ledger,
+			entryIDs: make(map[string]struct{}),
+		},
+		holds: &txHolds{
+			base:    u.holds,
+			pending: make(map[string]internal.Hold),
+		},
+	}, nil
+}
+
+type tx struct {
+	uow          *UnitOfWork
+	accounts     *txAccounts
+	transactions *txTransactions
+	ledger       *txLedger
+	holds        *txHolds
+	done         bool
+}
+
+func (t *tx) Accounts() internal.AccountsRepository {
+	return t.accounts
+}
+
+func (t *tx) Transactions() internal.TransactionsRepository {
+	return t.transactions
+}
+
+func (t *tx) Ledger() internal.LedgerRepository {
+	return t.ledger
+}
+
+func (t *tx) Holds() internal.HoldsRepository {
+	return t.holds
+}
+
+func (t *tx) Commit(_ context.Context) error {
+	if t.done {
+		return internal.ErrTxDone
+	}
+
+	u := t.uow
+	u.accounts.mutex.Lock()
+	defer u.accounts.mutex.Unlock()
+	u.transactions.mutex.Lock()
+	defer u.transactions.mutex.Unlock()
+	u.ledger.mutex.Lock()
+	defer u.ledger.mutex.Unlock()
+	u.holds.mutex.Lock()
+	defer u.holds.mutex.Unlock()
+
+	if err := t.accounts.check(); err != nil {
+		return err
+	}
+
+	// The whole transaction is logged as a single record, so it is either
+	// fully replayed or not at all.
+	var operations []operation
+	operations = append(operations, t.accounts.operations()...)
+	operations = append(operations, t.transactions.operations()...)
+	operations = append(operations, t.ledger.operations()...)
+	operations = append(operations, t.holds.operations()...)
+	if err := u.accounts.wal.write(operations...); err != nil {
+		return err
+	}
+
+	t.accounts.commit()
+	t.transactions.commit()
+	t.ledger.commit()
+	t.holds.commit()
+
+	t.finish()
+
+	return nil
+}
+
+func (t *tx) Rollback(_ context.Context) error {
+	if t.done {
+		return nil
+	}
+
+	t.finish()
+
+	return nil
+}
+
+func (t *tx) finish() {
+	t.done = true
+	t.uow.mutex.Unlock()
+}
+
+type txAccounts struct {
+	base    *AccountsRepository
+	pending map[string]internal.Account
+	// createdIDs keeps the order in which the accounts in pending were created
+	// within the transaction.
+	createdIDs []string
+	// baseVersions keeps the version of the updated accounts when they were
+	// read from the repository, to detect changes made outside the
+	// transaction before it commits.
+	baseVersions map[string]int64
+}
+
+func (ta *txAccounts) Create(ctx context.Context, account internal.Account) error {
+	if _, err := ta.Get(ctx, account.ID); err == nil {
+		return internal.ErrAccountAlreadyExists
+	}
+
+	ta.pending[account.ID] = account
+	ta.createdIDs = append(ta.createdIDs, account.ID)
+
+	return nil
+}
+
+func (ta *txAccounts) Get(ctx context.Context, id string) (*internal.Account, error) {
+	if account, ok := ta.pending[id]; ok {
+		return &account, nil
+	}
+
+	return ta.base.Get(ctx, id)
+}
+
+func (ta *txAccounts) List(ctx context.Context) ([]internal.Account, error) {
+	accounts, err := ta.base.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, account := range accounts {
+		if pending, ok := ta.pending[account.ID]; ok {
+			accounts[i] = pending
+		}
+	}
+
+	for _, id := range ta.createdIDs {
+		accounts = append(accounts, ta.pending[id])
+	}
+
+	return accounts, nil
+}
+
+func (ta *txAccounts) Find(ctx context.Context, query internal.AccountQuery) ([]internal.Account, error) {
+	accounts, err := ta.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return findAccounts(accounts, query), nil
+}
+
+func (ta *txAccounts) UpdateBalance(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	newBalance internal.Money,
+) error {
+	return ta.update(ctx, accountID, expectedVersion, func(account *internal.Account) {
+		account.Balance = newBalance
+	})
+}
+
+func (ta *txAccounts) UpdateStatus(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	status internal.AccountStatus,
+) error {
+	return ta.update(ctx, accountID, expectedVersion, func(account *internal.Account) {
+		account.Status = status
+	})
+}
+
+func (ta *txAccounts) UpdateOverdraftLimit(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	limit internal.Money,
+) error {
+	return ta.update(ctx, accountID, expectedVersion, func(account *internal.Account) {
+		account.OverdraftLimit = limit
+	})
+}
+
+func (ta *txAccounts) UpdateHeld(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	held internal.Money,
+) error {
+	return ta.update(ctx, accountID, expectedVersion, func(account *internal.Account) {
+		account.Held = held
+	})
+}
+
+func (ta *txAccounts) UpdateInterest(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	accountType internal.AccountType,
+	interest internal.AccountInterest,
+) error {
+	return ta.update(ctx, accountID, expectedVersion, func(account *internal.Account) {
+		account.Type = accountType
+		account.AccountInterest = interest
+	})
+}
+
+// update changes the account within the transaction as long as it's at the
+// expected version.
+func (ta *txAccounts) update(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	change func(*internal.Account),
+) error {
+	account, err := ta.Get(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	if account.Version != expectedVersion {
+		return internal.ErrVersionConflict{AccountID: accountID, ExpectedVersion: expectedVersion}
+	}
+
+	if _, ok := ta.pending[accountID]; !ok {
+		ta.baseVersions[accountID] = account.Version
+	}
+
+	change(account)
+	account.Version++
+	ta.pending[accountID] = *account
+
+	return nil
+}
+
+// check returns ErrVersionConflict when an account updated in the transaction
+// was changed outside it. The caller must hold the repository's mutex.
+func (ta *txAccounts) check() error {
+	for id, version := range ta.baseVersions {
+		if ta.base.memAccounts[id].Version != version {
+			return internal.ErrVersionConflict{AccountID: id, ExpectedVersion: version}
+		}
+	}
+
+	return nil
+}
+
+func (ta *txAccounts) operations() []operation {
+	var operations []operation
+	for _, id := range ta.createdIDs {
+		operations = append(operations, createAccountOperation(ta.pending[id]))
+	}
+
+	for id, account := range ta.pending {
+		if slices.Contains(ta.createdIDs, id) {
+			continue
+		}
+
+		base := ta.base.memAccounts[id]
+		operations = append(operations, updateBalanceOperation(account))
+		if account.Status != base.Status {
+			operations = append(operations, updateStatusOperation(account))
+		}
+		if account.OverdraftLimit != base.OverdraftLimit {
+			operations = append(operations, updateOverdraftLimitOperation(account))
+		}
+		if account.Held != base.Held {
+			operations = append(operations, updateHeldOperation(account))
+		}
+		if account.Type != base.Type || !account.AccountInterest.Equal(base.AccountInterest) {
+			operations = append(operations, updateInterestOperation(account))
+		}
+	}
+
+	return operations
+}
+
+// commit applies the changes. The caller must hold the repository's mutex.
+func (ta *txAccounts) commit() {
+	for _, id := range ta.createdIDs {
+		ta.base.create(ta.pending[id])
+	}
+
+	for id, account := range ta.pending {
+		ta.base.memAccounts[id] = account
+	}
+}
+
+type txTransactions struct {
+	base    *TransactionsReposiory
+	pending map[string]internal.Transaction
+}
+
+func (tt *txTransactions) Save(ctx context.Context, transaction internal.Transaction) error {
+	if _, err := tt.Get(ctx, transaction.ID); err == nil {
+		return errors.New("transaction with given ID already exists")
+	}
+
+	if transaction.TransferID != "" {
+		saved, err := tt.FindAllByTransfer(ctx, transaction.TransferID)
+		if err != nil {
+			return err
+		}
+
+		for _, other := range saved {
+			if sameTransferLeg(other, transaction) {
+				return internal.ErrTransferExists{TransferID: transaction.TransferID}
+			}
+		}
+	}
+
+	tt.pending[transaction.ID] = transaction
+
+	return nil
+}
+
+func (tt *txTransactions) Get(ctx context.Context, id string) (internal.Transaction, error) {
+	if transaction, ok := tt.pending[id]; ok {
+		return transaction, nil
+	}
+
+	return tt.base.Get(ctx, id)
+}
+
+func (tt *txTransactions) FindAllByAccount(ctx context.Context, accountID string) ([]internal.Transaction, error) {
+	transactions, err := tt.base.FindAllByAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transaction := range tt.pending {
+		if transaction.AccountID == accountID {
+			transactions = append(transactions, transaction)
+		}
+	}
+	slices.SortFunc(transactions, chronologically)
+
+	return transactions, nil
+}
+
+func (tt *txTransactions) Find(ctx context.Context, query internal.TransactionQuery) ([]internal.Transaction, error) {
+	// The limit is applied once the pending transactions are merged.
+	unlimited := query
+	unlimited.Limit = 0
+
+	transactions, err := tt.base.Find(ctx, unlimited)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transaction := range tt.pending {
+		if query.Matches(transaction) {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	return sortAndLimit(transactions, query), nil
+}
+
+func (tt *txTransactions) FindAllByTransfer(ctx context.Context, transferID string) ([]internal.Transaction, error) {
+	transactions, err := tt.base.FindAllByTransfer(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transaction := range tt.pending {
+		if transaction.TransferID == transferID {
+			transactions = append(transactions, transaction)
+		}
+	}
+	slices.SortFunc(transactions, byID)
+
+	return transactions, nil
+}
+
+func (tt *txTransactions) operations() []operation {
+	operations := make([]operation, 0, len(tt.pending))
+	for _, transaction := range tt.pending {
+		operations = append(operations, saveTransactionOperation(transaction))
+	}
+
+	return operations
+}
+
+// commit applies the changes. The caller must hold the repository's mutex.
+func (tt *txTransactions) commit() {
+	for id, transaction := range tt.pending {
+		tt.base.transactions[id] = transaction
+	}
+}
+
+type txLedger struct {
+	base     *LedgerRepository
+	pending  []internal.JournalEntry
+	entryIDs map[string]struct{}
+}
+
+func (tl *txLedger) Append(_ context.Context, entry internal.JournalEntry) error {
+	tl.base.mutex.RLock()
+	_, exists := tl.base.entryIDs[entry.ID]
+	tl.base.mutex.RUnlock()
+
+	if _, pending := tl.entryIDs[entry.ID]; exists || pending {
+		return errors.New("journal entry with given ID already exists")
+	}
+
+	tl.pending = append(tl.pending, entry)
+	tl.entryIDs[entry.ID] = struct{}{}
+
+	return nil
+}
+
+func (tl *txLedger) FindAllByAccount(ctx context.Context, accountID string) ([]internal.JournalEntry, error) {
+	entries, err := tl.base.FindAllByAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range tl.pending {
+		if slices.ContainsFunc(entry.Postings, func(p internal.Posting) bool { return p.AccountID == accountID }) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func (tl *txLedger) operations() []operation {
+	operations := make([]operation, 0, len(tl.pending))
+	for _, entry := range tl.pending {
+		operations = append(operations, appendEntryOperation(entry))
+	}
+
+	return operations
+}
+
+// commit applies the changes. The caller must hold the repository's mutex.
+func (tl *txLedger) commit() {
+	for _, entry := range tl.pending {
+		tl.base.append(entry)
+	}
+}
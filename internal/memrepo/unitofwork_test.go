@@ -0,0 +1,105 @@
+package memrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/memrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitOfWork_Commit(t *testing.T) {
+	var (
+		accountsRepo     = memrepo.NewAccountsRepository()
+		transactionsRepo = memrepo.NewTransactionsRepository()
+		ledgerRepo       = memrepo.NewLedgerRepository()
+		unitOfWork       = memrepo.NewUnitOfWork(accountsRepo, transactionsRepo, ledgerRepo, memrepo.NewHoldsRepository())
+		ctx              = context.Background()
+
+		account     = internal.Account{ID: uuid.NewString(), Currency: "EUR", Balance: internal.MustParseMoney("10.00")}
+		transaction = internal.Transaction{ID: uuid.NewString(), AccountID: account.ID, Type: internal.TxDeposit}
+		entry       = internal.JournalEntry{ID: uuid.NewString(), Postings: []internal.Posting{{AccountID: account.ID}}}
+	)
+
+	tx, err := unitOfWork.Begin(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, tx.Accounts().Create(ctx, account))
+	require.NoError(t, tx.Accounts().UpdateBalance(ctx, account.ID, 0, internal.MustParseMoney("20.00")))
+	require.NoError(t, tx.Transactions().Save(ctx, transaction))
+	require.NoError(t, tx.Ledger().Append(ctx, entry))
+
+	_, err = accountsRepo.Get(ctx, account.ID)
+	require.ErrorAs(t, err, &internal.ErrAccountNotFound{}, "changes must not be visible before commit")
+
+	txAccount, err := tx.Accounts().Get(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("20.00"), txAccount.Balance)
+
+	require.NoError(t, tx.Commit(ctx))
+	require.ErrorIs(t, tx.Commit(ctx), internal.ErrTxDone)
+	require.NoError(t, tx.Rollback(ctx))
+
+	repoAccount, err := accountsRepo.Get(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("20.00"), repoAccount.Balance)
+
+	_, err = transactionsRepo.Get(ctx, transaction.ID)
+	require.NoError(t, err)
+
+	entries, err := ledgerRepo.FindAllByAccount(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []internal.JournalEntry{entry}, entries)
+}
+
+func TestUnitOfWork_Rollback(t *testing.T) {
+	var (
+		accountsRepo     = memrepo.NewAccountsRepository()
+		transactionsRepo = memrepo.NewTransactionsRepository()
+		ledgerRepo       = memrepo.NewLedgerRepository()
+		unitOfWork       = memrepo.NewUnitOfWork(accountsRepo, transactionsRepo, ledgerRepo, memrepo.NewHoldsRepository())
+		ctx              = context.Background()
+
+		source      = internal.Account{ID: uuid.NewString(), Currency: "EUR", Balance: internal.MustParseMoney("10.00")}
+		destination = internal.Account{ID: uuid.NewString(), Currency: "EUR", Balance: internal.MustParseMoney("10.00")}
+		errFailure  = errors.New("failure")
+	)
+
+	require.NoError(t, accountsRepo.Create(ctx, source))
+	require.NoError(t, accountsRepo.Create(ctx, destination))
+
+	err := internal.RunInTx(ctx, unitOfWork, func(tx internal.Tx) error {
+		if err := tx.Accounts().UpdateBalance(ctx, source.ID, 0, internal.MustParseMoney("5.00")); err != nil {
+			return err
+		}
+
+		if err := tx.Ledger().Append(ctx, internal.JournalEntry{ID: uuid.NewString(), Timestamp: time.Now()}); err != nil {
+			return err
+		}
+
+		return errFailure
+	})
+	require.ErrorIs(t, err, errFailure)
+
+	repoSource, err := accountsRepo.Get(ctx, source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, source.Balance, repoSource.Balance)
+
+	repoDestination, err := accountsRepo.Get(ctx, destination.ID)
+	require.NoError(t, err)
+	assert.Equal(t, destination.Balance, repoDestination.Balance)
+
+	// The unit of work must be usable again after a rollback
+	require.NoError(t, internal.RunInTx(ctx, unitOfWork, func(tx internal.Tx) error {
+		return tx.Accounts().UpdateBalance(ctx, destination.ID, 0, internal.MustParseMoney("15.00"))
+	}))
+
+	repoDestination, err = accountsRepo.Get(ctx, destination.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("15.00"), repoDestination.Balance)
+}
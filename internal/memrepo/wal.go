@@ -0,0 +1,704 @@
+package memrepo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot"
+	// lockFileName is the file locked by the process using the log.
+	lockFileName = "lock"
+	// recordHeaderSize is the size of the length and the checksum that
+	// precede every record payload.
+	recordHeaderSize = 8
+	// maxRecordSize bounds the length read from a header, so a corrupted one
+	// isn't mistaken for a huge record.
+	maxRecordSize = 64 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// SyncPolicy tells when the write-ahead log is flushed to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes every record before the change is applied, so no
+	// acknowledged change is lost on a crash.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval flushes the log every WALOptions.SyncInterval. A crash may
+	// lose the changes of the last interval.
+	SyncInterval
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+type WALOptions struct {
+	Sync SyncPolicy
+	// SyncInterval is how often the log is flushed with SyncInterval.
+	SyncInterval time.Duration
+	// SnapshotEvery takes a snapshot and compacts the log after that many
+	// records. Zero disables automatic snapshots.
+	SnapshotEvery int
+}
+
+// WAL persists the in-memory repositories in an append-only log. Every change
+// is written as a checksummed record before being applied, and the log is
+// replayed on startup. Snapshots store the whole state so the log can be
+// truncated.
+//
+// The log lives in a directory with two files: wal.log, made of records
+// prefixed by their length and CRC-32C checksum, and snapshot, a single record
+// with the state up to a log sequence number. Records up to the snapshot's
+// sequence number are skipped on replay, so a crash between writing a
+// snapshot and truncating the log is harmless. The directory is locked while
+// the log is open, so no other process changes it meanwhile.
+type WAL struct {
+	dir     string
+	options WALOptions
+
+	accounts     *AccountsRepository
+	transactions *TransactionsReposiory
+	ledger       *LedgerRepository
+	holds        *HoldsRepository
+	schedules    *ScheduledTransfersRepository
+	snapshots    *BalanceSnapshotsRepository
+	// lock holds the lock of the directory until the log is closed.
+	lock *os.File
+
+	// mutex guards the fields below. It is always acquired after the
+	// repositories' mutexes.
+	mutex         *sync.Mutex
+	file          *os.File
+	size          int64
+	lsn           uint64
+	sinceSnapshot int
+	dirty         bool
+
+	snapshotRequests chan struct{}
+	stop             chan struct{}
+	done             chan struct{}
+}
+
+// record is a set of operations applied atomically.
+type record struct {
+	LSN        uint64      `json:"lsn"`
+	Operations []operation `json:"operations"`
+}
+
+const (
+	opCreateAccount   = "create_account"
+	opUpdateBalance   = "update_balance"
+	opUpdateStatus    = "update_status"
+	opUpdateOverdraft = "update_overdraft_limit"
+	opUpdateHeld      = "update_held"
+	opUpdateInterest  = "update_interest"
+	opSaveTransaction = "save_transaction"
+	opAppendEntry     = "append_entry"
+	opSaveHold        = "save_hold"
+	opSaveSchedule    = "save_scheduled_transfer"
+	opSaveSnapshot    = "save_balance_snapshot"
+)
+
+type operation struct {
+	Type        string                    `json:"type"`
+	Account     *internal.Account         `json:"account,omitempty"`
+	AccountID   string                    `json:"account_id,omitempty"`
+	Balance     *internal.Money           `json:"balance,omitempty"`
+	Status      internal.AccountStatus    `json:"status,omitempty"`
+	Limit       *internal.Money           `json:"limit,omitempty"`
+	Held        *internal.Money           `json:"held,omitempty"`
+	AccountType internal.AccountType      `json:"account_type,omitempty"`
+	Interest    *internal.AccountInterest `json:"interest,omitempty"`
+	Version     int64                     `json:"version,omitempty"`
+	Transaction *internal.Transaction     `json:"transaction,omitempty"`
+	Entry       *internal.JournalEntry    `json:"entry,omitempty"`
+	Hold        *internal.Hold            `json:"hold,omitempty"`
+
+	ScheduledTransfer *internal.ScheduledTransfer `json:"scheduled_transfer,omitempty"`
+	BalanceSnapshot   *internal.BalanceSnapshot   `json:"balance_snapshot,omitempty"`
+}
+
+func createAccountOperation(account internal.Account) operation {
+	return operation{Type: opCreateAccount, Account: &account}
+}
+
+// updateBalanceOperation records the new balance and version of the account.
+func updateBalanceOperation(account internal.Account) operation {
+	return operation{Type: opUpdateBalance, AccountID: account.ID, Balance: &account.Balance, Version: account.Version}
+}
+
+// updateStatusOperation records the new status and version of the account.
+func updateStatusOperation(account internal.Account) operation {
+	return operation{Type: opUpdateStatus, AccountID: account.ID, Status: account.Status, Version: account.Version}
+}
+
+// updateOverdraftLimitOperation records the new overdraft limit and version
+// of the account.
+func updateOverdraftLimitOperation(account internal.Account) operation {
+	return operation{Type: opUpdateOverdraft, AccountID: account.ID, Limit: &account.OverdraftLimit, Version: account.Version}
+}
+
+// updateHeldOperation records the new held amount and version of the account.
+func updateHeldOperation(account internal.Account) operation {
+	return operation{Type: opUpdateHeld, AccountID: account.ID, Held: &account.Held, Version: account.Version}
+}
+
+// updateInterestOperation records the new type, interest and version of the
+// account.
+func updateInterestOperation(account internal.Account) operation {
+	return operation{
+		Type:        opUpdateInterest,
+		AccountID:   account.ID,
+		AccountType: account.Type,
+		Interest:    &account.AccountInterest,
+		Version:     account.Version,
+	}
+}
+
+func saveTransactionOperation(transaction internal.Transaction) operation {
+	return operation{Type: opSaveTransaction, Transaction: &transaction}
+}
+
+func appendEntryOperation(entry internal.JournalEntry) operation {
+	return operation{Type: opAppendEntry, Entry: &entry}
+}
+
+// saveHoldOperation records the whole state of a new or updated hold.
+func saveHoldOperation(hold internal.Hold) operation {
+	return operation{Type: opSaveHold, Hold: &hold}
+}
+
+// saveScheduledTransferOperation records the whole state of a new or updated
+// scheduled transfer.
+func saveScheduledTransferOperation(scheduledTransfer internal.ScheduledTransfer) operation {
+	return operation{Type: opSaveSchedule, ScheduledTransfer: &scheduledTransfer}
+}
+
+func saveBalanceSnapshotOperation(balanceSnapshot internal.BalanceSnapshot) operation {
+	return operation{Type: opSaveSnapshot, BalanceSnapshot: &balanceSnapshot}
+}
+
+type snapshot struct {
+	LSN          uint64                  `json:"lsn"`
+	Accounts     []internal.Account      `json:"accounts"`
+	Transactions []internal.Transaction  `json:"transactions"`
+	Entries      []internal.JournalEntry `json:"entries"`
+	Holds        []internal.Hold         `json:"holds"`
+
+	ScheduledTransfers []internal.ScheduledTransfer `json:"scheduled_transfers"`
+	BalanceSnapshots   []internal.BalanceSnapshot   `json:"balance_snapshots"`
+}
+
+// OpenWAL restores the state stored in dir into the given empty repositories
+// and logs their changes from then on. A torn record at the end of the log,
+// left by a crash in the middle of a write, is discarded. It fails while the
+// log is open elsewhere, by this process or another one.
+func OpenWAL(
+	dir string,
+	options WALOptions,
+	accounts *AccountsRepository,
+	transactions *TransactionsReposiory,
+	ledger *LedgerRepository,
+	holds *HoldsRepository,
+	schedules *ScheduledTransfersRepository,
+	snapshots *BalanceSnapshotsRepository,
+) (*WAL, error) {
+	if options.Sync == SyncInterval && options.SyncInterval <= 0 {
+		return nil, errors.New("the sync interval should be greater than 0")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating WAL directory: %w", err)
+	}
+
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WAL{
+		dir:              dir,
+		options:          options,
+		accounts:         accounts,
+		transactions:     transactions,
+		ledger:           ledger,
+		holds:            holds,
+		schedules:        schedules,
+		snapshots:        snapshots,
+		lock:             lock,
+		mutex:            &sync.Mutex{},
+		snapshotRequests: make(chan struct{}, 1),
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+
+	if err := w.restoreSnapshot(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	if err := w.replay(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	accounts.wal = w
+	transactions.wal = w
+	ledger.wal = w
+	holds.wal = w
+	schedules.wal = w
+	snapshots.wal = w
+
+	go w.run()
+
+	return w, nil
+}
+
+// Snapshot stores the current state and truncates the log. Changes are
+// blocked while it runs.
+func (w *WAL) Snapshot() error {
+	w.accounts.mutex.Lock()
+	defer w.accounts.mutex.Unlock()
+	w.transactions.mutex.Lock()
+	defer w.transactions.mutex.Unlock()
+	w.ledger.mutex.Lock()
+	defer w.ledger.mutex.Unlock()
+	w.holds.mutex.Lock()
+	defer w.holds.mutex.Unlock()
+	w.schedules.mutex.Lock()
+	defer w.schedules.mutex.Unlock()
+	w.snapshots.mutex.Lock()
+	defer w.snapshots.mutex.Unlock()
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	state := snapshot{
+		LSN:          w.lsn,
+		Accounts:     make([]internal.Account, 0, len(w.accounts.ids)),
+		Transactions: make([]internal.Transaction, 0, len(w.transactions.transactions)),
+		Entries:      w.ledger.entries,
+		Holds:        make([]internal.Hold, 0, len(w.holds.holds)),
+
+		ScheduledTransfers: make([]internal.ScheduledTransfer, 0, len(w.schedules.scheduledTransfers)),
+		BalanceSnapshots:   make([]internal.BalanceSnapshot, 0),
+	}
+	for _, id := range w.accounts.ids {
+		state.Accounts = append(state.Accounts, w.accounts.memAccounts[id])
+	}
+	for _, transaction := range w.transactions.transactions {
+		state.Transactions = append(state.Transactions, transaction)
+	}
+	for _, hold := range w.holds.holds {
+		state.Holds = append(state.Holds, hold)
+	}
+	for _, scheduledTransfer := range w.schedules.scheduledTransfers {
+		state.ScheduledTransfers = append(state.ScheduledTransfers, scheduledTransfer)
+	}
+	for _, balanceSnapshots := range w.snapshots.snapshots {
+		state.BalanceSnapshots = append(state.BalanceSnapshots, balanceSnapshots...)
+	}
+
+	if err := w.writeSnapshot(state); err != nil {
+		return err
+	}
+
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("truncating WAL: %w", err)
+	}
+
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("syncing WAL: %w", err)
+	}
+
+	w.size = 0
+	w.sinceSnapshot = 0
+	w.dirty = false
+
+	return nil
+}
+
+// Close flushes and closes the log. The repositories must not be changed
+// afterwards.
+func (w *WAL) Close() error {
+	close(w.stop)
+	<-w.done
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	defer w.lock.Close()
+
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("syncing WAL: %w", err)
+	}
+
+	return w.file.Close()
+}
+
+// write appends the operations to the log as a single record. It does
+// nothing on a nil WAL, so repositories without persistence don't need to
+// check for it. The caller must hold the mutexes of the changed repositories.
+func (w *WAL) write(operations ...operation) error {
+	if w == nil || len(operations) == 0 {
+		return nil
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	payload, err := json.Marshal(record{LSN: w.lsn + 1, Operations: operations})
+	if err != nil {
+		return fmt.Errorf("encoding WAL record: %w", err)
+	}
+
+	data := frame(payload)
+	if _, err := w.file.Write(data); err != nil {
+		return w.discard(fmt.Errorf("writing WAL record: %w", err))
+	}
+
+	if w.options.Sync == SyncAlways {
+		if err := w.file.Sync(); err != nil {
+			return w.discard(fmt.Errorf("syncing WAL: %w", err))
+		}
+	} else {
+		w.dirty = true
+	}
+
+	w.size += int64(len(data))
+	w.lsn++
+	w.sinceSnapshot++
+
+	if w.options.SnapshotEvery > 0 && w.sinceSnapshot >= w.options.SnapshotEvery {
+		select {
+		case w.snapshotRequests <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// discard removes what was written of a failed record, so the change it
+// describes isn't replayed and later records aren't lost behind it.
+func (w *WAL) discard(err error) error {
+	if truncateErr := w.file.Truncate(w.size); truncateErr != nil {
+		return errors.Join(err, fmt.Errorf("discarding WAL record: %w", truncateErr))
+	}
+
+	return err
+}
+
+// run flushes the log and takes the snapshots in the background.
+func (w *WAL) run() {
+	defer close(w.done)
+
+	var tick <-chan time.Time
+	if w.options.Sync == SyncInterval {
+		ticker := time.NewTicker(w.options.SyncInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-tick:
+			if err := w.sync(); err != nil {
+				slog.Error("Syncing WAL", "error", err)
+			}
+		case <-w.snapshotRequests:
+			if err := w.Snapshot(); err != nil {
+				slog.Error("Taking WAL snapshot", "error", err)
+			}
+		}
+	}
+}
+
+func (w *WAL) sync() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.dirty {
+		return nil
+	}
+
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.dirty = false
+
+	return nil
+}
+
+func (w *WAL) restoreSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(w.dir, snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	payload, _, err := readRecord(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var state snapshot
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	for _, account := range state.Accounts {
+		w.accounts.create(account)
+	}
+	for _, transaction := range state.Transactions {
+		w.transactions.transactions[transaction.ID] = transaction
+	}
+	for _, entry := range state.Entries {
+		w.ledger.append(entry)
+	}
+	for _, hold := range state.Holds {
+		w.holds.holds[hold.ID] = hold
+	}
+	for _, scheduledTransfer := range state.ScheduledTransfers {
+		w.schedules.scheduledTransfers[scheduledTransfer.ID] = scheduledTransfer
+	}
+	for _, balanceSnapshot := range state.BalanceSnapshots {
+		w.snapshots.save(balanceSnapshot)
+	}
+	w.lsn = state.LSN
+
+	return nil
+}
+
+// replay applies the records of the log newer than the snapshot and leaves
+// the file ready to append new ones.
+func (w *WAL) replay() error {
+	file, err := os.OpenFile(filepath.Join(w.dir, walFileName), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening WAL: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("opening WAL: %w", err)
+	}
+
+	var (
+		reader = bufio.NewReader(file)
+		offset int64
+	)
+
+	for {
+		payload, size, err := readRecord(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		// A crash while appending the last record leaves it incomplete, or
+		// complete with part of its payload unwritten. It was never
+		// acknowledged, so it's dropped. Damage anywhere else means records
+		// that were acknowledged are lost, so the log isn't replayed.
+		torn := errors.Is(err, errTornRecord) || (errors.Is(err, errCorruptRecord) && offset+size == info.Size())
+		if torn {
+			if err := file.Truncate(offset); err != nil {
+				file.Close()
+				return fmt.Errorf("truncating torn WAL record: %w", err)
+			}
+			break
+		}
+
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("reading WAL record at offset %d: %w", offset, err)
+		}
+
+		var rec record
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			file.Close()
+			return fmt.Errorf("decoding WAL record at offset %d: %w", offset, err)
+		}
+
+		if rec.LSN > w.lsn {
+			w.apply(rec.Operations)
+			w.lsn = rec.LSN
+			w.sinceSnapshot++
+		}
+
+		offset += size
+	}
+
+	w.file = file
+	w.size = offset
+
+	return nil
+}
+
+func (w *WAL) apply(operations []operation) {
+	for _, op := range operations {
+		switch op.Type {
+		case opCreateAccount:
+			w.accounts.create(*op.Account)
+		case opUpdateBalance:
+			account := w.accounts.memAccounts[op.AccountID]
+			account.Balance = *op.Balance
+			account.Version = op.Version
+			w.accounts.memAccounts[op.AccountID] = account
+		case opUpdateStatus:
+			account := w.accounts.memAccounts[op.AccountID]
+			account.Status = op.Status
+			account.Version = op.Version
+			w.accounts.memAccounts[op.AccountID] = account
+		case opUpdateOverdraft:
+			account := w.accounts.memAccounts[op.AccountID]
+			account.OverdraftLimit = *op.Limit
+			account.Version = op.Version
+			w.accounts.memAccounts[op.AccountID] = account
+		case opUpdateHeld:
+			account := w.accounts.memAccounts[op.AccountID]
+			account.Held = *op.Held
+			account.Version = op.Version
+			w.accounts.memAccounts[op.AccountID] = account
+		case opUpdateInterest:
+			account := w.accounts.memAccounts[op.AccountID]
+			account.Type = op.AccountType
+			account.AccountInterest = *op.Interest
+			account.Version = op.Version
+			w.accounts.memAccounts[op.AccountID] = account
+		case opSaveTransaction:
+			w.transactions.transactions[op.Transaction.ID] = *op.Transaction
+		case opAppendEntry:
+			w.ledger.append(*op.Entry)
+		case opSaveHold:
+			w.holds.holds[op.Hold.ID] = *op.Hold
+		case opSaveSchedule:
+			w.schedules.scheduledTransfers[op.ScheduledTransfer.ID] = *op.ScheduledTransfer
+		case opSaveSnapshot:
+			w.snapshots.save(*op.BalanceSnapshot)
+		}
+	}
+}
+
+// writeSnapshot replaces the snapshot atomically: it's written to a temporary
+// file that is renamed once it's on stable storage.
+func (w *WAL) writeSnapshot(state snapshot) error {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	path := filepath.Join(w.dir, snapshotFileName)
+	tmp, err := os.CreateTemp(w.dir, snapshotFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(frame(payload)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing snapshot: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing snapshot: %w", err)
+	}
+
+	return syncDir(w.dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("opening WAL directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("syncing WAL directory: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	errTornRecord    = errors.New("incomplete record")
+	errCorruptRecord = errors.New("corrupt record")
+)
+
+// frame prefixes the payload with its length and checksum.
+func frame(payload []byte) []byte {
+	buf := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	copy(buf[recordHeaderSize:], payload)
+
+	return buf
+}
+
+// readRecord returns the next payload and the size of its record. It returns
+// io.EOF at the end of the input, errTornRecord when the input ends within the
+// record, and errCorruptRecord when its length is invalid or it doesn't match
+// its checksum, together with its size in the latter case.
+func readRecord(reader *bufio.Reader) ([]byte, int64, error) {
+	header := make([]byte, recordHeaderSize)
+	n, err := io.ReadFull(reader, header)
+	if errors.Is(err, io.EOF) {
+		return nil, 0, io.EOF
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, 0, errTornRecord
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > maxRecordSize {
+		return nil, 0, errCorruptRecord
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, 0, errTornRecord
+		}
+
+		return nil, 0, err
+	}
+
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, int64(n) + int64(length), errCorruptRecord
+	}
+
+	return payload, int64(n) + int64(length), nil
+}
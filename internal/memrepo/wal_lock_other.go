@@ -0,0 +1,20 @@
+//go:build !unix
+
+package memrepo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockDir only creates the lock file of the log's directory, as file locks
+// are only taken on Unix systems.
+func lockDir(dir string) (*os.File, error) {
+	file, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening WAL lock: %w", err)
+	}
+
+	return file, nil
+}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package memrepo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lockDir takes an exclusive lock on the directory of the log, so a single
+// process uses it at a time. The lock is released when the returned file is
+// closed, or when the process exits.
+func lockDir(dir string) (*os.File, error) {
+	file, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening WAL lock: %w", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("WAL directory %q is in use by another process", dir)
+		}
+
+		return nil, fmt.Errorf("locking WAL directory: %w", err)
+	}
+
+	return file, nil
+}
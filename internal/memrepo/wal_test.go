@@ -0,0 +1,412 @@
+package memrepo_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/memrepo"
+	"github.com/jyisus/bank-server/internal/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type persistedRepos struct {
+	accounts     *memrepo.AccountsRepository
+	transactions *memrepo.TransactionsReposiory
+	ledger       *memrepo.LedgerRepository
+	holds        *memrepo.HoldsRepository
+	schedules    *memrepo.ScheduledTransfersRepository
+	snapshots    *memrepo.BalanceSnapshotsRepository
+	unitOfWork   *memrepo.UnitOfWork
+	wal          *memrepo.WAL
+}
+
+// openRepos returns repositories restored from the WAL in dir.
+func openRepos(t *testing.T, dir string, options memrepo.WALOptions) persistedRepos {
+	t.Helper()
+
+	repos := persistedRepos{
+		accounts:     memrepo.NewAccountsRepository(),
+		transactions: memrepo.NewTransactionsRepository(),
+		ledger:       memrepo.NewLedgerRepository(),
+		holds:        memrepo.NewHoldsRepository(),
+		schedules:    memrepo.NewScheduledTransfersRepository(),
+		snapshots:    memrepo.NewBalanceSnapshotsRepository(),
+	}
+	repos.unitOfWork = memrepo.NewUnitOfWork(repos.accounts, repos.transactions, repos.ledger, repos.holds)
+
+	var err error
+	repos.wal, err = memrepo.OpenWAL(dir, options, repos.accounts, repos.transactions, repos.ledger, repos.holds, repos.schedules, repos.snapshots)
+	require.NoError(t, err)
+
+	return repos
+}
+
+func TestWAL_Conformance(t *testing.T) {
+	t.Run("Accounts", func(t *testing.T) {
+		repotest.TestAccountsRepository(t, func(t *testing.T) internal.AccountsRepository {
+			repos := openRepos(t, t.TempDir(), memrepo.WALOptions{Sync: memrepo.SyncNever, SnapshotEvery: 7})
+			t.Cleanup(func() { repos.wal.Close() })
+
+			return repos.accounts
+		})
+	})
+
+	t.Run("Transactions", func(t *testing.T) {
+		repotest.TestTransactionsRepository(t, func(t *testing.T) internal.TransactionsRepository {
+			repos := openRepos(t, t.TempDir(), memrepo.WALOptions{Sync: memrepo.SyncNever, SnapshotEvery: 7})
+			t.Cleanup(func() { repos.wal.Close() })
+
+			return repos.transactions
+		})
+	})
+
+	t.Run("ScheduledTransfers", func(t *testing.T) {
+		repotest.TestScheduledTransfersRepository(t, func(t *testing.T) internal.ScheduledTransfersRepository {
+			repos := openRepos(t, t.TempDir(), memrepo.WALOptions{Sync: memrepo.SyncNever, SnapshotEvery: 7})
+			t.Cleanup(func() { repos.wal.Close() })
+
+			return repos.schedules
+		})
+	})
+
+	t.Run("BalanceSnapshots", func(t *testing.T) {
+		repotest.TestBalanceSnapshotsRepository(t, func(t *testing.T) internal.BalanceSnapshotsRepository {
+			repos := openRepos(t, t.TempDir(), memrepo.WALOptions{Sync: memrepo.SyncNever, SnapshotEvery: 7})
+			t.Cleanup(func() { repos.wal.Close() })
+
+			return repos.snapshots
+		})
+	})
+}
+
+func TestWAL_Replay(t *testing.T) {
+	testCases := map[string]struct {
+		options  memrepo.WALOptions
+		snapshot bool
+	}{
+		"Sync always": {
+			options: memrepo.WALOptions{Sync: memrepo.SyncAlways},
+		},
+		"Sync interval": {
+			options: memrepo.WALOptions{Sync: memrepo.SyncInterval, SyncInterval: time.Millisecond},
+		},
+		"Sync never": {
+			options: memrepo.WALOptions{Sync: memrepo.SyncNever},
+		},
+		"From snapshot": {
+			options:  memrepo.WALOptions{Sync: memrepo.SyncAlways},
+			snapshot: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				dir   = t.TempDir()
+				repos = openRepos(t, dir, tc.options)
+				ctx   = context.Background()
+
+				account     = internal.Account{ID: uuid.NewString(), Owner: "Test", Currency: "EUR", Balance: internal.MustParseMoney("10.00")}
+				another     = internal.Account{ID: uuid.NewString(), Owner: "Another", Currency: "EUR", Balance: internal.MustParseMoney("0.00")}
+				transaction = internal.Transaction{
+					ID:        uuid.NewString(),
+					AccountID: account.ID,
+					Type:      internal.TxDeposit,
+					Amount:    internal.MustParseMoney("5.00"),
+					Currency:  "EUR",
+					Timestamp: time.Date(2024, 11, 24, 3, 26, 51, 0, time.UTC),
+				}
+				hold = internal.Hold{
+					ID:        uuid.NewString(),
+					AccountID: account.ID,
+					Amount:    internal.MustParseMoney("2.00"),
+					Currency:  "EUR",
+					Status:    internal.HoldActive,
+					CreatedAt: transaction.Timestamp,
+					ExpiresAt: transaction.Timestamp.Add(time.Hour),
+				}
+				scheduledTransfer = internal.ScheduledTransfer{
+					ID:              uuid.NewString(),
+					SourceAccountID: account.ID,
+					TransferPlan: internal.TransferPlan{
+						DestinationAccountID: another.ID,
+						Amount:               internal.MustParseMoney("1.00"),
+						Currency:             "EUR",
+						Schedule:             "@monthly",
+						StartAt:              transaction.Timestamp,
+					},
+					Status:    internal.ScheduledTransferActive,
+					NextRunAt: transaction.Timestamp.Add(time.Hour),
+					CreatedAt: transaction.Timestamp,
+				}
+				balanceSnapshot = internal.BalanceSnapshot{
+					AccountID: account.ID,
+					At:        transaction.Timestamp,
+					Balance:   internal.MustParseMoney("12.00"),
+					Currency:  "EUR",
+				}
+				entry = internal.JournalEntry{ID: uuid.NewString(), Reference: transaction.ID, Postings: []internal.Posting{
+					{AccountID: account.ID, Amount: internal.MustParseMoney("5.00"), Currency: "EUR"},
+					{AccountID: internal.SystemAccountCashIn, Amount: internal.MustParseMoney("-5.00"), Currency: "EUR"},
+				}}
+			)
+
+			require.NoError(t, repos.accounts.Create(ctx, account))
+			require.NoError(t, repos.accounts.UpdateBalance(ctx, account.ID, 0, internal.MustParseMoney("12.00")))
+			require.NoError(t, repos.schedules.Create(ctx, scheduledTransfer))
+			scheduledTransfer.Occurrences = 1
+			require.NoError(t, repos.schedules.Update(ctx, scheduledTransfer))
+			require.NoError(t, repos.snapshots.Save(ctx, balanceSnapshot))
+
+			err := internal.RunInTx(ctx, repos.unitOfWork, func(tx internal.Tx) error {
+				if err := tx.Accounts().Create(ctx, another); err != nil {
+					return err
+				}
+
+				if err := tx.Accounts().UpdateBalance(ctx, account.ID, 1, internal.MustParseMoney("17.00")); err != nil {
+					return err
+				}
+
+				if err := tx.Accounts().UpdateStatus(ctx, account.ID, 2, internal.AccountFrozen); err != nil {
+					return err
+				}
+
+				if err := tx.Transactions().Save(ctx, transaction); err != nil {
+					return err
+				}
+
+				if err := tx.Holds().Create(ctx, hold); err != nil {
+					return err
+				}
+
+				return tx.Ledger().Append(ctx, entry)
+			})
+			require.NoError(t, err)
+
+			err = internal.RunInTx(ctx, repos.unitOfWork, func(tx internal.Tx) error {
+				if err := tx.Accounts().UpdateBalance(ctx, account.ID, 3, internal.MustParseMoney("1.00")); err != nil {
+					return err
+				}
+
+				return errors.New("failure")
+			})
+			require.Error(t, err)
+
+			if tc.snapshot {
+				require.NoError(t, repos.wal.Snapshot())
+			}
+			require.NoError(t, repos.wal.Close())
+
+			restored := openRepos(t, dir, tc.options)
+			defer restored.wal.Close()
+
+			accounts, err := restored.accounts.List(ctx)
+			require.NoError(t, err)
+			account.Balance = internal.MustParseMoney("17.00")
+			account.Status = internal.AccountFrozen
+			account.Version = 3
+			assert.Equal(t, []internal.Account{account, another}, accounts)
+
+			restoredTransaction, err := restored.transactions.Get(ctx, transaction.ID)
+			require.NoError(t, err)
+			assert.Equal(t, transaction, restoredTransaction)
+
+			restoredHold, err := restored.holds.Get(ctx, hold.ID)
+			require.NoError(t, err)
+			assert.Equal(t, hold, restoredHold)
+
+			restoredScheduledTransfer, err := restored.schedules.Get(ctx, scheduledTransfer.ID)
+			require.NoError(t, err)
+			assert.Equal(t, scheduledTransfer, restoredScheduledTransfer)
+
+			restoredSnapshot, err := restored.snapshots.FindLatest(ctx, account.ID, balanceSnapshot.At)
+			require.NoError(t, err)
+			assert.Equal(t, &balanceSnapshot, restoredSnapshot)
+
+			entries, err := restored.ledger.FindAllByAccount(ctx, account.ID)
+			require.NoError(t, err)
+			assert.Equal(t, []internal.JournalEntry{entry}, entries)
+
+			require.ErrorIs(t, restored.accounts.Create(ctx, another), internal.ErrAccountAlreadyExists)
+		})
+	}
+}
+
+func TestWAL_TornRecord(t *testing.T) {
+	// Both cases damage the last record, as a crash in the middle of its write
+	// would.
+	testCases := map[string]func(t *testing.T, path string){
+		"Incomplete record": func(t *testing.T, path string) {
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+
+			require.NoError(t, os.Truncate(path, info.Size()-5))
+		},
+		"Checksum mismatch": func(t *testing.T, path string) {
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			data[len(data)-2] ^= 0xff
+			require.NoError(t, os.WriteFile(path, data, 0o644))
+		},
+	}
+
+	for testName, corrupt := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				dir   = t.TempDir()
+				repos = openRepos(t, dir, memrepo.WALOptions{Sync: memrepo.SyncAlways})
+				ctx   = context.Background()
+
+				account = internal.Account{ID: uuid.NewString(), Owner: "Test", Currency: "EUR", Balance: internal.MustParseMoney("10.00")}
+				torn    = internal.Account{ID: uuid.NewString(), Owner: "Torn", Currency: "EUR", Balance: internal.MustParseMoney("10.00")}
+				later   = internal.Account{ID: uuid.NewString(), Owner: "Later", Currency: "EUR", Balance: internal.MustParseMoney("10.00")}
+			)
+
+			require.NoError(t, repos.accounts.Create(ctx, account))
+			require.NoError(t, repos.accounts.Create(ctx, torn))
+			require.NoError(t, repos.wal.Close())
+
+			corrupt(t, filepath.Join(dir, "wal.log"))
+
+			restored := openRepos(t, dir, memrepo.WALOptions{Sync: memrepo.SyncAlways})
+			accounts, err := restored.accounts.List(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []internal.Account{account}, accounts)
+
+			// New records are appended after the last valid one.
+			require.NoError(t, restored.accounts.Create(ctx, later))
+			require.NoError(t, restored.wal.Close())
+
+			restored = openRepos(t, dir, memrepo.WALOptions{Sync: memrepo.SyncAlways})
+			defer restored.wal.Close()
+
+			accounts, err = restored.accounts.List(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []internal.Account{account, later}, accounts)
+		})
+	}
+}
+
+func TestWAL_CorruptRecord(t *testing.T) {
+	// Both cases damage the first record, which a crash can't do, so the
+	// records after it can't be trusted nor dropped.
+	testCases := map[string]func(data []byte){
+		"Checksum mismatch": func(data []byte) {
+			data[8] ^= 0xff
+		},
+		"Invalid length": func(data []byte) {
+			data[0] = 0xff
+		},
+	}
+
+	for testName, corrupt := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				dir   = t.TempDir()
+				path  = filepath.Join(dir, "wal.log")
+				repos = openRepos(t, dir, memrepo.WALOptions{Sync: memrepo.SyncAlways})
+				ctx   = context.Background()
+			)
+
+			for range 2 {
+				account := internal.Account{ID: uuid.NewString(), Owner: "Test", Currency: "EUR", Balance: internal.MustParseMoney("10.00")}
+				require.NoError(t, repos.accounts.Create(ctx, account))
+			}
+			require.NoError(t, repos.wal.Close())
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			corrupt(data)
+			require.NoError(t, os.WriteFile(path, data, 0o644))
+
+			_, err = memrepo.OpenWAL(
+				dir,
+				memrepo.WALOptions{Sync: memrepo.SyncAlways},
+				memrepo.NewAccountsRepository(),
+				memrepo.NewTransactionsRepository(),
+				memrepo.NewLedgerRepository(),
+				memrepo.NewHoldsRepository(),
+				memrepo.NewScheduledTransfersRepository(),
+				memrepo.NewBalanceSnapshotsRepository(),
+			)
+			require.Error(t, err)
+
+			actual, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, data, actual, "the log must be left as it is")
+		})
+	}
+}
+
+func TestWAL_Lock(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		options = memrepo.WALOptions{Sync: memrepo.SyncAlways}
+		repos   = openRepos(t, dir, options)
+	)
+
+	_, err := memrepo.OpenWAL(
+		dir,
+		options,
+		memrepo.NewAccountsRepository(),
+		memrepo.NewTransactionsRepository(),
+		memrepo.NewLedgerRepository(),
+		memrepo.NewHoldsRepository(),
+		memrepo.NewScheduledTransfersRepository(),
+		memrepo.NewBalanceSnapshotsRepository(),
+	)
+	require.Error(t, err, "the log must not be opened while it's open")
+
+	require.NoError(t, repos.wal.Close())
+
+	restored := openRepos(t, dir, options)
+	require.NoError(t, restored.wal.Close())
+}
+
+func TestWAL_Compaction(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		repos = openRepos(t, dir, memrepo.WALOptions{Sync: memrepo.SyncAlways, SnapshotEvery: 10})
+		ctx   = context.Background()
+		log   = filepath.Join(dir, "wal.log")
+	)
+
+	var accounts []internal.Account
+	for range 25 {
+		account := internal.Account{ID: uuid.NewString(), Owner: "Test", Currency: "EUR", Balance: internal.MustParseMoney("1.00")}
+		require.NoError(t, repos.accounts.Create(ctx, account))
+		accounts = append(accounts, account)
+	}
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, "snapshot"))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "a snapshot should be taken after 10 records")
+
+	require.NoError(t, repos.wal.Snapshot())
+
+	info, err := os.Stat(log)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "the log should be truncated after a snapshot")
+
+	require.NoError(t, repos.accounts.UpdateBalance(ctx, accounts[0].ID, 0, internal.MustParseMoney("3.00")))
+	require.NoError(t, repos.wal.Close())
+
+	restored := openRepos(t, dir, memrepo.WALOptions{Sync: memrepo.SyncAlways})
+	defer restored.wal.Close()
+
+	restoredAccounts, err := restored.accounts.List(ctx)
+	require.NoError(t, err)
+	accounts[0].Balance = internal.MustParseMoney("3.00")
+	accounts[0].Version = 1
+	assert.Equal(t, accounts, restoredAccounts)
+}
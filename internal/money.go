@@ -0,0 +1,329 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxMoneyDigits bounds the digits of every amount, whatever its scale, so
+// adding two of them never overflows an int64.
+const maxMoneyDigits = 18
+
+// maxMoneyUnits is the largest number of units an amount can have.
+const maxMoneyUnits int64 = 999_999_999_999_999_999
+
+// Money is an exact decimal amount stored as an integer number of units
+// scaled by 10^scale, so 20.30 is stored as {2030, 2}.
+type Money struct {
+	units int64
+	scale int32
+}
+
+// NewMoney returns the amount represented by the given minor units of the
+// currency, which must have at most maxMoneyDigits digits.
+func NewMoney(minorUnits int64, currency Currency) Money {
+	return Money{units: minorUnits, scale: currency.MinorUnits()}
+}
+
+// ParseMoney parses a decimal string such as "20.30" or "-5" without any loss of precision.
+func ParseMoney(s string) (Money, error) {
+	digits := s
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+	}
+
+	intPart, fracPart, hasDot := strings.Cut(digits, ".")
+	if intPart == "" || (hasDot && fracPart == "") || len(intPart)+len(fracPart) > maxMoneyDigits {
+		return Money{}, ErrInvalidValue{Msg: fmt.Sprintf("invalid amount %q", s)}
+	}
+
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return Money{}, ErrInvalidValue{Msg: fmt.Sprintf("invalid amount %q", s)}
+		}
+	}
+
+	units, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidValue{Msg: fmt.Sprintf("invalid amount %q", s)}
+	}
+
+	if strings.HasPrefix(s, "-") {
+		units = -units
+	}
+
+	return Money{units: units, scale: int32(len(fracPart))}, nil
+}
+
+// MustParseMoney is like ParseMoney but panics on invalid input. It is meant
+// for constants and tests.
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+// In validates that the amount fits the precision of the currency and returns
+// it expressed in the currency's minor units.
+func (m Money) In(currency Currency) (Money, error) {
+	m = m.normalize()
+	if m.scale > currency.MinorUnits() {
+		return Money{}, ErrInvalidValue{
+			Msg: fmt.Sprintf("amount %s has more than %d decimals allowed by %s", m, currency.MinorUnits(), currency),
+		}
+	}
+
+	return m.rescale(currency.MinorUnits())
+}
+
+// Round rounds the amount to the currency's minor units using half-even
+// (banker's) rounding.
+func (m Money) Round(currency Currency) (Money, error) {
+	target := currency.MinorUnits()
+	if m.scale <= target {
+		return m.rescale(target)
+	}
+
+	divisor := pow10(m.scale - target)
+	quotient, remainder := m.units/divisor, m.units%divisor
+	if remainder < 0 {
+		remainder = -remainder
+	}
+
+	switch half := divisor / 2; {
+	case remainder > half, remainder == half && quotient%2 != 0:
+		if m.units < 0 {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+
+	return Money{units: quotient, scale: target}, nil
+}
+
+// MinorUnits returns the amount as an integer number of the currency's minor
+// units. The amount must already be expressed in the currency.
+func (m Money) MinorUnits(currency Currency) (int64, error) {
+	rounded, err := m.Round(currency)
+	if err != nil {
+		return 0, err
+	}
+
+	return rounded.units, nil
+}
+
+// Add returns the sum of the amounts, or ErrInvalidValue when it's out of
+// range.
+func (m Money) Add(other Money) (Money, error) {
+	a, b, err := align(m, other)
+	if err != nil {
+		return Money{}, err
+	}
+
+	// Both have at most maxMoneyDigits digits, so the sum fits an int64.
+	return newBoundedMoney(a.units+b.units, a.scale)
+}
+
+// Sub returns the difference of the amounts, or ErrInvalidValue when it's out
+// of range.
+func (m Money) Sub(other Money) (Money, error) {
+	return m.Add(other.Neg())
+}
+
+func (m Money) Neg() Money {
+	return Money{units: -m.units, scale: m.scale}
+}
+
+// Cmp returns -1, 0 or +1 depending on whether m is lower, equal or greater than other.
+func (m Money) Cmp(other Money) int {
+	a, b, err := align(m, other)
+	if err != nil {
+		// The amounts can't be expressed with the same scale, but they can
+		// still be compared exactly.
+		return m.rat().Cmp(other.rat())
+	}
+
+	switch {
+	case a.units < b.units:
+		return -1
+	case a.units > b.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Money) IsZero() bool {
+	return m.units == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.units < 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.units > 0
+}
+
+func (m Money) String() string {
+	sign := ""
+	units := m.units
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	digits := strconv.FormatInt(units, 10)
+	if m.scale <= 0 {
+		return sign + digits
+	}
+
+	if pad := int(m.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+
+	point := len(digits) - int(m.scale)
+
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// MarshalJSON encodes the amount as a JSON string to avoid any float conversion.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON only accepts amounts encoded as JSON strings.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return ErrInvalidValue{Msg: "amounts must be encoded as strings"}
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+
+	return nil
+}
+
+// rat returns the exact value of the amount.
+func (m Money) rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(m.units), big.NewInt(pow10(m.scale)))
+}
+
+// mulRat multiplies the amount by factor and rounds the result half-even to
+// the given number of decimals.
+func (m Money) mulRat(factor *big.Rat, scale int32) (Money, error) {
+	if scale > maxMoneyDigits {
+		return Money{}, errMoneyOutOfRange
+	}
+
+	value := m.rat()
+	value.Mul(value, factor)
+
+	value.Mul(value, new(big.Rat).SetInt64(pow10(scale)))
+
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	twiceRemainder := new(big.Int).Lsh(new(big.Int).Abs(remainder), 1)
+
+	switch cmp := twiceRemainder.Cmp(value.Denom()); {
+	case cmp > 0, cmp == 0 && quotient.Bit(0) == 1:
+		if value.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	if !quotient.IsInt64() {
+		return Money{}, errMoneyOutOfRange
+	}
+
+	return newBoundedMoney(quotient.Int64(), scale)
+}
+
+// truncate drops the decimals beyond the given number, rounding towards zero.
+func (m Money) truncate(scale int32) (Money, error) {
+	if m.scale <= scale {
+		return m.rescale(scale)
+	}
+
+	return Money{units: m.units / pow10(m.scale-scale), scale: scale}, nil
+}
+
+// normalize strips trailing fractional zeros.
+func (m Money) normalize() Money {
+	for m.scale > 0 && m.units%10 == 0 {
+		m.units /= 10
+		m.scale--
+	}
+
+	return m
+}
+
+// rescale expresses the amount with more decimals, or returns
+// ErrInvalidValue when it would have too many digits.
+func (m Money) rescale(scale int32) (Money, error) {
+	if scale <= m.scale {
+		return m, nil
+	}
+
+	if scale > maxMoneyDigits {
+		return Money{}, errMoneyOutOfRange
+	}
+
+	factor := pow10(scale - m.scale)
+	if m.units > maxMoneyUnits/factor || m.units < -maxMoneyUnits/factor {
+		return Money{}, errMoneyOutOfRange
+	}
+
+	return Money{units: m.units * factor, scale: scale}, nil
+}
+
+func align(a, b Money) (Money, Money, error) {
+	scale := max(a.scale, b.scale)
+
+	a, err := a.rescale(scale)
+	if err != nil {
+		return Money{}, Money{}, err
+	}
+
+	b, err = b.rescale(scale)
+	if err != nil {
+		return Money{}, Money{}, err
+	}
+
+	return a, b, nil
+}
+
+var errMoneyOutOfRange = ErrInvalidValue{Msg: fmt.Sprintf("amount out of range, it can't have more than %d digits", maxMoneyDigits)}
+
+// newBoundedMoney returns the amount unless it has more than maxMoneyDigits
+// digits.
+func newBoundedMoney(units int64, scale int32) (Money, error) {
+	if units > maxMoneyUnits || units < -maxMoneyUnits {
+		return Money{}, errMoneyOutOfRange
+	}
+
+	return Money{units: units, scale: scale}, nil
+}
+
+// pow10 returns 10^n, for n up to maxMoneyDigits.
+func pow10(n int32) int64 {
+	result := int64(1)
+	for range n {
+		result *= 10
+	}
+
+	return result
+}
@@ -0,0 +1,129 @@
+package internal_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoney_Parse(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input         string
+		expected      string
+		expectedError error
+	}{
+		"Integer":           {input: "20", expected: "20"},
+		"Decimal":           {input: "20.30", expected: "20.30"},
+		"Negative":          {input: "-0.05", expected: "-0.05"},
+		"Empty":             {input: "", expectedError: &internal.ErrInvalidValue{}},
+		"Letters":           {input: "20a", expectedError: &internal.ErrInvalidValue{}},
+		"Trailing dot":      {input: "20.", expectedError: &internal.ErrInvalidValue{}},
+		"Exponent notation": {input: "2e3", expectedError: &internal.ErrInvalidValue{}},
+		"Too many digits":   {input: "1234567890123456789", expectedError: &internal.ErrInvalidValue{}},
+		"Explicit plus":     {input: "+5", expected: "5"},
+		"Both signs":        {input: "-+5", expectedError: &internal.ErrInvalidValue{}},
+		"Sign alone":        {input: "-", expectedError: &internal.ErrInvalidValue{}},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			actual, err := internal.ParseMoney(tc.input)
+			if tc.expectedError != nil {
+				require.ErrorAs(t, err, tc.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual.String())
+		})
+	}
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a := internal.MustParseMoney("20.3")
+	b := internal.MustParseMoney("0.15")
+
+	sum, err := a.Add(b)
+	require.NoError(t, err)
+	assert.Equal(t, "20.45", sum.String())
+
+	difference, err := a.Sub(b)
+	require.NoError(t, err)
+	assert.Equal(t, "20.15", difference.String())
+
+	assert.Equal(t, 1, a.Cmp(b))
+	assert.Equal(t, 0, a.Cmp(internal.MustParseMoney("20.300")))
+}
+
+func TestMoney_Overflow(t *testing.T) {
+	largest := internal.MustParseMoney("999999999999999999")
+
+	_, err := largest.Add(internal.MustParseMoney("1"))
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+
+	_, err = largest.Neg().Sub(internal.MustParseMoney("1"))
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+
+	_, err = largest.Add(internal.MustParseMoney("0.01"))
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{}, "aligning the scales overflows")
+
+	_, err = largest.In(internal.DefaultCurrency)
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+
+	_, err = largest.Round(internal.DefaultCurrency)
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+
+	amount, err := internal.MustParseMoney("9999999999999999.99").In(internal.DefaultCurrency)
+	require.NoError(t, err)
+	assert.Equal(t, "9999999999999999.99", amount.String())
+
+	assert.Equal(t, 1, largest.Cmp(internal.MustParseMoney("0.01")), "comparing never overflows")
+}
+
+func TestMoney_In(t *testing.T) {
+	amount, err := internal.MustParseMoney("20.3").In(internal.DefaultCurrency)
+	require.NoError(t, err)
+	assert.Equal(t, "20.30", amount.String())
+
+	amount, err = internal.MustParseMoney("1.500").In(internal.DefaultCurrency)
+	require.NoError(t, err)
+	assert.Equal(t, "1.50", amount.String())
+
+	_, err = internal.MustParseMoney("1.505").In(internal.DefaultCurrency)
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+}
+
+func TestMoney_Round(t *testing.T) {
+	testCases := map[string]string{
+		"1.005":  "1.00",
+		"1.015":  "1.02",
+		"1.0051": "1.01",
+		"-1.015": "-1.02",
+		"-1.005": "-1.00",
+		"3":      "3.00",
+	}
+
+	for input, expected := range testCases {
+		rounded, err := internal.MustParseMoney(input).Round(internal.DefaultCurrency)
+		require.NoError(t, err)
+		assert.Equal(t, expected, rounded.String(), input)
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(internal.MustParseMoney("20.30"))
+	require.NoError(t, err)
+	assert.Equal(t, `"20.30"`, string(data))
+
+	var amount internal.Money
+	require.NoError(t, json.Unmarshal([]byte(`"20.30"`), &amount))
+	assert.Equal(t, internal.MustParseMoney("20.30"), amount)
+
+	err = json.Unmarshal([]byte(`20.3`), &amount)
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+}
@@ -0,0 +1,324 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+const accountColumns = `id, owner, currency, balance, status, overdraft_limit, held, version, type,
+	interest_rate, accrued_interest, interest_accrued_through, opened_at`
+
+type AccountsRepository struct {
+	db querier
+	// forUpdate locks the rows read by Get until the transaction ends.
+	forUpdate bool
+}
+
+var _ internal.AccountsRepository = (*AccountsRepository)(nil)
+
+func NewAccountsRepository(db *sql.DB) *AccountsRepository {
+	return &AccountsRepository{db: db}
+}
+
+func (ar *AccountsRepository) Create(ctx context.Context, account internal.Account) error {
+	_, err := ar.db.ExecContext(ctx,
+		`INSERT INTO accounts (`+accountColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		account.ID, string(account.Owner), string(account.Currency), account.Balance.String(), string(account.Status),
+		account.OverdraftLimit.String(), account.Held.String(), account.Version, string(account.Type),
+		account.InterestRate.String(), account.AccruedInterest.String(), nullTime(account.InterestAccruedThrough),
+		nullTime(account.OpenedAt),
+	)
+	if isUniqueViolation(err) {
+		return internal.ErrAccountAlreadyExists
+	}
+
+	if err != nil {
+		return fmt.Errorf("inserting account: %w", err)
+	}
+
+	return nil
+}
+
+func (ar *AccountsRepository) Get(ctx context.Context, id string) (*internal.Account, error) {
+	query := `SELECT ` + accountColumns + ` FROM accounts WHERE id = $1`
+	if ar.forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	account, err := scanAccount(ar.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, internal.ErrAccountNotFound{AccountID: id}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (ar *AccountsRepository) List(ctx context.Context) ([]internal.Account, error) {
+	return ar.find(ctx, `SELECT `+accountColumns+` FROM accounts ORDER BY created_at, id`)
+}
+
+func (ar *AccountsRepository) Find(ctx context.Context, query internal.AccountQuery) ([]internal.Account, error) {
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"TRUE"}
+
+	if query.MinBalance != nil {
+		conditions = append(conditions, "balance >= "+arg(query.MinBalance.String()))
+	}
+
+	if query.MaxBalance != nil {
+		conditions = append(conditions, "balance <= "+arg(query.MaxBalance.String()))
+	}
+
+	if len(query.Statuses) > 0 {
+		placeholders := make([]string, len(query.Statuses))
+		for i, status := range query.Statuses {
+			placeholders[i] = arg(string(status))
+		}
+		conditions = append(conditions, "status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	if query.After != "" {
+		conditions = append(conditions, "(created_at, id) > (SELECT created_at, id FROM accounts WHERE id = "+arg(query.After)+")")
+	}
+
+	statement := `SELECT ` + accountColumns + ` FROM accounts WHERE ` + strings.Join(conditions, " AND ") + ` ORDER BY created_at, id`
+
+	// ILIKE ignores case by the rules of the database's locale, so the owner
+	// is filtered here to ignore it as the other storages do, and the limit
+	// along with it.
+	if query.OwnerPrefix != "" {
+		return ar.findMatching(ctx, query.Matches, query.Limit, statement, args...)
+	}
+
+	if query.Limit > 0 {
+		statement += ` LIMIT ` + arg(query.Limit)
+	}
+
+	return ar.find(ctx, statement, args...)
+}
+
+func (ar *AccountsRepository) find(ctx context.Context, query string, args ...any) ([]internal.Account, error) {
+	return ar.findMatching(ctx, nil, 0, query, args...)
+}
+
+// findMatching returns the accounts of the query that matches selects, all of
+// them when it's nil, up to the limit unless it's 0.
+func (ar *AccountsRepository) findMatching(
+	ctx context.Context,
+	matches func(internal.Account) bool,
+	limit int,
+	query string,
+	args ...any,
+) ([]internal.Account, error) {
+	rows, err := ar.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := make([]internal.Account, 0)
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		if matches != nil && !matches(account) {
+			continue
+		}
+
+		accounts = append(accounts, account)
+		if len(accounts) == limit {
+			break
+		}
+	}
+
+	return accounts, rows.Err()
+}
+
+func (ar *AccountsRepository) UpdateBalance(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	newBalance internal.Money,
+) error {
+	return ar.update(ctx, accountID, expectedVersion, []string{"balance"}, newBalance.String())
+}
+
+func (ar *AccountsRepository) UpdateStatus(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	status internal.AccountStatus,
+) error {
+	return ar.update(ctx, accountID, expectedVersion, []string{"status"}, string(status))
+}
+
+func (ar *AccountsRepository) UpdateOverdraftLimit(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	limit internal.Money,
+) error {
+	return ar.update(ctx, accountID, expectedVersion, []string{"overdraft_limit"}, limit.String())
+}
+
+func (ar *AccountsRepository) UpdateHeld(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	held internal.Money,
+) error {
+	return ar.update(ctx, accountID, expectedVersion, []string{"held"}, held.String())
+}
+
+func (ar *AccountsRepository) UpdateInterest(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	accountType internal.AccountType,
+	interest internal.AccountInterest,
+) error {
+	return ar.update(ctx, accountID, expectedVersion,
+		[]string{"type", "interest_rate", "accrued_interest", "interest_accrued_through"},
+		string(accountType), interest.InterestRate.String(), interest.AccruedInterest.String(), nullTime(interest.InterestAccruedThrough),
+	)
+}
+
+// update sets the columns of the account to the values, given in the same
+// order, as long as it's at the expected version.
+func (ar *AccountsRepository) update(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	columns []string,
+	values ...any,
+) error {
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		assignments[i] = fmt.Sprintf("%s = $%d", column, i+3)
+	}
+
+	result, err := ar.db.ExecContext(ctx,
+		`UPDATE accounts SET `+strings.Join(assignments, ", ")+`, version = version + 1 WHERE id = $1 AND version = $2`,
+		append([]any{accountID, expectedVersion}, values...)...,
+	)
+	if err != nil {
+		return fmt.Errorf("updating %s: %w", strings.Join(columns, ", "), err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("updating %s: %w", strings.Join(columns, ", "), err)
+	}
+
+	if updated == 0 {
+		// Either the account doesn't exist or it's at another version.
+		if _, err := ar.Get(ctx, accountID); err != nil {
+			return err
+		}
+
+		return internal.ErrVersionConflict{AccountID: accountID, ExpectedVersion: expectedVersion}
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAccount(row scanner) (internal.Account, error) {
+	var (
+		account  internal.Account
+		owner    string
+		currency string
+		balance  string
+		status   string
+		limit    string
+		held     string
+		rate     string
+		accrued  string
+		through  sql.NullTime
+		openedAt sql.NullTime
+	)
+
+	if err := row.Scan(
+		&account.ID,
+		&owner,
+		&currency,
+		&balance,
+		&status,
+		&limit,
+		&held,
+		&account.Version,
+		&account.Type,
+		&rate,
+		&accrued,
+		&through,
+		&openedAt,
+	); err != nil {
+		return internal.Account{}, err
+	}
+
+	account.Owner = internal.Name(owner)
+	account.Currency = internal.Currency(currency)
+	account.Status = internal.AccountStatus(status)
+
+	var err error
+	account.Balance, err = parseAmount(balance, account.Currency)
+	if err != nil {
+		return internal.Account{}, err
+	}
+
+	account.OverdraftLimit, err = parseAmount(limit, account.Currency)
+	if err != nil {
+		return internal.Account{}, err
+	}
+
+	account.Held, err = parseAmount(held, account.Currency)
+	if err != nil {
+		return internal.Account{}, err
+	}
+
+	account.InterestRate, err = internal.ParseInterestRate(rate)
+	if err != nil {
+		return internal.Account{}, fmt.Errorf("parsing stored interest rate: %w", err)
+	}
+
+	// The accrued interest keeps more decimals than the currency.
+	account.AccruedInterest, err = internal.ParseMoney(accrued)
+	if err != nil {
+		return internal.Account{}, fmt.Errorf("parsing stored amount: %w", err)
+	}
+
+	account.InterestAccruedThrough = timePtr(through)
+	account.OpenedAt = timePtr(openedAt)
+
+	return account, nil
+}
+
+// parseAmount reads a NUMERIC column expressed in the currency's minor units.
+func parseAmount(value string, currency internal.Currency) (internal.Money, error) {
+	amount, err := internal.ParseMoney(value)
+	if err != nil {
+		return internal.Money{}, fmt.Errorf("parsing stored amount: %w", err)
+	}
+
+	return amount.Round(currency)
+}
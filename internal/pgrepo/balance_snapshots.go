@@ -0,0 +1,90 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+const balanceSnapshotColumns = `account_id, at, balance, currency`
+
+type BalanceSnapshotsRepository struct {
+	db querier
+}
+
+var _ internal.BalanceSnapshotsRepository = (*BalanceSnapshotsRepository)(nil)
+
+func NewBalanceSnapshotsRepository(db *sql.DB) *BalanceSnapshotsRepository {
+	return &BalanceSnapshotsRepository{db: db}
+}
+
+func (br *BalanceSnapshotsRepository) Save(ctx context.Context, snapshot internal.BalanceSnapshot) error {
+	_, err := br.db.ExecContext(ctx,
+		`INSERT INTO balance_snapshots (`+balanceSnapshotColumns+`) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (account_id, at) DO UPDATE SET balance = excluded.balance, currency = excluded.currency`,
+		snapshot.AccountID, snapshot.At, snapshot.Balance.String(), string(snapshot.Currency),
+	)
+	if err != nil {
+		return fmt.Errorf("saving balance snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func (br *BalanceSnapshotsRepository) FindLatest(
+	ctx context.Context,
+	accountID string,
+	at time.Time,
+) (*internal.BalanceSnapshot, error) {
+	return br.findOne(ctx,
+		`SELECT `+balanceSnapshotColumns+` FROM balance_snapshots
+		WHERE account_id = $1 AND at <= $2 ORDER BY at DESC LIMIT 1`,
+		accountID, at,
+	)
+}
+
+func (br *BalanceSnapshotsRepository) FindNext(
+	ctx context.Context,
+	accountID string,
+	after time.Time,
+) (*internal.BalanceSnapshot, error) {
+	return br.findOne(ctx,
+		`SELECT `+balanceSnapshotColumns+` FROM balance_snapshots
+		WHERE account_id = $1 AND at > $2 ORDER BY at LIMIT 1`,
+		accountID, after,
+	)
+}
+
+// findOne returns the snapshot selected by the query, or nil when there's none.
+func (br *BalanceSnapshotsRepository) findOne(ctx context.Context, query string, args ...any) (*internal.BalanceSnapshot, error) {
+	var (
+		snapshot internal.BalanceSnapshot
+		at       time.Time
+		balance  string
+		currency string
+	)
+
+	err := br.db.QueryRowContext(ctx, query, args...).Scan(&snapshot.AccountID, &at, &balance, &currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("querying balance snapshots: %w", err)
+	}
+
+	snapshot.Currency = internal.Currency(currency)
+
+	snapshot.At = at.UTC()
+
+	snapshot.Balance, err = parseAmount(balance, snapshot.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
@@ -0,0 +1,156 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+const holdColumns = `id, account_id, amount, currency, status, created_at, expires_at,
+	captured_amount, transaction_id`
+
+type HoldsRepository struct {
+	db querier
+}
+
+var _ internal.HoldsRepository = (*HoldsRepository)(nil)
+
+func NewHoldsRepository(db *sql.DB) *HoldsRepository {
+	return &HoldsRepository{db: db}
+}
+
+func (hr *HoldsRepository) Create(ctx context.Context, hold internal.Hold) error {
+	_, err := hr.db.ExecContext(ctx,
+		`INSERT INTO holds (`+holdColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		hold.ID,
+		hold.AccountID,
+		hold.Amount.String(),
+		string(hold.Currency),
+		string(hold.Status),
+		hold.CreatedAt,
+		hold.ExpiresAt,
+		nullAmount(hold.CapturedAmount),
+		nullString(hold.TransactionID),
+	)
+	if isUniqueViolation(err) {
+		return errors.New("hold with given ID already exists")
+	}
+
+	if err != nil {
+		return fmt.Errorf("inserting hold: %w", err)
+	}
+
+	return nil
+}
+
+func (hr *HoldsRepository) Get(ctx context.Context, id string) (internal.Hold, error) {
+	hold, err := scanHold(hr.db.QueryRowContext(ctx,
+		`SELECT `+holdColumns+` FROM holds WHERE id = $1`, id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return internal.Hold{}, internal.ErrHoldNotFound{HoldID: id}
+	}
+
+	return hold, err
+}
+
+func (hr *HoldsRepository) Update(ctx context.Context, hold internal.Hold) error {
+	result, err := hr.db.ExecContext(ctx,
+		`UPDATE holds SET status = $1, captured_amount = $2, transaction_id = $3 WHERE id = $4`,
+		string(hold.Status), nullAmount(hold.CapturedAmount), nullString(hold.TransactionID), hold.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("updating hold: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("updating hold: %w", err)
+	}
+
+	if updated == 0 {
+		return internal.ErrHoldNotFound{HoldID: hold.ID}
+	}
+
+	return nil
+}
+
+func (hr *HoldsRepository) FindExpired(ctx context.Context, asOf time.Time) ([]internal.Hold, error) {
+	rows, err := hr.db.QueryContext(ctx,
+		`SELECT `+holdColumns+` FROM holds WHERE status = $1 AND expires_at <= $2 ORDER BY expires_at, id`,
+		string(internal.HoldActive), asOf,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying holds: %w", err)
+	}
+	defer rows.Close()
+
+	holds := make([]internal.Hold, 0)
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		holds = append(holds, hold)
+	}
+
+	return holds, rows.Err()
+}
+
+func scanHold(row scanner) (internal.Hold, error) {
+	var (
+		hold                          internal.Hold
+		amount, currency, status      string
+		createdAt, expiresAt          time.Time
+		capturedAmount, transactionID sql.NullString
+	)
+
+	if err := row.Scan(
+		&hold.ID,
+		&hold.AccountID,
+		&amount,
+		&currency,
+		&status,
+		&createdAt,
+		&expiresAt,
+		&capturedAmount,
+		&transactionID,
+	); err != nil {
+		return internal.Hold{}, err
+	}
+
+	hold.Currency = internal.Currency(currency)
+	hold.Status = internal.HoldStatus(status)
+	hold.TransactionID = transactionID.String
+	hold.CreatedAt = createdAt.UTC()
+	hold.ExpiresAt = expiresAt.UTC()
+
+	var err error
+	hold.Amount, err = parseAmount(amount, hold.Currency)
+	if err != nil {
+		return internal.Hold{}, err
+	}
+
+	if capturedAmount.Valid {
+		captured, err := parseAmount(capturedAmount.String, hold.Currency)
+		if err != nil {
+			return internal.Hold{}, err
+		}
+		hold.CapturedAmount = &captured
+	}
+
+	return hold, nil
+}
+
+func nullAmount(amount *internal.Money) sql.NullString {
+	if amount == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: amount.String(), Valid: true}
+}
@@ -0,0 +1,99 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+type LedgerRepository struct {
+	db querier
+}
+
+var _ internal.LedgerRepository = (*LedgerRepository)(nil)
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+func (lr *LedgerRepository) Append(ctx context.Context, entry internal.JournalEntry) error {
+	return inTx(ctx, lr.db, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO journal_entries (id, reference, timestamp) VALUES ($1, $2, $3)`,
+			entry.ID, entry.Reference, entry.Timestamp,
+		)
+		if isUniqueViolation(err) {
+			return errors.New("journal entry with given ID already exists")
+		}
+
+		if err != nil {
+			return fmt.Errorf("inserting journal entry: %w", err)
+		}
+
+		for i, posting := range entry.Postings {
+			if _, err := q.ExecContext(ctx,
+				`INSERT INTO postings (entry_id, position, account_id, amount, currency) VALUES ($1, $2, $3, $4, $5)`,
+				entry.ID, i, posting.AccountID, posting.Amount.String(), string(posting.Currency),
+			); err != nil {
+				return fmt.Errorf("inserting posting: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// FindAllByAccount returns the entries with postings for the account in the
+// order they were appended.
+func (lr *LedgerRepository) FindAllByAccount(ctx context.Context, accountID string) ([]internal.JournalEntry, error) {
+	rows, err := lr.db.QueryContext(ctx, `
+		SELECT e.id, e.reference, e.timestamp, p.account_id, p.amount, p.currency
+		FROM journal_entries e
+		JOIN postings p ON p.entry_id = e.id
+		WHERE e.id IN (SELECT entry_id FROM postings WHERE account_id = $1)
+		ORDER BY e.seq, p.position`,
+		accountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying journal entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]internal.JournalEntry, 0)
+	for rows.Next() {
+		var (
+			entryID, reference, postingAccountID, amount, currency string
+			timestamp                                              time.Time
+		)
+
+		if err := rows.Scan(&entryID, &reference, &timestamp, &postingAccountID, &amount, &currency); err != nil {
+			return nil, err
+		}
+
+		if len(entries) == 0 || entries[len(entries)-1].ID != entryID {
+			entries = append(entries, internal.JournalEntry{
+				ID:        entryID,
+				Reference: reference,
+				Timestamp: timestamp.UTC(),
+			})
+		}
+
+		parsed, err := parseAmount(amount, internal.Currency(currency))
+		if err != nil {
+			return nil, err
+		}
+
+		last := &entries[len(entries)-1]
+		last.Postings = append(last.Postings, internal.Posting{
+			AccountID: postingAccountID,
+			Amount:    parsed,
+			Currency:  internal.Currency(currency),
+		})
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,140 @@
+package pgrepo_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal/pgrepo"
+	"github.com/stretchr/testify/require"
+)
+
+// testDSN points to the server the tests run against: the one given in
+// PGREPO_TEST_DSN or a throwaway one launched from the postgres binaries on
+// PATH. Tests are skipped when neither is available.
+var testDSN string
+
+func TestMain(m *testing.M) {
+	var stop func()
+	testDSN = os.Getenv("PGREPO_TEST_DSN")
+	if testDSN == "" {
+		var err error
+		testDSN, stop, err = startPostgres()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "postgres not available, skipping integration tests: %s\n", err)
+		}
+	}
+
+	code := m.Run()
+
+	if stop != nil {
+		stop()
+	}
+
+	os.Exit(code)
+}
+
+// newTestDB returns a migrated and empty database only used by the test.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	if testDSN == "" {
+		t.Skip("postgres not available: set PGREPO_TEST_DSN or add the postgres binaries to PATH")
+	}
+
+	ctx := context.Background()
+	name := "bank_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	admin, err := sql.Open("pgx", testDSN)
+	require.NoError(t, err)
+	defer admin.Close()
+
+	_, err = admin.ExecContext(ctx, "CREATE DATABASE "+name)
+	require.NoError(t, err)
+
+	db, err := pgrepo.Open(ctx, withDatabase(testDSN, name))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		db.Close()
+
+		admin, err := sql.Open("pgx", testDSN)
+		if err != nil {
+			return
+		}
+		defer admin.Close()
+
+		admin.ExecContext(context.Background(), "DROP DATABASE IF EXISTS "+name)
+	})
+
+	return db
+}
+
+func withDatabase(dsn, name string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		u.Path = "/" + name
+		return u.String()
+	}
+
+	return dsn + " dbname=" + name
+}
+
+func startPostgres() (string, func(), error) {
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		return "", nil, err
+	}
+
+	pgCtl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "pgrepo")
+	if err != nil {
+		return "", nil, err
+	}
+
+	dataDir := filepath.Join(dir, "data")
+	if out, err := exec.Command(initdb, "-D", dataDir, "-U", "postgres", "--auth=trust").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb: %w: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=''", port, dir)
+	if out, err := exec.Command(pgCtl, "-D", dataDir, "-o", options, "-w", "start").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("pg_ctl start: %w: %s", err, out)
+	}
+
+	stop := func() {
+		exec.Command(pgCtl, "-D", dataDir, "-m", "immediate", "stop").Run()
+		os.RemoveAll(dir)
+	}
+
+	return fmt.Sprintf("host=%s port=%d user=postgres dbname=postgres sslmode=disable", dir, port), stop, nil
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,43 @@
+CREATE TABLE accounts (
+    id         TEXT PRIMARY KEY,
+    owner      TEXT NOT NULL,
+    currency   TEXT NOT NULL,
+    balance    NUMERIC NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE transactions (
+    id                      TEXT PRIMARY KEY,
+    account_id              TEXT NOT NULL,
+    type                    TEXT NOT NULL,
+    amount                  NUMERIC NOT NULL,
+    currency                TEXT NOT NULL,
+    timestamp               TIMESTAMPTZ NOT NULL,
+    transfer_id             TEXT,
+    counterparty_account_id TEXT,
+    rate_from               TEXT,
+    rate_to                 TEXT,
+    rate                    TEXT,
+    rate_timestamp          TIMESTAMPTZ
+);
+
+CREATE INDEX transactions_account_id_idx ON transactions (account_id, timestamp);
+CREATE INDEX transactions_transfer_id_idx ON transactions (transfer_id);
+
+CREATE TABLE journal_entries (
+    seq       BIGSERIAL UNIQUE,
+    id        TEXT PRIMARY KEY,
+    reference TEXT NOT NULL,
+    timestamp TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE postings (
+    entry_id   TEXT NOT NULL REFERENCES journal_entries (id),
+    position   INTEGER NOT NULL,
+    account_id TEXT NOT NULL,
+    amount     NUMERIC NOT NULL,
+    currency   TEXT NOT NULL,
+    PRIMARY KEY (entry_id, position)
+);
+
+CREATE INDEX postings_account_id_idx ON postings (account_id);
@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
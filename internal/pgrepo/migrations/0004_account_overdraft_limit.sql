@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN overdraft_limit NUMERIC NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+ALTER TABLE accounts ADD COLUMN held NUMERIC NOT NULL DEFAULT 0;
+
+CREATE TABLE holds (
+    id              TEXT PRIMARY KEY,
+    account_id      TEXT NOT NULL,
+    amount          NUMERIC NOT NULL,
+    currency        TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL,
+    expires_at      TIMESTAMPTZ NOT NULL,
+    captured_amount NUMERIC,
+    transaction_id  TEXT
+);
+
+CREATE INDEX holds_expires_at_idx ON holds (status, expires_at);
@@ -0,0 +1,24 @@
+CREATE TABLE scheduled_transfers (
+    id                     TEXT PRIMARY KEY,
+    source_account_id      TEXT NOT NULL,
+    destination_account_id TEXT NOT NULL,
+    amount                 NUMERIC NOT NULL,
+    currency               TEXT NOT NULL,
+    schedule               TEXT,
+    start_at               TIMESTAMPTZ NOT NULL,
+    end_at                 TIMESTAMPTZ,
+    max_occurrences        INTEGER NOT NULL DEFAULT 0,
+    status                 TEXT NOT NULL,
+    next_run_at            TIMESTAMPTZ NOT NULL,
+    retry_at               TIMESTAMPTZ,
+    occurrences            INTEGER NOT NULL DEFAULT 0,
+    failures               INTEGER NOT NULL DEFAULT 0,
+    attempts               INTEGER NOT NULL DEFAULT 0,
+    last_run_at            TIMESTAMPTZ,
+    last_transfer_id       TEXT,
+    last_error             TEXT,
+    created_at             TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX scheduled_transfers_source_account_id_idx ON scheduled_transfers (source_account_id, created_at);
+CREATE INDEX scheduled_transfers_next_run_at_idx ON scheduled_transfers (status, next_run_at);
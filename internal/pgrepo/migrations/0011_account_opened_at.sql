@@ -0,0 +1,4 @@
+ALTER TABLE accounts ADD COLUMN opened_at TIMESTAMPTZ;
+
+-- The rows were stored as the accounts were opened.
+UPDATE accounts SET opened_at = created_at;
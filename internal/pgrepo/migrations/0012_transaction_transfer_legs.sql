@@ -0,0 +1,4 @@
+-- A transfer has at most one transaction of each type on each account, so
+-- concurrent attempts to make the same transfer can't both be saved.
+DROP INDEX transactions_transfer_id_idx;
+CREATE UNIQUE INDEX transactions_transfer_leg_idx ON transactions (transfer_id, account_id, type);
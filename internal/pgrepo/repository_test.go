@@ -0,0 +1,127 @@
+package pgrepo_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fxrates"
+	"github.com/jyisus/bank-server/internal/pgrepo"
+	"github.com/jyisus/bank-server/internal/repotest"
+	"github.com/jyisus/bank-server/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountsRepository(t *testing.T) {
+	repotest.TestAccountsRepository(t, func(t *testing.T) internal.AccountsRepository {
+		return pgrepo.NewAccountsRepository(newTestDB(t))
+	})
+}
+
+func TestTransactionsRepository(t *testing.T) {
+	repotest.TestTransactionsRepository(t, func(t *testing.T) internal.TransactionsRepository {
+		return pgrepo.NewTransactionsRepository(newTestDB(t))
+	})
+}
+
+func TestHoldsRepository(t *testing.T) {
+	repotest.TestHoldsRepository(t, func(t *testing.T) internal.HoldsRepository {
+		return pgrepo.NewHoldsRepository(newTestDB(t))
+	})
+}
+
+func TestScheduledTransfersRepository(t *testing.T) {
+	repotest.TestScheduledTransfersRepository(t, func(t *testing.T) internal.ScheduledTransfersRepository {
+		return pgrepo.NewScheduledTransfersRepository(newTestDB(t))
+	})
+}
+
+func TestBalanceSnapshotsRepository(t *testing.T) {
+	repotest.TestBalanceSnapshotsRepository(t, func(t *testing.T) internal.BalanceSnapshotsRepository {
+		return pgrepo.NewBalanceSnapshotsRepository(newTestDB(t))
+	})
+}
+
+func TestUnitOfWork_Rollback(t *testing.T) {
+	var (
+		db           = newTestDB(t)
+		accountsRepo = pgrepo.NewAccountsRepository(db)
+		ledgerRepo   = pgrepo.NewLedgerRepository(db)
+		unitOfWork   = pgrepo.NewUnitOfWork(db)
+		ctx          = context.Background()
+		errFailure   = errors.New("failure")
+
+		account = internal.Account{ID: uuid.NewString(), Owner: "Test", Currency: "EUR", Balance: internal.MustParseMoney("10.00")}
+	)
+
+	require.NoError(t, accountsRepo.Create(ctx, account))
+
+	err := internal.RunInTx(ctx, unitOfWork, func(tx internal.Tx) error {
+		if err := tx.Accounts().UpdateBalance(ctx, account.ID, 0, internal.MustParseMoney("1.00")); err != nil {
+			return err
+		}
+
+		entry := internal.JournalEntry{ID: uuid.NewString(), Timestamp: time.Now(), Postings: []internal.Posting{
+			{AccountID: account.ID, Amount: internal.MustParseMoney("-9.00"), Currency: "EUR"},
+		}}
+		if err := tx.Ledger().Append(ctx, entry); err != nil {
+			return err
+		}
+
+		return errFailure
+	})
+	require.ErrorIs(t, err, errFailure)
+
+	repoAccount, err := accountsRepo.Get(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, account.Balance, repoAccount.Balance)
+
+	entries, err := ledgerRepo.FindAllByAccount(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestServices_ConcurrentTransfers(t *testing.T) {
+	var (
+		db              = newTestDB(t)
+		logger          = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		accountsRepo    = pgrepo.NewAccountsRepository(db)
+		ledgerRepo      = pgrepo.NewLedgerRepository(db)
+		unitOfWork      = pgrepo.NewUnitOfWork(db)
+		ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+		accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+		ctx             = context.Background()
+		transfers       = 20
+		wg              sync.WaitGroup
+	)
+
+	source, err := accountsService.CreateAccount(ctx, "Source", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+
+	destination, err := accountsService.CreateAccount(ctx, "Destination", "EUR", internal.MustParseMoney("0"))
+	require.NoError(t, err)
+
+	for range transfers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := accountsService.Transfer(ctx, source.ID, destination.ID, internal.MustParseMoney("1"), "")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	repoSource, err := accountsRepo.Get(ctx, source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("80.00"), repoSource.Balance)
+
+	require.NoError(t, ledger.Verify(ctx, source.ID))
+	require.NoError(t, ledger.Verify(ctx, destination.ID))
+}
@@ -0,0 +1,69 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+// UnitOfWork runs transactions on the database. Accounts read within a
+// transaction are locked with SELECT ... FOR UPDATE until it ends, so
+// concurrent balance changes on the same account are serialized.
+type UnitOfWork struct {
+	db *sql.DB
+}
+
+var _ internal.UnitOfWork = (*UnitOfWork)(nil)
+
+func NewUnitOfWork(db *sql.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+func (u *UnitOfWork) Begin(ctx context.Context) (internal.Tx, error) {
+	sqlTx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tx{sqlTx: sqlTx}, nil
+}
+
+type tx struct {
+	sqlTx *sql.Tx
+}
+
+func (t *tx) Accounts() internal.AccountsRepository {
+	return &AccountsRepository{db: t.sqlTx, forUpdate: true}
+}
+
+func (t *tx) Transactions() internal.TransactionsRepository {
+	return &TransactionsRepository{db: t.sqlTx}
+}
+
+func (t *tx) Ledger() internal.LedgerRepository {
+	return &LedgerRepository{db: t.sqlTx}
+}
+
+func (t *tx) Holds() internal.HoldsRepository {
+	return &HoldsRepository{db: t.sqlTx}
+}
+
+func (t *tx) Commit(_ context.Context) error {
+	if err := t.sqlTx.Commit(); errors.Is(err, sql.ErrTxDone) {
+		return internal.ErrTxDone
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *tx) Rollback(_ context.Context) error {
+	if err := t.sqlTx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+
+	return nil
+}
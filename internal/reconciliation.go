@@ -0,0 +1,77 @@
+package internal
+
+import "time"
+
+// Discrepancy is an account whose stored balance doesn't match the one
+// recomputed from its history: its opening balance plus the changes of all its
+// transactions but the adjustments, which only make up for changes made
+// outside of it.
+type Discrepancy struct {
+	AccountID  string   `json:"account_id"`
+	Currency   Currency `json:"currency"`
+	Balance    Money    `json:"balance"`
+	Recomputed Money    `json:"recomputed"`
+	// Difference is what the balance has over the recomputed one.
+	Difference Money `json:"difference"`
+	// AdjustmentID is the journal entry that corrected the balance, when it
+	// was corrected.
+	AdjustmentID string `json:"adjustment_id,omitempty"`
+}
+
+// Reconcile recomputes the balance of the account from its opening balance and
+// its transactions, leaving out the adjustments, and returns the discrepancy
+// with its stored balance, or nil when they match.
+func Reconcile(account Account, opening Money, transactions []Transaction) (*Discrepancy, error) {
+	history := make([]Transaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		if !transaction.IsAdjustment() {
+			history = append(history, transaction)
+		}
+	}
+
+	changes, err := BalanceChanges(history)
+	if err != nil {
+		return nil, err
+	}
+
+	recomputed, err := opening.Add(changes)
+	if err != nil {
+		return nil, err
+	}
+
+	if recomputed.Cmp(account.Balance) == 0 {
+		return nil, nil
+	}
+
+	difference, err := account.Balance.Sub(recomputed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Discrepancy{
+		AccountID:  account.ID,
+		Currency:   account.Currency,
+		Balance:    account.Balance,
+		Recomputed: recomputed,
+		Difference: difference,
+	}, nil
+}
+
+// NewAdjustmentTransaction returns the transaction that corrects the balance
+// of the account of the discrepancy to the recomputed one, so the correction
+// shows in its history.
+func NewAdjustmentTransaction(id string, discrepancy Discrepancy, timestamp time.Time) Transaction {
+	txType, amount := TransactionType(TxAdjustmentOut), discrepancy.Difference
+	if amount.IsNegative() {
+		txType, amount = TxAdjustmentIn, amount.Neg()
+	}
+
+	return Transaction{
+		ID:        id,
+		AccountID: discrepancy.AccountID,
+		Type:      txType,
+		Amount:    amount,
+		Currency:  discrepancy.Currency,
+		Timestamp: timestamp,
+	}
+}
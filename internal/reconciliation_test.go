@@ -0,0 +1,86 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	var (
+		now     = time.Date(2024, 11, 1, 10, 0, 0, 0, time.UTC)
+		account = internal.Account{ID: uuid.NewString(), Balance: internal.MustParseMoney("100.00"), Currency: "EUR"}
+	)
+
+	opening, err := internal.NewOpeningEntry(uuid.NewString(), account, now)
+	require.NoError(t, err)
+
+	deposit := internal.Transaction{
+		ID:        uuid.NewString(),
+		AccountID: account.ID,
+		Type:      internal.TxDeposit,
+		Amount:    internal.MustParseMoney("40.00"),
+		Currency:  "EUR",
+		Timestamp: now,
+	}
+
+	deposited, err := internal.NewTransactionEntry(uuid.NewString(), deposit)
+	require.NoError(t, err)
+
+	entries := []internal.JournalEntry{opening, deposited}
+	openingBalance, err := internal.OpeningBalance(entries, account.ID, account.Currency)
+	require.NoError(t, err)
+	assert.Equal(t, "100.00", openingBalance.String())
+
+	withoutOpening, err := internal.OpeningBalance(entries[1:], account.ID, account.Currency)
+	require.NoError(t, err)
+	assert.True(t, withoutOpening.IsZero())
+
+	account.Balance = internal.MustParseMoney("140.00")
+	discrepancy, err := internal.Reconcile(account, openingBalance, []internal.Transaction{deposit})
+	require.NoError(t, err)
+	assert.Nil(t, discrepancy)
+
+	discrepancy, err = internal.Reconcile(account, openingBalance, nil)
+	require.NoError(t, err)
+	require.NotNil(t, discrepancy)
+	assert.Equal(t, account.ID, discrepancy.AccountID)
+	assert.Equal(t, "140.00", discrepancy.Balance.String())
+	assert.Equal(t, "100.00", discrepancy.Recomputed.String())
+	assert.Equal(t, "40.00", discrepancy.Difference.String())
+
+	adjustment, err := internal.NewAdjustmentEntry(uuid.NewString(), uuid.NewString(), *discrepancy, now)
+	require.NoError(t, err)
+
+	entries = append(entries[:1], adjustment)
+	assertBalanceOf(t, "60.00", entries, account.ID, account.Currency)
+	assertBalanceOf(t, "40.00", entries, internal.SystemAccountSuspense, account.Currency)
+
+	openingBalance, err = internal.OpeningBalance(entries, account.ID, account.Currency)
+	require.NoError(t, err)
+	assert.Equal(t, "100.00", openingBalance.String())
+	// The adjustment transaction takes the balance back to the recomputed one
+	// without becoming part of the history.
+	adjusted := internal.NewAdjustmentTransaction(uuid.NewString(), *discrepancy, now)
+	assert.Equal(t, internal.TransactionType(internal.TxAdjustmentOut), adjusted.Type)
+	assert.Equal(t, "40.00", adjusted.Amount.String())
+	assert.Equal(t, "-40.00", adjusted.BalanceChange().String())
+
+	account.Balance = internal.MustParseMoney("100.00")
+	discrepancy, err = internal.Reconcile(account, openingBalance, []internal.Transaction{adjusted})
+	require.NoError(t, err)
+	assert.Nil(t, discrepancy)
+
+	account.Balance = internal.MustParseMoney("90.00")
+	discrepancy, err = internal.Reconcile(account, openingBalance, []internal.Transaction{adjusted})
+	require.NoError(t, err)
+	require.NotNil(t, discrepancy)
+
+	adjusted = internal.NewAdjustmentTransaction(uuid.NewString(), *discrepancy, now)
+	assert.Equal(t, internal.TransactionType(internal.TxAdjustmentIn), adjusted.Type)
+	assert.Equal(t, "10.00", adjusted.BalanceChange().String())
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrenceHorizon is how far Next looks for a matching time, enough to find
+// the next 29th of February.
+const recurrenceHorizon = 5 * 366 * 24 * time.Hour
+
+// Recurrence is a cron-like schedule of times in UTC, parsed from the five
+// standard fields "minute hour day-of-month month day-of-week". Fields accept
+// "*", values, ranges, lists and steps such as "*/15", "1-5" or "0,30", and
+// days of the week go from 0 (Sunday) to 7 (Sunday again). As in cron, when
+// both days of the month and of the week are restricted, times matching
+// either of them match. The descriptors @hourly, @daily, @weekly, @monthly
+// and @yearly are supported too.
+type Recurrence struct {
+	expression string
+
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+var recurrenceDescriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// ParseRecurrence parses the schedule, which must happen at some time after
+// now.
+func ParseRecurrence(expression string, now time.Time) (Recurrence, error) {
+	expression = strings.TrimSpace(expression)
+
+	fields := strings.Fields(expression)
+	if descriptor, ok := recurrenceDescriptors[expression]; ok {
+		fields = strings.Fields(descriptor)
+	}
+
+	if len(fields) != 5 {
+		return Recurrence{}, ErrInvalidValue{Msg: fmt.Sprintf("schedule %q should have 5 fields", expression)}
+	}
+
+	recurrence := Recurrence{
+		expression:    expression,
+		anyDayOfMonth: strings.HasPrefix(fields[2], "*"),
+		anyDayOfWeek:  strings.HasPrefix(fields[4], "*"),
+	}
+
+	var err error
+	for i, field := range []struct {
+		bits     *uint64
+		min, max int
+	}{
+		{&recurrence.minutes, 0, 59},
+		{&recurrence.hours, 0, 23},
+		{&recurrence.daysOfMonth, 1, 31},
+		{&recurrence.months, 1, 12},
+		{&recurrence.daysOfWeek, 0, 7},
+	} {
+		*field.bits, err = parseRecurrenceField(fields[i], field.min, field.max)
+		if err != nil {
+			return Recurrence{}, ErrInvalidValue{Msg: fmt.Sprintf("schedule %q: %s", expression, err)}
+		}
+	}
+
+	// Both 0 and 7 are Sunday.
+	if recurrence.daysOfWeek&(1<<7) != 0 {
+		recurrence.daysOfWeek |= 1
+	}
+
+	if recurrence.Next(now).IsZero() {
+		return Recurrence{}, ErrInvalidValue{Msg: fmt.Sprintf("schedule %q never happens", expression)}
+	}
+
+	return recurrence, nil
+}
+
+func MustParseRecurrence(expression string, now time.Time) Recurrence {
+	recurrence, err := ParseRecurrence(expression, now)
+	if err != nil {
+		panic(err)
+	}
+
+	return recurrence
+}
+
+// parseRecurrenceField returns the set of values of the field as bits.
+func parseRecurrenceField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		valueRange, stepText, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepText)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+		}
+
+		from, to := min, max
+		if valueRange != "*" {
+			fromText, toText, isRange := strings.Cut(valueRange, "-")
+
+			var err error
+			from, err = strconv.Atoi(fromText)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", fromText)
+			}
+
+			to = from
+			if isRange {
+				to, err = strconv.Atoi(toText)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q", toText)
+				}
+			} else if hasStep {
+				to = max
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("%q is out of the range %d-%d", part, min, max)
+		}
+
+		for value := from; value <= to; value += step {
+			set |= 1 << value
+		}
+	}
+
+	return set, nil
+}
+
+// Next returns the first time of the recurrence after the given one, or the
+// zero time when there's none within the next years.
+func (r Recurrence) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(recurrenceHorizon)
+
+	for t.Before(limit) {
+		switch {
+		case !has(r.months, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !r.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !has(r.hours, t.Hour()):
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !has(r.minutes, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (r Recurrence) matchesDay(t time.Time) bool {
+	dayOfMonth := has(r.daysOfMonth, t.Day())
+	dayOfWeek := has(r.daysOfWeek, int(t.Weekday()))
+
+	// Fields starting with "*" don't restrict the day, so only the other
+	// one does.
+	if r.anyDayOfMonth || r.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+
+	return dayOfMonth || dayOfWeek
+}
+
+func (r Recurrence) String() string {
+	return r.expression
+}
+
+func has(set uint64, value int) bool {
+	return set&(1<<value) != 0
+}
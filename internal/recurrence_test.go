@@ -0,0 +1,115 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	now := time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		expression    string
+		expectedError error
+	}{
+		"Every minute":           {expression: "* * * * *"},
+		"Lists, ranges and step": {expression: "0,30 9-17 */2 1-12/3 1-5"},
+		"Descriptor":             {expression: "@monthly"},
+		"Sunday as 7":            {expression: "0 0 * * 7"},
+		"Empty":                  {expression: "", expectedError: &internal.ErrInvalidValue{}},
+		"Missing field":          {expression: "* * * *", expectedError: &internal.ErrInvalidValue{}},
+		"Out of range minute":    {expression: "60 * * * *", expectedError: &internal.ErrInvalidValue{}},
+		"Out of range day":       {expression: "0 0 0 * *", expectedError: &internal.ErrInvalidValue{}},
+		"Reversed range":         {expression: "5-1 * * * *", expectedError: &internal.ErrInvalidValue{}},
+		"Zero step":              {expression: "*/0 * * * *", expectedError: &internal.ErrInvalidValue{}},
+		"Not a number":           {expression: "a * * * *", expectedError: &internal.ErrInvalidValue{}},
+		"Unknown descriptor":     {expression: "@every", expectedError: &internal.ErrInvalidValue{}},
+		"Impossible date":        {expression: "0 0 30 2 *", expectedError: &internal.ErrInvalidValue{}},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			recurrence, err := internal.ParseRecurrence(tc.expression, now)
+			if tc.expectedError != nil {
+				require.ErrorAs(t, err, tc.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expression, recurrence.String())
+		})
+	}
+}
+
+func TestRecurrence_Next(t *testing.T) {
+	// It's a Wednesday.
+	after := time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		expression string
+		after      time.Time
+		expected   time.Time
+	}{
+		"Every quarter": {
+			expression: "*/15 * * * *",
+			expected:   time.Date(2024, 1, 31, 10, 45, 0, 0, time.UTC),
+		},
+		"Daily": {
+			expression: "@daily",
+			expected:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"First of every month": {
+			expression: "0 9 1 * *",
+			expected:   time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC),
+		},
+		"Months without the day are skipped": {
+			expression: "0 0 31 * *",
+			expected:   time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		},
+		"Leap day": {
+			expression: "0 0 29 2 *",
+			expected:   time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		"Working days": {
+			expression: "0 12 * * 1-5",
+			expected:   time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+		},
+		"Sunday as 7": {
+			expression: "0 0 * * 7",
+			expected:   time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC),
+		},
+		"Either day of the month or of the week": {
+			expression: "0 0 13 * 5",
+			expected:   time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC),
+		},
+		"Stepped days of the month and days of the week": {
+			expression: "0 0 */2 * 1",
+			expected:   time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC),
+		},
+		"Strictly after": {
+			expression: "0 10 31 1 *",
+			after:      time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC),
+			expected:   time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC),
+		},
+		"Times are in UTC": {
+			expression: "0 9 1 * *",
+			after:      time.Date(2024, 2, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600)),
+			expected:   time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			if tc.after.IsZero() {
+				tc.after = after
+			}
+
+			next := internal.MustParseRecurrence(tc.expression, after).Next(tc.after)
+			assert.Equal(t, tc.expected, next)
+		})
+	}
+}
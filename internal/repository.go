@@ -1,16 +1,93 @@
 package internal
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type AccountsRepository interface {
 	Create(ctx context.Context, account Account) error
 	Get(ctx context.Context, id string) (*Account, error)
 	List(ctx context.Context) ([]Account, error)
-	UpdateBalance(ctx context.Context, accountID string, newBalance float32) error
+	// Find returns the accounts selected by the query in creation order, up to
+	// its limit.
+	Find(ctx context.Context, query AccountQuery) ([]Account, error)
+	// UpdateBalance sets the balance of the account and increases its version,
+	// as long as the account is still at the expected version. Otherwise it
+	// returns ErrVersionConflict.
+	UpdateBalance(ctx context.Context, accountID string, expectedVersion int64, newBalance Money) error
+	// UpdateStatus sets the status of the account and increases its version,
+	// with the same version check as UpdateBalance.
+	UpdateStatus(ctx context.Context, accountID string, expectedVersion int64, status AccountStatus) error
+	// UpdateOverdraftLimit sets the overdraft limit of the account and
+	// increases its version, with the same version check as UpdateBalance.
+	UpdateOverdraftLimit(ctx context.Context, accountID string, expectedVersion int64, limit Money) error
+	// UpdateHeld sets the amount held by the account's holds and increases
+	// its version, with the same version check as UpdateBalance.
+	UpdateHeld(ctx context.Context, accountID string, expectedVersion int64, held Money) error
+	// UpdateInterest sets the type and the interest of the account and
+	// increases its version, with the same version check as UpdateBalance.
+	UpdateInterest(
+		ctx context.Context,
+		accountID string,
+		expectedVersion int64,
+		accountType AccountType,
+		interest AccountInterest,
+	) error
 }
 
 type TransactionsRepository interface {
 	Save(ctx context.Context, transaction Transaction) error
+	// Get returns ErrTransactionNotFound when there's no transaction with the
+	// id.
 	Get(ctx context.Context, id string) (Transaction, error)
 	FindAllByAccount(ctx context.Context, accountID string) ([]Transaction, error)
+	// Find returns the transactions selected by the query, in its order and
+	// up to its limit.
+	Find(ctx context.Context, query TransactionQuery) ([]Transaction, error)
+	FindAllByTransfer(ctx context.Context, transferID string) ([]Transaction, error)
+}
+
+type LedgerRepository interface {
+	Append(ctx context.Context, entry JournalEntry) error
+	FindAllByAccount(ctx context.Context, accountID string) ([]JournalEntry, error)
+}
+
+type HoldsRepository interface {
+	Create(ctx context.Context, hold Hold) error
+	// Get returns ErrHoldNotFound when there's no hold with the id.
+	Get(ctx context.Context, id string) (Hold, error)
+	// Update replaces a stored hold, or returns ErrHoldNotFound.
+	Update(ctx context.Context, hold Hold) error
+	// FindExpired returns the active holds expiring at or before asOf, the
+	// first to expire first.
+	FindExpired(ctx context.Context, asOf time.Time) ([]Hold, error)
+}
+
+type ScheduledTransfersRepository interface {
+	Create(ctx context.Context, scheduledTransfer ScheduledTransfer) error
+	// Get returns ErrScheduledTransferNotFound when there's no scheduled
+	// transfer with the id.
+	Get(ctx context.Context, id string) (ScheduledTransfer, error)
+	// Update replaces the stored scheduled transfer, or returns
+	// ErrScheduledTransferNotFound when it doesn't exist.
+	Update(ctx context.Context, scheduledTransfer ScheduledTransfer) error
+	// FindAllByAccount returns the scheduled transfers from the account, in
+	// creation order.
+	FindAllByAccount(ctx context.Context, accountID string) ([]ScheduledTransfer, error)
+	// FindDue returns the active scheduled transfers due at the given time,
+	// the earliest due first and then by ID.
+	FindDue(ctx context.Context, asOf time.Time) ([]ScheduledTransfer, error)
+}
+
+type BalanceSnapshotsRepository interface {
+	// Save stores the snapshot, replacing the one of the account at the same
+	// time if there's one.
+	Save(ctx context.Context, snapshot BalanceSnapshot) error
+	// FindLatest returns the latest snapshot of the account at or before the
+	// given time, or nil when there's none.
+	FindLatest(ctx context.Context, accountID string, at time.Time) (*BalanceSnapshot, error)
+	// FindNext returns the earliest snapshot of the account after the given
+	// time, or nil when there's none.
+	FindNext(ctx context.Context, accountID string, after time.Time) (*BalanceSnapshot, error)
 }
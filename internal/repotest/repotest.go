@@ -0,0 +1,1363 @@
+// Package repotest provides the behavioural contract every repository
+// implementation must fulfil. Backends prove their compatibility by running
+// the suites from their own tests:
+//
+//	func TestAccountsRepository(t *testing.T) {
+//		repotest.TestAccountsRepository(t, func(t *testing.T) internal.AccountsRepository {
+//			return memrepo.NewAccountsRepository()
+//		})
+//	}
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrency is the number of goroutines used by the concurrency checks.
+const concurrency = 20
+
+// AccountsRepositoryFactory returns an empty repository only used by the
+// given test.
+type AccountsRepositoryFactory func(t *testing.T) internal.AccountsRepository
+
+// TransactionsRepositoryFactory returns an empty repository only used by the
+// given test.
+type TransactionsRepositoryFactory func(t *testing.T) internal.TransactionsRepository
+
+// HoldsRepositoryFactory returns an empty repository only used by the given
+// test.
+type HoldsRepositoryFactory func(t *testing.T) internal.HoldsRepository
+
+// ScheduledTransfersRepositoryFactory returns an empty repository only used by
+// the given test.
+type ScheduledTransfersRepositoryFactory func(t *testing.T) internal.ScheduledTransfersRepository
+
+// BalanceSnapshotsRepositoryFactory returns an empty repository only used by
+// the given test.
+type BalanceSnapshotsRepositoryFactory func(t *testing.T) internal.BalanceSnapshotsRepository
+
+// TestAccountsRepository checks the AccountsRepository contract:
+//   - accounts are returned as they were created, as copies of the stored ones;
+//   - creating an account with an existing ID returns ErrAccountAlreadyExists;
+//   - reading or updating a missing account returns ErrAccountNotFound;
+//   - balance, status, overdraft limit and held amount updates increase the
+//     version and fail with ErrVersionConflict when the account isn't at the
+//     expected version;
+//   - List returns the accounts in creation order;
+//   - Find returns the accounts matching the query's filters in creation
+//     order, after its cursor and up to its limit;
+//   - it is safe for concurrent use.
+func TestAccountsRepository(t *testing.T, newRepo AccountsRepositoryFactory) {
+	t.Run("Create and Get", func(t *testing.T) {
+		var (
+			repo    = newRepo(t)
+			ctx     = context.Background()
+			account = fakeAccount("20.30")
+		)
+
+		require.NoError(t, repo.Create(ctx, account))
+
+		repoAccount, err := repo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, account, *repoAccount)
+
+		repoAccount.Balance = internal.MustParseMoney("0.00")
+		repoAccount, err = repo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, account.Balance, repoAccount.Balance, "changing a returned account must not change the stored one")
+	})
+
+	t.Run("Duplicated ID", func(t *testing.T) {
+		var (
+			repo    = newRepo(t)
+			ctx     = context.Background()
+			account = fakeAccount("20.30")
+		)
+
+		require.NoError(t, repo.Create(ctx, account))
+
+		duplicate := fakeAccount("1.00")
+		duplicate.ID = account.ID
+		require.ErrorIs(t, repo.Create(ctx, duplicate), internal.ErrAccountAlreadyExists)
+
+		repoAccount, err := repo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, account, *repoAccount, "the existing account must not be overwritten")
+	})
+
+	t.Run("Missing ID", func(t *testing.T) {
+		var (
+			repo = newRepo(t)
+			ctx  = context.Background()
+			id   = uuid.NewString()
+		)
+
+		_, err := repo.Get(ctx, id)
+		var notFound internal.ErrAccountNotFound
+		require.ErrorAs(t, err, &notFound)
+		assert.Equal(t, id, notFound.AccountID)
+
+		err = repo.UpdateBalance(ctx, id, 0, internal.MustParseMoney("1.00"))
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+
+		err = repo.UpdateStatus(ctx, id, 0, internal.AccountFrozen)
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+
+		err = repo.UpdateOverdraftLimit(ctx, id, 0, internal.MustParseMoney("100.00"))
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+
+		err = repo.UpdateHeld(ctx, id, 0, internal.MustParseMoney("100.00"))
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+	})
+
+	t.Run("UpdateBalance", func(t *testing.T) {
+		var (
+			repo    = newRepo(t)
+			ctx     = context.Background()
+			account = fakeAccount("20.30")
+			other   = fakeAccount("5.00")
+		)
+
+		require.NoError(t, repo.Create(ctx, account))
+		require.NoError(t, repo.Create(ctx, other))
+		require.NoError(t, repo.UpdateBalance(ctx, account.ID, 0, internal.MustParseMoney("7.50")))
+
+		repoAccount, err := repo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.MustParseMoney("7.50"), repoAccount.Balance)
+		assert.Equal(t, int64(1), repoAccount.Version)
+		assert.Equal(t, account.Owner, repoAccount.Owner)
+
+		err = repo.UpdateBalance(ctx, account.ID, 0, internal.MustParseMoney("1.00"))
+		var conflict internal.ErrVersionConflict
+		require.ErrorAs(t, err, &conflict, "updates from a stale version must fail")
+		assert.Equal(t, account.ID, conflict.AccountID)
+
+		require.NoError(t, repo.UpdateBalance(ctx, account.ID, 1, internal.MustParseMoney("8.00")))
+
+		repoAccount, err = repo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.MustParseMoney("8.00"), repoAccount.Balance)
+		assert.Equal(t, int64(2), repoAccount.Version)
+
+		repoOther, err := repo.Get(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Equal(t, other, *repoOther, "other accounts must not change")
+	})
+
+	t.Run("UpdateStatus", func(t *testing.T) {
+		var (
+			repo    = newRepo(t)
+			ctx     = context.Background()
+			account = fakeAccount("20.30")
+		)
+
+		require.NoError(t, repo.Create(ctx, account))
+		require.NoError(t, repo.UpdateBalance(ctx, account.ID, 0, internal.MustParseMoney("7.50")))
+		require.NoError(t, repo.UpdateStatus(ctx, account.ID, 1, internal.AccountFrozen))
+
+		repoAccount, err := repo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.AccountFrozen, repoAccount.Status)
+		assert.Equal(t, int64(2), repoAccount.Version)
+		assert.Equal(t, internal.MustParseMoney("7.50"), repoAccount.Balance, "the balance must not change")
+
+		err = repo.UpdateStatus(ctx, account.ID, 1, internal.AccountClosed)
+		require.ErrorAs(t, err, &internal.ErrVersionConflict{}, "updates from a stale version must fail")
+
+		require.NoError(t, repo.UpdateBalance(ctx, account.ID, 2, internal.MustParseMoney("8.00")))
+
+		repoAccount, err = repo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.AccountFrozen, repoAccount.Status, "balance updates must keep the status")
+	})
+
+	t.Run("UpdateOverdraftLimit", func(t *testing.T) {
+		var (
+			repo    = newRepo(t)
+			ctx     = context.Background()
+			account = fakeAccount("20.30")
+		)
+
+		require.NoError(t, repo.Create(ctx, account))
+		require.NoError(t, repo.UpdateOverdraftLimit(ctx, account.ID, 0, internal.MustParseMoney("150.00")))
+		require.NoError(t, repo.UpdateBalance(ctx, account.ID, 1, internal.MustParseMoney("-80.00")))
+
+		repoAccount, err := repo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.MustParseMoney("150.00"), repoAccount.OverdraftLimit)
+		assert.Equal(t, internal.MustParseMoney("-80.00"), repoAccount.Balance, "balances may be negative")
+		assert.Equal(t, int64(2), repoAccount.Version)
+
+		err = repo.UpdateOverdraftLimit(ctx, account.ID, 0, internal.MustParseMoney("0.00"))
+		require.ErrorAs(t, err, &internal.ErrVersionConflict{}, "updates from a stale version must fail")
+	})
+
+	t.Run("UpdateHeld", func(t *testing.T) {
+		var (
+			repo    = newRepo(t)
+			ctx     = context.Background()
+			account = fakeAccount("20.30")
+		)
+
+		require.NoError(t, repo.Create(ctx, account))
+		require.NoError(t, repo.UpdateHeld(ctx, account.ID, 0, internal.MustParseMoney("12.00")))
+
+		repoAccount, err := repo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.MustParseMoney("12.00"), repoAccount.Held)
+		assert.Equal(t, account.Balance, repoAccount.Balance, "the balance must not change")
+		assert.Equal(t, int64(1), repoAccount.Version)
+
+		err = repo.UpdateHeld(ctx, account.ID, 0, internal.MustParseMoney("0.00"))
+		require.ErrorAs(t, err, &internal.ErrVersionConflict{}, "updates from a stale version must fail")
+	})
+
+	t.Run("UpdateInterest", func(t *testing.T) {
+		var (
+			repo           = newRepo(t)
+			ctx            = context.Background()
+			account        = fakeAccount("20.30")
+			accruedThrough = time.Date(2024, 11, 23, 0, 0, 0, 0, time.UTC)
+			interest       = internal.AccountInterest{
+				InterestRate:           internal.MustParseInterestRate("0.025"),
+				AccruedInterest:        internal.MustParseMoney("0.012345"),
+				InterestAccruedThrough: &accruedThrough,
+			}
+		)
+
+		require.NoError(t, repo.Create(ctx, account))
+		require.NoError(t, repo.UpdateInterest(ctx, account.ID, 0, internal.AccountSavings, interest))
+
+		repoAccount, err := repo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.AccountSavings, repoAccount.Type)
+		assert.True(t, interest.Equal(repoAccount.AccountInterest), "got %+v", repoAccount.AccountInterest)
+		assert.Equal(t, account.Balance, repoAccount.Balance, "the balance must not change")
+		assert.Equal(t, int64(1), repoAccount.Version)
+
+		err = repo.UpdateInterest(ctx, account.ID, 0, internal.AccountChecking, internal.AccountInterest{})
+		require.ErrorAs(t, err, &internal.ErrVersionConflict{}, "updates from a stale version must fail")
+	})
+
+	t.Run("List in creation order", func(t *testing.T) {
+		var (
+			repo = newRepo(t)
+			ctx  = context.Background()
+		)
+
+		accounts, err := repo.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, accounts)
+
+		var expected []internal.Account
+		for i := range 5 {
+			account := fakeAccount(fmt.Sprintf("%d.00", i))
+			require.NoError(t, repo.Create(ctx, account))
+			expected = append(expected, account)
+		}
+
+		accounts, err = repo.List(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, expected, accounts)
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		var (
+			repo     = newRepo(t)
+			ctx      = context.Background()
+			accounts = make([]internal.Account, 0, 6)
+		)
+
+		for _, fields := range []struct {
+			owner   internal.Name
+			balance string
+			status  internal.AccountStatus
+		}{
+			{owner: "Alice Smith", balance: "10.00", status: internal.AccountActive},
+			{owner: "Bob Stone", balance: "50.00", status: internal.AccountFrozen},
+			{owner: "alice Jones", balance: "100.00", status: internal.AccountActive},
+			{owner: "Al_bert", balance: "0.00", status: internal.AccountClosed},
+			{owner: "ALBERTO", balance: "75.50", status: internal.AccountActive},
+			{owner: "Ángel Ruiz", balance: "20.00", status: internal.AccountFrozen},
+		} {
+			account := fakeAccount(fields.balance)
+			account.Owner = fields.owner
+			account.Status = fields.status
+			require.NoError(t, repo.Create(ctx, account))
+			accounts = append(accounts, account)
+		}
+
+		var (
+			ten   = internal.MustParseMoney("10")
+			fifty = internal.MustParseMoney("50")
+			most  = internal.MustParseMoney("75.5")
+		)
+
+		testCases := map[string]struct {
+			query    internal.AccountQuery
+			expected []int
+		}{
+			"All":               {query: internal.AccountQuery{}, expected: []int{0, 1, 2, 3, 4, 5}},
+			"Owner prefix":      {query: internal.AccountQuery{OwnerPrefix: "ali"}, expected: []int{0, 2}},
+			"Owner case":        {query: internal.AccountQuery{OwnerPrefix: "AL"}, expected: []int{0, 2, 3, 4}},
+			"Owner wildcards":   {query: internal.AccountQuery{OwnerPrefix: "al_"}, expected: []int{3}},
+			"Owner percent":     {query: internal.AccountQuery{OwnerPrefix: "%"}, expected: []int{}},
+			"Owner non-ASCII":   {query: internal.AccountQuery{OwnerPrefix: "á"}, expected: []int{5}},
+			"Minimum balance":   {query: internal.AccountQuery{MinBalance: &fifty}, expected: []int{1, 2, 4}},
+			"Balance range":     {query: internal.AccountQuery{MinBalance: &ten, MaxBalance: &most}, expected: []int{0, 1, 4, 5}},
+			"Status":            {query: internal.AccountQuery{Statuses: []internal.AccountStatus{internal.AccountActive}}, expected: []int{0, 2, 4}},
+			"Several statuses":  {query: internal.AccountQuery{Statuses: []internal.AccountStatus{internal.AccountFrozen, internal.AccountClosed}}, expected: []int{1, 3, 5}},
+			"Limit":             {query: internal.AccountQuery{Limit: 2}, expected: []int{0, 1}},
+			"After":             {query: internal.AccountQuery{After: accounts[1].ID}, expected: []int{2, 3, 4, 5}},
+			"Combined":          {query: internal.AccountQuery{OwnerPrefix: "al", Statuses: []internal.AccountStatus{internal.AccountActive}, After: accounts[0].ID, Limit: 1}, expected: []int{2}},
+			"After missing one": {query: internal.AccountQuery{After: uuid.NewString()}, expected: []int{}},
+		}
+
+		for testName, tc := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				found, err := repo.Find(ctx, tc.query)
+				require.NoError(t, err)
+
+				expected := make([]internal.Account, 0, len(tc.expected))
+				for _, i := range tc.expected {
+					expected = append(expected, accounts[i])
+				}
+				assert.Equal(t, expected, found)
+			})
+		}
+	})
+
+	t.Run("Find exact balances", func(t *testing.T) {
+		var (
+			repo     = newRepo(t)
+			ctx      = context.Background()
+			accounts = make([]internal.Account, 0, 3)
+		)
+
+		// The balances are closer than floating point numbers can tell apart.
+		for _, balance := range []string{"9999999999999999.97", "9999999999999999.98", "9999999999999999.99"} {
+			account := fakeAccount(balance)
+			require.NoError(t, repo.Create(ctx, account))
+			accounts = append(accounts, account)
+		}
+
+		var (
+			lowest  = money("9999999999999999.97")
+			highest = money("9999999999999999.99")
+		)
+
+		testCases := map[string]struct {
+			query    internal.AccountQuery
+			expected []int
+		}{
+			"Minimum balance":       {query: internal.AccountQuery{MinBalance: &highest}, expected: []int{2}},
+			"Maximum balance":       {query: internal.AccountQuery{MaxBalance: &lowest}, expected: []int{0}},
+			"Single balance":        {query: internal.AccountQuery{MinBalance: &lowest, MaxBalance: &lowest}, expected: []int{0}},
+			"Limit after the bound": {query: internal.AccountQuery{MaxBalance: &highest, After: accounts[0].ID, Limit: 1}, expected: []int{1}},
+		}
+
+		for testName, tc := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				found, err := repo.Find(ctx, tc.query)
+				require.NoError(t, err)
+
+				expected := make([]internal.Account, 0, len(tc.expected))
+				for _, i := range tc.expected {
+					expected = append(expected, accounts[i])
+				}
+				assert.Equal(t, expected, found)
+			})
+		}
+	})
+
+	t.Run("Concurrent creations", func(t *testing.T) {
+		var (
+			repo      = newRepo(t)
+			ctx       = context.Background()
+			duplicate = fakeAccount("1.00")
+			created   = make(chan internal.Account, concurrency)
+			wg        sync.WaitGroup
+			mutex     sync.Mutex
+			winners   int
+		)
+
+		for range concurrency {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				account := fakeAccount("1.00")
+				if assert.NoError(t, repo.Create(ctx, account)) {
+					created <- account
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				err := repo.Create(ctx, duplicate)
+				if err == nil {
+					mutex.Lock()
+					winners++
+					mutex.Unlock()
+					return
+				}
+				assert.ErrorIs(t, err, internal.ErrAccountAlreadyExists)
+			}()
+		}
+		wg.Wait()
+		close(created)
+
+		assert.Equal(t, 1, winners, "only one creation of the same ID must succeed")
+
+		for account := range created {
+			repoAccount, err := repo.Get(ctx, account.ID)
+			require.NoError(t, err)
+			assert.Equal(t, account, *repoAccount)
+		}
+
+		accounts, err := repo.List(ctx)
+		require.NoError(t, err)
+		assert.Len(t, accounts, concurrency+1)
+	})
+
+	t.Run("Concurrent updates of different accounts", func(t *testing.T) {
+		var (
+			repo     = newRepo(t)
+			ctx      = context.Background()
+			accounts = make([]internal.Account, concurrency)
+			wg       sync.WaitGroup
+		)
+
+		for i := range accounts {
+			accounts[i] = fakeAccount("0.00")
+			require.NoError(t, repo.Create(ctx, accounts[i]))
+		}
+
+		for i, account := range accounts {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, repo.UpdateBalance(ctx, account.ID, 0, internal.MustParseMoney(fmt.Sprintf("%d.00", i))))
+			}()
+			go func() {
+				defer wg.Done()
+				_, err := repo.Get(ctx, account.ID)
+				assert.NoError(t, err)
+				_, err = repo.List(ctx)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		for i, account := range accounts {
+			repoAccount, err := repo.Get(ctx, account.ID)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(fmt.Sprintf("%d.00", i)), repoAccount.Balance)
+		}
+	})
+
+	t.Run("Concurrent updates of the same account", func(t *testing.T) {
+		var (
+			repo    = newRepo(t)
+			ctx     = context.Background()
+			account = fakeAccount("0.00")
+			wg      sync.WaitGroup
+			mutex   sync.Mutex
+			winners int
+		)
+
+		require.NoError(t, repo.Create(ctx, account))
+
+		for range concurrency {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := repo.UpdateBalance(ctx, account.ID, 0, internal.MustParseMoney("1.00"))
+				if err == nil {
+					mutex.Lock()
+					winners++
+					mutex.Unlock()
+					return
+				}
+				assert.ErrorAs(t, err, &internal.ErrVersionConflict{})
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, winners, "only one update from the same version must succeed")
+
+		repoAccount, err := repo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), repoAccount.Version)
+	})
+}
+
+// TestTransactionsRepository checks the TransactionsRepository contract:
+//   - transactions are returned as they were saved;
+//   - saving a transaction with an existing ID fails;
+//   - saving a second transaction of a transfer with the same type on the
+//     same account returns ErrTransferExists;
+//   - reading a missing transaction returns ErrTransactionNotFound;
+//   - FindAllByAccount returns the account's transactions ordered by
+//     timestamp and then by ID, whatever the order they were saved in;
+//   - Find returns the account's transactions matching the query's filters,
+//     in its order, after its cursor and up to its limit;
+//   - FindAllByTransfer returns the transfer's transactions ordered by ID;
+//   - it is safe for concurrent use.
+func TestTransactionsRepository(t *testing.T, newRepo TransactionsRepositoryFactory) {
+	t.Run("Save and Get", func(t *testing.T) {
+		var (
+			repo        = newRepo(t)
+			ctx         = context.Background()
+			deposit     = fakeTransaction("account", internal.TxDeposit, timestamp)
+			reversal    = fakeTransaction("account", internal.TxWithdrawal, timestamp)
+			fee         = fakeTransaction("account", internal.TxFee, timestamp)
+			transferOut = fakeTransferTransaction("account", "transfer", timestamp)
+		)
+
+		reversal.ReversalOf = deposit.ID
+		fee.FeeFor = reversal.ID
+
+		require.NoError(t, repo.Save(ctx, deposit))
+		require.NoError(t, repo.Save(ctx, reversal))
+		require.NoError(t, repo.Save(ctx, fee))
+		require.NoError(t, repo.Save(ctx, transferOut))
+
+		repoDeposit, err := repo.Get(ctx, deposit.ID)
+		require.NoError(t, err)
+		assert.Equal(t, deposit, repoDeposit)
+
+		repoReversal, err := repo.Get(ctx, reversal.ID)
+		require.NoError(t, err)
+		assert.Equal(t, reversal, repoReversal)
+
+		repoFee, err := repo.Get(ctx, fee.ID)
+		require.NoError(t, err)
+		assert.Equal(t, fee, repoFee)
+
+		repoTransferOut, err := repo.Get(ctx, transferOut.ID)
+		require.NoError(t, err)
+		assertTransferTransaction(t, transferOut, repoTransferOut)
+	})
+
+	t.Run("Duplicated ID", func(t *testing.T) {
+		var (
+			repo        = newRepo(t)
+			ctx         = context.Background()
+			transaction = fakeTransaction("account", internal.TxDeposit, timestamp)
+		)
+
+		require.NoError(t, repo.Save(ctx, transaction))
+
+		duplicate := fakeTransaction("another-account", internal.TxWithdrawal, timestamp)
+		duplicate.ID = transaction.ID
+		require.Error(t, repo.Save(ctx, duplicate))
+
+		repoTransaction, err := repo.Get(ctx, transaction.ID)
+		require.NoError(t, err)
+		assert.Equal(t, transaction, repoTransaction, "the existing transaction must not be overwritten")
+	})
+
+	t.Run("Duplicated transfer leg", func(t *testing.T) {
+		var (
+			repo = newRepo(t)
+			ctx  = context.Background()
+			out  = fakeTransferTransaction("source", "transfer", timestamp)
+			in   = fakeTransferTransaction("destination", "transfer", timestamp)
+		)
+
+		in.Type = internal.TxTransferIn
+		require.NoError(t, repo.Save(ctx, out))
+		require.NoError(t, repo.Save(ctx, in))
+
+		testCases := map[string]struct {
+			transaction internal.Transaction
+			expectedErr bool
+		}{
+			"Same leg":         {transaction: fakeTransferTransaction("source", "transfer", timestamp), expectedErr: true},
+			"Another transfer": {transaction: fakeTransferTransaction("source", "another-transfer", timestamp)},
+		}
+
+		for testName, tc := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				err := repo.Save(ctx, tc.transaction)
+				if !tc.expectedErr {
+					require.NoError(t, err)
+					return
+				}
+
+				var exists internal.ErrTransferExists
+				require.ErrorAs(t, err, &exists)
+				assert.Equal(t, "transfer", exists.TransferID)
+
+				_, err = repo.Get(ctx, tc.transaction.ID)
+				require.ErrorAs(t, err, &internal.ErrTransactionNotFound{}, "the duplicate must not be saved")
+			})
+		}
+
+		transactions, err := repo.FindAllByTransfer(ctx, "transfer")
+		require.NoError(t, err)
+		assert.Len(t, transactions, 2)
+	})
+
+	t.Run("Missing ID", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Get(context.Background(), uuid.NewString())
+		require.ErrorAs(t, err, &internal.ErrTransactionNotFound{})
+	})
+
+	t.Run("FindAllByAccount in chronological order", func(t *testing.T) {
+		var (
+			repo  = newRepo(t)
+			ctx   = context.Background()
+			third = fakeTransaction("account", internal.TxDeposit, timestamp.Add(2*time.Hour))
+			first = fakeTransaction("account", internal.TxDeposit, timestamp)
+			// second and tied share the timestamp, so they are ordered by ID.
+			second = fakeTransaction("account", internal.TxWithdrawal, timestamp.Add(time.Hour))
+			tied   = fakeTransaction("account", internal.TxDeposit, timestamp.Add(time.Hour))
+			other  = fakeTransaction("another-account", internal.TxDeposit, timestamp)
+		)
+
+		if tied.ID < second.ID {
+			second, tied = tied, second
+		}
+
+		for _, transaction := range []internal.Transaction{third, tied, other, second, first} {
+			require.NoError(t, repo.Save(ctx, transaction))
+		}
+
+		transactions, err := repo.FindAllByAccount(ctx, "account")
+		require.NoError(t, err)
+		assert.Equal(t, []internal.Transaction{first, second, tied, third}, transactions)
+
+		transactions, err = repo.FindAllByAccount(ctx, "missing-account")
+		require.NoError(t, err)
+		assert.Empty(t, transactions)
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		var (
+			repo         = newRepo(t)
+			ctx          = context.Background()
+			transactions = make([]internal.Transaction, 0, 6)
+		)
+
+		// Every other transaction is a withdrawal, and the last two share
+		// their timestamp.
+		for i, amount := range []string{"5.00", "10.00", "20.30", "100.00", "9.99", "10.01"} {
+			txType := internal.TxDeposit
+			if i%2 == 1 {
+				txType = internal.TxWithdrawal
+			}
+
+			transaction := fakeTransaction("account", txType, timestamp.Add(time.Duration(min(i, 4))*time.Hour))
+			transaction.Amount = internal.MustParseMoney(amount)
+			transactions = append(transactions, transaction)
+		}
+
+		if transactions[5].ID < transactions[4].ID {
+			transactions[4].ID, transactions[5].ID = transactions[5].ID, transactions[4].ID
+		}
+
+		for _, transaction := range append(slices.Clone(transactions), fakeTransaction("another-account", internal.TxDeposit, timestamp)) {
+			require.NoError(t, repo.Save(ctx, transaction))
+		}
+
+		var (
+			ten    = internal.MustParseMoney("10")
+			twenty = internal.MustParseMoney("20.30")
+			from   = timestamp.Add(time.Hour)
+			to     = timestamp.Add(4 * time.Hour)
+			cursor = internal.CursorOf(transactions[2])
+		)
+
+		testCases := map[string]struct {
+			query    internal.TransactionQuery
+			expected []int
+		}{
+			"All":             {query: internal.TransactionQuery{}, expected: []int{0, 1, 2, 3, 4, 5}},
+			"Descending":      {query: internal.TransactionQuery{Order: internal.SortDescending}, expected: []int{5, 4, 3, 2, 1, 0}},
+			"Types":           {query: internal.TransactionQuery{Types: []internal.TransactionType{internal.TxWithdrawal}}, expected: []int{1, 3, 5}},
+			"Several types":   {query: internal.TransactionQuery{Types: []internal.TransactionType{internal.TxWithdrawal, internal.TxDeposit}}, expected: []int{0, 1, 2, 3, 4, 5}},
+			"Minimum amount":  {query: internal.TransactionQuery{MinAmount: &ten}, expected: []int{1, 2, 3, 5}},
+			"Amount range":    {query: internal.TransactionQuery{MinAmount: &ten, MaxAmount: &twenty}, expected: []int{1, 2, 5}},
+			"Date range":      {query: internal.TransactionQuery{From: &from, To: &to}, expected: []int{1, 2, 3}},
+			"Limit":           {query: internal.TransactionQuery{Limit: 2}, expected: []int{0, 1}},
+			"After":           {query: internal.TransactionQuery{After: &cursor}, expected: []int{3, 4, 5}},
+			"Before":          {query: internal.TransactionQuery{After: &cursor, Order: internal.SortDescending}, expected: []int{1, 0}},
+			"Combined":        {query: internal.TransactionQuery{Types: []internal.TransactionType{internal.TxWithdrawal}, MinAmount: &ten, After: &cursor, Limit: 1}, expected: []int{3}},
+			"Missing account": {query: internal.TransactionQuery{AccountID: "missing-account"}, expected: []int{}},
+		}
+
+		for testName, tc := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				if tc.query.AccountID == "" {
+					tc.query.AccountID = "account"
+				}
+
+				found, err := repo.Find(ctx, tc.query)
+				require.NoError(t, err)
+
+				expected := make([]internal.Transaction, 0, len(tc.expected))
+				for _, i := range tc.expected {
+					expected = append(expected, transactions[i])
+				}
+				assert.Equal(t, expected, found)
+			})
+		}
+
+		// Walking the pages returns every transaction once, even those sharing
+		// their timestamp across pages.
+		for _, order := range []internal.SortOrder{internal.SortAscending, internal.SortDescending} {
+			query := internal.TransactionQuery{AccountID: "account", Order: order, Limit: 5}
+
+			var walked []internal.Transaction
+			for {
+				page, err := repo.Find(ctx, query)
+				require.NoError(t, err)
+				if len(page) == 0 {
+					break
+				}
+
+				walked = append(walked, page...)
+				cursor := internal.CursorOf(page[len(page)-1])
+				query.After = &cursor
+			}
+
+			expected := slices.Clone(transactions)
+			if order == internal.SortDescending {
+				slices.Reverse(expected)
+			}
+			assert.Equal(t, expected, walked, order)
+		}
+	})
+
+	t.Run("Find exact amounts", func(t *testing.T) {
+		var (
+			repo         = newRepo(t)
+			ctx          = context.Background()
+			transactions = make([]internal.Transaction, 0, 3)
+		)
+
+		// The amounts are closer than floating point numbers can tell apart.
+		for i, amount := range []string{"9999999999999999.97", "9999999999999999.98", "9999999999999999.99"} {
+			transaction := fakeTransaction("account", internal.TxDeposit, timestamp.Add(time.Duration(i)*time.Hour))
+			transaction.Amount = money(amount)
+			require.NoError(t, repo.Save(ctx, transaction))
+			transactions = append(transactions, transaction)
+		}
+
+		var (
+			lowest  = money("9999999999999999.97")
+			highest = money("9999999999999999.99")
+		)
+
+		testCases := map[string]struct {
+			query    internal.TransactionQuery
+			expected []int
+		}{
+			"Minimum amount":        {query: internal.TransactionQuery{MinAmount: &highest}, expected: []int{2}},
+			"Maximum amount":        {query: internal.TransactionQuery{MaxAmount: &lowest}, expected: []int{0}},
+			"Single amount":         {query: internal.TransactionQuery{MinAmount: &lowest, MaxAmount: &lowest}, expected: []int{0}},
+			"Limit after the bound": {query: internal.TransactionQuery{MinAmount: &highest, Order: internal.SortDescending, Limit: 1}, expected: []int{2}},
+		}
+
+		for testName, tc := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				tc.query.AccountID = "account"
+
+				found, err := repo.Find(ctx, tc.query)
+				require.NoError(t, err)
+
+				expected := make([]internal.Transaction, 0, len(tc.expected))
+				for _, i := range tc.expected {
+					expected = append(expected, transactions[i])
+				}
+				assert.Equal(t, expected, found)
+			})
+		}
+	})
+
+	t.Run("FindAllByTransfer", func(t *testing.T) {
+		var (
+			repo      = newRepo(t)
+			ctx       = context.Background()
+			out       = fakeTransferTransaction("source", "transfer", timestamp)
+			in        = fakeTransferTransaction("destination", "transfer", timestamp)
+			unrelated = fakeTransferTransaction("source", "another-transfer", timestamp)
+		)
+
+		in.Type = internal.TxTransferIn
+		if in.ID < out.ID {
+			out, in = in, out
+		}
+
+		for _, transaction := range []internal.Transaction{in, unrelated, out} {
+			require.NoError(t, repo.Save(ctx, transaction))
+		}
+
+		transactions, err := repo.FindAllByTransfer(ctx, "transfer")
+		require.NoError(t, err)
+		require.Len(t, transactions, 2)
+		assertTransferTransaction(t, out, transactions[0])
+		assertTransferTransaction(t, in, transactions[1])
+
+		transactions, err = repo.FindAllByTransfer(ctx, "missing-transfer")
+		require.NoError(t, err)
+		assert.Empty(t, transactions)
+	})
+
+	t.Run("Concurrent saves", func(t *testing.T) {
+		var (
+			repo  = newRepo(t)
+			ctx   = context.Background()
+			saved = make(chan internal.Transaction, concurrency)
+			wg    sync.WaitGroup
+		)
+
+		for i := range concurrency {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				transaction := fakeTransaction("account", internal.TxDeposit, timestamp.Add(time.Duration(i)*time.Second))
+				if assert.NoError(t, repo.Save(ctx, transaction)) {
+					saved <- transaction
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				_, err := repo.FindAllByAccount(ctx, "account")
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		close(saved)
+
+		for transaction := range saved {
+			repoTransaction, err := repo.Get(ctx, transaction.ID)
+			require.NoError(t, err)
+			assert.Equal(t, transaction, repoTransaction)
+		}
+
+		transactions, err := repo.FindAllByAccount(ctx, "account")
+		require.NoError(t, err)
+		require.Len(t, transactions, concurrency)
+		for i, transaction := range transactions {
+			assert.Equal(t, timestamp.Add(time.Duration(i)*time.Second), transaction.Timestamp)
+		}
+	})
+}
+
+// TestHoldsRepository checks the HoldsRepository contract:
+//   - holds are returned as they were created or last updated;
+//   - reading or updating a missing hold returns ErrHoldNotFound;
+//   - FindExpired only returns the active holds expired at the given time,
+//     the first to expire first;
+//   - it is safe for concurrent use.
+func TestHoldsRepository(t *testing.T, newRepo HoldsRepositoryFactory) {
+	t.Run("Create and Get", func(t *testing.T) {
+		var (
+			repo = newRepo(t)
+			ctx  = context.Background()
+			hold = fakeHold(timestamp.Add(time.Hour))
+		)
+
+		require.NoError(t, repo.Create(ctx, hold))
+
+		repoHold, err := repo.Get(ctx, hold.ID)
+		require.NoError(t, err)
+		assert.Equal(t, hold, repoHold)
+	})
+
+	t.Run("Duplicated ID", func(t *testing.T) {
+		var (
+			repo = newRepo(t)
+			ctx  = context.Background()
+			hold = fakeHold(timestamp.Add(time.Hour))
+		)
+
+		require.NoError(t, repo.Create(ctx, hold))
+		require.Error(t, repo.Create(ctx, hold))
+	})
+
+	t.Run("Missing ID", func(t *testing.T) {
+		var (
+			repo = newRepo(t)
+			ctx  = context.Background()
+			hold = fakeHold(timestamp.Add(time.Hour))
+		)
+
+		_, err := repo.Get(ctx, hold.ID)
+		var notFound internal.ErrHoldNotFound
+		require.ErrorAs(t, err, &notFound)
+		assert.Equal(t, hold.ID, notFound.HoldID)
+
+		require.ErrorAs(t, repo.Update(ctx, hold), &internal.ErrHoldNotFound{})
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		var (
+			repo     = newRepo(t)
+			ctx      = context.Background()
+			hold     = fakeHold(timestamp.Add(time.Hour))
+			captured = internal.MustParseMoney("7.50")
+		)
+
+		require.NoError(t, repo.Create(ctx, hold))
+
+		hold.Status = internal.HoldCaptured
+		hold.CapturedAmount = &captured
+		hold.TransactionID = uuid.NewString()
+		require.NoError(t, repo.Update(ctx, hold))
+
+		repoHold, err := repo.Get(ctx, hold.ID)
+		require.NoError(t, err)
+		assert.Equal(t, hold, repoHold)
+	})
+
+	t.Run("FindExpired", func(t *testing.T) {
+		var (
+			repo     = newRepo(t)
+			ctx      = context.Background()
+			later    = fakeHold(timestamp.Add(2 * time.Hour))
+			earlier  = fakeHold(timestamp.Add(time.Hour))
+			future   = fakeHold(timestamp.Add(4 * time.Hour))
+			released = fakeHold(timestamp.Add(time.Hour))
+		)
+
+		released.Status = internal.HoldReleased
+		for _, hold := range []internal.Hold{later, earlier, future, released} {
+			require.NoError(t, repo.Create(ctx, hold))
+		}
+
+		holds, err := repo.FindExpired(ctx, timestamp.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []internal.Hold{earlier, later}, holds, "holds expiring exactly at the given time are expired")
+
+		holds, err = repo.FindExpired(ctx, timestamp)
+		require.NoError(t, err)
+		assert.Empty(t, holds)
+	})
+
+	t.Run("Concurrent creations", func(t *testing.T) {
+		var (
+			repo = newRepo(t)
+			ctx  = context.Background()
+			wg   sync.WaitGroup
+		)
+
+		holds := make([]internal.Hold, concurrency)
+		for i := range holds {
+			holds[i] = fakeHold(timestamp.Add(time.Duration(i+1) * time.Minute))
+		}
+
+		for _, hold := range holds {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, repo.Create(ctx, hold))
+			}()
+		}
+		wg.Wait()
+
+		expired, err := repo.FindExpired(ctx, timestamp.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, holds, expired)
+	})
+}
+
+// TestScheduledTransfersRepository checks the ScheduledTransfersRepository
+// contract:
+//   - scheduled transfers are returned as they were created or last updated;
+//   - creating a scheduled transfer with an existing ID fails;
+//   - reading or updating a missing one returns ErrScheduledTransferNotFound;
+//   - FindAllByAccount returns the account's scheduled transfers in creation
+//     order;
+//   - FindDue only returns the active scheduled transfers due at the given
+//     time, retries included, the first due first;
+//   - it is safe for concurrent use.
+func TestScheduledTransfersRepository(t *testing.T, newRepo ScheduledTransfersRepositoryFactory) {
+	t.Run("Create and Get", func(t *testing.T) {
+		var (
+			repo              = newRepo(t)
+			ctx               = context.Background()
+			scheduledTransfer = fakeScheduledTransfer(uuid.NewString(), timestamp.Add(time.Hour))
+		)
+
+		require.NoError(t, repo.Create(ctx, scheduledTransfer))
+
+		repoScheduledTransfer, err := repo.Get(ctx, scheduledTransfer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, scheduledTransfer, repoScheduledTransfer)
+	})
+
+	t.Run("Duplicated ID", func(t *testing.T) {
+		var (
+			repo              = newRepo(t)
+			ctx               = context.Background()
+			scheduledTransfer = fakeScheduledTransfer(uuid.NewString(), timestamp.Add(time.Hour))
+		)
+
+		require.NoError(t, repo.Create(ctx, scheduledTransfer))
+		require.Error(t, repo.Create(ctx, scheduledTransfer))
+	})
+
+	t.Run("Missing ID", func(t *testing.T) {
+		var (
+			repo              = newRepo(t)
+			ctx               = context.Background()
+			scheduledTransfer = fakeScheduledTransfer(uuid.NewString(), timestamp.Add(time.Hour))
+		)
+
+		_, err := repo.Get(ctx, scheduledTransfer.ID)
+		var notFound internal.ErrScheduledTransferNotFound
+		require.ErrorAs(t, err, &notFound)
+		assert.Equal(t, scheduledTransfer.ID, notFound.ScheduledTransferID)
+
+		require.ErrorAs(t, repo.Update(ctx, scheduledTransfer), &internal.ErrScheduledTransferNotFound{})
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		var (
+			repo              = newRepo(t)
+			ctx               = context.Background()
+			scheduledTransfer = fakeScheduledTransfer(uuid.NewString(), timestamp.Add(time.Hour))
+			endAt             = timestamp.Add(48 * time.Hour)
+			retryAt           = timestamp.Add(2 * time.Hour)
+			lastRunAt         = timestamp.Add(time.Hour)
+		)
+
+		require.NoError(t, repo.Create(ctx, scheduledTransfer))
+
+		scheduledTransfer.Schedule = "0 * * * *"
+		scheduledTransfer.EndAt = &endAt
+		scheduledTransfer.MaxOccurrences = 10
+		scheduledTransfer.RetryAt = &retryAt
+		scheduledTransfer.Occurrences = 2
+		scheduledTransfer.Failures = 1
+		scheduledTransfer.Attempts = 1
+		scheduledTransfer.LastRunAt = &lastRunAt
+		scheduledTransfer.LastTransferID = uuid.NewString()
+		scheduledTransfer.LastError = "insufficient balance"
+		require.NoError(t, repo.Update(ctx, scheduledTransfer))
+
+		repoScheduledTransfer, err := repo.Get(ctx, scheduledTransfer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, scheduledTransfer, repoScheduledTransfer)
+	})
+
+	t.Run("FindAllByAccount", func(t *testing.T) {
+		var (
+			repo      = newRepo(t)
+			ctx       = context.Background()
+			accountID = uuid.NewString()
+			first     = fakeScheduledTransfer(accountID, timestamp.Add(time.Hour))
+			second    = fakeScheduledTransfer(accountID, timestamp.Add(time.Hour))
+			other     = fakeScheduledTransfer(uuid.NewString(), timestamp.Add(time.Hour))
+		)
+
+		second.CreatedAt = first.CreatedAt.Add(time.Second)
+		for _, scheduledTransfer := range []internal.ScheduledTransfer{second, other, first} {
+			require.NoError(t, repo.Create(ctx, scheduledTransfer))
+		}
+
+		scheduledTransfers, err := repo.FindAllByAccount(ctx, accountID)
+		require.NoError(t, err)
+		assert.Equal(t, []internal.ScheduledTransfer{first, second}, scheduledTransfers)
+
+		scheduledTransfers, err = repo.FindAllByAccount(ctx, uuid.NewString())
+		require.NoError(t, err)
+		assert.Empty(t, scheduledTransfers)
+	})
+
+	t.Run("FindDue", func(t *testing.T) {
+		var (
+			repo      = newRepo(t)
+			ctx       = context.Background()
+			later     = fakeScheduledTransfer(uuid.NewString(), timestamp.Add(2*time.Hour))
+			earlier   = fakeScheduledTransfer(uuid.NewString(), timestamp.Add(time.Hour))
+			retried   = fakeScheduledTransfer(uuid.NewString(), timestamp)
+			future    = fakeScheduledTransfer(uuid.NewString(), timestamp.Add(4*time.Hour))
+			cancelled = fakeScheduledTransfer(uuid.NewString(), timestamp.Add(time.Hour))
+			retryAt   = timestamp.Add(90 * time.Minute)
+		)
+
+		retried.RetryAt = &retryAt
+		cancelled.Status = internal.ScheduledTransferCancelled
+		for _, scheduledTransfer := range []internal.ScheduledTransfer{later, earlier, retried, future, cancelled} {
+			require.NoError(t, repo.Create(ctx, scheduledTransfer))
+		}
+
+		scheduledTransfers, err := repo.FindDue(ctx, timestamp.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []internal.ScheduledTransfer{earlier, retried, later}, scheduledTransfers,
+			"scheduled transfers due exactly at the given time are due")
+
+		scheduledTransfers, err = repo.FindDue(ctx, timestamp)
+		require.NoError(t, err)
+		assert.Empty(t, scheduledTransfers, "retries replace the time of the occurrence")
+	})
+
+	t.Run("Concurrent creations", func(t *testing.T) {
+		var (
+			repo = newRepo(t)
+			ctx  = context.Background()
+			wg   sync.WaitGroup
+		)
+
+		scheduledTransfers := make([]internal.ScheduledTransfer, concurrency)
+		for i := range scheduledTransfers {
+			scheduledTransfers[i] = fakeScheduledTransfer(uuid.NewString(), timestamp.Add(time.Duration(i+1)*time.Minute))
+		}
+
+		for _, scheduledTransfer := range scheduledTransfers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, repo.Create(ctx, scheduledTransfer))
+			}()
+		}
+		wg.Wait()
+
+		due, err := repo.FindDue(ctx, timestamp.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, scheduledTransfers, due)
+	})
+}
+
+// TestBalanceSnapshotsRepository checks the BalanceSnapshotsRepository
+// contract:
+//   - snapshots are returned as they were saved;
+//   - saving a snapshot of an account at the time of another replaces it;
+//   - FindLatest returns the account's latest snapshot at or before the given
+//     time, and FindNext its earliest one after it, or nil when there's none;
+//   - it is safe for concurrent use.
+func TestBalanceSnapshotsRepository(t *testing.T, newRepo BalanceSnapshotsRepositoryFactory) {
+	t.Run("FindLatest and FindNext", func(t *testing.T) {
+		var (
+			repo      = newRepo(t)
+			ctx       = context.Background()
+			accountID = uuid.NewString()
+			first     = fakeBalanceSnapshot(accountID, timestamp, "10.00")
+			second    = fakeBalanceSnapshot(accountID, timestamp.AddDate(0, 0, 1), "20.00")
+			third     = fakeBalanceSnapshot(accountID, timestamp.AddDate(0, 0, 2), "-5.00")
+		)
+
+		// They're saved out of order, along with the snapshots of another
+		// account in between.
+		for _, snapshot := range []internal.BalanceSnapshot{
+			third,
+			first,
+			fakeBalanceSnapshot(uuid.NewString(), timestamp.Add(time.Hour), "99.00"),
+			second,
+			fakeBalanceSnapshot(uuid.NewString(), timestamp.AddDate(0, 0, 1).Add(time.Hour), "99.00"),
+		} {
+			require.NoError(t, repo.Save(ctx, snapshot))
+		}
+
+		testCases := map[string]struct {
+			at             time.Time
+			expectedLatest *internal.BalanceSnapshot
+			expectedNext   *internal.BalanceSnapshot
+		}{
+			"Before all":     {at: timestamp.Add(-time.Second), expectedNext: &first},
+			"At the first":   {at: timestamp, expectedLatest: &first, expectedNext: &second},
+			"Between":        {at: timestamp.Add(time.Hour), expectedLatest: &first, expectedNext: &second},
+			"At the second":  {at: second.At, expectedLatest: &second, expectedNext: &third},
+			"At the last":    {at: third.At, expectedLatest: &third},
+			"After all":      {at: third.At.Add(time.Second), expectedLatest: &third},
+			"Another offset": {at: second.At.In(time.FixedZone("UTC+2", 2*60*60)), expectedLatest: &second, expectedNext: &third},
+		}
+
+		for testName, tc := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				latest, err := repo.FindLatest(ctx, accountID, tc.at)
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedLatest, latest)
+
+				next, err := repo.FindNext(ctx, accountID, tc.at)
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedNext, next)
+			})
+		}
+
+		latest, err := repo.FindLatest(ctx, uuid.NewString(), third.At)
+		require.NoError(t, err)
+		assert.Nil(t, latest, "accounts without snapshots have none")
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		var (
+			repo      = newRepo(t)
+			ctx       = context.Background()
+			accountID = uuid.NewString()
+			snapshot  = fakeBalanceSnapshot(accountID, timestamp, "10.00")
+		)
+
+		require.NoError(t, repo.Save(ctx, snapshot))
+
+		snapshot.Balance = internal.MustParseMoney("12.50")
+		require.NoError(t, repo.Save(ctx, snapshot))
+
+		latest, err := repo.FindLatest(ctx, accountID, timestamp)
+		require.NoError(t, err)
+		assert.Equal(t, &snapshot, latest)
+
+		next, err := repo.FindNext(ctx, accountID, timestamp.Add(-time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, &snapshot, next)
+
+		next, err = repo.FindNext(ctx, accountID, timestamp)
+		require.NoError(t, err)
+		assert.Nil(t, next, "the snapshot was replaced, not added")
+	})
+
+	t.Run("Concurrent saves", func(t *testing.T) {
+		var (
+			repo      = newRepo(t)
+			ctx       = context.Background()
+			accountID = uuid.NewString()
+			wg        sync.WaitGroup
+		)
+
+		snapshots := make([]internal.BalanceSnapshot, concurrency)
+		for i := range snapshots {
+			snapshots[i] = fakeBalanceSnapshot(accountID, timestamp.AddDate(0, 0, i), fmt.Sprintf("%d.00", i))
+		}
+
+		for _, snapshot := range snapshots {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, repo.Save(ctx, snapshot))
+			}()
+		}
+		wg.Wait()
+
+		at := timestamp.Add(-time.Second)
+		for _, snapshot := range snapshots {
+			next, err := repo.FindNext(ctx, accountID, at)
+			require.NoError(t, err)
+			assert.Equal(t, &snapshot, next)
+			at = next.At
+		}
+	})
+}
+
+// timestamp is a whole second in UTC, so it survives the round trip through
+// any storage precision.
+var timestamp = time.Date(2024, 11, 24, 3, 26, 51, 0, time.UTC)
+
+// money returns the amount in the default currency, as the backends store it.
+func money(amount string) internal.Money {
+	value, err := internal.MustParseMoney(amount).Round(internal.DefaultCurrency)
+	if err != nil {
+		panic(err)
+	}
+
+	return value
+}
+
+func fakeAccount(balance string) internal.Account {
+	openedAt := timestamp
+
+	return internal.Account{
+		ID:             uuid.NewString(),
+		Owner:          "Test User",
+		Currency:       internal.DefaultCurrency,
+		Balance:        money(balance),
+		Status:         internal.AccountActive,
+		OverdraftLimit: internal.NewMoney(0, internal.DefaultCurrency),
+		Held:           internal.NewMoney(0, internal.DefaultCurrency),
+		Type:           internal.AccountChecking,
+		OpenedAt:       &openedAt,
+	}
+}
+
+func fakeBalanceSnapshot(accountID string, at time.Time, balance string) internal.BalanceSnapshot {
+	return internal.BalanceSnapshot{
+		AccountID: accountID,
+		At:        at,
+		Balance:   money(balance),
+		Currency:  internal.DefaultCurrency,
+	}
+}
+
+func fakeHold(expiresAt time.Time) internal.Hold {
+	return internal.Hold{
+		ID:        uuid.NewString(),
+		AccountID: uuid.NewString(),
+		Amount:    internal.MustParseMoney("10.00"),
+		Currency:  internal.DefaultCurrency,
+		Status:    internal.HoldActive,
+		CreatedAt: timestamp,
+		ExpiresAt: expiresAt,
+	}
+}
+
+func fakeScheduledTransfer(sourceAccountID string, nextRunAt time.Time) internal.ScheduledTransfer {
+	return internal.ScheduledTransfer{
+		ID:              uuid.NewString(),
+		SourceAccountID: sourceAccountID,
+		TransferPlan: internal.TransferPlan{
+			DestinationAccountID: uuid.NewString(),
+			Amount:               internal.MustParseMoney("10.00"),
+			Currency:             internal.DefaultCurrency,
+			StartAt:              nextRunAt,
+		},
+		Status:    internal.ScheduledTransferActive,
+		NextRunAt: nextRunAt,
+		CreatedAt: timestamp,
+	}
+}
+
+func fakeTransaction(accountID, txType string, timestamp time.Time) internal.Transaction {
+	return internal.Transaction{
+		ID:        uuid.NewString(),
+		AccountID: accountID,
+		Type:      internal.TransactionType(txType),
+		Amount:    internal.MustParseMoney("20.30"),
+		Currency:  internal.DefaultCurrency,
+		Timestamp: timestamp,
+	}
+}
+
+func fakeTransferTransaction(accountID, transferID string, timestamp time.Time) internal.Transaction {
+	transaction := fakeTransaction(accountID, internal.TxTransferOut, timestamp)
+	transaction.TransferID = transferID
+	transaction.CounterpartyAccountID = "counterparty"
+	transaction.ExchangeRate = &internal.ExchangeRate{
+		From:      internal.DefaultCurrency,
+		To:        "USD",
+		Rate:      internal.MustParseRate("1.0834"),
+		Timestamp: timestamp,
+	}
+
+	return transaction
+}
+
+// assertTransferTransaction compares exchange rates by value, as backends may
+// represent the same rate differently.
+func assertTransferTransaction(t *testing.T, expected, actual internal.Transaction) {
+	t.Helper()
+
+	require.NotNil(t, actual.ExchangeRate)
+	assert.Equal(t, expected.ExchangeRate.Rate.String(), actual.ExchangeRate.Rate.String())
+
+	expectedRate, actualRate := *expected.ExchangeRate, *actual.ExchangeRate
+	expectedRate.Rate, actualRate.Rate = internal.Rate{}, internal.Rate{}
+	assert.Equal(t, expectedRate, actualRate)
+
+	expected.ExchangeRate, actual.ExchangeRate = nil, nil
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,179 @@
+package internal
+
+import "time"
+
+// Reversed adds up the amounts of the transactions reversing the original one.
+func Reversed(original Transaction, transactions []Transaction) (Money, error) {
+	reversed := NewMoney(0, original.Currency)
+	for _, transaction := range transactions {
+		if transaction.ReversalOf != original.ID {
+			continue
+		}
+
+		var err error
+		if reversed, err = reversed.Add(transaction.Amount); err != nil {
+			return Money{}, err
+		}
+	}
+
+	return reversed, nil
+}
+
+// ReversalAmount checks that the amount can still be reversed from the
+// original transaction, given what its previous reversals gave back, and
+// returns it in the transaction's currency. A nil amount means all that
+// remains.
+func ReversalAmount(original Transaction, reversed Money, amount *Money) (Money, error) {
+	if original.ReversalOf != "" {
+		return Money{}, ErrInvalidValue{Msg: "reversals can't be reversed"}
+	}
+
+	remaining, err := original.Amount.Sub(reversed)
+	if err != nil {
+		return Money{}, err
+	}
+
+	if amount == nil {
+		if !remaining.IsPositive() {
+			return Money{}, ErrReversalExceeded{TransactionID: original.ID, Remaining: remaining, Requested: remaining}
+		}
+
+		return remaining, nil
+	}
+
+	value, err := amount.In(original.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	if !value.IsPositive() {
+		return Money{}, ErrInvalidValue{Msg: "the amount to reverse should be greater than 0"}
+	}
+
+	if value.Cmp(remaining) > 0 {
+		return Money{}, ErrReversalExceeded{TransactionID: original.ID, Remaining: remaining, Requested: value}
+	}
+
+	return value, nil
+}
+
+// NewReversal returns the transaction that gives back the amount of the
+// original deposit or withdrawal.
+func NewReversal(id string, original Transaction, amount Money, timestamp time.Time) (Transaction, error) {
+	var txType TransactionType
+	switch original.Type {
+	case TxDeposit:
+		txType = TxWithdrawal
+	case TxWithdrawal:
+		txType = TxDeposit
+	default:
+		return Transaction{}, ErrInvalidValue{Msg: "only deposits and withdrawals can be reversed on their own"}
+	}
+
+	return Transaction{
+		ID:         id,
+		AccountID:  original.AccountID,
+		Type:       txType,
+		Amount:     amount,
+		Currency:   original.Currency,
+		Timestamp:  timestamp,
+		ReversalOf: original.ID,
+	}, nil
+}
+
+// TransferReversalAmounts returns the amounts to give back of both sides of a
+// transfer when reversing the amount of one of them, given what their previous
+// reversals gave back. The other side's amount is converted at the original
+// exchange rate, except when the given side is fully reversed, which reverses
+// all that remains of the other one too.
+func TransferReversalAmounts(
+	given,
+	other Transaction,
+	givenReversed,
+	otherReversed Money,
+	amount *Money,
+) (Money, Money, error) {
+	givenAmount, err := ReversalAmount(given, givenReversed, amount)
+	if err != nil {
+		return Money{}, Money{}, err
+	}
+
+	otherRemaining, err := other.Amount.Sub(otherReversed)
+	if err != nil {
+		return Money{}, Money{}, err
+	}
+
+	givenRemaining, err := given.Amount.Sub(givenReversed)
+	if err != nil {
+		return Money{}, Money{}, err
+	}
+
+	if givenAmount.Cmp(givenRemaining) == 0 {
+		return givenAmount, otherRemaining, nil
+	}
+
+	rate := ExchangeRate{From: given.Currency, To: other.Currency, Rate: IdentityRate()}
+	if er := given.ExchangeRate; er != nil {
+		rate.Rate = er.Rate
+		if er.From != given.Currency {
+			rate.Rate = er.Rate.Inverse()
+		}
+	}
+
+	otherAmount, err := rate.Convert(givenAmount)
+	if err != nil {
+		return Money{}, Money{}, err
+	}
+
+	if otherAmount.Cmp(otherRemaining) > 0 {
+		otherAmount = otherRemaining
+	}
+
+	if !otherAmount.IsPositive() {
+		return Money{}, Money{}, ErrInvalidValue{Msg: "the amount is too small to be converted"}
+	}
+
+	return givenAmount, otherAmount, nil
+}
+
+// NewTransferReversal returns the transfer that sends back outAmount of the
+// original outgoing transaction and inAmount of the incoming one, together
+// with the transactions that book it on the destination and source accounts.
+func NewTransferReversal(
+	id,
+	outID,
+	inID string,
+	out,
+	in Transaction,
+	outAmount,
+	inAmount Money,
+	timestamp time.Time,
+) (Transfer, Transaction, Transaction) {
+	quote := Quote{
+		SourceAmount:        inAmount,
+		SourceCurrency:      in.Currency,
+		DestinationAmount:   outAmount,
+		DestinationCurrency: out.Currency,
+		Rate:                IdentityRate(),
+		RateTimestamp:       timestamp,
+		Fee:                 NewMoney(0, in.Currency),
+	}
+	if er := out.ExchangeRate; er != nil {
+		quote.Rate = er.Rate.Inverse()
+		quote.RateTimestamp = er.Timestamp
+	}
+
+	transfer := Transfer{
+		ID:                   id,
+		SourceAccountID:      in.AccountID,
+		DestinationAccountID: out.AccountID,
+		Timestamp:            timestamp,
+		Quote:                quote,
+	}
+
+	reversalOut, reversalIn := NewTransferTransactions(outID, inID, transfer)
+	reversalOut.ReversalOf = in.ID
+	reversalIn.ReversalOf = out.ID
+
+	return transfer, reversalOut, reversalIn
+}
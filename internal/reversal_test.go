@@ -0,0 +1,168 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReversalAmount(t *testing.T) {
+	original := internal.Transaction{
+		ID:        uuid.NewString(),
+		AccountID: uuid.NewString(),
+		Type:      internal.TxDeposit,
+		Amount:    internal.MustParseMoney("100.00"),
+		Currency:  "EUR",
+	}
+
+	amount := func(s string) *internal.Money {
+		value := internal.MustParseMoney(s)
+		return &value
+	}
+
+	testCases := map[string]struct {
+		original       internal.Transaction
+		reversed       string
+		amount         *internal.Money
+		expectedAmount string
+		expectedError  error
+	}{
+		"Full reversal": {
+			original:       original,
+			reversed:       "0",
+			expectedAmount: "100.00",
+		},
+		"Rest after a partial reversal": {
+			original:       original,
+			reversed:       "30",
+			expectedAmount: "70.00",
+		},
+		"Partial reversal": {
+			original:       original,
+			reversed:       "30",
+			amount:         amount("70"),
+			expectedAmount: "70.00",
+		},
+		"Already reversed": {
+			original:      original,
+			reversed:      "100",
+			expectedError: &internal.ErrReversalExceeded{},
+		},
+		"More than what remains": {
+			original:      original,
+			reversed:      "30",
+			amount:        amount("70.01"),
+			expectedError: &internal.ErrReversalExceeded{},
+		},
+		"Zero amount": {
+			original:      original,
+			reversed:      "0",
+			amount:        amount("0"),
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Invalid precision": {
+			original:      original,
+			reversed:      "0",
+			amount:        amount("1.001"),
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Reversal of a reversal": {
+			original:      internal.Transaction{ID: uuid.NewString(), Amount: internal.MustParseMoney("1.00"), Currency: "EUR", ReversalOf: original.ID},
+			reversed:      "0",
+			expectedError: &internal.ErrInvalidValue{},
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			value, err := internal.ReversalAmount(tc.original, internal.MustParseMoney(tc.reversed), tc.amount)
+			if tc.expectedError != nil {
+				require.ErrorAs(t, err, tc.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(tc.expectedAmount), value)
+		})
+	}
+}
+
+func TestNewReversal(t *testing.T) {
+	deposit, err := internal.NewTransaction(uuid.NewString(), uuid.NewString(), internal.TxDeposit, internal.MustParseMoney("10"), "EUR", time.Now())
+	require.NoError(t, err)
+
+	reversal, err := internal.NewReversal(uuid.NewString(), deposit, internal.MustParseMoney("4.00"), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, internal.TransactionType(internal.TxWithdrawal), reversal.Type)
+	assert.Equal(t, deposit.ID, reversal.ReversalOf)
+	assert.Equal(t, deposit.AccountID, reversal.AccountID)
+
+	// The money goes back to the system account it came from.
+	entry, err := internal.NewTransactionEntry(uuid.NewString(), reversal)
+	require.NoError(t, err)
+	assert.Equal(t, []internal.Posting{
+		{AccountID: deposit.AccountID, Amount: internal.MustParseMoney("-4.00"), Currency: "EUR"},
+		{AccountID: internal.SystemAccountCashIn, Amount: internal.MustParseMoney("4.00"), Currency: "EUR"},
+	}, entry.Postings)
+
+	reversals := []internal.Transaction{reversal, deposit}
+	reversed, err := internal.Reversed(deposit, reversals)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("4.00"), reversed)
+
+	_, err = internal.NewReversal(uuid.NewString(), internal.Transaction{Type: internal.TxTransferOut}, internal.MustParseMoney("1"), time.Now())
+	require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+}
+
+func TestTransferReversal(t *testing.T) {
+	quote, err := internal.NewQuote(internal.MustParseMoney("100.00"), internal.ExchangeRate{
+		From: "EUR",
+		To:   "USD",
+		Rate: internal.MustParseRate("1.0834"),
+	})
+	require.NoError(t, err)
+
+	transfer := internal.Transfer{
+		ID:                   uuid.NewString(),
+		SourceAccountID:      uuid.NewString(),
+		DestinationAccountID: uuid.NewString(),
+		Timestamp:            time.Now(),
+		Quote:                quote,
+	}
+	out, in := internal.NewTransferTransactions(uuid.NewString(), uuid.NewString(), transfer)
+
+	amount := internal.MustParseMoney("10")
+	outAmount, inAmount, err := internal.TransferReversalAmounts(out, in, internal.MustParseMoney("0"), internal.MustParseMoney("0"), &amount)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("10.00"), outAmount)
+	assert.Equal(t, internal.MustParseMoney("10.83"), inAmount)
+
+	amount = internal.MustParseMoney("10.83")
+	inAmount, outAmount, err = internal.TransferReversalAmounts(in, out, internal.MustParseMoney("0"), internal.MustParseMoney("0"), &amount)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("10.00"), outAmount)
+	assert.Equal(t, internal.MustParseMoney("10.83"), inAmount)
+
+	// Reversing the rest gives back exactly what remains on both sides.
+	outAmount, inAmount, err = internal.TransferReversalAmounts(out, in, internal.MustParseMoney("10.00"), internal.MustParseMoney("10.83"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("90.00"), outAmount)
+	assert.Equal(t, internal.MustParseMoney("97.51"), inAmount)
+
+	reversalTransfer, reversalOut, reversalIn := internal.NewTransferReversal(
+		uuid.NewString(), uuid.NewString(), uuid.NewString(), out, in, outAmount, inAmount, time.Now(),
+	)
+	assert.Equal(t, in.AccountID, reversalOut.AccountID)
+	assert.Equal(t, in.ID, reversalOut.ReversalOf)
+	assert.Equal(t, out.AccountID, reversalIn.AccountID)
+	assert.Equal(t, out.ID, reversalIn.ReversalOf)
+
+	entry, err := internal.NewTransferEntry(uuid.NewString(), reversalTransfer)
+	require.NoError(t, err)
+	assertBalanceOf(t, "90.00", []internal.JournalEntry{entry}, out.AccountID, "EUR")
+	assertBalanceOf(t, "-97.51", []internal.JournalEntry{entry}, in.AccountID, "USD")
+}
@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"time"
+)
+
+// ScheduledTransferStatus is the state of a scheduled transfer. They're
+// created active and end up completed, failed or cancelled.
+type ScheduledTransferStatus string
+
+const (
+	ScheduledTransferActive    ScheduledTransferStatus = "active"
+	ScheduledTransferCompleted ScheduledTransferStatus = "completed"
+	ScheduledTransferFailed    ScheduledTransferStatus = "failed"
+	ScheduledTransferCancelled ScheduledTransferStatus = "cancelled"
+)
+
+const (
+	// ScheduledTransferMaxAttempts is how many times an occurrence is tried
+	// before giving up on it.
+	ScheduledTransferMaxAttempts = 3
+	// ScheduledTransferRetryDelay is the wait before retrying a failed
+	// occurrence for the first time. It doubles on every retry.
+	ScheduledTransferRetryDelay = 5 * time.Minute
+)
+
+// TransferPlan is what a scheduled transfer sends and when.
+type TransferPlan struct {
+	DestinationAccountID string   `json:"to_account_id"`
+	Amount               Money    `json:"amount"`
+	Currency             Currency `json:"currency"`
+	// Schedule is the Recurrence of recurring transfers, and empty for one-off
+	// ones.
+	Schedule string `json:"schedule,omitempty"`
+	// StartAt is when one-off transfers run, and the earliest time for
+	// recurring ones, which start right away by default.
+	StartAt time.Time `json:"start_at"`
+	// EndAt and MaxOccurrences optionally limit recurring transfers.
+	EndAt          *time.Time `json:"end_at,omitempty"`
+	MaxOccurrences int        `json:"max_occurrences,omitempty"`
+}
+
+// ScheduledTransfer is a standing order that transfers money from its source
+// account once or recurrently.
+type ScheduledTransfer struct {
+	ID              string `json:"id"`
+	SourceAccountID string `json:"from_account_id"`
+	TransferPlan
+
+	Status ScheduledTransferStatus `json:"status"`
+	// NextRunAt is the time of the next occurrence, which is retried at
+	// RetryAt while it fails.
+	NextRunAt time.Time  `json:"next_run_at"`
+	RetryAt   *time.Time `json:"retry_at,omitempty"`
+	// Occurrences counts the occurrences run or given up, and Failures the
+	// ones given up. Attempts counts the failed attempts of the next one.
+	Occurrences int `json:"occurrences"`
+	Failures    int `json:"failures"`
+	Attempts    int `json:"attempts"`
+
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"`
+	LastTransferID string     `json:"last_transfer_id,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func NewScheduledTransfer(id, sourceAccountID string, plan TransferPlan, now time.Time) (ScheduledTransfer, error) {
+	st := ScheduledTransfer{
+		ID:              id,
+		SourceAccountID: sourceAccountID,
+		Status:          ScheduledTransferActive,
+		CreatedAt:       now,
+	}
+
+	if err := st.Replan(plan, now); err != nil {
+		return ScheduledTransfer{}, err
+	}
+
+	return st, nil
+}
+
+// Replan replaces the plan of an active scheduled transfer. The occurrences
+// already run still count towards MaxOccurrences.
+func (st *ScheduledTransfer) Replan(plan TransferPlan, now time.Time) error {
+	if st.Status != ScheduledTransferActive {
+		return ErrScheduledTransferNotActive{ScheduledTransferID: st.ID, Status: st.Status}
+	}
+
+	if plan.DestinationAccountID == "" {
+		return ErrInvalidValue{Msg: "the destination account is required"}
+	}
+
+	if plan.DestinationAccountID == st.SourceAccountID {
+		return ErrInvalidValue{Msg: "the destination account should be different from the source one"}
+	}
+
+	if _, err := NewCurrency(string(plan.Currency)); err != nil {
+		return err
+	}
+
+	amount, err := plan.Amount.In(plan.Currency)
+	if err != nil {
+		return err
+	}
+
+	if !amount.IsPositive() {
+		return ErrInvalidValue{Msg: "the amount to transfer should be greater than 0"}
+	}
+	plan.Amount = amount
+
+	if plan.MaxOccurrences < 0 {
+		return ErrInvalidValue{Msg: "the maximum number of occurrences can't be negative"}
+	}
+
+	if plan.MaxOccurrences > 0 && st.Occurrences >= plan.MaxOccurrences {
+		return ErrInvalidValue{Msg: "the maximum number of occurrences has already been reached"}
+	}
+
+	nextRunAt := plan.StartAt
+	if plan.Schedule == "" {
+		if plan.StartAt.IsZero() {
+			return ErrInvalidValue{Msg: "one-off scheduled transfers need a start time"}
+		}
+
+		if plan.StartAt.Before(now) {
+			return ErrInvalidValue{Msg: "the start time should be in the future"}
+		}
+	} else {
+		recurrence, err := ParseRecurrence(plan.Schedule, now)
+		if err != nil {
+			return err
+		}
+
+		if plan.StartAt.IsZero() {
+			plan.StartAt = now
+		}
+
+		// The first occurrence may be at the start time itself.
+		nextRunAt = recurrence.Next(latest(plan.StartAt, now).Add(-time.Nanosecond))
+
+		if plan.EndAt != nil && nextRunAt.After(*plan.EndAt) {
+			return ErrInvalidValue{Msg: "the schedule doesn't happen before the end time"}
+		}
+	}
+
+	st.TransferPlan = plan
+	st.NextRunAt = nextRunAt
+	st.RetryAt = nil
+	st.Attempts = 0
+
+	return nil
+}
+
+// DueAt returns when the scheduled transfer should run next.
+func (st *ScheduledTransfer) DueAt() time.Time {
+	if st.RetryAt != nil {
+		return *st.RetryAt
+	}
+
+	return st.NextRunAt
+}
+
+// IsDue reports whether an active scheduled transfer should have run at the
+// given time.
+func (st *ScheduledTransfer) IsDue(now time.Time) bool {
+	return st.Status == ScheduledTransferActive && !st.DueAt().After(now)
+}
+
+// RecordSuccess moves to the next occurrence after the transfer of the current
+// one was made.
+func (st *ScheduledTransfer) RecordSuccess(now time.Time, transferID string) {
+	st.LastRunAt = &now
+	st.LastTransferID = transferID
+	st.LastError = ""
+
+	st.advance(now)
+}
+
+// RecordFailure schedules a retry of the current occurrence, or gives up on it
+// after ScheduledTransferMaxAttempts. Giving up fails one-off transfers and
+// moves recurring ones to their next occurrence.
+func (st *ScheduledTransfer) RecordFailure(now time.Time, err error) {
+	st.LastRunAt = &now
+	st.LastError = err.Error()
+	st.Attempts++
+
+	if st.Attempts < ScheduledTransferMaxAttempts {
+		retryAt := now.Add(ScheduledTransferRetryDelay << (st.Attempts - 1))
+		st.RetryAt = &retryAt
+		return
+	}
+
+	st.Failures++
+	if st.Schedule == "" {
+		st.Occurrences++
+		st.RetryAt = nil
+		st.Status = ScheduledTransferFailed
+		return
+	}
+
+	st.advance(now)
+}
+
+// Cancel stops an active scheduled transfer for good.
+func (st *ScheduledTransfer) Cancel() error {
+	if st.Status != ScheduledTransferActive {
+		return ErrScheduledTransferNotActive{ScheduledTransferID: st.ID, Status: st.Status}
+	}
+
+	st.Status = ScheduledTransferCancelled
+	st.RetryAt = nil
+
+	return nil
+}
+
+// advance moves to the occurrence after the current one, or completes the
+// scheduled transfer when there's none. Occurrences missed while transfers
+// weren't run are skipped.
+func (st *ScheduledTransfer) advance(now time.Time) {
+	st.Occurrences++
+	st.Attempts = 0
+	st.RetryAt = nil
+
+	if st.Schedule == "" || (st.MaxOccurrences > 0 && st.Occurrences >= st.MaxOccurrences) {
+		st.Status = ScheduledTransferCompleted
+		return
+	}
+
+	recurrence, err := ParseRecurrence(st.Schedule, now)
+	if err != nil {
+		st.LastError = err.Error()
+		st.Status = ScheduledTransferFailed
+		return
+	}
+
+	next := recurrence.Next(latest(st.NextRunAt, now))
+	if next.IsZero() || (st.EndAt != nil && next.After(*st.EndAt)) {
+		st.Status = ScheduledTransferCompleted
+		return
+	}
+
+	st.NextRunAt = next
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,178 @@
+package internal_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledTransfer_New(t *testing.T) {
+	var (
+		now           = time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)
+		sourceID      = uuid.NewString()
+		destinationID = uuid.NewString()
+		endAt         = time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
+	)
+
+	testCases := map[string]struct {
+		plan              internal.TransferPlan
+		expectedNextRunAt time.Time
+		expectedError     error
+	}{
+		"One-off": {
+			plan:              internal.TransferPlan{StartAt: now.Add(time.Hour)},
+			expectedNextRunAt: now.Add(time.Hour),
+		},
+		"Recurring": {
+			plan:              internal.TransferPlan{Schedule: "0 9 1 * *"},
+			expectedNextRunAt: time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC),
+		},
+		"Recurring from the start time": {
+			plan:              internal.TransferPlan{Schedule: "0 9 1 * *", StartAt: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)},
+			expectedNextRunAt: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+		},
+		"One-off in the past": {
+			plan:          internal.TransferPlan{StartAt: now.Add(-time.Hour)},
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"One-off without start time": {
+			plan:          internal.TransferPlan{},
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Invalid schedule": {
+			plan:          internal.TransferPlan{Schedule: "every day"},
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Ending before the first occurrence": {
+			plan:          internal.TransferPlan{Schedule: "0 9 1 3 *", EndAt: &endAt},
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Negative maximum of occurrences": {
+			plan:          internal.TransferPlan{Schedule: "@daily", MaxOccurrences: -1},
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Negative amount": {
+			plan:          internal.TransferPlan{Schedule: "@daily", Amount: internal.MustParseMoney("-5")},
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Transfer to itself": {
+			plan:          internal.TransferPlan{Schedule: "@daily", DestinationAccountID: sourceID},
+			expectedError: &internal.ErrInvalidValue{},
+		},
+		"Invalid currency": {
+			plan:          internal.TransferPlan{Schedule: "@daily", Currency: "XYZ"},
+			expectedError: &internal.ErrInvalidValue{},
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			if tc.plan.DestinationAccountID == "" {
+				tc.plan.DestinationAccountID = destinationID
+			}
+
+			if tc.plan.Amount == (internal.Money{}) {
+				tc.plan.Amount = internal.MustParseMoney("20")
+			}
+
+			if tc.plan.Currency == "" {
+				tc.plan.Currency = "EUR"
+			}
+
+			scheduledTransfer, err := internal.NewScheduledTransfer(uuid.NewString(), sourceID, tc.plan, now)
+			if tc.expectedError != nil {
+				require.ErrorAs(t, err, tc.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, internal.ScheduledTransferActive, scheduledTransfer.Status)
+			assert.Equal(t, tc.expectedNextRunAt, scheduledTransfer.NextRunAt)
+			assert.Equal(t, internal.MustParseMoney("20.00"), scheduledTransfer.Amount)
+			assert.False(t, scheduledTransfer.IsDue(now))
+			assert.True(t, scheduledTransfer.IsDue(tc.expectedNextRunAt))
+		})
+	}
+}
+
+func TestScheduledTransfer_Runs(t *testing.T) {
+	var (
+		created = time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)
+		plan    = internal.TransferPlan{
+			DestinationAccountID: uuid.NewString(),
+			Amount:               internal.MustParseMoney("20"),
+			Currency:             "EUR",
+			Schedule:             "0 9 1 * *",
+			MaxOccurrences:       3,
+		}
+		failure = errors.New("insufficient balance")
+	)
+
+	scheduledTransfer, err := internal.NewScheduledTransfer(uuid.NewString(), uuid.NewString(), plan, created)
+	require.NoError(t, err)
+
+	february := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	scheduledTransfer.RecordSuccess(february, "first")
+	assert.Equal(t, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), scheduledTransfer.NextRunAt)
+	assert.Equal(t, 1, scheduledTransfer.Occurrences)
+	assert.Equal(t, "first", scheduledTransfer.LastTransferID)
+
+	// Failed occurrences are retried with a growing delay, until given up.
+	march := scheduledTransfer.NextRunAt
+	scheduledTransfer.RecordFailure(march, failure)
+	require.NotNil(t, scheduledTransfer.RetryAt)
+	assert.Equal(t, march.Add(internal.ScheduledTransferRetryDelay), scheduledTransfer.DueAt())
+	assert.Equal(t, failure.Error(), scheduledTransfer.LastError)
+
+	retry := scheduledTransfer.DueAt()
+	scheduledTransfer.RecordFailure(retry, failure)
+	assert.Equal(t, retry.Add(2*internal.ScheduledTransferRetryDelay), scheduledTransfer.DueAt())
+	assert.Equal(t, march, scheduledTransfer.NextRunAt)
+
+	scheduledTransfer.RecordFailure(scheduledTransfer.DueAt(), failure)
+	assert.Nil(t, scheduledTransfer.RetryAt)
+	assert.Equal(t, internal.ScheduledTransferActive, scheduledTransfer.Status)
+	assert.Equal(t, time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC), scheduledTransfer.NextRunAt)
+	assert.Equal(t, 2, scheduledTransfer.Occurrences)
+	assert.Equal(t, 1, scheduledTransfer.Failures)
+	assert.Zero(t, scheduledTransfer.Attempts)
+
+	// Occurrences missed while the transfers weren't run are skipped.
+	june := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	scheduledTransfer.RecordSuccess(june, "last")
+	assert.Equal(t, internal.ScheduledTransferCompleted, scheduledTransfer.Status)
+	assert.Equal(t, 3, scheduledTransfer.Occurrences)
+	assert.False(t, scheduledTransfer.IsDue(time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)))
+
+	require.ErrorAs(t, scheduledTransfer.Cancel(), &internal.ErrScheduledTransferNotActive{})
+	require.ErrorAs(t, scheduledTransfer.Replan(plan, june), &internal.ErrScheduledTransferNotActive{})
+}
+
+func TestScheduledTransfer_OneOffFailure(t *testing.T) {
+	var (
+		now  = time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)
+		plan = internal.TransferPlan{
+			DestinationAccountID: uuid.NewString(),
+			Amount:               internal.MustParseMoney("20"),
+			Currency:             "EUR",
+			StartAt:              now.Add(time.Hour),
+		}
+	)
+
+	scheduledTransfer, err := internal.NewScheduledTransfer(uuid.NewString(), uuid.NewString(), plan, now)
+	require.NoError(t, err)
+
+	for range internal.ScheduledTransferMaxAttempts {
+		require.Equal(t, internal.ScheduledTransferActive, scheduledTransfer.Status)
+		scheduledTransfer.RecordFailure(scheduledTransfer.DueAt(), errors.New("account frozen"))
+	}
+
+	assert.Equal(t, internal.ScheduledTransferFailed, scheduledTransfer.Status)
+	assert.Equal(t, 1, scheduledTransfer.Failures)
+	assert.Equal(t, "account frozen", scheduledTransfer.LastError)
+}
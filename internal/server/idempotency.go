@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader tells the client the response is a replay.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	idempotencyKeyTTL        = 24 * time.Hour
+	maxIdempotencyKeyLength  = 255
+)
+
+// idempotent makes the handler safe to retry. The first response to a request
+// with an Idempotency-Key header is stored and replayed verbatim when the
+// request is retried with the same key, and requests with the same key are
+// handled one at a time. Reusing a key for a different request fails.
+// Server errors aren't stored, so those requests can be retried.
+func idempotent(store internal.IdempotencyStore, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			processError(w, internal.ErrInvalidValue{Msg: "the idempotency key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Error reading request", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		unlock, err := store.Lock(ctx, key)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+		defer unlock()
+
+		fingerprint := requestFingerprint(r, body)
+
+		stored, ok, err := store.Get(ctx, key)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		if ok {
+			if stored.Fingerprint != fingerprint {
+				processError(w, internal.ErrIdempotencyKeyReused{Key: key})
+				return
+			}
+
+			w.Header().Set("Content-Type", stored.ContentType)
+			w.Header().Set(idempotentReplayedHeader, "true")
+			w.WriteHeader(stored.StatusCode)
+			w.Write(stored.Body)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next(recorder, r)
+
+		if recorder.statusCode >= http.StatusInternalServerError {
+			return
+		}
+
+		response := internal.IdempotentResponse{
+			Fingerprint: fingerprint,
+			StatusCode:  recorder.statusCode,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}
+		if err := store.Save(ctx, key, response, idempotencyKeyTTL); err != nil {
+			// The response was already sent: a retry will run the request again.
+			slog.Error("Storing idempotent response", "key", key, "error", err)
+		}
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response written to the client.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(statusCode int) {
+	if !rr.wroteHeader {
+		rr.statusCode = statusCode
+		rr.wroteHeader = true
+	}
+
+	rr.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rr *responseRecorder) Write(data []byte) (int, error) {
+	rr.wroteHeader = true
+	rr.body.Write(data)
+
+	return rr.ResponseWriter.Write(data)
+}
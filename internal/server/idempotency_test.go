@@ -0,0 +1,125 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+
+		accountsRepo    = app.accountsRepo
+		accountsService = app.accountsService
+	)
+
+	send := func(path, key, body string) *httptest.ResponseRecorder {
+		headers := map[string]string{}
+		if key != "" {
+			headers["Idempotency-Key"] = key
+		}
+
+		return app.do(http.MethodPost, path, body, headers)
+	}
+
+	account, err := accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("0"))
+	require.NoError(t, err)
+	depositPath := "/accounts/" + account.ID + "/transactions"
+
+	t.Run("Retries are replayed", func(t *testing.T) {
+		first := send(depositPath, "deposit-1", `{"type": "deposit", "amount": "10"}`)
+		require.Equal(t, http.StatusOK, first.Code)
+
+		retry := send(depositPath, "deposit-1", `{"type": "deposit", "amount": "10"}`)
+		assert.Equal(t, http.StatusOK, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, first.Header().Get("Content-Type"), retry.Header().Get("Content-Type"))
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+
+		repoAccount, err := accountsRepo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.MustParseMoney("10.00"), repoAccount.Balance)
+	})
+
+	t.Run("Errors are replayed", func(t *testing.T) {
+		first := send(depositPath, "withdrawal-1", `{"type": "withdrawal", "amount": "1000"}`)
+		require.Equal(t, http.StatusForbidden, first.Code)
+
+		retry := send(depositPath, "withdrawal-1", `{"type": "withdrawal", "amount": "1000"}`)
+		assert.Equal(t, http.StatusForbidden, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+	})
+
+	t.Run("Key reused with a different request", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, send(depositPath, "deposit-2", `{"type": "deposit", "amount": "10"}`).Code)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, send(depositPath, "deposit-2", `{"type": "deposit", "amount": "20"}`).Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, send("/transfer", "deposit-2", `{"type": "deposit", "amount": "10"}`).Code)
+	})
+
+	t.Run("Requests without key are not deduplicated", func(t *testing.T) {
+		before, err := accountsRepo.Get(ctx, account.ID)
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusOK, send(depositPath, "", `{"type": "deposit", "amount": "1"}`).Code)
+		require.Equal(t, http.StatusOK, send(depositPath, "", `{"type": "deposit", "amount": "1"}`).Code)
+
+		after, err := accountsRepo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		expected, err := before.Balance.Add(internal.MustParseMoney("2"))
+		require.NoError(t, err)
+		assert.Equal(t, expected, after.Balance)
+	})
+
+	t.Run("Concurrent duplicates", func(t *testing.T) {
+		var (
+			wg        sync.WaitGroup
+			mutex     sync.Mutex
+			responses = make(map[string]int)
+		)
+
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w := send("/accounts", "account-1", `{"owner": "Concurrent", "initial_balance": "5"}`)
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				responses[w.Body.String()]++
+				assert.Equal(t, http.StatusCreated, w.Code)
+			}()
+		}
+		wg.Wait()
+
+		require.Len(t, responses, 1, "every duplicate should get the same response")
+
+		var created struct {
+			ID string `json:"id"`
+		}
+		for body := range responses {
+			require.NoError(t, json.Unmarshal([]byte(body), &created))
+		}
+
+		accounts, err := accountsRepo.List(ctx)
+		require.NoError(t, err)
+
+		var owned int
+		for _, account := range accounts {
+			if account.Owner == "Concurrent" {
+				owned++
+				assert.Equal(t, created.ID, account.ID)
+			}
+		}
+		assert.Equal(t, 1, owned)
+	})
+}
@@ -1,26 +1,66 @@
 package server
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jyisus/bank-server/internal"
 	"github.com/jyisus/bank-server/internal/service"
+	"github.com/jyisus/bank-server/internal/statements"
 )
 
 func addRoutes(
 	mux *http.ServeMux,
 	accountsService *service.AccountService,
 	transactionsService *service.TransactionService,
+	holdsService *service.HoldService,
+	scheduledTransfersService *service.ScheduledTransferService,
+	interestService *service.InterestService,
+	balanceService *service.BalanceService,
+	reconciliationService *service.ReconciliationService,
+	idempotencyStore internal.IdempotencyStore,
+	clock internal.Clock,
+	adminToken string,
 ) {
-	mux.HandleFunc("POST /accounts", createNewAccountHandler(accountsService))
+	mux.HandleFunc("POST /accounts", idempotent(idempotencyStore, createNewAccountHandler(accountsService)))
 	mux.HandleFunc("GET /accounts/{id}", retrieveAccountDetails(accountsService))
 	mux.HandleFunc("GET /accounts", retrieveAllAccounts(accountsService))
-	mux.HandleFunc("POST /accounts/{id}/transactions", createTransactionHandler(transactionsService))
+	mux.HandleFunc("POST /accounts/{id}/freeze", changeAccountStatus(accountsService.FreezeAccount, accountsService.FreezeAccountIfVersion))
+	mux.HandleFunc("POST /accounts/{id}/unfreeze", changeAccountStatus(accountsService.UnfreezeAccount, accountsService.UnfreezeAccountIfVersion))
+	mux.HandleFunc("POST /accounts/{id}/close", changeAccountStatus(accountsService.CloseAccount, accountsService.CloseAccountIfVersion))
+	mux.HandleFunc("POST /accounts/{id}/transactions", idempotent(idempotencyStore, createTransactionHandler(transactionsService)))
 	mux.HandleFunc("GET /accounts/{id}/transactions", retrieveAllTransactions(transactionsService))
-	mux.HandleFunc("POST /transfer", transferBetweenAccounts(accountsService))
+	mux.HandleFunc("GET /accounts/{id}/statements", retrieveStatement(transactionsService, clock))
+	mux.HandleFunc("GET /accounts/{id}/balance", retrieveHistoricalBalance(balanceService))
+	mux.HandleFunc("POST /transfer", idempotent(idempotencyStore, transferBetweenAccounts(accountsService)))
+	mux.HandleFunc("POST /transfer/quote", quoteTransfer(accountsService))
+	mux.HandleFunc("GET /transfers/{id}", retrieveTransfer(transactionsService))
+	mux.HandleFunc("POST /transactions/{id}/reverse", idempotent(idempotencyStore, reverseTransaction(transactionsService)))
+	mux.HandleFunc("POST /accounts/{id}/holds", idempotent(idempotencyStore, placeHold(holdsService)))
+	mux.HandleFunc("GET /holds/{id}", retrieveHold(holdsService))
+	mux.HandleFunc("POST /holds/{id}/capture", idempotent(idempotencyStore, captureHold(holdsService)))
+	mux.HandleFunc("POST /holds/{id}/release", releaseHold(holdsService))
+	mux.HandleFunc("POST /accounts/{id}/scheduled-transfers", idempotent(idempotencyStore, createScheduledTransfer(scheduledTransfersService)))
+	mux.HandleFunc("GET /accounts/{id}/scheduled-transfers", retrieveAllScheduledTransfers(scheduledTransfersService))
+	mux.HandleFunc("GET /accounts/{id}/scheduled-transfers/{scheduledTransferID}", retrieveScheduledTransfer(scheduledTransfersService))
+	mux.HandleFunc("PUT /accounts/{id}/scheduled-transfers/{scheduledTransferID}", updateScheduledTransfer(scheduledTransfersService))
+	mux.HandleFunc("DELETE /accounts/{id}/scheduled-transfers/{scheduledTransferID}", cancelScheduledTransfer(scheduledTransfersService))
+
+	mux.HandleFunc("PUT /admin/accounts/{id}/overdraft-limit", adminOnly(adminToken, setOverdraftLimit(accountsService)))
+	mux.HandleFunc("PUT /admin/accounts/{id}/interest", adminOnly(adminToken, setInterestTerms(interestService)))
+	mux.HandleFunc("POST /admin/interest/accrue", adminOnly(adminToken, accrueInterest(interestService)))
+	mux.HandleFunc("POST /admin/reconciliations", adminOnly(adminToken, reconcileBalances(reconciliationService)))
 }
 
 var accountRepo = map[string]internal.Account{}
@@ -31,8 +71,9 @@ func createNewAccountHandler(
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		type createAccountRequest struct {
-			Owner          string  `json:"owner"`
-			InitialBalance float32 `json:"initial_balance"`
+			Owner          string         `json:"owner"`
+			Currency       string         `json:"currency"`
+			InitialBalance internal.Money `json:"initial_balance"`
 		}
 
 		req, err := decode[createAccountRequest](r)
@@ -41,7 +82,11 @@ func createNewAccountHandler(
 			return
 		}
 
-		newAccount, err := accountsService.CreateAccount(context.Background(), req.Owner, req.InitialBalance)
+		if req.Currency == "" {
+			req.Currency = string(internal.DefaultCurrency)
+		}
+
+		newAccount, err := accountsService.CreateAccount(context.Background(), req.Owner, req.Currency, req.InitialBalance)
 		if err != nil {
 			processError(w, err)
 			return
@@ -61,37 +106,262 @@ func retrieveAccountDetails(accountsService *service.AccountService) http.Handle
 	return func(w http.ResponseWriter, r *http.Request) {
 		accountID := r.PathValue("id")
 
-		service, err := accountsService.GetAccount(context.Background(), accountID)
+		account, err := accountsService.GetAccount(context.Background(), accountID)
 		if err != nil {
 			processError(w, err)
 			return
 		}
 
-		encode(w, http.StatusOK, service)
+		etag := accountETag(account)
+		w.Header().Set("ETag", etag)
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		encode(w, http.StatusOK, account)
 	}
 }
 
+// retrieveAllAccounts returns a page of the accounts, and the cursor of the
+// next one in the Next-Cursor header unless it's the last.
 func retrieveAllAccounts(accountsService *service.AccountService) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseAccountQuery(r)
+		if err != nil {
+			processError(w, err)
+			return
+		}
 
-		accounts, err := accountsService.ListAccounts(context.Background())
+		page, err := accountsService.ListAccounts(context.Background(), query)
 		if err != nil {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			processError(w, err)
 			return
 		}
 
-		if len(accounts) == 0 {
-			encode(w, http.StatusNoContent, accounts)
+		if page.NextCursor != "" {
+			w.Header().Set("Next-Cursor", page.NextCursor)
+		}
+
+		if len(page.Accounts) == 0 {
+			encode(w, http.StatusNoContent, page.Accounts)
 			return
 		}
 
-		encode(w, http.StatusOK, accounts)
+		encode(w, http.StatusOK, page.Accounts)
+	}
+}
+
+// parseAccountQuery reads the filters of the accounts from the query string:
+// owner, the start of the owner's name, min_balance, max_balance, status,
+// which can be repeated or separated by commas, limit and cursor.
+func parseAccountQuery(r *http.Request) (internal.AccountQuery, error) {
+	var (
+		values = r.URL.Query()
+		query  = internal.AccountQuery{OwnerPrefix: values.Get("owner"), After: values.Get("cursor")}
+		err    error
+	)
+
+	for _, value := range values["status"] {
+		for _, status := range strings.Split(value, ",") {
+			query.Statuses = append(query.Statuses, internal.AccountStatus(strings.TrimSpace(status)))
+		}
+	}
+
+	if query.MinBalance, err = parseOptionalMoney(values, "min_balance"); err != nil {
+		return internal.AccountQuery{}, err
+	}
+
+	if query.MaxBalance, err = parseOptionalMoney(values, "max_balance"); err != nil {
+		return internal.AccountQuery{}, err
+	}
+
+	if query.Limit, err = parseOptionalLimit(values); err != nil {
+		return internal.AccountQuery{}, err
+	}
+
+	return query, nil
+}
+
+// changeAccountStatus responds with the account after applying the status
+// change, only to the version required by the If-Match header if any.
+func changeAccountStatus(
+	change func(ctx context.Context, id string) (*internal.Account, error),
+	changeIfVersion func(ctx context.Context, id string, expectedVersion int64) (*internal.Account, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountID := r.PathValue("id")
+
+		expectedVersion, conditional, err := parseIfMatch(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var account *internal.Account
+		if conditional {
+			account, err = changeIfVersion(context.Background(), accountID, expectedVersion)
+		} else {
+			account, err = change(context.Background(), accountID)
+		}
+
+		if conditional && errors.As(err, &internal.ErrVersionConflict{}) {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		w.Header().Set("ETag", accountETag(account))
+		encode(w, http.StatusOK, account)
+	}
+}
+
+func setOverdraftLimit(accountsService *service.AccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type overdraftLimitRequest struct {
+			OverdraftLimit internal.Money `json:"overdraft_limit"`
+		}
+
+		accountID := r.PathValue("id")
+
+		req, err := decode[overdraftLimitRequest](r)
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		expectedVersion, conditional, err := parseIfMatch(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var account *internal.Account
+		if conditional {
+			account, err = accountsService.SetOverdraftLimitIfVersion(context.Background(), accountID, expectedVersion, req.OverdraftLimit)
+		} else {
+			account, err = accountsService.SetOverdraftLimit(context.Background(), accountID, req.OverdraftLimit)
+		}
+
+		if conditional && errors.As(err, &internal.ErrVersionConflict{}) {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		w.Header().Set("ETag", accountETag(account))
+		encode(w, http.StatusOK, account)
+	}
+}
+
+func setInterestTerms(interestService *service.InterestService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type interestTermsRequest struct {
+			Type         string                `json:"type"`
+			InterestRate internal.InterestRate `json:"interest_rate"`
+		}
+
+		accountID := r.PathValue("id")
+
+		req, err := decode[interestTermsRequest](r)
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		accountType, err := internal.NewAccountType(req.Type)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		expectedVersion, conditional, err := parseIfMatch(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var account *internal.Account
+		if conditional {
+			account, err = interestService.SetInterestTermsIfVersion(context.Background(), accountID, expectedVersion, accountType, req.InterestRate)
+		} else {
+			account, err = interestService.SetInterestTerms(context.Background(), accountID, accountType, req.InterestRate)
+		}
+
+		if conditional && errors.As(err, &internal.ErrVersionConflict{}) {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		w.Header().Set("ETag", accountETag(account))
+		encode(w, http.StatusOK, account)
+	}
+}
+
+// accrueInterest accrues the interest of the accounts through the as_of day,
+// yesterday by default, so missed days can be backfilled.
+func accrueInterest(interestService *service.InterestService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var asOf time.Time
+		if value := r.URL.Query().Get("as_of"); value != "" {
+			var err error
+			asOf, err = time.Parse(time.DateOnly, value)
+			if err != nil {
+				http.Error(w, "Invalid as_of date, it should be like 2006-01-02", http.StatusBadRequest)
+				return
+			}
+		}
+
+		accrual, err := interestService.AccrueInterest(context.Background(), asOf)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusOK, accrual)
+	}
+}
+
+func reconcileBalances(reconciliationService *service.ReconciliationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var adjust bool
+		if value := r.URL.Query().Get("adjust"); value != "" {
+			var err error
+			adjust, err = strconv.ParseBool(value)
+			if err != nil {
+				http.Error(w, "Invalid adjust, it should be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		reconciliation, err := reconciliationService.Reconcile(context.Background(), adjust)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusOK, reconciliation)
 	}
 }
 
 type CreateTransactionRequest struct {
-	Type   string  `json:"type"`
-	Amount float32 `json:"amount"`
+	Type     string         `json:"type"`
+	Amount   internal.Money `json:"amount"`
+	Currency string         `json:"currency"`
 }
 
 func createTransactionHandler(transactionService *service.TransactionService) http.HandlerFunc {
@@ -105,77 +375,672 @@ func createTransactionHandler(transactionService *service.TransactionService) ht
 			return
 		}
 
-		tx, err := transactionService.SaveTransaction(
-			context.Background(),
-			accountID,
-			transaction.Type,
-			transaction.Amount,
+		currency, err := parseOptionalCurrency(transaction.Currency)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		expectedVersion, conditional, err := parseIfMatch(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var (
+			tx      *internal.Transaction
+			account *internal.Account
 		)
+		if conditional {
+			tx, account, err = transactionService.SaveTransactionIfVersion(
+				context.Background(),
+				accountID,
+				expectedVersion,
+				transaction.Type,
+				transaction.Amount,
+				currency,
+			)
+		} else {
+			tx, account, err = transactionService.SaveTransaction(
+				context.Background(),
+				accountID,
+				transaction.Type,
+				transaction.Amount,
+				currency,
+			)
+		}
+
+		if conditional && errors.As(err, &internal.ErrVersionConflict{}) {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
 		if err != nil {
 			processError(w, err)
 			return
 		}
 
+		w.Header().Set("ETag", accountETag(account))
 		encode(w, http.StatusOK, tx)
 	}
 }
 
+// retrieveAllTransactions returns a page of the account's transactions, and
+// the cursor of the next one in the Next-Cursor header unless it's the last.
 func retrieveAllTransactions(transactionService *service.TransactionService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		accountID := r.PathValue("id")
+		query, err := parseTransactionQuery(r)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		page, err := transactionService.ListAccountTransactions(context.Background(), query)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		if page.NextCursor != "" {
+			w.Header().Set("Next-Cursor", page.NextCursor)
+		}
+
+		if len(page.Transactions) == 0 {
+			encode(w, http.StatusNoContent, page.Transactions)
+			return
+		}
+
+		encode(w, http.StatusOK, page.Transactions)
+	}
+}
+
+// parseTransactionQuery reads the filters of the account's transactions from
+// the query string: type, which can be repeated or separated by commas,
+// min_amount, max_amount, from and to as RFC 3339 timestamps, sort (asc or
+// desc), limit and cursor.
+func parseTransactionQuery(r *http.Request) (internal.TransactionQuery, error) {
+	var (
+		values = r.URL.Query()
+		query  = internal.TransactionQuery{AccountID: r.PathValue("id")}
+	)
+
+	for _, value := range values["type"] {
+		for _, txType := range strings.Split(value, ",") {
+			parsed, err := internal.ParseTransactionType(strings.TrimSpace(txType))
+			if err != nil {
+				return internal.TransactionQuery{}, err
+			}
+
+			query.Types = append(query.Types, parsed)
+		}
+	}
+
+	var err error
+	if query.MinAmount, err = parseOptionalMoney(values, "min_amount"); err != nil {
+		return internal.TransactionQuery{}, err
+	}
 
-		transactions, err := transactionService.RetrieveAccountTransactions(context.Background(), accountID)
+	if query.MaxAmount, err = parseOptionalMoney(values, "max_amount"); err != nil {
+		return internal.TransactionQuery{}, err
+	}
+
+	if query.From, err = parseOptionalTimestamp(values, "from"); err != nil {
+		return internal.TransactionQuery{}, err
+	}
+
+	if query.To, err = parseOptionalTimestamp(values, "to"); err != nil {
+		return internal.TransactionQuery{}, err
+	}
+
+	if value := values.Get("sort"); value != "" {
+		order, err := internal.NewSortOrder(value)
+		if err != nil {
+			return internal.TransactionQuery{}, err
+		}
+
+		query.Order = order
+	}
+
+	if query.Limit, err = parseOptionalLimit(values); err != nil {
+		return internal.TransactionQuery{}, err
+	}
+
+	if value := values.Get("cursor"); value != "" {
+		cursor, err := internal.ParseTransactionCursor(value)
+		if err != nil {
+			return internal.TransactionQuery{}, err
+		}
+
+		query.After = &cursor
+	}
+
+	return query, nil
+}
+
+func parseOptionalMoney(values url.Values, name string) (*internal.Money, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	amount, err := internal.ParseMoney(value)
+	if err != nil {
+		return nil, internal.ErrInvalidValue{Msg: fmt.Sprintf("invalid %s %q", name, value)}
+	}
+
+	return &amount, nil
+}
+
+// parseOptionalLimit returns the page size in limit, or 0 when there's none.
+func parseOptionalLimit(values url.Values) (int, error) {
+	value := values.Get("limit")
+	if value == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return 0, internal.ErrInvalidValue{Msg: fmt.Sprintf("invalid limit %q", value)}
+	}
+
+	return limit, nil
+}
+
+func parseOptionalTimestamp(values url.Values, name string) (*time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, internal.ErrInvalidValue{Msg: fmt.Sprintf("invalid %s %q, it should be like 2006-01-02T15:04:05Z", name, value)}
+	}
+
+	return &t, nil
+}
+
+// retrieveHistoricalBalance returns the balance the account had at the RFC
+// 3339 timestamp in at.
+func retrieveHistoricalBalance(balanceService *service.BalanceService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		at, err := parseOptionalTimestamp(r.URL.Query(), "at")
 		if err != nil {
 			processError(w, err)
 			return
 		}
 
-		if len(transactions) == 0 {
-			encode(w, http.StatusNoContent, transactions)
+		if at == nil {
+			processError(w, internal.ErrInvalidValue{Msg: "the time of the balance is needed in at"})
+			return
+		}
+
+		balance, err := balanceService.BalanceAt(context.Background(), r.PathValue("id"), *at)
+		if err != nil {
+			processError(w, err)
 			return
 		}
-		// slog.Info("Retrieving all accounts", "accounts", len(transactions))
 
-		encode(w, http.StatusOK, transactions)
+		encode(w, http.StatusOK, balance)
 	}
 }
 
+func retrieveStatement(transactionService *service.TransactionService, clock internal.Clock) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, err := parseStatementPeriod(r.URL.Query(), clock.Now())
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		format := cmp.Or(r.URL.Query().Get("format"), "json")
+		write, ok := statementWriters[format]
+		if !ok {
+			processError(w, internal.ErrInvalidValue{Msg: fmt.Sprintf("invalid format %q, it should be json, csv or pdf", format)})
+			return
+		}
+
+		statement, err := transactionService.GenerateStatement(context.Background(), r.PathValue("id"), from, to)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		// The statement is rendered before anything is written, so a failure
+		// can still be reported with its status.
+		var body bytes.Buffer
+		if err := write.render(&body, *statement); err != nil {
+			processError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", write.contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(
+			"attachment; filename=\"statement-%s-%s-%s.%s\"",
+			statement.AccountID,
+			statement.From.Format(time.DateOnly),
+			statement.To.Format(time.DateOnly),
+			format,
+		))
+		w.WriteHeader(http.StatusOK)
+		body.WriteTo(w)
+	}
+}
+
+type statementWriter struct {
+	contentType string
+	render      func(io.Writer, internal.Statement) error
+}
+
+var statementWriters = map[string]statementWriter{
+	"json": {contentType: "application/json", render: func(w io.Writer, statement internal.Statement) error {
+		return json.NewEncoder(w).Encode(statement)
+	}},
+	"csv": {contentType: "text/csv", render: statements.WriteCSV},
+	"pdf": {contentType: "application/pdf", render: statements.WritePDF},
+}
+
+// parseStatementPeriod reads the days of the statement from from and to, both
+// included. Without them, the statement covers the last calendar month before
+// now.
+func parseStatementPeriod(values url.Values, now time.Time) (time.Time, time.Time, error) {
+	fromValue, toValue := values.Get("from"), values.Get("to")
+	if fromValue == "" && toValue == "" {
+		firstOfMonth := internal.Date(now).AddDate(0, 0, 1-now.UTC().Day())
+		return firstOfMonth.AddDate(0, -1, 0), firstOfMonth.AddDate(0, 0, -1), nil
+	}
+
+	if fromValue == "" || toValue == "" {
+		return time.Time{}, time.Time{}, internal.ErrInvalidValue{Msg: "both from and to are needed, or none of them"}
+	}
+
+	from, err := time.Parse(time.DateOnly, fromValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, internal.ErrInvalidValue{Msg: fmt.Sprintf("invalid from %q, it should be like 2006-01-02", fromValue)}
+	}
+
+	to, err := time.Parse(time.DateOnly, toValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, internal.ErrInvalidValue{Msg: fmt.Sprintf("invalid to %q, it should be like 2006-01-02", toValue)}
+	}
+
+	return from, to, nil
+}
+
+type transferRequest struct {
+	FromAccountID string         `json:"from_account_id"`
+	ToAccountID   string         `json:"to_account_id"`
+	Amount        internal.Money `json:"amount"`
+	Currency      string         `json:"currency,omitempty"`
+}
+
 func transferBetweenAccounts(accountsService *service.AccountService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		type tranferRequest struct {
-			FromAccountID string  `json:"from_account_id"`
-			ToAccountID   string  `json:"to_account_id"`
-			Amount        float32 `json:"amount"`
+		req, err := decode[transferRequest](r)
+		if err != nil {
+			http.Error(w, "Error decoding request", http.StatusBadRequest)
+			return
 		}
 
-		req, err := decode[tranferRequest](r)
+		currency, err := parseOptionalCurrency(req.Currency)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		transfer, err := accountsService.Transfer(
+			context.Background(),
+			req.FromAccountID,
+			req.ToAccountID,
+			req.Amount,
+			currency,
+		)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusOK, transfer)
+	}
+}
+
+// quoteTransfer returns what the transfer would send, deliver and cost without
+// making it.
+func quoteTransfer(accountsService *service.AccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := decode[transferRequest](r)
 		if err != nil {
 			http.Error(w, "Error decoding request", http.StatusBadRequest)
+			return
+		}
+
+		currency, err := parseOptionalCurrency(req.Currency)
+		if err != nil {
+			processError(w, err)
+			return
 		}
 
-		if err := accountsService.Transfer(
+		quote, err := accountsService.QuoteTransfer(
 			context.Background(),
 			req.FromAccountID,
 			req.ToAccountID,
 			req.Amount,
-		); err != nil {
+			currency,
+		)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusOK, quote)
+	}
+}
+
+func retrieveTransfer(transactionService *service.TransactionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		transferID := r.PathValue("id")
+
+		transactions, err := transactionService.RetrieveTransfer(context.Background(), transferID)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		response := struct {
+			ID           string                 `json:"id"`
+			Transactions []internal.Transaction `json:"transactions"`
+		}{
+			ID:           transferID,
+			Transactions: transactions,
+		}
+
+		encode(w, http.StatusOK, response)
+	}
+}
+
+func reverseTransaction(transactionService *service.TransactionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type reverseTransactionRequest struct {
+			Amount *internal.Money `json:"amount,omitempty"`
+		}
+
+		transactionID := r.PathValue("id")
+
+		// The body is optional, as all that remains is reversed without amount.
+		var req reverseTransactionRequest
+		if r.ContentLength != 0 {
+			var err error
+			if req, err = decode[reverseTransactionRequest](r); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		reversals, err := transactionService.ReverseTransaction(context.Background(), transactionID, req.Amount)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		response := struct {
+			ReversalOf   string                 `json:"reversal_of"`
+			Transactions []internal.Transaction `json:"transactions"`
+		}{
+			ReversalOf:   transactionID,
+			Transactions: reversals,
+		}
+
+		encode(w, http.StatusOK, response)
+	}
+}
+
+func placeHold(holdsService *service.HoldService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type placeHoldRequest struct {
+			Amount    internal.Money `json:"amount"`
+			Currency  string         `json:"currency,omitempty"`
+			ExpiresAt time.Time      `json:"expires_at"`
+		}
+
+		accountID := r.PathValue("id")
+
+		req, err := decode[placeHoldRequest](r)
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		currency, err := parseOptionalCurrency(req.Currency)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		hold, err := holdsService.PlaceHold(context.Background(), accountID, req.Amount, currency, req.ExpiresAt)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusCreated, hold)
+	}
+}
+
+func retrieveHold(holdsService *service.HoldService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hold, err := holdsService.GetHold(context.Background(), r.PathValue("id"))
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusOK, hold)
+	}
+}
+
+func captureHold(holdsService *service.HoldService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type captureHoldRequest struct {
+			Amount *internal.Money `json:"amount,omitempty"`
+		}
+
+		// The body is optional, as the whole hold is captured without amount.
+		var req captureHoldRequest
+		if r.ContentLength != 0 {
+			var err error
+			if req, err = decode[captureHoldRequest](r); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		hold, err := holdsService.CaptureHold(context.Background(), r.PathValue("id"), req.Amount)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusOK, hold)
+	}
+}
+
+func releaseHold(holdsService *service.HoldService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hold, err := holdsService.ReleaseHold(context.Background(), r.PathValue("id"))
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusOK, hold)
+	}
+}
+
+func createScheduledTransfer(scheduledTransfersService *service.ScheduledTransferService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		plan, err := decodeTransferPlan(r)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		scheduledTransfer, err := scheduledTransfersService.CreateScheduledTransfer(context.Background(), r.PathValue("id"), plan)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusCreated, scheduledTransfer)
+	}
+}
+
+func retrieveAllScheduledTransfers(scheduledTransfersService *service.ScheduledTransferService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheduledTransfers, err := scheduledTransfersService.ListScheduledTransfers(context.Background(), r.PathValue("id"))
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusOK, scheduledTransfers)
+	}
+}
+
+func retrieveScheduledTransfer(scheduledTransfersService *service.ScheduledTransferService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheduledTransfer, err := scheduledTransfersService.GetScheduledTransfer(
+			context.Background(),
+			r.PathValue("id"),
+			r.PathValue("scheduledTransferID"),
+		)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusOK, scheduledTransfer)
+	}
+}
+
+func updateScheduledTransfer(scheduledTransfersService *service.ScheduledTransferService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		plan, err := decodeTransferPlan(r)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		scheduledTransfer, err := scheduledTransfersService.UpdateScheduledTransfer(
+			context.Background(),
+			r.PathValue("id"),
+			r.PathValue("scheduledTransferID"),
+			plan,
+		)
+		if err != nil {
+			processError(w, err)
+			return
+		}
+
+		encode(w, http.StatusOK, scheduledTransfer)
+	}
+}
+
+// cancelScheduledTransfer keeps the cancelled scheduled transfer, which is
+// returned, so its history isn't lost.
+func cancelScheduledTransfer(scheduledTransfersService *service.ScheduledTransferService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheduledTransfer, err := scheduledTransfersService.CancelScheduledTransfer(
+			context.Background(),
+			r.PathValue("id"),
+			r.PathValue("scheduledTransferID"),
+		)
+		if err != nil {
 			processError(w, err)
 			return
 		}
 
-		encode(w, http.StatusOK, req)
+		encode(w, http.StatusOK, scheduledTransfer)
+	}
+}
+
+func decodeTransferPlan(r *http.Request) (internal.TransferPlan, error) {
+	plan, err := decode[internal.TransferPlan](r)
+	if err != nil {
+		return internal.TransferPlan{}, internal.ErrInvalidValue{Msg: "Invalid request body"}
+	}
+
+	plan.Currency, err = parseOptionalCurrency(string(plan.Currency))
+	if err != nil {
+		return internal.TransferPlan{}, err
+	}
+
+	return plan, nil
+}
+
+// parseOptionalCurrency returns an empty currency when no code is given so the
+// services can fall back to the account's currency.
+func parseOptionalCurrency(code string) (internal.Currency, error) {
+	if code == "" {
+		return "", nil
+	}
+
+	return internal.NewCurrency(code)
+}
+
+// accountETag identifies the account's current state by its version.
+func accountETag(account *internal.Account) string {
+	return strconv.Quote(strconv.FormatInt(account.Version, 10))
+}
+
+// parseIfMatch returns the account version required by the If-Match header.
+// It reports false when there's no header or it's "*", which any existing
+// account matches.
+func parseIfMatch(r *http.Request) (int64, bool, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" || header == "*" {
+		return 0, false, nil
 	}
+
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, false, errors.New("If-Match should be a single strong ETag")
+	}
+
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("ETag %s doesn't match any account version", header)
+	}
+
+	return version, true, nil
 }
 
 func processError(w http.ResponseWriter, err error) {
 	switch {
-	case errors.As(err, &internal.ErrAccountNotFound{}):
+	case errors.As(err, &internal.ErrAccountNotFound{}),
+		errors.As(err, &internal.ErrTransferNotFound{}),
+		errors.As(err, &internal.ErrTransactionNotFound{}),
+		errors.As(err, &internal.ErrHoldNotFound{}),
+		errors.As(err, &internal.ErrScheduledTransferNotFound{}):
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	case errors.As(err, &internal.ErrInsufficientBalance{}):
 		// NOTE: I'm using 403 here beacuse it common in this context, but I'm not sure if it's the best fit
 		http.Error(w, err.Error(), http.StatusForbidden)
 		return
+	case errors.As(err, &internal.ErrCurrencyMismatch{}),
+		errors.As(err, &internal.ErrExchangeRateNotFound{}),
+		errors.As(err, &internal.ErrIdempotencyKeyReused{}):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case errors.As(err, &internal.ErrVersionConflict{}),
+		errors.As(err, &internal.ErrAccountStatus{}),
+		errors.As(err, &internal.ErrAccountNotEmpty{}),
+		errors.As(err, &internal.ErrHoldNotActive{}),
+		errors.As(err, &internal.ErrScheduledTransferNotActive{}),
+		errors.As(err, &internal.ErrReversalExceeded{}),
+		errors.As(err, &internal.ErrHistoryMismatch{}):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
 	case errors.As(err, &internal.ErrInvalidValue{}):
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
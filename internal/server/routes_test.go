@@ -0,0 +1,943 @@
+package server_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/memrepo"
+	"github.com/jyisus/bank-server/internal/server"
+	"github.com/jyisus/bank-server/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountETag(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+	)
+
+	account, err := app.accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("10"))
+	require.NoError(t, err)
+
+	accountPath := "/accounts/" + account.ID
+	depositPath := accountPath + "/transactions"
+	deposit := `{"type": "deposit", "amount": "5"}`
+
+	w := app.do(http.MethodGet, accountPath, "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"0"`, etag)
+
+	w = app.do(http.MethodGet, accountPath, "", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// Every successful deposit changes the version, so the cases run in order.
+	testCases := []struct {
+		name           string
+		ifMatch        string
+		expectedStatus int
+	}{
+		{name: "Current version", ifMatch: `"0"`, expectedStatus: http.StatusOK},
+		{name: "Stale version", ifMatch: `"0"`, expectedStatus: http.StatusPreconditionFailed},
+		{name: "Any version", ifMatch: "*", expectedStatus: http.StatusOK},
+		{name: "Invalid ETag", ifMatch: "0", expectedStatus: http.StatusBadRequest},
+		{name: "Unconditional write", ifMatch: "", expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tc.ifMatch != "" {
+				headers["If-Match"] = tc.ifMatch
+			}
+
+			before, err := app.accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+
+			w := app.do(http.MethodPost, depositPath, deposit, headers)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+
+			after, err := app.accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+
+			if tc.expectedStatus != http.StatusOK {
+				assert.Equal(t, before, after, "the account must not change when the precondition fails")
+				return
+			}
+
+			assert.Equal(t, before.Version+1, after.Version)
+			expected, err := before.Balance.Add(internal.MustParseMoney("5"))
+			require.NoError(t, err)
+			assert.Equal(t, expected, after.Balance)
+			assert.Equal(t, strconv.Quote(strconv.FormatInt(after.Version, 10)), w.Header().Get("ETag"))
+		})
+	}
+
+	w = app.do(http.MethodGet, accountPath, "", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, w.Code, "the account changed since the ETag was returned")
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+}
+
+func TestAccountETag_AccountChanges(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+	)
+
+	account, err := app.accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("10"))
+	require.NoError(t, err)
+
+	var (
+		accountPath  = "/accounts/" + account.ID
+		limitPath    = "/admin/accounts/" + account.ID + "/overdraft-limit"
+		interestPath = "/admin/accounts/" + account.ID + "/interest"
+		interest     = `{"type": "savings", "interest_rate": "0.02"}`
+	)
+
+	// Every successful change increases the version, so the cases run in
+	// order.
+	testCases := []struct {
+		name            string
+		method          string
+		path            string
+		body            string
+		ifMatch         string
+		expectedStatus  int
+		expectedVersion int64
+	}{
+		{name: "Freeze stale version", method: http.MethodPost, path: accountPath + "/freeze", ifMatch: `"5"`, expectedStatus: http.StatusPreconditionFailed},
+		{name: "Freeze invalid ETag", method: http.MethodPost, path: accountPath + "/freeze", ifMatch: "0", expectedStatus: http.StatusBadRequest},
+		{name: "Freeze", method: http.MethodPost, path: accountPath + "/freeze", ifMatch: `"0"`, expectedStatus: http.StatusOK, expectedVersion: 1},
+		{name: "Unfreeze stale version", method: http.MethodPost, path: accountPath + "/unfreeze", ifMatch: `"0"`, expectedStatus: http.StatusPreconditionFailed, expectedVersion: 1},
+		{name: "Unfreeze", method: http.MethodPost, path: accountPath + "/unfreeze", ifMatch: `"1"`, expectedStatus: http.StatusOK, expectedVersion: 2},
+		{name: "Limit stale version", method: http.MethodPut, path: limitPath, body: `{"overdraft_limit": "100"}`, ifMatch: `"1"`, expectedStatus: http.StatusPreconditionFailed, expectedVersion: 2},
+		{name: "Limit invalid ETag", method: http.MethodPut, path: limitPath, body: `{"overdraft_limit": "100"}`, ifMatch: `W/"2"`, expectedStatus: http.StatusBadRequest, expectedVersion: 2},
+		{name: "Limit", method: http.MethodPut, path: limitPath, body: `{"overdraft_limit": "100"}`, ifMatch: `"2"`, expectedStatus: http.StatusOK, expectedVersion: 3},
+		{name: "Interest stale version", method: http.MethodPut, path: interestPath, body: interest, ifMatch: `"2"`, expectedStatus: http.StatusPreconditionFailed, expectedVersion: 3},
+		{name: "Interest invalid ETag", method: http.MethodPut, path: interestPath, body: interest, ifMatch: "3", expectedStatus: http.StatusBadRequest, expectedVersion: 3},
+		{name: "Interest", method: http.MethodPut, path: interestPath, body: interest, ifMatch: `"3"`, expectedStatus: http.StatusOK, expectedVersion: 4},
+		{name: "Close stale version", method: http.MethodPost, path: accountPath + "/close", ifMatch: `"3"`, expectedStatus: http.StatusPreconditionFailed, expectedVersion: 4},
+		{name: "Close invalid ETag", method: http.MethodPost, path: accountPath + "/close", ifMatch: "three", expectedStatus: http.StatusBadRequest, expectedVersion: 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			headers := map[string]string{
+				"Authorization": "Bearer " + testAdminToken,
+				"If-Match":      tc.ifMatch,
+			}
+
+			before, err := app.accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+
+			w := app.do(tc.method, tc.path, tc.body, headers)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+
+			after, err := app.accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedVersion, after.Version)
+
+			if tc.expectedStatus != http.StatusOK {
+				assert.Equal(t, before, after, "the account must not change when the precondition fails")
+				return
+			}
+
+			assert.Equal(t, strconv.Quote(strconv.FormatInt(after.Version, 10)), w.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestAccountLifecycle(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+	)
+
+	account, err := app.accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("10"))
+	require.NoError(t, err)
+
+	other, err := app.accountsService.CreateAccount(ctx, "Other", "EUR", internal.MustParseMoney("10"))
+	require.NoError(t, err)
+
+	accountPath := "/accounts/" + account.ID
+	transfer := `{"from_account_id": "` + other.ID + `", "to_account_id": "` + account.ID + `", "amount": "1"}`
+
+	// Every step depends on the status left by the previous ones, so they run
+	// in order.
+	testCases := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		accountStatus  internal.AccountStatus
+	}{
+		{name: "Freeze", path: accountPath + "/freeze", expectedStatus: http.StatusOK, accountStatus: internal.AccountFrozen},
+		{name: "Freeze twice", path: accountPath + "/freeze", expectedStatus: http.StatusConflict, accountStatus: internal.AccountFrozen},
+		{name: "Deposit into frozen account", path: accountPath + "/transactions", body: `{"type": "deposit", "amount": "5"}`, expectedStatus: http.StatusConflict, accountStatus: internal.AccountFrozen},
+		{name: "Transfer to frozen account", path: "/transfer", body: transfer, expectedStatus: http.StatusConflict, accountStatus: internal.AccountFrozen},
+		{name: "Close frozen account", path: accountPath + "/close", expectedStatus: http.StatusConflict, accountStatus: internal.AccountFrozen},
+		{name: "Unfreeze", path: accountPath + "/unfreeze", expectedStatus: http.StatusOK, accountStatus: internal.AccountActive},
+		{name: "Close account with balance", path: accountPath + "/close", expectedStatus: http.StatusConflict, accountStatus: internal.AccountActive},
+		{name: "Withdraw balance", path: accountPath + "/transactions", body: `{"type": "withdrawal", "amount": "10"}`, expectedStatus: http.StatusOK, accountStatus: internal.AccountActive},
+		{name: "Close", path: accountPath + "/close", expectedStatus: http.StatusOK, accountStatus: internal.AccountClosed},
+		{name: "Unfreeze closed account", path: accountPath + "/unfreeze", expectedStatus: http.StatusConflict, accountStatus: internal.AccountClosed},
+		{name: "Deposit into closed account", path: accountPath + "/transactions", body: `{"type": "deposit", "amount": "5"}`, expectedStatus: http.StatusConflict, accountStatus: internal.AccountClosed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := app.do(http.MethodPost, tc.path, tc.body, nil)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+
+			repoAccount, err := app.accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.accountStatus, repoAccount.Status)
+		})
+	}
+
+	repoOther, err := app.accountsRepo.Get(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("10.00"), repoOther.Balance, "the failed transfer must not change the source account")
+
+	w := app.do(http.MethodPost, "/accounts/missing/freeze", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestOverdraftLimit(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+	)
+
+	account, err := app.accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("10"))
+	require.NoError(t, err)
+
+	var (
+		limitPath       = "/admin/accounts/" + account.ID + "/overdraft-limit"
+		transactionPath = "/accounts/" + account.ID + "/transactions"
+		admin           = map[string]string{"Authorization": "Bearer " + testAdminToken}
+	)
+
+	// Every step depends on the balance and limit left by the previous ones,
+	// so they run in order.
+	testCases := []struct {
+		name            string
+		method          string
+		path            string
+		body            string
+		headers         map[string]string
+		expectedStatus  int
+		expectedBalance string
+		expectedLimit   string
+	}{
+		{name: "Withdrawal without limit", method: http.MethodPost, path: transactionPath, body: `{"type": "withdrawal", "amount": "50"}`, expectedStatus: http.StatusForbidden, expectedBalance: "10.00", expectedLimit: "0.00"},
+		{name: "Unauthenticated", method: http.MethodPut, path: limitPath, body: `{"overdraft_limit": "100"}`, expectedStatus: http.StatusUnauthorized, expectedBalance: "10.00", expectedLimit: "0.00"},
+		{name: "Wrong token", method: http.MethodPut, path: limitPath, body: `{"overdraft_limit": "100"}`, headers: map[string]string{"Authorization": "Bearer wrong"}, expectedStatus: http.StatusUnauthorized, expectedBalance: "10.00", expectedLimit: "0.00"},
+		{name: "Set limit", method: http.MethodPut, path: limitPath, body: `{"overdraft_limit": "100"}`, headers: admin, expectedStatus: http.StatusOK, expectedBalance: "10.00", expectedLimit: "100.00"},
+		{name: "Withdrawal within limit", method: http.MethodPost, path: transactionPath, body: `{"type": "withdrawal", "amount": "50"}`, expectedStatus: http.StatusOK, expectedBalance: "-40.00", expectedLimit: "100.00"},
+		{name: "Withdrawal beyond limit", method: http.MethodPost, path: transactionPath, body: `{"type": "withdrawal", "amount": "60.01"}`, expectedStatus: http.StatusForbidden, expectedBalance: "-40.00", expectedLimit: "100.00"},
+		{name: "Limit below overdraft", method: http.MethodPut, path: limitPath, body: `{"overdraft_limit": "39.99"}`, headers: admin, expectedStatus: http.StatusBadRequest, expectedBalance: "-40.00", expectedLimit: "100.00"},
+		{name: "Negative limit", method: http.MethodPut, path: limitPath, body: `{"overdraft_limit": "-1"}`, headers: admin, expectedStatus: http.StatusBadRequest, expectedBalance: "-40.00", expectedLimit: "100.00"},
+		{name: "Lower limit", method: http.MethodPut, path: limitPath, body: `{"overdraft_limit": "40"}`, headers: admin, expectedStatus: http.StatusOK, expectedBalance: "-40.00", expectedLimit: "40.00"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := app.do(tc.method, tc.path, tc.body, tc.headers)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+
+			repoAccount, err := app.accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(tc.expectedBalance), repoAccount.Balance)
+			assert.Equal(t, internal.MustParseMoney(tc.expectedLimit), repoAccount.OverdraftLimit)
+		})
+	}
+}
+
+func TestHolds(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+	)
+
+	account, err := app.accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+
+	holdsPath := "/accounts/" + account.ID + "/holds"
+
+	placeHold := func(t *testing.T, body string) internal.Hold {
+		t.Helper()
+
+		w := app.do(http.MethodPost, holdsPath, body, nil)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var hold internal.Hold
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&hold))
+
+		return hold
+	}
+
+	captured := placeHold(t, `{"amount": "60"}`)
+	released := placeHold(t, `{"amount": "30", "expires_at": "`+time.Now().Add(time.Hour).Format(time.RFC3339)+`"}`)
+
+	// Every step depends on the holds and balance left by the previous ones,
+	// so they run in order.
+	testCases := []struct {
+		name            string
+		path            string
+		body            string
+		expectedStatus  int
+		expectedBalance string
+		expectedHeld    string
+	}{
+		{name: "Hold above the available balance", path: holdsPath, body: `{"amount": "10.01"}`, expectedStatus: http.StatusForbidden, expectedBalance: "100.00", expectedHeld: "90.00"},
+		{name: "Hold already expired", path: holdsPath, body: `{"amount": "1", "expires_at": "2000-01-01T00:00:00Z"}`, expectedStatus: http.StatusBadRequest, expectedBalance: "100.00", expectedHeld: "90.00"},
+		{name: "Withdraw held funds", path: "/accounts/" + account.ID + "/transactions", body: `{"type": "withdrawal", "amount": "10.01"}`, expectedStatus: http.StatusForbidden, expectedBalance: "100.00", expectedHeld: "90.00"},
+		{name: "Capture above the held amount", path: "/holds/" + captured.ID + "/capture", body: `{"amount": "60.01"}`, expectedStatus: http.StatusBadRequest, expectedBalance: "100.00", expectedHeld: "90.00"},
+		{name: "Capture part of the hold", path: "/holds/" + captured.ID + "/capture", body: `{"amount": "25"}`, expectedStatus: http.StatusOK, expectedBalance: "75.00", expectedHeld: "30.00"},
+		{name: "Capture twice", path: "/holds/" + captured.ID + "/capture", expectedStatus: http.StatusConflict, expectedBalance: "75.00", expectedHeld: "30.00"},
+		{name: "Release", path: "/holds/" + released.ID + "/release", expectedStatus: http.StatusOK, expectedBalance: "75.00", expectedHeld: "0.00"},
+		{name: "Capture released hold", path: "/holds/" + released.ID + "/capture", expectedStatus: http.StatusConflict, expectedBalance: "75.00", expectedHeld: "0.00"},
+		{name: "Release missing hold", path: "/holds/missing/release", expectedStatus: http.StatusNotFound, expectedBalance: "75.00", expectedHeld: "0.00"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := app.do(http.MethodPost, tc.path, tc.body, nil)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+
+			repoAccount, err := app.accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(tc.expectedBalance), repoAccount.Balance)
+			assert.Equal(t, internal.MustParseMoney(tc.expectedHeld), repoAccount.Held)
+		})
+	}
+
+	w := app.do(http.MethodGet, "/holds/"+captured.ID, "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var hold internal.Hold
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&hold))
+	assert.Equal(t, internal.HoldCaptured, hold.Status)
+	assert.Equal(t, internal.MustParseMoney("25.00"), *hold.CapturedAmount)
+	assert.NotEmpty(t, hold.TransactionID)
+}
+
+func TestReverseTransaction(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+	)
+
+	account, err := app.accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("10"))
+	require.NoError(t, err)
+
+	deposit, _, err := app.transactionsService.SaveTransaction(ctx, account.ID, internal.TxDeposit, internal.MustParseMoney("50"), "")
+	require.NoError(t, err)
+
+	reversePath := "/transactions/" + deposit.ID + "/reverse"
+
+	// Every step depends on what the previous ones reversed, so they run in
+	// order.
+	testCases := []struct {
+		name            string
+		path            string
+		body            string
+		expectedStatus  int
+		expectedBalance string
+	}{
+		{name: "Partial reversal", path: reversePath, body: `{"amount": "20"}`, expectedStatus: http.StatusOK, expectedBalance: "40.00"},
+		{name: "More than what remains", path: reversePath, body: `{"amount": "30.01"}`, expectedStatus: http.StatusConflict, expectedBalance: "40.00"},
+		{name: "Rest of the transaction", path: reversePath, expectedStatus: http.StatusOK, expectedBalance: "10.00"},
+		{name: "Double reversal", path: reversePath, expectedStatus: http.StatusConflict, expectedBalance: "10.00"},
+		{name: "Missing transaction", path: "/transactions/missing/reverse", expectedStatus: http.StatusNotFound, expectedBalance: "10.00"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := app.do(http.MethodPost, tc.path, tc.body, nil)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+
+			repoAccount, err := app.accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(tc.expectedBalance), repoAccount.Balance)
+		})
+	}
+}
+
+func TestTransferQuote(t *testing.T) {
+	var (
+		app = newTestAppWithFees(t, internal.FeeSchedules{
+			internal.AccountChecking: {Transfer: internal.FeeRule{Percentage: internal.MustParsePercentage("1")}},
+		})
+		ctx = context.Background()
+	)
+
+	source, err := app.accountsService.CreateAccount(ctx, "Source", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+
+	destination, err := app.accountsService.CreateAccount(ctx, "Destination", "EUR", internal.MustParseMoney("10"))
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		body           string
+		expectedStatus int
+		expectedFee    string
+	}{
+		"Quote":                 {body: `{"amount": "50"}`, expectedStatus: http.StatusOK, expectedFee: "0.50"},
+		"Rounded fee":           {body: `{"amount": "12.25"}`, expectedStatus: http.StatusOK, expectedFee: "0.12"},
+		"Zero amount":           {body: `{"amount": "0"}`, expectedStatus: http.StatusBadRequest},
+		"Currency mismatch":     {body: `{"amount": "50", "currency": "USD"}`, expectedStatus: http.StatusUnprocessableEntity},
+		"Missing account":       {body: `{"amount": "50", "to_account_id": "missing"}`, expectedStatus: http.StatusNotFound},
+		"Invalid body":          {body: `{"amount": 50}`, expectedStatus: http.StatusBadRequest},
+		"More than the balance": {body: `{"amount": "500"}`, expectedStatus: http.StatusOK, expectedFee: "5.00"},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			body := map[string]any{"from_account_id": source.ID, "to_account_id": destination.ID}
+			require.NoError(t, json.Unmarshal([]byte(tc.body), &body))
+			request, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			w := app.do(http.MethodPost, "/transfer/quote", string(request), nil)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+
+			if tc.expectedStatus == http.StatusOK {
+				var quote internal.Quote
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&quote))
+				assert.Equal(t, internal.MustParseMoney(tc.expectedFee), quote.Fee)
+			}
+		})
+	}
+
+	// Quotes don't move money, and transfers charge what they quote.
+	repoSource, err := app.accountsRepo.Get(ctx, source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("100.00"), repoSource.Balance)
+
+	w := app.do(http.MethodPost, "/transfer", `{"from_account_id": "`+source.ID+`", "to_account_id": "`+destination.ID+`", "amount": "50"}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	repoSource, err = app.accountsRepo.Get(ctx, source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("49.50"), repoSource.Balance)
+}
+
+func TestAccountListing(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+	)
+
+	for _, owner := range []string{"Alice", "Bob", "alicia", "Carol"} {
+		_, err := app.accountsService.CreateAccount(ctx, owner, "EUR", internal.MustParseMoney("10"))
+		require.NoError(t, err)
+	}
+
+	bob, err := app.accountsService.ListAccounts(ctx, internal.AccountQuery{OwnerPrefix: "Bob"})
+	require.NoError(t, err)
+	_, err = app.accountsService.FreezeAccount(ctx, bob.Accounts[0].ID)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		query          string
+		expectedStatus int
+		expectedOwners []string
+	}{
+		"All":             {query: "", expectedStatus: http.StatusOK, expectedOwners: []string{"Alice", "Bob", "alicia", "Carol"}},
+		"Owner":           {query: "?owner=ali", expectedStatus: http.StatusOK, expectedOwners: []string{"Alice", "alicia"}},
+		"Status":          {query: "?status=active,closed", expectedStatus: http.StatusOK, expectedOwners: []string{"Alice", "alicia", "Carol"}},
+		"Balance":         {query: "?min_balance=20", expectedStatus: http.StatusNoContent},
+		"Invalid status":  {query: "?status=deleted", expectedStatus: http.StatusBadRequest},
+		"Invalid balance": {query: "?max_balance=lots", expectedStatus: http.StatusBadRequest},
+		"Invalid limit":   {query: "?limit=-1", expectedStatus: http.StatusBadRequest},
+		"Invalid cursor":  {query: "?cursor=missing", expectedStatus: http.StatusBadRequest},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			w := app.do(http.MethodGet, "/accounts"+tc.query, "", nil)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, tc.expectedOwners, decodeOwners(t, w.Body))
+			}
+		})
+	}
+
+	t.Run("Pages", func(t *testing.T) {
+		var owners []string
+		for next := "/accounts?limit=3"; next != ""; {
+			w := app.do(http.MethodGet, next, "", nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			owners = append(owners, decodeOwners(t, w.Body)...)
+
+			next = ""
+			if cursor := w.Header().Get("Next-Cursor"); cursor != "" {
+				next = "/accounts?limit=3&cursor=" + cursor
+			}
+		}
+
+		assert.Equal(t, []string{"Alice", "Bob", "alicia", "Carol"}, owners)
+	})
+}
+
+// decodeOwners returns the owners of the accounts in the body.
+func decodeOwners(t *testing.T, body io.Reader) []string {
+	t.Helper()
+
+	var accounts []internal.Account
+	require.NoError(t, json.NewDecoder(body).Decode(&accounts))
+
+	owners := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		owners = append(owners, string(account.Owner))
+	}
+
+	return owners
+}
+
+func TestTransactionHistory(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+	)
+
+	account, err := app.accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+
+	_, _, err = app.transactionsService.SaveTransaction(ctx, account.ID, internal.TxDeposit, internal.MustParseMoney("40"), "")
+	require.NoError(t, err)
+
+	for _, amount := range []string{"10", "20", "30"} {
+		_, _, err := app.transactionsService.SaveTransaction(ctx, account.ID, internal.TxWithdrawal, internal.MustParseMoney(amount), "")
+		require.NoError(t, err)
+	}
+
+	path := "/accounts/" + account.ID + "/transactions"
+
+	testCases := map[string]struct {
+		path            string
+		expectedStatus  int
+		expectedAmounts []string
+	}{
+		"Default":         {path: path, expectedStatus: http.StatusOK, expectedAmounts: []string{"40.00", "10.00", "20.00", "30.00"}},
+		"Type":            {path: path + "?type=deposit", expectedStatus: http.StatusOK, expectedAmounts: []string{"40.00"}},
+		"Amount range":    {path: path + "?type=withdrawal&min_amount=15&max_amount=30&sort=desc", expectedStatus: http.StatusOK, expectedAmounts: []string{"30.00", "20.00"}},
+		"Date range":      {path: path + "?to=2000-01-01T00:00:00Z", expectedStatus: http.StatusNoContent},
+		"Invalid type":    {path: path + "?type=bonus", expectedStatus: http.StatusBadRequest},
+		"Invalid amount":  {path: path + "?min_amount=ten", expectedStatus: http.StatusBadRequest},
+		"Invalid date":    {path: path + "?from=yesterday", expectedStatus: http.StatusBadRequest},
+		"Invalid sort":    {path: path + "?sort=amount", expectedStatus: http.StatusBadRequest},
+		"Invalid limit":   {path: path + "?limit=0", expectedStatus: http.StatusBadRequest},
+		"Invalid cursor":  {path: path + "?cursor=nope", expectedStatus: http.StatusBadRequest},
+		"Missing account": {path: "/accounts/missing/transactions", expectedStatus: http.StatusNotFound},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			w := app.do(http.MethodGet, tc.path, "", nil)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, tc.expectedAmounts, decodeAmounts(t, w.Body))
+			}
+		})
+	}
+
+	t.Run("Pages", func(t *testing.T) {
+		var amounts []string
+		for next := path + "?limit=3"; next != ""; {
+			w := app.do(http.MethodGet, next, "", nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			amounts = append(amounts, decodeAmounts(t, w.Body)...)
+
+			next = ""
+			if cursor := w.Header().Get("Next-Cursor"); cursor != "" {
+				next = path + "?limit=3&cursor=" + cursor
+			}
+		}
+
+		assert.Equal(t, []string{"40.00", "10.00", "20.00", "30.00"}, amounts)
+	})
+}
+
+// decodeAmounts returns the amounts of the transactions in the body.
+func decodeAmounts(t *testing.T, body io.Reader) []string {
+	t.Helper()
+
+	var transactions []internal.Transaction
+	require.NoError(t, json.NewDecoder(body).Decode(&transactions))
+
+	amounts := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		amounts = append(amounts, transaction.Amount.String())
+	}
+
+	return amounts
+}
+
+func TestStatements(t *testing.T) {
+	var (
+		app   = newTestApp(t)
+		ctx   = context.Background()
+		today = time.Now().UTC().Format(time.DateOnly)
+	)
+
+	account, err := app.accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+
+	_, _, err = app.transactionsService.SaveTransaction(ctx, account.ID, internal.TxDeposit, internal.MustParseMoney("40"), "")
+	require.NoError(t, err)
+
+	_, _, err = app.transactionsService.SaveTransaction(ctx, account.ID, internal.TxWithdrawal, internal.MustParseMoney("10"), "")
+	require.NoError(t, err)
+
+	path := "/accounts/" + account.ID + "/statements"
+
+	t.Run("JSON", func(t *testing.T) {
+		w := app.do(http.MethodGet, path+"?from="+today+"&to="+today, "", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var statement struct {
+			OpeningBalance string `json:"opening_balance"`
+			ClosingBalance string `json:"closing_balance"`
+			Transactions   []struct {
+				Amount  string `json:"amount"`
+				Balance string `json:"balance"`
+			} `json:"transactions"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&statement))
+		assert.Equal(t, "100.00", statement.OpeningBalance)
+		assert.Equal(t, "130.00", statement.ClosingBalance)
+		require.Len(t, statement.Transactions, 2)
+		assert.Equal(t, "140.00", statement.Transactions[0].Balance)
+		assert.Equal(t, "130.00", statement.Transactions[1].Balance)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		w := app.do(http.MethodGet, path+"?from="+today+"&to="+today+"&format=csv", "", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		assert.Equal(t,
+			`attachment; filename="statement-`+account.ID+"-"+today+"-"+today+`.csv"`,
+			w.Header().Get("Content-Disposition"),
+		)
+
+		rows, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 5)
+		assert.Equal(t, "-10.00", rows[3][3])
+		assert.Equal(t, []string{"closing_balance", "130.00"}, []string{rows[4][2], rows[4][5]})
+	})
+
+	t.Run("PDF", func(t *testing.T) {
+		w := app.do(http.MethodGet, path+"?from="+today+"&to="+today+"&format=pdf", "", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(w.Body.String(), "%PDF-"))
+	})
+
+	t.Run("Last month by default", func(t *testing.T) {
+		w := app.do(http.MethodGet, path, "", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		// All the transactions were made after last month.
+		assert.Contains(t, w.Body.String(), `"opening_balance":"100.00"`)
+		assert.Contains(t, w.Body.String(), `"transactions":[]`)
+	})
+
+	testCases := map[string]struct {
+		path           string
+		expectedStatus int
+	}{
+		"Only from":       {path: path + "?from=" + today, expectedStatus: http.StatusBadRequest},
+		"Invalid date":    {path: path + "?from=yesterday&to=" + today, expectedStatus: http.StatusBadRequest},
+		"Inverted period": {path: path + "?from=" + today + "&to=2000-01-01", expectedStatus: http.StatusBadRequest},
+		"Invalid format":  {path: path + "?format=xlsx", expectedStatus: http.StatusBadRequest},
+		"Missing account": {path: "/accounts/missing/statements", expectedStatus: http.StatusNotFound},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			w := app.do(http.MethodGet, tc.path, "", nil)
+			assert.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestHistoricalBalance(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+	)
+
+	account, err := app.accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+
+	start := account.OpenedAt.Format(time.RFC3339Nano)
+	beforeOpening := account.OpenedAt.Add(-time.Second).Format(time.RFC3339Nano)
+
+	_, err = app.balanceService.TakeBalanceSnapshots(ctx)
+	require.NoError(t, err)
+
+	_, _, err = app.transactionsService.SaveTransaction(ctx, account.ID, internal.TxDeposit, internal.MustParseMoney("40"), "")
+	require.NoError(t, err)
+
+	path := "/accounts/" + account.ID + "/balance"
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	testCases := map[string]struct {
+		path            string
+		expectedStatus  int
+		expectedBalance string
+	}{
+		"Before the opening": {path: path + "?at=" + beforeOpening, expectedStatus: http.StatusOK, expectedBalance: "0.00"},
+		"Before the deposit": {path: path + "?at=" + start, expectedStatus: http.StatusOK, expectedBalance: "100.00"},
+		"After the deposit":  {path: path + "?at=" + now, expectedStatus: http.StatusOK, expectedBalance: "140.00"},
+		"Missing time":       {path: path, expectedStatus: http.StatusBadRequest},
+		"Invalid time":       {path: path + "?at=yesterday", expectedStatus: http.StatusBadRequest},
+		"In the future":      {path: path + "?at=2999-01-01T00:00:00Z", expectedStatus: http.StatusBadRequest},
+		"Missing account":    {path: "/accounts/missing/balance?at=" + start, expectedStatus: http.StatusNotFound},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			w := app.do(http.MethodGet, tc.path, "", nil)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+
+			if tc.expectedStatus == http.StatusOK {
+				var balance service.HistoricalBalance
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&balance))
+				assert.Equal(t, account.ID, balance.AccountID)
+				assert.Equal(t, tc.expectedBalance, balance.Balance.String())
+			}
+		})
+	}
+
+	t.Run("History mismatch", func(t *testing.T) {
+		repoAccount, err := app.accountsRepo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		require.NoError(t, app.accountsRepo.UpdateBalance(ctx, account.ID, repoAccount.Version, internal.MustParseMoney("1000.00")))
+
+		w := app.do(http.MethodGet, path+"?at="+now, "", nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "doesn't reconcile")
+	})
+}
+
+func TestScheduledTransfers(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+	)
+
+	source, err := app.accountsService.CreateAccount(ctx, "Source", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+
+	destination, err := app.accountsService.CreateAccount(ctx, "Destination", "EUR", internal.MustParseMoney("0"))
+	require.NoError(t, err)
+
+	schedulesPath := "/accounts/" + source.ID + "/scheduled-transfers"
+	tomorrow := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+
+	w := app.do(http.MethodPost, schedulesPath, `{"to_account_id": "`+destination.ID+`", "amount": "20", "schedule": "0 9 1 * *"}`, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var monthly internal.ScheduledTransfer
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&monthly))
+	assert.Equal(t, internal.ScheduledTransferActive, monthly.Status)
+	assert.Equal(t, internal.Currency("EUR"), monthly.Currency)
+	assert.Equal(t, 1, monthly.NextRunAt.Day())
+	assert.Equal(t, 9, monthly.NextRunAt.Hour())
+
+	monthlyPath := schedulesPath + "/" + monthly.ID
+
+	// Every step depends on the scheduled transfers left by the previous ones,
+	// so they run in order.
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "One-off transfer", method: http.MethodPost, path: schedulesPath, body: `{"to_account_id": "` + destination.ID + `", "amount": "5", "start_at": "` + tomorrow + `"}`, expectedStatus: http.StatusCreated},
+		{name: "One-off transfer in the past", method: http.MethodPost, path: schedulesPath, body: `{"to_account_id": "` + destination.ID + `", "amount": "5", "start_at": "2000-01-01T00:00:00Z"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid schedule", method: http.MethodPost, path: schedulesPath, body: `{"to_account_id": "` + destination.ID + `", "amount": "5", "schedule": "0 9 32 * *"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Transfer to itself", method: http.MethodPost, path: schedulesPath, body: `{"to_account_id": "` + source.ID + `", "amount": "5", "schedule": "@daily"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Missing destination", method: http.MethodPost, path: schedulesPath, body: `{"to_account_id": "missing", "amount": "5", "schedule": "@daily"}`, expectedStatus: http.StatusNotFound},
+		{name: "Other currency", method: http.MethodPost, path: schedulesPath, body: `{"to_account_id": "` + destination.ID + `", "amount": "5", "currency": "USD", "schedule": "@daily"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Missing account", method: http.MethodGet, path: "/accounts/missing/scheduled-transfers", expectedStatus: http.StatusNotFound},
+		{name: "Get", method: http.MethodGet, path: monthlyPath, expectedStatus: http.StatusOK},
+		{name: "Get from another account", method: http.MethodGet, path: "/accounts/" + destination.ID + "/scheduled-transfers/" + monthly.ID, expectedStatus: http.StatusNotFound},
+		{name: "Update", method: http.MethodPut, path: monthlyPath, body: `{"to_account_id": "` + destination.ID + `", "amount": "25", "schedule": "@weekly", "max_occurrences": 4}`, expectedStatus: http.StatusOK},
+		{name: "Cancel", method: http.MethodDelete, path: monthlyPath, expectedStatus: http.StatusOK},
+		{name: "Cancel twice", method: http.MethodDelete, path: monthlyPath, expectedStatus: http.StatusConflict},
+		{name: "Update cancelled", method: http.MethodPut, path: monthlyPath, body: `{"to_account_id": "` + destination.ID + `", "amount": "25", "schedule": "@weekly"}`, expectedStatus: http.StatusConflict},
+		{name: "Cancel missing", method: http.MethodDelete, path: schedulesPath + "/missing", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := app.do(tc.method, tc.path, tc.body, nil)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+
+	w = app.do(http.MethodGet, schedulesPath, "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var scheduledTransfers []internal.ScheduledTransfer
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&scheduledTransfers))
+	require.Len(t, scheduledTransfers, 2)
+	assert.Equal(t, monthly.ID, scheduledTransfers[0].ID)
+	assert.Equal(t, internal.ScheduledTransferCancelled, scheduledTransfers[0].Status)
+	assert.Equal(t, "@weekly", scheduledTransfers[0].Schedule)
+	assert.Equal(t, internal.MustParseMoney("25.00"), scheduledTransfers[0].Amount)
+	assert.Equal(t, internal.ScheduledTransferActive, scheduledTransfers[1].Status)
+	assert.Empty(t, scheduledTransfers[1].Schedule)
+}
+
+func TestInterest(t *testing.T) {
+	var (
+		app = newTestApp(t)
+		ctx = context.Background()
+	)
+
+	account, err := app.accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("1000"))
+	require.NoError(t, err)
+
+	var (
+		interestPath = "/admin/accounts/" + account.ID + "/interest"
+		yesterday    = time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+		admin        = map[string]string{"Authorization": "Bearer " + testAdminToken}
+	)
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{name: "Unauthenticated", method: http.MethodPut, path: interestPath, body: `{"type": "savings", "interest_rate": "0.02"}`, expectedStatus: http.StatusUnauthorized},
+		{name: "Invalid type", method: http.MethodPut, path: interestPath, body: `{"type": "brokerage"}`, headers: admin, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid rate", method: http.MethodPut, path: interestPath, body: `{"type": "savings", "interest_rate": "2"}`, headers: admin, expectedStatus: http.StatusBadRequest},
+		{name: "Rate of checking account", method: http.MethodPut, path: interestPath, body: `{"type": "checking", "interest_rate": "0.02"}`, headers: admin, expectedStatus: http.StatusBadRequest},
+		{name: "Missing account", method: http.MethodPut, path: "/admin/accounts/missing/interest", body: `{"type": "savings"}`, headers: admin, expectedStatus: http.StatusNotFound},
+		{name: "Set terms", method: http.MethodPut, path: interestPath, body: `{"type": "savings", "interest_rate": "0.02"}`, headers: admin, expectedStatus: http.StatusOK},
+		{name: "Accrue unauthenticated", method: http.MethodPost, path: "/admin/interest/accrue", expectedStatus: http.StatusUnauthorized},
+		{name: "Accrue", method: http.MethodPost, path: "/admin/interest/accrue", headers: admin, expectedStatus: http.StatusOK},
+		{name: "Accrue as of yesterday", method: http.MethodPost, path: "/admin/interest/accrue?as_of=" + yesterday, headers: admin, expectedStatus: http.StatusOK},
+		{name: "Accrue as of today", method: http.MethodPost, path: "/admin/interest/accrue?as_of=" + time.Now().UTC().Format(time.DateOnly), headers: admin, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid as of", method: http.MethodPost, path: "/admin/interest/accrue?as_of=yesterday", headers: admin, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := app.do(tc.method, tc.path, tc.body, tc.headers)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+
+	w := app.do(http.MethodGet, "/accounts/"+account.ID, "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var repoAccount internal.Account
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&repoAccount))
+	assert.Equal(t, internal.AccountSavings, repoAccount.Type)
+	assert.Equal(t, "0.02", repoAccount.InterestRate.String())
+	require.NotNil(t, repoAccount.InterestAccruedThrough)
+	assert.Equal(t, yesterday, repoAccount.InterestAccruedThrough.Format(time.DateOnly))
+}
+
+func TestReconciliation(t *testing.T) {
+	var (
+		app   = newTestApp(t)
+		ctx   = context.Background()
+		admin = map[string]string{"Authorization": "Bearer " + testAdminToken}
+	)
+
+	account, err := app.accountsService.CreateAccount(ctx, "Test", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+
+	_, _, err = app.transactionsService.SaveTransaction(ctx, account.ID, internal.TxDeposit, internal.MustParseMoney("40"), "")
+	require.NoError(t, err)
+
+	// The balance drifts away from the history.
+	repoAccount, err := app.accountsRepo.Get(ctx, account.ID)
+	require.NoError(t, err)
+	require.NoError(t, app.accountsRepo.UpdateBalance(ctx, account.ID, repoAccount.Version, internal.MustParseMoney("150.00")))
+
+	reconcile := func(t *testing.T, path string) service.Reconciliation {
+		t.Helper()
+
+		w := app.do(http.MethodPost, path, "", admin)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var reconciliation service.Reconciliation
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&reconciliation))
+		assert.Equal(t, 1, reconciliation.Accounts)
+
+		return reconciliation
+	}
+
+	w := app.do(http.MethodPost, "/admin/reconciliations", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = app.do(http.MethodPost, "/admin/reconciliations?adjust=maybe", "", admin)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	reconciliation := reconcile(t, "/admin/reconciliations")
+	require.Len(t, reconciliation.Discrepancies, 1)
+	assert.Equal(t, account.ID, reconciliation.Discrepancies[0].AccountID)
+	assert.Equal(t, "150.00", reconciliation.Discrepancies[0].Balance.String())
+	assert.Equal(t, "140.00", reconciliation.Discrepancies[0].Recomputed.String())
+	assert.Equal(t, "10.00", reconciliation.Discrepancies[0].Difference.String())
+	assert.Empty(t, reconciliation.Discrepancies[0].AdjustmentID)
+
+	reconciliation = reconcile(t, "/admin/reconciliations?adjust=true")
+	require.Len(t, reconciliation.Discrepancies, 1)
+	assert.NotEmpty(t, reconciliation.Discrepancies[0].AdjustmentID)
+
+	reconciliation = reconcile(t, "/admin/reconciliations")
+	assert.Empty(t, reconciliation.Discrepancies)
+
+	w = app.do(http.MethodGet, "/accounts/"+account.ID, "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var adjusted internal.Account
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&adjusted))
+	assert.Equal(t, "140.00", adjusted.Balance.String())
+}
+
+func TestAdminEndpointsDisabled(t *testing.T) {
+	app := newTestApp(t)
+	app.handler = server.New(
+		app.accountsService,
+		app.transactionsService,
+		app.holdsService,
+		app.scheduledTransfersService,
+		app.interestService,
+		app.balanceService,
+		app.reconciliationService,
+		memrepo.NewIdempotencyStore(),
+		internal.SystemClock{},
+		"",
+	)
+
+	w := app.do(http.MethodPut, "/admin/accounts/missing/overdraft-limit", `{"overdraft_limit": "100"}`, map[string]string{"Authorization": "Bearer "})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
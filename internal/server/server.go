@@ -3,15 +3,36 @@ package server
 import (
 	"net/http"
 
+	"github.com/jyisus/bank-server/internal"
 	"github.com/jyisus/bank-server/internal/service"
 )
 
 func New(
 	accountsService *service.AccountService,
 	transactionsService *service.TransactionService,
+	holdsService *service.HoldService,
+	scheduledTransfersService *service.ScheduledTransferService,
+	interestService *service.InterestService,
+	balanceService *service.BalanceService,
+	reconciliationService *service.ReconciliationService,
+	idempotencyStore internal.IdempotencyStore,
+	clock internal.Clock,
+	adminToken string,
 ) http.Handler {
 	mux := http.NewServeMux()
-	addRoutes(mux, accountsService, transactionsService)
+	addRoutes(
+		mux,
+		accountsService,
+		transactionsService,
+		holdsService,
+		scheduledTransfersService,
+		interestService,
+		balanceService,
+		reconciliationService,
+		idempotencyStore,
+		clock,
+		adminToken,
+	)
 
 	return mux
 }
@@ -0,0 +1,122 @@
+package server_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fxrates"
+	"github.com/jyisus/bank-server/internal/memrepo"
+	"github.com/jyisus/bank-server/internal/server"
+	"github.com/jyisus/bank-server/internal/service"
+)
+
+// testAdminToken authenticates the requests to the admin endpoints.
+const testAdminToken = "admin-token"
+
+type testApp struct {
+	handler                   http.Handler
+	accountsRepo              *memrepo.AccountsRepository
+	accountsService           *service.AccountService
+	transactionsService       *service.TransactionService
+	holdsService              *service.HoldService
+	scheduledTransfersService *service.ScheduledTransferService
+	interestService           *service.InterestService
+	balanceService            *service.BalanceService
+	reconciliationService     *service.ReconciliationService
+}
+
+func newTestApp(t *testing.T) testApp {
+	t.Helper()
+
+	return newTestAppWithFees(t, nil)
+}
+
+// newTestAppWithFees returns an app charging the given fees.
+func newTestAppWithFees(t *testing.T, fees internal.FeeSchedules) testApp {
+	t.Helper()
+
+	var (
+		logger                    = slog.New(slog.NewTextHandler(io.Discard, nil))
+		accountsRepo              = memrepo.NewAccountsRepository()
+		transactionsRepo          = memrepo.NewTransactionsRepository()
+		ledgerRepo                = memrepo.NewLedgerRepository()
+		holdsRepo                 = memrepo.NewHoldsRepository()
+		unitOfWork                = memrepo.NewUnitOfWork(accountsRepo, transactionsRepo, ledgerRepo, holdsRepo)
+		ledger                    = service.NewLedger(logger, accountsRepo, ledgerRepo)
+		accountsService           = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, fees, internal.SystemClock{})
+		transactionsService       = service.NewTransactionService(logger, accountsRepo, transactionsRepo, unitOfWork, ledger, fees, internal.SystemClock{})
+		holdsService              = service.NewHoldService(logger, accountsRepo, holdsRepo, unitOfWork, ledger, internal.SystemClock{})
+		scheduledTransfersService = service.NewScheduledTransferService(
+			logger,
+			accountsRepo,
+			memrepo.NewScheduledTransfersRepository(),
+			accountsService,
+			internal.SystemClock{},
+		)
+		interestService = service.NewInterestService(
+			logger,
+			accountsRepo,
+			unitOfWork,
+			ledger,
+			internal.SystemClock{},
+			internal.DayCountActual365,
+		)
+		balanceService = service.NewBalanceService(
+			logger,
+			accountsRepo,
+			transactionsRepo,
+			memrepo.NewBalanceSnapshotsRepository(),
+			ledger,
+			internal.SystemClock{},
+		)
+		reconciliationService = service.NewReconciliationService(
+			logger,
+			accountsRepo,
+			transactionsRepo,
+			unitOfWork,
+			ledger,
+			internal.SystemClock{},
+		)
+	)
+
+	return testApp{
+		handler: server.New(
+			accountsService,
+			transactionsService,
+			holdsService,
+			scheduledTransfersService,
+			interestService,
+			balanceService,
+			reconciliationService,
+			memrepo.NewIdempotencyStore(),
+			internal.SystemClock{},
+			testAdminToken,
+		),
+		accountsRepo:              accountsRepo,
+		accountsService:           accountsService,
+		transactionsService:       transactionsService,
+		holdsService:              holdsService,
+		scheduledTransfersService: scheduledTransfersService,
+		interestService:           interestService,
+		balanceService:            balanceService,
+		reconciliationService:     reconciliationService,
+	}
+}
+
+// do sends the request to the app with the given headers.
+func (app testApp) do(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+
+	w := httptest.NewRecorder()
+	app.handler.ServeHTTP(w, r)
+
+	return w
+}
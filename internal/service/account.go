@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"sync"
 
 	"github.com/google/uuid"
 	"github.com/jyisus/bank-server/internal"
@@ -14,19 +13,39 @@ import (
 type AccountService struct {
 	logger             *slog.Logger
 	accountsRepository internal.AccountsRepository
+	unitOfWork         internal.UnitOfWork
+	fxRates            internal.FXRateProvider
+	ledger             *Ledger
+	fees               internal.FeeSchedules
+	clock              internal.Clock
 }
 
 func NewAccountService(
 	logger *slog.Logger,
 	accountsRepository internal.AccountsRepository,
+	unitOfWork internal.UnitOfWork,
+	fxRates internal.FXRateProvider,
+	ledger *Ledger,
+	fees internal.FeeSchedules,
+	clock internal.Clock,
 ) *AccountService {
 	return &AccountService{
 		logger:             logger,
 		accountsRepository: accountsRepository,
+		unitOfWork:         unitOfWork,
+		fxRates:            fxRates,
+		ledger:             ledger,
+		fees:               fees,
+		clock:              clock,
 	}
 }
 
-func (s AccountService) CreateAccount(ctx context.Context, owner string, initialBalance float32) (*internal.Account, error) {
+func (s AccountService) CreateAccount(
+	ctx context.Context,
+	owner,
+	currency string,
+	initialBalance internal.Money,
+) (*internal.Account, error) {
 	// Check if initial balance is valid (> 0, only 2 decimals)
 	id := uuid.NewString()
 
@@ -34,16 +53,21 @@ func (s AccountService) CreateAccount(ctx context.Context, owner string, initial
 		return nil, err
 	}
 
-	account, err := internal.NewAccount(id, owner, initialBalance)
+	account, err := internal.NewAccount(id, owner, currency, initialBalance)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.accountsRepository.Create(ctx, account); err != nil {
+	openedAt := s.clock.Now().UTC()
+	account.OpenedAt = &openedAt
+
+	if err := internal.RunInTx(ctx, s.unitOfWork, func(tx internal.Tx) error {
+		return s.ledger.OpenAccount(ctx, tx, account)
+	}); err != nil {
 		return nil, err
 	}
 
-	s.logger.Debug("New account created", "ID", id, "owner", owner, "initial_balance", initialBalance)
+	s.logger.Debug("New account created", "ID", id, "owner", owner, "currency", currency, "initial_balance", initialBalance)
 
 	return &account, nil
 }
@@ -59,58 +83,413 @@ func (s AccountService) GetAccount(ctx context.Context, id string) (*internal.Ac
 	return account, nil
 }
 
-func (s AccountService) ListAccounts(ctx context.Context) ([]internal.Account, error) {
-	accounts, err := s.accountsRepository.List(ctx)
+// ListAccounts returns a page of the accounts selected by the query, in
+// creation order, of DefaultAccountsLimit accounts when it has no limit.
+func (s AccountService) ListAccounts(ctx context.Context, query internal.AccountQuery) (*internal.AccountPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	if query.Limit == 0 {
+		query.Limit = internal.DefaultAccountsLimit
+	}
+
+	if query.After != "" {
+		if _, err := s.accountsRepository.Get(ctx, query.After); err != nil {
+			if errors.As(err, &internal.ErrAccountNotFound{}) {
+				return nil, internal.ErrInvalidValue{Msg: fmt.Sprintf("invalid cursor %q", query.After)}
+			}
+
+			return nil, err
+		}
+	}
+
+	// The extra account tells whether there's a next page.
+	limit := query.Limit
+	query.Limit++
+
+	accounts, err := s.accountsRepository.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &internal.AccountPage{Accounts: accounts}
+	if len(accounts) > limit {
+		page.Accounts = accounts[:limit]
+		page.NextCursor = accounts[limit-1].ID
+	}
+
+	s.logger.Debug("Returning accounts list", "totalAccounts", len(page.Accounts))
+
+	return page, nil
+}
+
+// QuoteTransfer returns what a transfer would send, deliver and cost with the
+// current exchange rate and fees, without making it.
+func (s AccountService) QuoteTransfer(
+	ctx context.Context,
+	sourceAccountID,
+	destinationAccountID string,
+	amount internal.Money,
+	currency internal.Currency,
+) (*internal.Quote, error) {
+	quote, _, _, err := s.quoteTransfer(ctx, sourceAccountID, destinationAccountID, amount, currency)
 	if err != nil {
 		return nil, err
 	}
 
-	s.logger.Debug("Returning accounts list", "totalAccounts", len(accounts))
+	return &quote, nil
+}
+
+// Transfer moves the amount, expressed in the source account's currency, to the
+// destination account converting it with the current exchange rate when both
+// accounts use different currencies. An empty currency means the source
+// account's currency. The transfer fee is charged to the source account as a
+// transaction of its own, linked to the outgoing one.
+func (s AccountService) Transfer(
+	ctx context.Context,
+	sourceAccountID,
+	destinationAccountID string,
+	amount internal.Money,
+	currency internal.Currency,
+) (*internal.Transfer, error) {
+	transfer, _, err := s.transferOnce(ctx, uuid.NewString(), sourceAccountID, destinationAccountID, amount, currency)
+	return transfer, err
+}
+
+// transferOnce makes the transfer with the given ID unless it's already been
+// made, which it reports with false and a nil transfer. The check is part of
+// the transaction making it, so callers retrying a transfer under the same ID
+// can't make it twice, and the storage refuses a second copy of its
+// transactions in case another process makes it at the same time.
+func (s AccountService) transferOnce(
+	ctx context.Context,
+	id,
+	sourceAccountID,
+	destinationAccountID string,
+	amount internal.Money,
+	currency internal.Currency,
+) (*internal.Transfer, bool, error) {
+	quote, sourceAccount, destinationAccount, err := s.quoteTransfer(
+		ctx,
+		sourceAccountID,
+		destinationAccountID,
+		amount,
+		currency,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	transfer := &internal.Transfer{
+		ID:                   id,
+		SourceAccountID:      sourceAccount.ID,
+		DestinationAccountID: destinationAccount.ID,
+		Timestamp:            s.clock.Now(),
+		Quote:                quote,
+	}
+
+	entry, err := internal.NewTransferEntry(uuid.NewString(), *transfer)
+	if err != nil {
+		return nil, false, err
+	}
+
+	out, in := internal.NewTransferTransactions(uuid.NewString(), uuid.NewString(), *transfer)
+
+	var fee *internal.Transaction
+	if transfer.Fee.IsPositive() {
+		feeTransaction := internal.NewFeeTransaction(uuid.NewString(), *sourceAccount, transfer.Fee, out.ID, transfer.Timestamp)
+		fee = &feeTransaction
+	}
+
+	unlock, err := s.ledger.Lock(ctx, sourceAccount.ID, destinationAccount.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	// Other processes sharing the storage may still change the accounts, so
+	// conflicts are retried.
+	made := false
+	err = retryOnConflict(ctx, func() error {
+		made = false
+
+		return internal.RunInTx(ctx, s.unitOfWork, func(tx internal.Tx) error {
+			transactions, err := tx.Transactions().FindAllByTransfer(ctx, transfer.ID)
+			if err != nil {
+				return err
+			}
+
+			if len(transactions) > 0 {
+				return nil
+			}
+
+			if err := s.ledger.Post(ctx, tx, entry); err != nil {
+				return err
+			}
+
+			if err := tx.Transactions().Save(ctx, out); err != nil {
+				return fmt.Errorf("saving source transaction: %w", err)
+			}
+
+			if err := tx.Transactions().Save(ctx, in); err != nil {
+				return fmt.Errorf("saving destination transaction: %w", err)
+			}
+
+			if fee != nil {
+				if err := chargeFee(ctx, tx, s.ledger, *fee); err != nil {
+					return err
+				}
+			}
+
+			made = true
+
+			return nil
+		})
+	})
+	if errors.As(err, &internal.ErrTransferExists{}) {
+		made, err = false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !made {
+		s.logger.Info("Transfer already made", "ID", transfer.ID)
+		return nil, false, nil
+	}
+
+	s.logger.Info(
+		"Transfer completed",
+		"ID", transfer.ID,
+		"source", transfer.SourceAccountID,
+		"destination", transfer.DestinationAccountID,
+		"source_amount", transfer.SourceAmount,
+		"source_currency", transfer.SourceCurrency,
+		"destination_amount", transfer.DestinationAmount,
+		"destination_currency", transfer.DestinationCurrency,
+		"rate", transfer.Rate,
+		"rate_timestamp", transfer.RateTimestamp,
+		"fee", transfer.Fee,
+	)
 
-	return accounts, nil
+	return transfer, true, nil
 }
 
-func (s AccountService) Transfer(ctx context.Context, sourceAccountID, destinationAccountID string, amount float32) error {
+// quoteTransfer returns the quote of the transfer together with its accounts,
+// checking it moves a positive amount between two different accounts.
+func (s AccountService) quoteTransfer(
+	ctx context.Context,
+	sourceAccountID,
+	destinationAccountID string,
+	amount internal.Money,
+	currency internal.Currency,
+) (internal.Quote, *internal.Account, *internal.Account, error) {
+	if !amount.IsPositive() {
+		return internal.Quote{}, nil, nil, internal.ErrInvalidValue{Msg: "the amount to transfer should be greater than 0"}
+	}
+
+	if sourceAccountID == destinationAccountID {
+		return internal.Quote{}, nil, nil, internal.ErrInvalidValue{Msg: "the destination account should be different from the source one"}
+	}
+
 	sourceAccount, err := s.accountsRepository.Get(ctx, sourceAccountID)
 	if err != nil {
-		return fmt.Errorf("getting source account: %w", err)
+		return internal.Quote{}, nil, nil, fmt.Errorf("getting source account: %w", err)
 	}
 
 	destinationAccount, err := s.accountsRepository.Get(ctx, destinationAccountID)
 	if err != nil {
-		return fmt.Errorf("getting destination account: %w", err)
+		return internal.Quote{}, nil, nil, fmt.Errorf("getting destination account: %w", err)
 	}
 
-	if err := sourceAccount.Withdraw(amount); err != nil {
-		return err
+	if currency == "" {
+		currency = sourceAccount.Currency
+	}
+
+	if currency != sourceAccount.Currency {
+		return internal.Quote{}, nil, nil, internal.ErrCurrencyMismatch{Expected: sourceAccount.Currency, Actual: currency}
 	}
 
-	destinationAccount.Deposit(amount)
+	amount, err = amount.In(currency)
+	if err != nil {
+		return internal.Quote{}, nil, nil, err
+	}
+
+	rate, err := s.fxRates.Rate(ctx, sourceAccount.Currency, destinationAccount.Currency)
+	if err != nil {
+		return internal.Quote{}, nil, nil, fmt.Errorf("getting exchange rate: %w", err)
+	}
+
+	quote, err := internal.NewQuote(amount, rate)
+	if err != nil {
+		return internal.Quote{}, nil, nil, err
+	}
+
+	if !quote.DestinationAmount.IsPositive() {
+		return internal.Quote{}, nil, nil, internal.ErrInvalidValue{Msg: "the amount is too small to be converted"}
+	}
+
+	quote.Fee, err = s.fees.TransferFee(*sourceAccount, amount)
+	if err != nil {
+		return internal.Quote{}, nil, nil, err
+	}
+
+	return quote, sourceAccount, destinationAccount, nil
+}
 
-	wg := sync.WaitGroup{}
-	wg.Add(2)
+// FreezeAccount blocks every movement of money of the account until it's
+// unfrozen.
+func (s AccountService) FreezeAccount(ctx context.Context, id string) (*internal.Account, error) {
+	return s.changeStatus(ctx, id, nil, (*internal.Account).Freeze)
+}
+
+// FreezeAccountIfVersion freezes the account only if it's still at the given
+// version. Otherwise it returns ErrVersionConflict.
+func (s AccountService) FreezeAccountIfVersion(ctx context.Context, id string, expectedVersion int64) (*internal.Account, error) {
+	return s.changeStatus(ctx, id, &expectedVersion, (*internal.Account).Freeze)
+}
+
+// UnfreezeAccount lets a frozen account move money again.
+func (s AccountService) UnfreezeAccount(ctx context.Context, id string) (*internal.Account, error) {
+	return s.changeStatus(ctx, id, nil, (*internal.Account).Unfreeze)
+}
+
+// UnfreezeAccountIfVersion unfreezes the account only if it's still at the
+// given version. Otherwise it returns ErrVersionConflict.
+func (s AccountService) UnfreezeAccountIfVersion(ctx context.Context, id string, expectedVersion int64) (*internal.Account, error) {
+	return s.changeStatus(ctx, id, &expectedVersion, (*internal.Account).Unfreeze)
+}
 
-	var errs error
+// CloseAccount closes an account with a zero balance for good.
+func (s AccountService) CloseAccount(ctx context.Context, id string) (*internal.Account, error) {
+	return s.changeStatus(ctx, id, nil, (*internal.Account).Close)
+}
 
-	go func() {
-		defer wg.Done()
-		err = s.accountsRepository.UpdateBalance(ctx, sourceAccount.ID, sourceAccount.Balance)
-		if err != nil {
-			errs = errors.Join(errs, fmt.Errorf("updating source account balance: %w", err))
+// CloseAccountIfVersion closes the account only if it's still at the given
+// version. Otherwise it returns ErrVersionConflict.
+func (s AccountService) CloseAccountIfVersion(ctx context.Context, id string, expectedVersion int64) (*internal.Account, error) {
+	return s.changeStatus(ctx, id, &expectedVersion, (*internal.Account).Close)
+}
+
+// SetOverdraftLimit lets the balance of the account go down to -limit.
+func (s AccountService) SetOverdraftLimit(
+	ctx context.Context,
+	id string,
+	limit internal.Money,
+) (*internal.Account, error) {
+	return s.setOverdraftLimit(ctx, id, nil, limit)
+}
+
+// SetOverdraftLimitIfVersion changes the overdraft limit only if the account
+// is still at the given version. Otherwise it returns ErrVersionConflict.
+func (s AccountService) SetOverdraftLimitIfVersion(
+	ctx context.Context,
+	id string,
+	expectedVersion int64,
+	limit internal.Money,
+) (*internal.Account, error) {
+	return s.setOverdraftLimit(ctx, id, &expectedVersion, limit)
+}
+
+func (s AccountService) setOverdraftLimit(
+	ctx context.Context,
+	id string,
+	expectedVersion *int64,
+	limit internal.Money,
+) (*internal.Account, error) {
+	account, err := s.updateAccount(ctx, id, expectedVersion, func(tx internal.Tx, account *internal.Account) error {
+		if err := account.SetOverdraftLimit(limit); err != nil {
+			return err
 		}
-	}()
 
-	go func() {
-		defer wg.Done()
-		err = s.accountsRepository.UpdateBalance(ctx, destinationAccount.ID, destinationAccount.Balance)
-		if err != nil {
-			errs = errors.Join(errs, fmt.Errorf("updating destination account balance: %w", err))
+		return tx.Accounts().UpdateOverdraftLimit(ctx, id, account.Version, account.OverdraftLimit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Account overdraft limit changed", "ID", id, "overdraft_limit", account.OverdraftLimit)
+
+	return account, nil
+}
+
+// changeStatus applies the status transition to the account and returns it
+// with its new status.
+func (s AccountService) changeStatus(
+	ctx context.Context,
+	id string,
+	expectedVersion *int64,
+	transition func(*internal.Account) error,
+) (*internal.Account, error) {
+	account, err := s.updateAccount(ctx, id, expectedVersion, func(tx internal.Tx, account *internal.Account) error {
+		if err := transition(account); err != nil {
+			return err
 		}
-	}()
 
-	wg.Wait()
+		return tx.Accounts().UpdateStatus(ctx, id, account.Version, account.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Account status changed", "ID", id, "status", account.Status)
+
+	return account, nil
+}
+
+// updateAccount reads the account and lets update change and store it within
+// a transaction, while no other operation uses the account. When an expected
+// version is given, the account must still be at it. It returns the account
+// as stored.
+func (s AccountService) updateAccount(
+	ctx context.Context,
+	id string,
+	expectedVersion *int64,
+	update func(tx internal.Tx, account *internal.Account) error,
+) (*internal.Account, error) {
+	unlock, err := s.ledger.Lock(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var account *internal.Account
+	save := func() error {
+		return internal.RunInTx(ctx, s.unitOfWork, func(tx internal.Tx) error {
+			var err error
+			account, err = tx.Accounts().Get(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			if expectedVersion != nil && account.Version != *expectedVersion {
+				return internal.ErrVersionConflict{AccountID: id, ExpectedVersion: *expectedVersion}
+			}
+
+			if err := update(tx, account); err != nil {
+				return fmt.Errorf("updating account %q: %w", id, err)
+			}
+			account.Version++
+
+			return nil
+		})
+	}
 
-	return errs
+	// A conflict on a conditional update means the client's precondition no
+	// longer holds, so it's reported instead of retried.
+	if expectedVersion != nil {
+		err = save()
+	} else {
+		err = retryOnConflict(ctx, save)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
 }
 
 func (s AccountService) checkIfAccountExists(ctx context.Context, id string) error {
@@ -5,12 +5,14 @@ import (
 	"log/slog"
 	"math/rand"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bxcodec/faker/v3"
 	"github.com/google/uuid"
 	"github.com/jyisus/bank-server/internal"
-	"github.com/jyisus/bank-server/internal/memrepo"
+	"github.com/jyisus/bank-server/internal/fxrates"
 	"github.com/jyisus/bank-server/internal/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -21,48 +23,69 @@ func TestAccountsService_CreateAccount(t *testing.T) {
 
 	testCases := map[string]struct {
 		owner          string
-		initialBalance float32
+		currency       string
+		initialBalance internal.Money
 		expectedError  error
 	}{
 		"Account successfully created": {
 			owner:          faker.Name(),
-			initialBalance: rand.Float32() * 100,
+			currency:       "EUR",
+			initialBalance: internal.NewMoney(rand.Int63n(10000), internal.DefaultCurrency),
 			expectedError:  nil,
 		},
 		"Invalid name": {
 			owner:          "123",
-			initialBalance: rand.Float32() * 100,
+			currency:       "EUR",
+			initialBalance: internal.NewMoney(rand.Int63n(10000), internal.DefaultCurrency),
+			expectedError:  &internal.ErrInvalidValue{},
+		},
+		"Invalid currency": {
+			owner:          faker.Name(),
+			currency:       "ABC",
+			initialBalance: internal.MustParseMoney("10"),
+			expectedError:  &internal.ErrInvalidValue{},
+		},
+		"Invalid initial balance precision": {
+			owner:          faker.Name(),
+			currency:       "EUR",
+			initialBalance: internal.MustParseMoney("10.001"),
 			expectedError:  &internal.ErrInvalidValue{},
 		},
 		"Invalid initial balance": {
 			owner:          faker.Name(),
-			initialBalance: -100,
+			currency:       "EUR",
+			initialBalance: internal.MustParseMoney("-100"),
 			expectedError:  &internal.ErrInvalidValue{},
 		},
 	}
 
 	for testName, tc := range testCases {
 		t.Run(testName, func(t *testing.T) {
-			var (
-				accountsRepo    = memrepo.NewAccountsRepository()
-				logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
-				accountsService = service.NewAccountService(logger, accountsRepo)
-				ctx             = context.Background()
-			)
-
-			actualAccount, err := accountsService.CreateAccount(ctx, tc.owner, tc.initialBalance)
-			if err != nil {
-				require.ErrorAs(t, err, tc.expectedError)
-				return
-			}
-
-			assert.Equal(t, tc.owner, string(actualAccount.Owner))
-			assert.Equal(t, tc.initialBalance, actualAccount.Balance)
-
-			repoAccount, err := accountsRepo.Get(ctx, actualAccount.ID)
-			require.NoError(t, err)
+			forEachBackend(t, func(t *testing.T, b backend) {
+				var (
+					accountsRepo    = b.accounts
+					logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+					ledgerRepo      = b.ledger
+					unitOfWork      = b.unitOfWork
+					ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+					accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+					ctx             = context.Background()
+				)
+
+				actualAccount, err := accountsService.CreateAccount(ctx, tc.owner, tc.currency, tc.initialBalance)
+				if err != nil {
+					require.ErrorAs(t, err, tc.expectedError)
+					return
+				}
+
+				assert.Equal(t, tc.owner, string(actualAccount.Owner))
+				assert.Equal(t, tc.initialBalance, actualAccount.Balance)
+
+				repoAccount, err := accountsRepo.Get(ctx, actualAccount.ID)
+				require.NoError(t, err)
 
-			assert.Equal(t, actualAccount, repoAccount)
+				assert.Equal(t, actualAccount, repoAccount)
+			})
 		})
 	}
 }
@@ -89,25 +112,30 @@ func TestAccountsService_GetAccount(t *testing.T) {
 
 	for testName, tc := range testCases {
 		t.Run(testName, func(t *testing.T) {
-			var (
-				accountsRepo    = memrepo.NewAccountsRepository()
-				logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
-				accountsService = service.NewAccountService(logger, accountsRepo)
-				ctx             = context.Background()
-			)
-
-			if tc.accountExists {
-				err := accountsRepo.Create(ctx, *tc.account)
-				require.NoError(t, err)
-			}
-
-			actualAccount, err := accountsService.GetAccount(ctx, tc.account.ID)
-			if err != nil {
-				require.ErrorAs(t, err, tc.expectedError)
-				return
-			}
-
-			assert.Equal(t, tc.account, actualAccount)
+			forEachBackend(t, func(t *testing.T, b backend) {
+				var (
+					accountsRepo    = b.accounts
+					logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+					ledgerRepo      = b.ledger
+					unitOfWork      = b.unitOfWork
+					ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+					accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+					ctx             = context.Background()
+				)
+
+				if tc.accountExists {
+					err := accountsRepo.Create(ctx, *tc.account)
+					require.NoError(t, err)
+				}
+
+				actualAccount, err := accountsService.GetAccount(ctx, tc.account.ID)
+				if err != nil {
+					require.ErrorAs(t, err, tc.expectedError)
+					return
+				}
+
+				assert.Equal(t, tc.account, actualAccount)
+			})
 		})
 	}
 }
@@ -129,165 +157,608 @@ func TestAccountsService_ListAccounts(t *testing.T) {
 
 	for testName, tc := range testCases {
 		t.Run(testName, func(t *testing.T) {
-			var (
-				accountsRepo    = memrepo.NewAccountsRepository()
-				logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
-				accountsService = service.NewAccountService(logger, accountsRepo)
-				ctx             = context.Background()
-			)
-
-			for _, account := range tc.accounts {
-				require.NoError(t, accountsRepo.Create(ctx, account))
-			}
+			forEachBackend(t, func(t *testing.T, b backend) {
+				var (
+					accountsRepo    = b.accounts
+					logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+					ledgerRepo      = b.ledger
+					unitOfWork      = b.unitOfWork
+					ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+					accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+					ctx             = context.Background()
+				)
+
+				for _, account := range tc.accounts {
+					require.NoError(t, accountsRepo.Create(ctx, account))
+				}
+
+				page, err := accountsService.ListAccounts(ctx, internal.AccountQuery{})
+				require.NoError(t, err)
+				assert.Empty(t, page.NextCursor)
+
+				if len(tc.accounts) == 0 {
+					assert.Empty(t, page.Accounts)
+				} else {
+					assert.Equal(t, tc.accounts, page.Accounts)
+				}
+			})
+		})
+	}
+}
+
+func TestAccountsService_ListAccounts_Pages(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo    = b.accounts
+			logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledger          = service.NewLedger(logger, accountsRepo, b.ledger)
+			accountsService = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			ctx             = context.Background()
+			expected        []internal.Account
+		)
+
+		for range 5 {
+			account := fakeAccount(t)
+			require.NoError(t, accountsRepo.Create(ctx, *account))
+			expected = append(expected, *account)
+		}
 
-			actualAccounts, err := accountsService.ListAccounts(ctx)
+		var (
+			query  = internal.AccountQuery{Limit: 2}
+			walked []internal.Account
+		)
+		for pages := 1; ; pages++ {
+			page, err := accountsService.ListAccounts(ctx, query)
 			require.NoError(t, err)
+			walked = append(walked, page.Accounts...)
 
-			if len(tc.accounts) == 0 {
-				assert.Empty(t, actualAccounts)
-			} else {
-				assert.ElementsMatch(t, tc.accounts, actualAccounts)
+			if page.NextCursor == "" {
+				assert.Equal(t, 3, pages)
+				break
 			}
-		})
-	}
+
+			query.After = page.NextCursor
+		}
+		assert.Equal(t, expected, walked)
+
+		_, err := accountsService.ListAccounts(ctx, internal.AccountQuery{After: uuid.NewString()})
+		require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+
+		_, err = accountsService.ListAccounts(ctx, internal.AccountQuery{Statuses: []internal.AccountStatus{"deleted"}})
+		require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+	})
 }
 
 func TestAccountsService_Transfer_OK(t *testing.T) {
-	var (
-		accountsRepo    = memrepo.NewAccountsRepository()
-		logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
-		accountsService = service.NewAccountService(logger, accountsRepo)
-		ctx             = context.Background()
-
-		sourceAccount = &internal.Account{
-			ID:      uuid.NewString(),
-			Balance: 100,
-		}
-		destinationAccount = &internal.Account{
-			ID:      uuid.NewString(),
-			Balance: 100,
-		}
-		transferAmount = float32(100)
-	)
-
-	require.NoError(t, accountsRepo.Create(ctx, *sourceAccount))
-	require.NoError(t, accountsRepo.Create(ctx, *destinationAccount))
-
-	err := accountsService.Transfer(
-		ctx,
-		sourceAccount.ID,
-		destinationAccount.ID,
-		transferAmount,
-	)
-	require.NoError(t, err)
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo    = b.accounts
+			logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledgerRepo      = b.ledger
+			unitOfWork      = b.unitOfWork
+			ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+			accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			ctx             = context.Background()
+
+			sourceAccount = &internal.Account{
+				ID:       uuid.NewString(),
+				Currency: internal.DefaultCurrency,
+				Balance:  internal.MustParseMoney("100.00"),
+			}
+			destinationAccount = &internal.Account{
+				ID:       uuid.NewString(),
+				Currency: internal.DefaultCurrency,
+				Balance:  internal.MustParseMoney("100.00"),
+			}
+			transferAmount = internal.MustParseMoney("100")
+		)
 
-	actualSourceAccount, err := accountsRepo.Get(ctx, sourceAccount.ID)
-	require.NoError(t, err)
+		require.NoError(t, accountsRepo.Create(ctx, *sourceAccount))
+		require.NoError(t, accountsRepo.Create(ctx, *destinationAccount))
 
-	actualDstAccount, err := accountsRepo.Get(ctx, destinationAccount.ID)
-	require.NoError(t, err)
+		_, err := accountsService.Transfer(
+			ctx,
+			sourceAccount.ID,
+			destinationAccount.ID,
+			transferAmount,
+			"",
+		)
+		require.NoError(t, err)
+
+		actualSourceAccount, err := accountsRepo.Get(ctx, sourceAccount.ID)
+		require.NoError(t, err)
+
+		actualDstAccount, err := accountsRepo.Get(ctx, destinationAccount.ID)
+		require.NoError(t, err)
+
+		expectedSourceBalance, err := sourceAccount.Balance.Sub(transferAmount)
+		require.NoError(t, err)
 
-	expectedSourceBalance := sourceAccount.Balance - transferAmount
-	expectedDstBalance := destinationAccount.Balance + transferAmount
+		expectedDstBalance, err := destinationAccount.Balance.Add(transferAmount)
+		require.NoError(t, err)
 
-	assert.Equal(t, expectedSourceBalance, actualSourceAccount.Balance)
-	assert.Equal(t, expectedDstBalance, actualDstAccount.Balance)
+		assert.Equal(t, expectedSourceBalance, actualSourceAccount.Balance)
+		assert.Equal(t, expectedDstBalance, actualDstAccount.Balance)
+	})
 }
 
 func TestAccountsService_Transfer_SourceAccountDoesntExist(t *testing.T) {
-	var (
-		accountsRepo    = memrepo.NewAccountsRepository()
-		logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
-		accountsService = service.NewAccountService(logger, accountsRepo)
-		ctx             = context.Background()
-
-		sourceAccountID    = uuid.NewString()
-		destinationAccount = &internal.Account{
-			ID:      uuid.NewString(),
-			Balance: 100,
-		}
-		transferAmount = float32(100)
-	)
-
-	require.NoError(t, accountsRepo.Create(ctx, *destinationAccount))
-
-	err := accountsService.Transfer(
-		ctx,
-		sourceAccountID,
-		destinationAccount.ID,
-		transferAmount,
-	)
-	require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo    = b.accounts
+			logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledgerRepo      = b.ledger
+			unitOfWork      = b.unitOfWork
+			ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+			accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			ctx             = context.Background()
+
+			sourceAccountID    = uuid.NewString()
+			destinationAccount = &internal.Account{
+				ID:       uuid.NewString(),
+				Currency: internal.DefaultCurrency,
+				Balance:  internal.MustParseMoney("100.00"),
+			}
+			transferAmount = internal.MustParseMoney("100")
+		)
+
+		require.NoError(t, accountsRepo.Create(ctx, *destinationAccount))
+
+		_, err := accountsService.Transfer(
+			ctx,
+			sourceAccountID,
+			destinationAccount.ID,
+			transferAmount,
+			"",
+		)
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+	})
 }
 
 func TestAccountsService_Transfer_DstAccountDoesntExist(t *testing.T) {
-	var (
-		accountsRepo    = memrepo.NewAccountsRepository()
-		logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
-		accountsService = service.NewAccountService(logger, accountsRepo)
-		ctx             = context.Background()
-
-		sourceAccount = &internal.Account{
-			ID:      uuid.NewString(),
-			Balance: 100,
-		}
-		destinationAccountID = uuid.NewString()
-		transferAmount       = float32(100)
-	)
-
-	require.NoError(t, accountsRepo.Create(ctx, *sourceAccount))
-
-	err := accountsService.Transfer(
-		ctx,
-		sourceAccount.ID,
-		destinationAccountID,
-		transferAmount,
-	)
-	require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo    = b.accounts
+			logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledgerRepo      = b.ledger
+			unitOfWork      = b.unitOfWork
+			ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+			accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			ctx             = context.Background()
+
+			sourceAccount = &internal.Account{
+				ID:       uuid.NewString(),
+				Currency: internal.DefaultCurrency,
+				Balance:  internal.MustParseMoney("100.00"),
+			}
+			destinationAccountID = uuid.NewString()
+			transferAmount       = internal.MustParseMoney("100")
+		)
+
+		require.NoError(t, accountsRepo.Create(ctx, *sourceAccount))
+
+		_, err := accountsService.Transfer(
+			ctx,
+			sourceAccount.ID,
+			destinationAccountID,
+			transferAmount,
+			"",
+		)
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+	})
 }
 
 func TestAccountsService_Transfer_InsufficientFounts(t *testing.T) {
-	var (
-		accountsRepo    = memrepo.NewAccountsRepository()
-		logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
-		accountsService = service.NewAccountService(logger, accountsRepo)
-		ctx             = context.Background()
-
-		sourceAccount = &internal.Account{
-			ID:      uuid.NewString(),
-			Balance: 100,
-		}
-		destinationAccount = &internal.Account{
-			ID:      uuid.NewString(),
-			Balance: 100,
-		}
-		transferAmount = float32(200)
-	)
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo    = b.accounts
+			logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledgerRepo      = b.ledger
+			unitOfWork      = b.unitOfWork
+			ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+			accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			ctx             = context.Background()
+
+			sourceAccount = &internal.Account{
+				ID:       uuid.NewString(),
+				Currency: internal.DefaultCurrency,
+				Balance:  internal.MustParseMoney("100.00"),
+			}
+			destinationAccount = &internal.Account{
+				ID:       uuid.NewString(),
+				Currency: internal.DefaultCurrency,
+				Balance:  internal.MustParseMoney("100.00"),
+			}
+			transferAmount = internal.MustParseMoney("200")
+		)
+
+		require.NoError(t, accountsRepo.Create(ctx, *sourceAccount))
+		require.NoError(t, accountsRepo.Create(ctx, *destinationAccount))
+
+		_, err := accountsService.Transfer(
+			ctx,
+			sourceAccount.ID,
+			destinationAccount.ID,
+			transferAmount,
+			"",
+		)
+		require.ErrorAs(t, err, &internal.ErrInsufficientBalance{})
+
+		actualSourceAccount, err := accountsRepo.Get(ctx, sourceAccount.ID)
+		require.NoError(t, err)
+
+		actualDstAccount, err := accountsRepo.Get(ctx, destinationAccount.ID)
+		require.NoError(t, err)
+
+		assert.Equal(t, sourceAccount.Balance, actualSourceAccount.Balance)
+		assert.Equal(t, destinationAccount.Balance, actualDstAccount.Balance)
+	})
+}
+
+func TestAccountsService_Transfer_InvalidValue(t *testing.T) {
+	testCases := map[string]struct {
+		amount       string
+		selfTransfer bool
+	}{
+		"Zero amount":     {amount: "0"},
+		"Negative amount": {amount: "-10"},
+		"Self-transfer":   {amount: "10", selfTransfer: true},
+	}
 
-	require.NoError(t, accountsRepo.Create(ctx, *sourceAccount))
-	require.NoError(t, accountsRepo.Create(ctx, *destinationAccount))
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			forEachBackend(t, func(t *testing.T, b backend) {
+				var (
+					accountsRepo    = b.accounts
+					logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+					ledger          = service.NewLedger(logger, accountsRepo, b.ledger)
+					accountsService = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+					ctx             = context.Background()
+				)
+
+				source, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+				require.NoError(t, err)
 
-	err := accountsService.Transfer(
-		ctx,
-		sourceAccount.ID,
-		destinationAccount.ID,
-		transferAmount,
-	)
-	require.ErrorAs(t, err, &internal.ErrInsufficientBalance{})
+				destination, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+				require.NoError(t, err)
 
-	actualSourceAccount, err := accountsRepo.Get(ctx, sourceAccount.ID)
-	require.NoError(t, err)
+				if tc.selfTransfer {
+					destination = source
+				}
 
-	actualDstAccount, err := accountsRepo.Get(ctx, destinationAccount.ID)
-	require.NoError(t, err)
+				_, err = accountsService.Transfer(ctx, source.ID, destination.ID, internal.MustParseMoney(tc.amount), "")
+				require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+
+				for _, account := range []*internal.Account{source, destination} {
+					actual, err := accountsRepo.Get(ctx, account.ID)
+					require.NoError(t, err)
+					assert.Equal(t, account.Balance, actual.Balance)
+				}
+			})
+		})
+	}
+}
+
+func TestAccountsService_Transfer_CurrencyMismatch(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo    = b.accounts
+			logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledgerRepo      = b.ledger
+			unitOfWork      = b.unitOfWork
+			ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+			accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			ctx             = context.Background()
+
+			sourceAccount = &internal.Account{
+				ID:       uuid.NewString(),
+				Currency: "EUR",
+				Balance:  internal.MustParseMoney("100.00"),
+			}
+			destinationAccount = &internal.Account{
+				ID:       uuid.NewString(),
+				Currency: "EUR",
+				Balance:  internal.MustParseMoney("100.00"),
+			}
+			transferAmount = internal.MustParseMoney("10")
+		)
+
+		require.NoError(t, accountsRepo.Create(ctx, *sourceAccount))
+		require.NoError(t, accountsRepo.Create(ctx, *destinationAccount))
+
+		_, err := accountsService.Transfer(
+			ctx,
+			sourceAccount.ID,
+			destinationAccount.ID,
+			transferAmount,
+			"USD",
+		)
+		require.ErrorAs(t, err, &internal.ErrCurrencyMismatch{})
+
+		actualSourceAccount, err := accountsRepo.Get(ctx, sourceAccount.ID)
+		require.NoError(t, err)
+
+		assert.Equal(t, sourceAccount.Balance, actualSourceAccount.Balance)
+	})
+}
+
+func TestAccountsService_Transfer_CrossCurrency(t *testing.T) {
+	t.Parallel()
+
+	rateTimestamp := time.Date(2024, 11, 24, 0, 0, 0, 0, time.UTC)
+	fxRates := fxrates.NewStaticProvider(internal.ExchangeRate{
+		From:      "EUR",
+		To:        "USD",
+		Rate:      internal.MustParseRate("1.0834"),
+		Timestamp: rateTimestamp,
+	})
+
+	testCases := map[string]struct {
+		sourceCurrency      internal.Currency
+		destinationCurrency internal.Currency
+		amount              internal.Money
+		expectedQuote       internal.Quote
+		expectedError       error
+	}{
+		"Configured rate": {
+			sourceCurrency:      "EUR",
+			destinationCurrency: "USD",
+			amount:              internal.MustParseMoney("10"),
+			expectedQuote: internal.Quote{
+				SourceAmount:        internal.MustParseMoney("10.00"),
+				SourceCurrency:      "EUR",
+				DestinationAmount:   internal.MustParseMoney("10.83"),
+				DestinationCurrency: "USD",
+				Rate:                internal.MustParseRate("1.0834"),
+				RateTimestamp:       rateTimestamp,
+			},
+		},
+		"Inverse rate": {
+			sourceCurrency:      "USD",
+			destinationCurrency: "EUR",
+			amount:              internal.MustParseMoney("10.83"),
+			expectedQuote: internal.Quote{
+				SourceAmount:        internal.MustParseMoney("10.83"),
+				SourceCurrency:      "USD",
+				DestinationAmount:   internal.MustParseMoney("10.00"),
+				DestinationCurrency: "EUR",
+				Rate:                internal.MustParseRate("1.0834").Inverse(),
+				RateTimestamp:       rateTimestamp,
+			},
+		},
+		"Unknown rate": {
+			sourceCurrency:      "EUR",
+			destinationCurrency: "JPY",
+			amount:              internal.MustParseMoney("10"),
+			expectedError:       &internal.ErrExchangeRateNotFound{},
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			forEachBackend(t, func(t *testing.T, b backend) {
+				var (
+					accountsRepo    = b.accounts
+					logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+					ledgerRepo      = b.ledger
+					unitOfWork      = b.unitOfWork
+					ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+					accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxRates, ledger, nil, internal.SystemClock{})
+					ctx             = context.Background()
+
+					sourceAccount = internal.Account{
+						ID:       uuid.NewString(),
+						Currency: tc.sourceCurrency,
+						Balance:  internal.MustParseMoney("100.00"),
+					}
+					destinationAccount = internal.Account{
+						ID:       uuid.NewString(),
+						Currency: tc.destinationCurrency,
+						Balance:  internal.MustParseMoney("0.00"),
+					}
+				)
+
+				require.NoError(t, accountsRepo.Create(ctx, sourceAccount))
+				require.NoError(t, accountsRepo.Create(ctx, destinationAccount))
+
+				transfer, err := accountsService.Transfer(ctx, sourceAccount.ID, destinationAccount.ID, tc.amount, "")
+				if tc.expectedError != nil {
+					require.ErrorAs(t, err, tc.expectedError)
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedQuote.SourceAmount, transfer.SourceAmount)
+				assert.Equal(t, tc.expectedQuote.DestinationAmount, transfer.DestinationAmount)
+				assert.Equal(t, tc.expectedQuote.SourceCurrency, transfer.SourceCurrency)
+				assert.Equal(t, tc.expectedQuote.DestinationCurrency, transfer.DestinationCurrency)
+				assert.Equal(t, 0, tc.expectedQuote.Rate.Cmp(transfer.Rate))
+				assert.Equal(t, tc.expectedQuote.RateTimestamp, transfer.RateTimestamp)
+
+				actualSourceAccount, err := accountsRepo.Get(ctx, sourceAccount.ID)
+				require.NoError(t, err)
+
+				actualDstAccount, err := accountsRepo.Get(ctx, destinationAccount.ID)
+				require.NoError(t, err)
+
+				expectedSourceBalance, err := sourceAccount.Balance.Sub(transfer.SourceAmount)
+				require.NoError(t, err)
+				assert.Equal(t, expectedSourceBalance, actualSourceAccount.Balance)
+				assert.Equal(t, transfer.DestinationAmount, actualDstAccount.Balance)
+			})
+		})
+	}
+}
+
+func TestAccountsService_ChangeStatus(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo    = b.accounts
+			logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledgerRepo      = b.ledger
+			unitOfWork      = b.unitOfWork
+			ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+			accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			ctx             = context.Background()
+		)
+
+		account, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("10.00"))
+		require.NoError(t, err)
+		assert.Equal(t, internal.AccountActive, account.Status)
+
+		frozen, err := accountsService.FreezeAccount(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.AccountFrozen, frozen.Status)
+
+		repoAccount, err := accountsRepo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, *frozen, *repoAccount)
+
+		_, err = accountsService.CloseAccount(ctx, account.ID)
+		require.ErrorAs(t, err, &internal.ErrAccountStatus{})
+
+		_, err = accountsService.UnfreezeAccount(ctx, account.ID)
+		require.NoError(t, err)
+
+		_, err = accountsService.CloseAccount(ctx, account.ID)
+		require.ErrorAs(t, err, &internal.ErrAccountNotEmpty{})
+
+		repoAccount, err = accountsRepo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.AccountActive, repoAccount.Status)
+		assert.Equal(t, int64(2), repoAccount.Version)
+
+		_, err = accountsService.FreezeAccount(ctx, uuid.NewString())
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+	})
+}
+
+func TestAccountsService_Transfer_Overdraft(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo    = b.accounts
+			logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledgerRepo      = b.ledger
+			unitOfWork      = b.unitOfWork
+			ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+			accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			ctx             = context.Background()
+		)
+
+		source, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("10.00"))
+		require.NoError(t, err)
+
+		destination, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("0.00"))
+		require.NoError(t, err)
+
+		_, err = accountsService.SetOverdraftLimit(ctx, source.ID, internal.MustParseMoney("100"))
+		require.NoError(t, err)
+
+		_, err = accountsService.Transfer(ctx, source.ID, destination.ID, internal.MustParseMoney("110"), "")
+		require.NoError(t, err)
+
+		_, err = accountsService.Transfer(ctx, source.ID, destination.ID, internal.MustParseMoney("0.01"), "")
+		var insufficient internal.ErrInsufficientBalance
+		require.ErrorAs(t, err, &insufficient)
+		assert.Equal(t, internal.MustParseMoney("0.00"), insufficient.Available)
+
+		repoSource, err := accountsRepo.Get(ctx, source.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.MustParseMoney("-100.00"), repoSource.Balance)
+		assert.Equal(t, internal.MustParseMoney("100.00"), repoSource.OverdraftLimit)
+		assert.NoError(t, ledger.Verify(ctx, source.ID))
+	})
+}
+
+func TestAccountsService_ConcurrentOperations(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo        = b.accounts
+			transactionsRepo    = b.transactions
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+			ledgerRepo          = b.ledger
+			unitOfWork          = b.unitOfWork
+			ledger              = service.NewLedger(logger, accountsRepo, ledgerRepo)
+			accountsService     = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			transactionsService = service.NewTransactionService(logger, accountsRepo, transactionsRepo, unitOfWork, ledger, nil, internal.SystemClock{})
+			ctx                 = context.Background()
+			operations          = 2000
+
+			accounts []internal.Account
+			expected = internal.MustParseMoney("0.00")
+			mutex    sync.Mutex
+			wg       sync.WaitGroup
+		)
+
+		for range 8 {
+			account, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+			require.NoError(t, err)
+			accounts = append(accounts, *account)
+			expected, err = expected.Add(account.Balance)
+			require.NoError(t, err)
+		}
+
+		for i := range operations {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				// The destination is any other account, as accounts can't
+				// transfer to themselves.
+				var (
+					sourceIndex = rand.Intn(len(accounts))
+					source      = accounts[sourceIndex]
+					destination = accounts[(sourceIndex+1+rand.Intn(len(accounts)-1))%len(accounts)]
+					amount      = internal.NewMoney(rand.Int63n(1000)+1, internal.DefaultCurrency)
+					err         error
+				)
+
+				// Most operations are transfers, in both directions between
+				// the same accounts; the rest change the total balance.
+				switch i % 10 {
+				case 0:
+					_, _, err = transactionsService.SaveTransaction(ctx, source.ID, internal.TxDeposit, amount, "")
+					if err == nil {
+						mutex.Lock()
+						expected, err = expected.Add(amount)
+						mutex.Unlock()
+					}
+				case 1:
+					_, _, err = transactionsService.SaveTransaction(ctx, source.ID, internal.TxWithdrawal, amount, "")
+					if err == nil {
+						mutex.Lock()
+						expected, err = expected.Sub(amount)
+						mutex.Unlock()
+					}
+				default:
+					_, err = accountsService.Transfer(ctx, source.ID, destination.ID, amount, "")
+				}
+
+				if err != nil {
+					assert.ErrorAs(t, err, &internal.ErrInsufficientBalance{})
+				}
+			}()
+		}
+		wg.Wait()
+
+		total := internal.MustParseMoney("0.00")
+		for _, account := range accounts {
+			actual, err := accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+			assert.False(t, actual.Balance.IsNegative())
+			assert.NoError(t, ledger.Verify(ctx, account.ID))
+
+			total, err = total.Add(actual.Balance)
+			require.NoError(t, err)
+		}
 
-	assert.Equal(t, sourceAccount.Balance, actualSourceAccount.Balance)
-	assert.Equal(t, destinationAccount.Balance, actualDstAccount.Balance)
+		assert.Equal(t, expected, total, "the balances should only change by the deposits and withdrawals")
+	})
 }
 
 func fakeAccount(t *testing.T) *internal.Account {
 	t.Helper()
-	account, err := internal.NewAccount(uuid.NewString(), faker.Name(), rand.Float32()*100)
+	account, err := internal.NewAccount(uuid.NewString(), faker.Name(), "EUR", internal.NewMoney(rand.Int63n(10000), internal.DefaultCurrency))
 	require.NoError(t, err)
 
 	return &account
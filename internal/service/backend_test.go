@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/memrepo"
+	"github.com/jyisus/bank-server/internal/sqliterepo"
+	"github.com/stretchr/testify/require"
+)
+
+// backend groups the repositories of one storage implementation so the
+// service tests can run the same behaviour against all of them.
+type backend struct {
+	accounts           internal.AccountsRepository
+	transactions       internal.TransactionsRepository
+	ledger             internal.LedgerRepository
+	holds              internal.HoldsRepository
+	scheduledTransfers internal.ScheduledTransfersRepository
+	balanceSnapshots   internal.BalanceSnapshotsRepository
+	unitOfWork         internal.UnitOfWork
+}
+
+var backends = map[string]func(t *testing.T) backend{
+	"memrepo": func(t *testing.T) backend {
+		accountsRepo := memrepo.NewAccountsRepository()
+		transactionsRepo := memrepo.NewTransactionsRepository()
+		ledgerRepo := memrepo.NewLedgerRepository()
+		holdsRepo := memrepo.NewHoldsRepository()
+
+		return backend{
+			accounts:           accountsRepo,
+			transactions:       transactionsRepo,
+			ledger:             ledgerRepo,
+			holds:              holdsRepo,
+			scheduledTransfers: memrepo.NewScheduledTransfersRepository(),
+			balanceSnapshots:   memrepo.NewBalanceSnapshotsRepository(),
+			unitOfWork:         memrepo.NewUnitOfWork(accountsRepo, transactionsRepo, ledgerRepo, holdsRepo),
+		}
+	},
+	"sqliterepo": func(t *testing.T) backend {
+		db, err := sqliterepo.Open(context.Background(), filepath.Join(t.TempDir(), "bank.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		return backend{
+			accounts:           sqliterepo.NewAccountsRepository(db),
+			transactions:       sqliterepo.NewTransactionsRepository(db),
+			ledger:             sqliterepo.NewLedgerRepository(db),
+			holds:              sqliterepo.NewHoldsRepository(db),
+			scheduledTransfers: sqliterepo.NewScheduledTransfersRepository(db),
+			balanceSnapshots:   sqliterepo.NewBalanceSnapshotsRepository(db),
+			unitOfWork:         sqliterepo.NewUnitOfWork(db),
+		}
+	},
+}
+
+// forEachBackend runs the test once per storage backend with fresh
+// repositories.
+func forEachBackend(t *testing.T, test func(t *testing.T, b backend)) {
+	t.Helper()
+
+	for backendName, newBackend := range backends {
+		t.Run(backendName, func(t *testing.T) {
+			test(t, newBackend(t))
+		})
+	}
+}
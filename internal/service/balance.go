@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+// BalanceService tells the balance accounts had at any point in time, by
+// replaying their transactions from the daily snapshots it takes of their
+// balances. Time comes from its clock, so tests can move it at will.
+type BalanceService struct {
+	logger                 *slog.Logger
+	accountsRepository     internal.AccountsRepository
+	transactionsRepository internal.TransactionsRepository
+	snapshotsRepository    internal.BalanceSnapshotsRepository
+	ledger                 *Ledger
+	clock                  internal.Clock
+	// snapshotDay is the day the snapshots were last taken for, so they're
+	// only taken once a day.
+	snapshotDay *atomic.Pointer[time.Time]
+}
+
+func NewBalanceService(
+	logger *slog.Logger,
+	accountsRepository internal.AccountsRepository,
+	transactionsRepository internal.TransactionsRepository,
+	snapshotsRepository internal.BalanceSnapshotsRepository,
+	ledger *Ledger,
+	clock internal.Clock,
+) *BalanceService {
+	return &BalanceService{
+		logger:                 logger,
+		accountsRepository:     accountsRepository,
+		transactionsRepository: transactionsRepository,
+		snapshotsRepository:    snapshotsRepository,
+		ledger:                 ledger,
+		clock:                  clock,
+		snapshotDay:            &atomic.Pointer[time.Time]{},
+	}
+}
+
+// HistoricalBalance is the balance of an account at a point in time, after
+// the transactions made at it.
+type HistoricalBalance struct {
+	AccountID string            `json:"account_id"`
+	At        time.Time         `json:"at"`
+	Balance   internal.Money    `json:"balance"`
+	Currency  internal.Currency `json:"currency"`
+}
+
+// BalanceAt returns the balance the account had at the given time. Only the
+// transactions between the snapshots around the time are replayed, or up to
+// the current balance when there's no snapshot after it. The history has to
+// reconcile with the current balance of the account, both from those snapshots
+// and from the latest one, or ErrHistoryMismatch is returned. The balance
+// before the account was opened is zero.
+func (s BalanceService) BalanceAt(ctx context.Context, accountID string, at time.Time) (*HistoricalBalance, error) {
+	now := s.clock.Now()
+	if at.After(now) {
+		return nil, internal.ErrInvalidValue{Msg: "the balance can't be known ahead of time"}
+	}
+
+	unlock, err := s.ledger.Lock(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	account, err := s.accountsRepository.Get(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !account.OpenedBy(at) {
+		return &HistoricalBalance{AccountID: accountID, At: at, Balance: internal.NewMoney(0, account.Currency), Currency: account.Currency}, nil
+	}
+
+	current := internal.BalanceSnapshot{AccountID: account.ID, At: now, Balance: account.Balance, Currency: account.Currency}
+
+	from, err := s.snapshotsRepository.FindLatest(ctx, accountID, at)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := s.snapshotsRepository.FindNext(ctx, accountID, at)
+	if err != nil {
+		return nil, err
+	}
+
+	query := internal.TransactionQuery{AccountID: accountID, From: &at}
+	if from != nil {
+		query.From = &from.At
+	}
+
+	if to == nil {
+		to = &current
+	} else {
+		query.To = &to.At
+
+		if err := s.reconcileLatest(ctx, current); err != nil {
+			return nil, err
+		}
+	}
+
+	transactions, err := s.transactionsRepository.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := internal.ReplayBalance(at, from, *to, transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Debug("Returning historical balance", "account", accountID, "at", at, "balance", balance, "replayed", len(transactions))
+
+	return &HistoricalBalance{AccountID: accountID, At: at, Balance: balance, Currency: account.Currency}, nil
+}
+
+// reconcileLatest checks that the transactions made since the latest snapshot
+// of the account lead to its current balance.
+func (s BalanceService) reconcileLatest(ctx context.Context, current internal.BalanceSnapshot) error {
+	latest, err := s.snapshotsRepository.FindLatest(ctx, current.AccountID, current.At)
+	if err != nil || latest == nil {
+		return err
+	}
+
+	since, err := s.transactionsRepository.Find(ctx, internal.TransactionQuery{AccountID: current.AccountID, From: &latest.At})
+	if err != nil {
+		return err
+	}
+
+	_, err = internal.ReplayBalance(current.At, latest, current, since)
+
+	return err
+}
+
+// TakeBalanceSnapshots takes the daily snapshot of the balance of every
+// account, and returns how many were taken. Each one is checked against the
+// previous snapshot of the account first: the snapshots of accounts whose
+// history doesn't reconcile aren't taken, as they would hide the mismatch.
+func (s BalanceService) TakeBalanceSnapshots(ctx context.Context) (int, error) {
+	now := s.clock.Now()
+	day := internal.Date(now.Add(-time.Hour))
+
+	if taken := s.snapshotDay.Load(); taken != nil && taken.Equal(day) {
+		return 0, nil
+	}
+
+	accounts, err := s.accountsRepository.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	taken := 0
+	for _, account := range accounts {
+		ok, err := s.takeBalanceSnapshot(ctx, account.ID, now)
+		if errors.As(err, &internal.ErrHistoryMismatch{}) {
+			s.logger.Error("Balance snapshot not taken", "account", account.ID, "error", err)
+			continue
+		}
+
+		if err != nil {
+			return taken, fmt.Errorf("taking balance snapshot of account %q: %w", account.ID, err)
+		}
+
+		if ok {
+			taken++
+		}
+	}
+
+	s.snapshotDay.Store(&day)
+
+	if taken > 0 {
+		s.logger.Info("Balance snapshots taken", "count", taken, "day", day.Format(time.DateOnly))
+	}
+
+	return taken, nil
+}
+
+// takeBalanceSnapshot takes the snapshot of the account unless it's already
+// been taken, and reports whether it was taken now. The balance at the time of
+// the snapshot is worked out back from the current one.
+func (s BalanceService) takeBalanceSnapshot(ctx context.Context, accountID string, now time.Time) (bool, error) {
+	unlock, err := s.ledger.Lock(ctx, accountID)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	account, err := s.accountsRepository.Get(ctx, accountID)
+	if err != nil {
+		return false, err
+	}
+
+	at := internal.BalanceSnapshotTime(*account, now)
+
+	previous, err := s.snapshotsRepository.FindLatest(ctx, accountID, at)
+	if err != nil {
+		return false, err
+	}
+
+	if previous != nil && previous.At.Equal(at) {
+		return false, nil
+	}
+
+	since, err := s.transactionsRepository.Find(ctx, internal.TransactionQuery{AccountID: accountID, From: &at})
+	if err != nil {
+		return false, err
+	}
+
+	changes, err := internal.BalanceChanges(since)
+	if err != nil {
+		return false, err
+	}
+
+	balance, err := account.Balance.Sub(changes)
+	if err != nil {
+		return false, err
+	}
+
+	snapshot := internal.BalanceSnapshot{
+		AccountID: accountID,
+		At:        at,
+		Balance:   balance,
+		Currency:  account.Currency,
+	}
+
+	if previous != nil {
+		between, err := s.transactionsRepository.Find(ctx, internal.TransactionQuery{
+			AccountID: accountID,
+			From:      &previous.At,
+			To:        &at,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		if _, err := internal.ReplayBalance(at, previous, snapshot, between); err != nil {
+			return false, err
+		}
+	}
+
+	if err := s.snapshotsRepository.Save(ctx, snapshot); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
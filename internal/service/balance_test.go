@@ -0,0 +1,168 @@
+package service_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v3"
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fxrates"
+	"github.com/jyisus/bank-server/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceService(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			logger         = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			accountsRepo   = b.accounts
+			ledger         = service.NewLedger(logger, accountsRepo, b.ledger)
+			clock          = &testClock{}
+			balanceService = service.NewBalanceService(logger, accountsRepo, b.transactions, b.balanceSnapshots, ledger, clock)
+			account        = fakeAccount(t)
+			start          = time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+			ctx            = context.Background()
+		)
+
+		save := func(t *testing.T, txType, amount string, timestamp time.Time) {
+			t.Helper()
+
+			require.NoError(t, b.transactions.Save(ctx, internal.Transaction{
+				ID:        uuid.NewString(),
+				AccountID: account.ID,
+				Type:      internal.TransactionType(txType),
+				Amount:    internal.MustParseMoney(amount),
+				Currency:  account.Currency,
+				Timestamp: timestamp,
+			}))
+		}
+
+		// The account was opened empty, and its balance is what its
+		// transactions left.
+		account.Balance = internal.MustParseMoney("70.00")
+		require.NoError(t, accountsRepo.Create(ctx, *account))
+		save(t, internal.TxDeposit, "50.00", start.Add(10*time.Hour))
+		save(t, internal.TxWithdrawal, "10.00", start.AddDate(0, 0, 1).Add(10*time.Hour))
+		save(t, internal.TxDeposit, "30.00", start.AddDate(0, 0, 2).Add(10*time.Hour))
+
+		takeSnapshots := func(t *testing.T, now time.Time, expected int) {
+			t.Helper()
+
+			clock.now = now
+			taken, err := balanceService.TakeBalanceSnapshots(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, expected, taken)
+		}
+
+		takeSnapshots(t, start.AddDate(0, 0, 1).Add(9*time.Hour), 1)
+		takeSnapshots(t, start.AddDate(0, 0, 1).Add(10*time.Hour), 0)
+		takeSnapshots(t, start.AddDate(0, 0, 2).Add(12*time.Hour), 1)
+
+		snapshot, err := b.balanceSnapshots.FindLatest(ctx, account.ID, start.AddDate(0, 0, 1))
+		require.NoError(t, err)
+		require.NotNil(t, snapshot)
+		assert.Equal(t, start.AddDate(0, 0, 1), snapshot.At)
+		assert.Zero(t, snapshot.Balance.Cmp(internal.MustParseMoney("50.00")))
+
+		clock.now = start.AddDate(0, 0, 3).Add(12 * time.Hour)
+
+		testCases := map[string]struct {
+			at       time.Time
+			expected string
+		}{
+			"Before the snapshots":   {at: start.Add(9 * time.Hour), expected: "0.00"},
+			"At a transaction":       {at: start.Add(10 * time.Hour), expected: "50.00"},
+			"Between snapshots":      {at: start.AddDate(0, 0, 1).Add(10 * time.Hour), expected: "40.00"},
+			"After the last":         {at: start.AddDate(0, 0, 2).Add(23 * time.Hour), expected: "70.00"},
+			"Now":                    {at: clock.now, expected: "70.00"},
+			"In another time offset": {at: start.Add(10 * time.Hour).In(time.FixedZone("UTC+2", 2*60*60)), expected: "50.00"},
+		}
+
+		for testName, tc := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				balance, err := balanceService.BalanceAt(ctx, account.ID, tc.at)
+				require.NoError(t, err)
+				assert.Equal(t, account.ID, balance.AccountID)
+				assert.Equal(t, account.Currency, balance.Currency)
+				assert.Zero(t, balance.Balance.Cmp(internal.MustParseMoney(tc.expected)), "expected %s, got %s", tc.expected, balance.Balance)
+			})
+		}
+
+		_, err = balanceService.BalanceAt(ctx, account.ID, clock.now.Add(time.Minute))
+		require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+
+		_, err = balanceService.BalanceAt(ctx, uuid.NewString(), start)
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+
+		t.Run("Transaction missing from the balance", func(t *testing.T) {
+			save(t, internal.TxDeposit, "5.00", start.AddDate(0, 0, 1).Add(12*time.Hour))
+
+			_, err := balanceService.BalanceAt(ctx, account.ID, start.AddDate(0, 0, 1).Add(11*time.Hour))
+			var mismatch internal.ErrHistoryMismatch
+			require.ErrorAs(t, err, &mismatch)
+			assert.Equal(t, start.AddDate(0, 0, 1), mismatch.From)
+			assert.Equal(t, start.AddDate(0, 0, 2), mismatch.To)
+
+			_, err = balanceService.BalanceAt(ctx, account.ID, start.AddDate(0, 0, 2).Add(23*time.Hour))
+			require.NoError(t, err, "the history since the latest snapshot still reconciles")
+		})
+
+		t.Run("Balance changed without a transaction", func(t *testing.T) {
+			repoAccount, err := accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+			require.NoError(t, accountsRepo.UpdateBalance(ctx, account.ID, repoAccount.Version, internal.MustParseMoney("1000.00")))
+
+			_, err = balanceService.BalanceAt(ctx, account.ID, start.Add(9*time.Hour))
+			require.ErrorAs(t, err, &internal.ErrHistoryMismatch{}, "the latest snapshot doesn't lead to the balance")
+
+			takeSnapshots(t, start.AddDate(0, 0, 4).Add(12*time.Hour), 0)
+		})
+	})
+}
+
+func TestBalanceService_BeforeOpening(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			logger          = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			accountsRepo    = b.accounts
+			ledger          = service.NewLedger(logger, accountsRepo, b.ledger)
+			openedAt        = time.Date(2024, 11, 1, 10, 0, 0, 0, time.UTC)
+			clock           = &testClock{now: openedAt}
+			accountsService = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, nil, clock)
+			balanceService  = service.NewBalanceService(logger, accountsRepo, b.transactions, b.balanceSnapshots, ledger, clock)
+			ctx             = context.Background()
+		)
+
+		account, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		clock.now = openedAt.AddDate(0, 0, 2)
+		_, err = balanceService.TakeBalanceSnapshots(ctx)
+		require.NoError(t, err)
+
+		testCases := map[string]struct {
+			at       time.Time
+			expected string
+		}{
+			"Before the opening":     {at: openedAt.Add(-time.Hour), expected: "0.00"},
+			"Before the opening day": {at: openedAt.AddDate(0, 0, -1), expected: "0.00"},
+			"At the opening":         {at: openedAt, expected: "100.00"},
+			"After the opening":      {at: openedAt.AddDate(0, 0, 1), expected: "100.00"},
+		}
+
+		for testName, tc := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				balance, err := balanceService.BalanceAt(ctx, account.ID, tc.at)
+				require.NoError(t, err)
+				assert.Equal(t, tc.at, balance.At)
+				assert.Equal(t, internal.Currency("EUR"), balance.Currency)
+				assert.Zero(t, balance.Balance.Cmp(internal.MustParseMoney(tc.expected)), "expected %s, got %s", tc.expected, balance.Balance)
+			})
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+)
+
+// FeeService charges the monthly maintenance fees of the accounts. The fees of
+// withdrawals and transfers are charged by the services making them. Time
+// comes from its clock, so tests can move it at will.
+type FeeService struct {
+	logger             *slog.Logger
+	accountsRepository internal.AccountsRepository
+	unitOfWork         internal.UnitOfWork
+	ledger             *Ledger
+	fees               internal.FeeSchedules
+	clock              internal.Clock
+	// deferred are the accounts whose fee of the month couldn't be charged
+	// yet, so that's only warned about once.
+	deferred *deferredFees
+}
+
+func NewFeeService(
+	logger *slog.Logger,
+	accountsRepository internal.AccountsRepository,
+	unitOfWork internal.UnitOfWork,
+	ledger *Ledger,
+	fees internal.FeeSchedules,
+	clock internal.Clock,
+) *FeeService {
+	return &FeeService{
+		logger:             logger,
+		accountsRepository: accountsRepository,
+		unitOfWork:         unitOfWork,
+		ledger:             ledger,
+		fees:               fees,
+		clock:              clock,
+		deferred:           &deferredFees{mutex: &sync.Mutex{}},
+	}
+}
+
+// ChargeMaintenanceFees charges the maintenance fee of the month ended to the
+// accounts, and returns how many were charged. Each account is charged once a
+// month, even across restarts, as the fee transaction of each month has a
+// fixed ID. Accounts opened after the month started aren't charged its fee.
+// The fee of accounts that aren't active or can't afford it is deferred: it's
+// retried on every run of the following month, and waived after it.
+func (s FeeService) ChargeMaintenanceFees(ctx context.Context) (int, error) {
+	now := s.clock.Now()
+	year, month, _ := now.UTC().Date()
+	ended := time.Date(year, month-1, 1, 0, 0, 0, 0, time.UTC)
+
+	accounts, err := s.accountsRepository.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	charged := 0
+	for _, account := range accounts {
+		if !account.OpenedBy(ended) {
+			continue
+		}
+
+		fee, err := s.fees.MaintenanceFee(account)
+		if err != nil {
+			return charged, fmt.Errorf("charging maintenance fee of account %q: %w", account.ID, err)
+		}
+
+		if !fee.IsPositive() {
+			continue
+		}
+
+		ok, err := s.chargeMaintenanceFee(ctx, account.ID, ended, now)
+		if err != nil {
+			return charged, fmt.Errorf("charging maintenance fee of account %q: %w", account.ID, err)
+		}
+
+		if ok {
+			charged++
+		}
+	}
+
+	if charged > 0 {
+		s.logger.Info("Maintenance fees charged", "count", charged, "month", ended.Format("2006-01"))
+	}
+
+	return charged, nil
+}
+
+// chargeMaintenanceFee charges the maintenance fee of the month to the account
+// unless it's already been charged, and reports whether it was charged now.
+func (s FeeService) chargeMaintenanceFee(ctx context.Context, accountID string, month, now time.Time) (bool, error) {
+	id := uuid.NewSHA1(uuid.NameSpaceURL, []byte("maintenance-fee:"+accountID+":"+month.Format("2006-01"))).String()
+
+	unlock, err := s.ledger.Lock(ctx, accountID)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	charged := false
+	err = retryOnConflict(ctx, func() error {
+		charged = false
+
+		return internal.RunInTx(ctx, s.unitOfWork, func(tx internal.Tx) error {
+			_, err := tx.Transactions().Get(ctx, id)
+			if err == nil {
+				return nil
+			}
+
+			if !errors.As(err, &internal.ErrTransactionNotFound{}) {
+				return err
+			}
+
+			account, err := tx.Accounts().Get(ctx, accountID)
+			if err != nil {
+				return err
+			}
+
+			amount, err := s.fees.MaintenanceFee(*account)
+			if err != nil {
+				return err
+			}
+
+			fee := internal.NewFeeTransaction(id, *account, amount, "", now)
+			if err := chargeFee(ctx, tx, s.ledger, fee); err != nil {
+				return err
+			}
+
+			charged = true
+
+			return nil
+		})
+	})
+
+	if errors.As(err, &internal.ErrAccountStatus{}) || errors.As(err, &internal.ErrInsufficientBalance{}) {
+		if s.deferred.add(accountID, month) {
+			s.logger.Warn("Maintenance fee deferred", "account", accountID, "month", month.Format("2006-01"), "error", err)
+		}
+		return false, nil
+	}
+
+	return charged, err
+}
+
+// deferredFees are the accounts whose maintenance fee of a month was deferred.
+// Only the latest month is kept.
+type deferredFees struct {
+	month    time.Time
+	accounts map[string]bool
+	mutex    *sync.Mutex
+}
+
+// add adds the account to the deferred ones of the month, and reports whether
+// it wasn't already.
+func (d *deferredFees) add(accountID string, month time.Time) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.month.Equal(month) {
+		d.month = month
+		d.accounts = make(map[string]bool)
+	}
+
+	if d.accounts[accountID] {
+		return false
+	}
+
+	d.accounts[accountID] = true
+
+	return true
+}
+
+// chargeFee posts the fee transaction within the given transaction.
+func chargeFee(ctx context.Context, tx internal.Tx, ledger *Ledger, fee internal.Transaction) error {
+	entry, err := internal.NewTransactionEntry(uuid.NewString(), fee)
+	if err != nil {
+		return err
+	}
+
+	if err := ledger.Post(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	if err := tx.Transactions().Save(ctx, fee); err != nil {
+		return fmt.Errorf("saving fee: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,304 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v3"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fxrates"
+	"github.com/jyisus/bank-server/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFees(t *testing.T) {
+	var (
+		one  = internal.MustParseMoney("1")
+		fees = internal.FeeSchedules{
+			internal.AccountChecking: {
+				Withdrawal:  internal.FeeRule{Flat: internal.MustParseMoney("0.50")},
+				Transfer:    internal.FeeRule{Percentage: internal.MustParsePercentage("2"), Min: &one},
+				Maintenance: internal.MustParseMoney("3"),
+			},
+		}
+	)
+
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo        = b.accounts
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledger              = service.NewLedger(logger, accountsRepo, b.ledger)
+			accountsService     = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, fees, internal.SystemClock{})
+			transactionsService = service.NewTransactionService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, fees, internal.SystemClock{})
+			interestService     = service.NewInterestService(logger, accountsRepo, b.unitOfWork, ledger, internal.SystemClock{}, internal.DayCountActual365)
+			ctx                 = context.Background()
+		)
+
+		assertBalance := func(t *testing.T, accountID, expected string) {
+			t.Helper()
+
+			account, err := accountsRepo.Get(ctx, accountID)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(expected), account.Balance)
+			assert.NoError(t, ledger.Verify(ctx, accountID))
+		}
+
+		source, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		destination, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("10.00"))
+		require.NoError(t, err)
+
+		t.Run("Withdrawal", func(t *testing.T) {
+			withdrawal, saved, err := transactionsService.SaveTransaction(ctx, source.ID, internal.TxWithdrawal, internal.MustParseMoney("10"), "")
+			require.NoError(t, err)
+			assertBalance(t, source.ID, "89.50")
+
+			// The account is returned as stored after both the withdrawal and
+			// its fee.
+			repoAccount, err := accountsRepo.Get(ctx, source.ID)
+			require.NoError(t, err)
+			assert.Equal(t, repoAccount, saved)
+
+			transactions, err := transactionsService.RetrieveAccountTransactions(ctx, source.ID)
+			require.NoError(t, err)
+			fee := findFee(t, transactions, withdrawal.ID)
+			assert.Equal(t, internal.MustParseMoney("0.50"), fee.Amount)
+
+			// Deposits are free.
+			_, _, err = transactionsService.SaveTransaction(ctx, source.ID, internal.TxDeposit, internal.MustParseMoney("0.50"), "")
+			require.NoError(t, err)
+			assertBalance(t, source.ID, "90.00")
+		})
+
+		t.Run("Transfer", func(t *testing.T) {
+			quote, err := accountsService.QuoteTransfer(ctx, source.ID, destination.ID, internal.MustParseMoney("20"), "")
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney("1.00"), quote.Fee, "the minimum applies")
+			assertBalance(t, source.ID, "90.00")
+
+			transfer, err := accountsService.Transfer(ctx, source.ID, destination.ID, internal.MustParseMoney("20"), "")
+			require.NoError(t, err)
+			assert.Equal(t, quote.Fee, transfer.Fee)
+			assertBalance(t, source.ID, "69.00")
+			assertBalance(t, destination.ID, "30.00")
+
+			transactions, err := transactionsService.RetrieveTransfer(ctx, transfer.ID)
+			require.NoError(t, err)
+			require.Len(t, transactions, 2, "the fee isn't part of the transfer")
+
+			sourceTransactions, err := transactionsService.RetrieveAccountTransactions(ctx, source.ID)
+			require.NoError(t, err)
+			fee := findFee(t, sourceTransactions, transactions[0].ID)
+			assert.Equal(t, internal.MustParseMoney("1.00"), fee.Amount)
+		})
+
+		t.Run("Fee not affordable", func(t *testing.T) {
+			_, _, err := transactionsService.SaveTransaction(ctx, source.ID, internal.TxWithdrawal, internal.MustParseMoney("69"), "")
+			require.ErrorAs(t, err, &internal.ErrInsufficientBalance{})
+
+			_, err = accountsService.Transfer(ctx, source.ID, destination.ID, internal.MustParseMoney("68"), "")
+			require.ErrorAs(t, err, &internal.ErrInsufficientBalance{})
+
+			assertBalance(t, source.ID, "69.00")
+			assertBalance(t, destination.ID, "30.00")
+		})
+
+		t.Run("Revenue", func(t *testing.T) {
+			revenue, err := ledger.Balance(ctx, internal.SystemAccountRevenue, "EUR")
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney("1.50"), revenue)
+		})
+
+		t.Run("Accounts without fees", func(t *testing.T) {
+			savings, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+			require.NoError(t, err)
+
+			_, err = interestService.SetInterestTerms(ctx, savings.ID, internal.AccountSavings, internal.MustParseInterestRate("0"))
+			require.NoError(t, err)
+
+			_, _, err = transactionsService.SaveTransaction(ctx, savings.ID, internal.TxWithdrawal, internal.MustParseMoney("100"), "")
+			require.NoError(t, err)
+			assertBalance(t, savings.ID, "0.00")
+		})
+	})
+}
+
+func TestFeeService_ChargeMaintenanceFees(t *testing.T) {
+	fees := internal.FeeSchedules{
+		internal.AccountChecking: {Maintenance: internal.MustParseMoney("3")},
+	}
+
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo        = b.accounts
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledger              = service.NewLedger(logger, accountsRepo, b.ledger)
+			clock               = &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+			accountsService     = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, fees, clock)
+			transactionsService = service.NewTransactionService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, fees, clock)
+			feeService          = service.NewFeeService(logger, accountsRepo, b.unitOfWork, ledger, fees, clock)
+			ctx                 = context.Background()
+		)
+
+		account, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		poor, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("1.00"))
+		require.NoError(t, err)
+
+		frozen, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		_, err = accountsService.FreezeAccount(ctx, frozen.ID)
+		require.NoError(t, err)
+
+		chargeAt := func(t *testing.T, service *service.FeeService, now time.Time, expectedCharged int, expectedBalance string) {
+			t.Helper()
+
+			clock.now = now
+			charged, err := service.ChargeMaintenanceFees(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, expectedCharged, charged)
+
+			repoAccount, err := accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(expectedBalance), repoAccount.Balance)
+			assert.NoError(t, ledger.Verify(ctx, account.ID))
+		}
+
+		// Accounts opened within the month aren't charged its fee.
+		clock.now = time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+		recent, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		chargeAt(t, feeService, time.Date(2024, 2, 1, 0, 5, 0, 0, time.UTC), 1, "97.00")
+		chargeAt(t, feeService, time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC), 0, "97.00")
+
+		// The fee of each month is charged once, even by a new service.
+		restarted := service.NewFeeService(logger, accountsRepo, b.unitOfWork, ledger, fees, clock)
+		chargeAt(t, restarted, clock.now, 0, "97.00")
+		chargeAt(t, restarted, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 2, "94.00")
+
+		// Accounts that can't be charged keep their balance.
+		for id, expected := range map[string]string{poor.ID: "1.00", frozen.ID: "100.00", recent.ID: "97.00"} {
+			repoAccount, err := accountsRepo.Get(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(expected), repoAccount.Balance)
+		}
+
+		transactions, err := transactionsService.RetrieveAccountTransactions(ctx, account.ID)
+		require.NoError(t, err)
+
+		var charged []internal.Transaction
+		for _, transaction := range transactions {
+			if transaction.Type == internal.TxFee {
+				charged = append(charged, transaction)
+			}
+		}
+		require.Len(t, charged, 2)
+		assert.Empty(t, charged[0].FeeFor)
+	})
+}
+
+// findFee returns the fee charged for the transaction.
+func findFee(t *testing.T, transactions []internal.Transaction, feeFor string) internal.Transaction {
+	t.Helper()
+
+	for _, transaction := range transactions {
+		if transaction.Type == internal.TxFee && transaction.FeeFor == feeFor {
+			return transaction
+		}
+	}
+
+	require.Failf(t, "fee not found", "no fee charged for transaction %q", feeFor)
+
+	return internal.Transaction{}
+}
+
+func TestFeeService_DeferredMaintenanceFees(t *testing.T) {
+	fees := internal.FeeSchedules{
+		internal.AccountChecking: {Maintenance: internal.MustParseMoney("3")},
+	}
+
+	testCases := map[string]struct {
+		initialBalance  string
+		setup           func(t *testing.T, accountsService *service.AccountService, account *internal.Account)
+		fund            func(t *testing.T, accountsService *service.AccountService, transactionsService *service.TransactionService, account *internal.Account)
+		expectedBalance string
+	}{
+		"Insufficient balance": {
+			initialBalance: "1.00",
+			setup:          func(*testing.T, *service.AccountService, *internal.Account) {},
+			fund: func(t *testing.T, _ *service.AccountService, transactionsService *service.TransactionService, account *internal.Account) {
+				_, _, err := transactionsService.SaveTransaction(context.Background(), account.ID, internal.TxDeposit, internal.MustParseMoney("10.00"), "")
+				require.NoError(t, err)
+			},
+			expectedBalance: "8.00",
+		},
+		"Frozen account": {
+			initialBalance: "100.00",
+			setup: func(t *testing.T, accountsService *service.AccountService, account *internal.Account) {
+				_, err := accountsService.FreezeAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+			},
+			fund: func(t *testing.T, accountsService *service.AccountService, _ *service.TransactionService, account *internal.Account) {
+				_, err := accountsService.UnfreezeAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+			},
+			expectedBalance: "97.00",
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			forEachBackend(t, func(t *testing.T, b backend) {
+				var (
+					logs                bytes.Buffer
+					accountsRepo        = b.accounts
+					logger              = slog.New(slog.NewTextHandler(&logs, nil))
+					ledger              = service.NewLedger(logger, accountsRepo, b.ledger)
+					clock               = &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+					accountsService     = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, fees, clock)
+					transactionsService = service.NewTransactionService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, fees, clock)
+					feeService          = service.NewFeeService(logger, accountsRepo, b.unitOfWork, ledger, fees, clock)
+					ctx                 = context.Background()
+				)
+
+				account, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney(tc.initialBalance))
+				require.NoError(t, err)
+				tc.setup(t, accountsService, account)
+
+				chargeAt := func(t *testing.T, now time.Time, expectedCharged int) {
+					t.Helper()
+
+					clock.now = now
+					charged, err := feeService.ChargeMaintenanceFees(ctx)
+					require.NoError(t, err)
+					assert.Equal(t, expectedCharged, charged)
+				}
+
+				// The fee is deferred, and only warned about the first time.
+				chargeAt(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 0)
+				chargeAt(t, time.Date(2024, 2, 1, 0, 1, 0, 0, time.UTC), 0)
+				assert.Equal(t, 1, strings.Count(logs.String(), "Maintenance fee deferred"))
+
+				// It's charged once the account can afford it within the month.
+				tc.fund(t, accountsService, transactionsService, account)
+				chargeAt(t, time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), 1)
+				chargeAt(t, time.Date(2024, 2, 11, 0, 0, 0, 0, time.UTC), 0)
+
+				repoAccount, err := accountsRepo.Get(ctx, account.ID)
+				require.NoError(t, err)
+				assert.Equal(t, internal.MustParseMoney(tc.expectedBalance), repoAccount.Balance)
+				assert.Equal(t, 1, strings.Count(logs.String(), "Maintenance fee deferred"))
+			})
+		})
+	}
+}
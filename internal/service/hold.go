@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+)
+
+// DefaultHoldDuration is how long holds last when no expiry time is given.
+const DefaultHoldDuration = 7 * 24 * time.Hour
+
+// HoldService reserves funds of an account before they're taken, as card
+// authorizations do. Holds reduce the available balance of the account but
+// not its balance, which only changes when they're captured.
+type HoldService struct {
+	logger             *slog.Logger
+	accountsRepository internal.AccountsRepository
+	holdsRepository    internal.HoldsRepository
+	unitOfWork         internal.UnitOfWork
+	ledger             *Ledger
+	clock              internal.Clock
+}
+
+func NewHoldService(
+	logger *slog.Logger,
+	accountsRepository internal.AccountsRepository,
+	holdsRepository internal.HoldsRepository,
+	unitOfWork internal.UnitOfWork,
+	ledger *Ledger,
+	clock internal.Clock,
+) *HoldService {
+	return &HoldService{
+		logger:             logger,
+		accountsRepository: accountsRepository,
+		holdsRepository:    holdsRepository,
+		unitOfWork:         unitOfWork,
+		ledger:             ledger,
+		clock:              clock,
+	}
+}
+
+// PlaceHold reserves the amount of the account until expiresAt, or for
+// DefaultHoldDuration when it's zero. An empty currency means the account's
+// currency.
+func (s HoldService) PlaceHold(
+	ctx context.Context,
+	accountID string,
+	amount internal.Money,
+	currency internal.Currency,
+	expiresAt time.Time,
+) (*internal.Hold, error) {
+	account, err := s.accountsRepository.Get(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("getting hold's account: %w", err)
+	}
+
+	if currency == "" {
+		currency = account.Currency
+	}
+
+	now := s.clock.Now()
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(DefaultHoldDuration)
+	}
+
+	hold, err := internal.NewHold(uuid.NewString(), accountID, amount, currency, now, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.ledger.Lock(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := retryOnConflict(ctx, func() error {
+		return internal.RunInTx(ctx, s.unitOfWork, func(tx internal.Tx) error {
+			account, err := tx.Accounts().Get(ctx, accountID)
+			if err != nil {
+				return err
+			}
+
+			if err := account.PlaceHold(hold.Amount, hold.Currency); err != nil {
+				return err
+			}
+
+			if err := tx.Accounts().UpdateHeld(ctx, accountID, account.Version, account.Held); err != nil {
+				return fmt.Errorf("updating held amount of account %q: %w", accountID, err)
+			}
+
+			if err := tx.Holds().Create(ctx, hold); err != nil {
+				return fmt.Errorf("saving hold: %w", err)
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Hold placed", "ID", hold.ID, "account", accountID, "amount", hold.Amount, "expires_at", hold.ExpiresAt)
+
+	return &hold, nil
+}
+
+func (s HoldService) GetHold(ctx context.Context, id string) (*internal.Hold, error) {
+	hold, err := s.holdsRepository.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hold, nil
+}
+
+// CaptureHold withdraws the amount, up to the held one, from the account and
+// releases the rest of the hold. A nil amount captures the whole hold.
+func (s HoldService) CaptureHold(ctx context.Context, id string, amount *internal.Money) (*internal.Hold, error) {
+	now := s.clock.Now()
+	txID := uuid.NewString()
+
+	hold, err := s.finishHold(ctx, id, func(tx internal.Tx, hold *internal.Hold, account *internal.Account) error {
+		captured := hold.Amount
+		if amount != nil {
+			captured = *amount
+		}
+
+		if err := hold.Capture(captured, txID, now); err != nil {
+			return err
+		}
+
+		// The held amount is made available first, so the withdrawal can
+		// take it.
+		if err := account.ReleaseHold(hold.Amount, hold.Currency); err != nil {
+			return err
+		}
+
+		if err := tx.Accounts().UpdateHeld(ctx, account.ID, account.Version, account.Held); err != nil {
+			return fmt.Errorf("updating held amount of account %q: %w", account.ID, err)
+		}
+
+		transaction, err := internal.NewTransaction(
+			txID,
+			account.ID,
+			internal.TxWithdrawal,
+			*hold.CapturedAmount,
+			hold.Currency,
+			now,
+		)
+		if err != nil {
+			return err
+		}
+
+		entry, err := internal.NewTransactionEntry(uuid.NewString(), transaction)
+		if err != nil {
+			return err
+		}
+
+		if err := s.ledger.Post(ctx, tx, entry); err != nil {
+			return err
+		}
+
+		if err := tx.Transactions().Save(ctx, transaction); err != nil {
+			return fmt.Errorf("saving transaction: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Hold captured", "ID", id, "account", hold.AccountID, "amount", hold.CapturedAmount, "transaction", txID)
+
+	return hold, nil
+}
+
+// ReleaseHold makes the held amount available again without taking it.
+func (s HoldService) ReleaseHold(ctx context.Context, id string) (*internal.Hold, error) {
+	hold, err := s.finishHold(ctx, id, func(tx internal.Tx, hold *internal.Hold, account *internal.Account) error {
+		if err := hold.Release(); err != nil {
+			return err
+		}
+
+		return s.releaseHeld(ctx, tx, hold, account)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Hold released", "ID", id, "account", hold.AccountID)
+
+	return hold, nil
+}
+
+// ExpireHolds releases the active holds expired at asOf, and returns how many
+// were expired.
+func (s HoldService) ExpireHolds(ctx context.Context, asOf time.Time) (int, error) {
+	holds, err := s.holdsRepository.FindExpired(ctx, asOf)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, hold := range holds {
+		_, err := s.finishHold(ctx, hold.ID, func(tx internal.Tx, hold *internal.Hold, account *internal.Account) error {
+			if err := hold.Expire(asOf); err != nil {
+				return err
+			}
+
+			return s.releaseHeld(ctx, tx, hold, account)
+		})
+
+		// The hold may have been captured or released since it was found.
+		if errors.As(err, &internal.ErrHoldNotActive{}) {
+			continue
+		}
+
+		if err != nil {
+			return expired, fmt.Errorf("expiring hold %q: %w", hold.ID, err)
+		}
+
+		expired++
+	}
+
+	if expired > 0 {
+		s.logger.Info("Holds expired", "count", expired, "as_of", asOf)
+	}
+
+	return expired, nil
+}
+
+// finishHold runs fn, which changes the hold and its account, within a
+// transaction while no other operation uses the account, and stores the hold.
+func (s HoldService) finishHold(
+	ctx context.Context,
+	id string,
+	fn func(tx internal.Tx, hold *internal.Hold, account *internal.Account) error,
+) (*internal.Hold, error) {
+	hold, err := s.holdsRepository.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.ledger.Lock(ctx, hold.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := retryOnConflict(ctx, func() error {
+		return internal.RunInTx(ctx, s.unitOfWork, func(tx internal.Tx) error {
+			// The account is read first, which locks it in backends that
+			// lock on read, so the hold can't change after it's read.
+			account, err := tx.Accounts().Get(ctx, hold.AccountID)
+			if err != nil {
+				return err
+			}
+
+			hold, err = tx.Holds().Get(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			if err := fn(tx, &hold, account); err != nil {
+				return err
+			}
+
+			if err := tx.Holds().Update(ctx, hold); err != nil {
+				return fmt.Errorf("saving hold: %w", err)
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return &hold, nil
+}
+
+// releaseHeld makes the amount of the hold available again in the account.
+func (s HoldService) releaseHeld(ctx context.Context, tx internal.Tx, hold *internal.Hold, account *internal.Account) error {
+	if err := account.ReleaseHold(hold.Amount, hold.Currency); err != nil {
+		return err
+	}
+
+	if err := tx.Accounts().UpdateHeld(ctx, account.ID, account.Version, account.Held); err != nil {
+		return fmt.Errorf("updating held amount of account %q: %w", account.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package service_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v3"
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fxrates"
+	"github.com/jyisus/bank-server/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHoldService(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo        = b.accounts
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledger              = service.NewLedger(logger, accountsRepo, b.ledger)
+			accountsService     = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			transactionsService = service.NewTransactionService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, nil, internal.SystemClock{})
+			holdsService        = service.NewHoldService(logger, accountsRepo, b.holds, b.unitOfWork, ledger, internal.SystemClock{})
+			ctx                 = context.Background()
+		)
+
+		account, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		assertAccount := func(t *testing.T, balance, held string) {
+			t.Helper()
+
+			repoAccount, err := accountsRepo.Get(ctx, account.ID)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(balance), repoAccount.Balance)
+			assert.Equal(t, internal.MustParseMoney(held), repoAccount.Held)
+			assert.NoError(t, ledger.Verify(ctx, account.ID))
+		}
+
+		captured, err := holdsService.PlaceHold(ctx, account.ID, internal.MustParseMoney("60"), "", time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, internal.HoldActive, captured.Status)
+		assertAccount(t, "100.00", "60.00")
+
+		// The held amount can't be spent.
+		_, _, err = transactionsService.SaveTransaction(ctx, account.ID, internal.TxWithdrawal, internal.MustParseMoney("50"), "")
+		require.ErrorAs(t, err, &internal.ErrInsufficientBalance{})
+
+		_, err = holdsService.PlaceHold(ctx, account.ID, internal.MustParseMoney("50"), "", time.Time{})
+		require.ErrorAs(t, err, &internal.ErrInsufficientBalance{})
+
+		amount := internal.MustParseMoney("45")
+		captured, err = holdsService.CaptureHold(ctx, captured.ID, &amount)
+		require.NoError(t, err)
+		assert.Equal(t, internal.HoldCaptured, captured.Status)
+		assert.Equal(t, internal.MustParseMoney("45.00"), *captured.CapturedAmount)
+		assertAccount(t, "55.00", "0.00")
+
+		transactions, err := transactionsService.RetrieveAccountTransactions(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, transactions, 1)
+		assert.Equal(t, captured.TransactionID, transactions[0].ID)
+		assert.Equal(t, internal.TransactionType(internal.TxWithdrawal), transactions[0].Type)
+
+		_, err = holdsService.CaptureHold(ctx, captured.ID, nil)
+		require.ErrorAs(t, err, &internal.ErrHoldNotActive{})
+
+		released, err := holdsService.PlaceHold(ctx, account.ID, internal.MustParseMoney("20"), "", time.Time{})
+		require.NoError(t, err)
+		assertAccount(t, "55.00", "20.00")
+
+		released, err = holdsService.ReleaseHold(ctx, released.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.HoldReleased, released.Status)
+		assertAccount(t, "55.00", "0.00")
+
+		expiresAt := time.Now().Add(time.Hour)
+		expired, err := holdsService.PlaceHold(ctx, account.ID, internal.MustParseMoney("30"), "", expiresAt)
+		require.NoError(t, err)
+		assertAccount(t, "55.00", "30.00")
+
+		count, err := holdsService.ExpireHolds(ctx, expiresAt.Add(-time.Minute))
+		require.NoError(t, err)
+		assert.Zero(t, count)
+
+		count, err = holdsService.ExpireHolds(ctx, expiresAt)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assertAccount(t, "55.00", "0.00")
+
+		expired, err = holdsService.GetHold(ctx, expired.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.HoldExpired, expired.Status)
+
+		_, err = holdsService.ReleaseHold(ctx, expired.ID)
+		require.ErrorAs(t, err, &internal.ErrHoldNotActive{})
+
+		_, err = holdsService.GetHold(ctx, uuid.NewString())
+		require.ErrorAs(t, err, &internal.ErrHoldNotFound{})
+
+		_, err = holdsService.PlaceHold(ctx, uuid.NewString(), internal.MustParseMoney("1"), "", time.Time{})
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+	})
+}
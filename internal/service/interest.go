@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+)
+
+// InterestService accrues the daily interest of the savings accounts and posts
+// it at the end of each month. Time comes from its clock, so tests can move it
+// at will.
+type InterestService struct {
+	logger             *slog.Logger
+	accountsRepository internal.AccountsRepository
+	unitOfWork         internal.UnitOfWork
+	ledger             *Ledger
+	clock              internal.Clock
+	dayCount           internal.DayCount
+}
+
+func NewInterestService(
+	logger *slog.Logger,
+	accountsRepository internal.AccountsRepository,
+	unitOfWork internal.UnitOfWork,
+	ledger *Ledger,
+	clock internal.Clock,
+	dayCount internal.DayCount,
+) *InterestService {
+	return &InterestService{
+		logger:             logger,
+		accountsRepository: accountsRepository,
+		unitOfWork:         unitOfWork,
+		ledger:             ledger,
+		clock:              clock,
+		dayCount:           dayCount,
+	}
+}
+
+// InterestAccrual is the outcome of accruing the interest of the accounts
+// through a day.
+type InterestAccrual struct {
+	AsOf time.Time `json:"as_of"`
+	// Accounts is how many accounts had interest to accrue.
+	Accounts int `json:"accounts"`
+	// Transactions are the interest postings made.
+	Transactions []internal.Transaction `json:"transactions"`
+}
+
+// AccrueInterest accrues the interest of every account through the given day,
+// which must be over, posting it for the months ended in between. A zero day
+// means yesterday. Accounts already accrued through the day are skipped, so
+// running it again for the same day, or for an earlier one, does nothing
+// twice, while a later one backfills the days missed.
+func (s InterestService) AccrueInterest(ctx context.Context, asOf time.Time) (*InterestAccrual, error) {
+	today := internal.Date(s.clock.Now())
+	if asOf.IsZero() {
+		asOf = today.AddDate(0, 0, -1)
+	}
+
+	asOf = internal.Date(asOf)
+	if !asOf.Before(today) {
+		return nil, internal.ErrInvalidValue{Msg: "interest can only be accrued through days that are over"}
+	}
+
+	accounts, err := s.accountsRepository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accrual := &InterestAccrual{AsOf: asOf, Transactions: []internal.Transaction{}}
+	for _, account := range accounts {
+		if !account.AccruesInterestThrough(asOf) {
+			continue
+		}
+
+		_, transactions, err := s.updateInterest(ctx, account.ID, nil, asOf, func(*internal.Account) error { return nil })
+		if err != nil {
+			return accrual, fmt.Errorf("accruing interest of account %q: %w", account.ID, err)
+		}
+
+		accrual.Accounts++
+		accrual.Transactions = append(accrual.Transactions, transactions...)
+	}
+
+	if accrual.Accounts > 0 {
+		s.logger.Info("Interest accrued", "accounts", accrual.Accounts, "postings", len(accrual.Transactions), "as_of", asOf)
+	}
+
+	return accrual, nil
+}
+
+// SetInterestTerms changes the type and the interest rate of the account from
+// today on. The interest due until yesterday is accrued with the previous
+// terms first.
+func (s InterestService) SetInterestTerms(
+	ctx context.Context,
+	id string,
+	accountType internal.AccountType,
+	rate internal.InterestRate,
+) (*internal.Account, error) {
+	return s.setInterestTerms(ctx, id, nil, accountType, rate)
+}
+
+// SetInterestTermsIfVersion changes the interest terms only if the account is
+// still at the given version. Otherwise it returns ErrVersionConflict.
+func (s InterestService) SetInterestTermsIfVersion(
+	ctx context.Context,
+	id string,
+	expectedVersion int64,
+	accountType internal.AccountType,
+	rate internal.InterestRate,
+) (*internal.Account, error) {
+	return s.setInterestTerms(ctx, id, &expectedVersion, accountType, rate)
+}
+
+func (s InterestService) setInterestTerms(
+	ctx context.Context,
+	id string,
+	expectedVersion *int64,
+	accountType internal.AccountType,
+	rate internal.InterestRate,
+) (*internal.Account, error) {
+	today := s.clock.Now()
+	yesterday := internal.Date(today).AddDate(0, 0, -1)
+
+	account, _, err := s.updateInterest(ctx, id, expectedVersion, yesterday, func(account *internal.Account) error {
+		return account.SetInterestTerms(accountType, rate, today)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Account interest terms changed", "ID", id, "type", account.Type, "interest_rate", account.InterestRate)
+
+	return account, nil
+}
+
+// updateInterest accrues the interest of the account through the given day
+// and posts what's due, lets change change its interest, and stores it, all
+// within a transaction while no other operation uses the account. When an
+// expected version is given, the account must still be at it. It returns the
+// account as stored and the interest transactions.
+func (s InterestService) updateInterest(
+	ctx context.Context,
+	id string,
+	expectedVersion *int64,
+	through time.Time,
+	change func(account *internal.Account) error,
+) (*internal.Account, []internal.Transaction, error) {
+	unlock, err := s.ledger.Lock(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+
+	var (
+		account      *internal.Account
+		transactions []internal.Transaction
+	)
+
+	save := func() error {
+		transactions = nil
+
+		return internal.RunInTx(ctx, s.unitOfWork, func(tx internal.Tx) error {
+			var err error
+			account, err = tx.Accounts().Get(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			if expectedVersion != nil && account.Version != *expectedVersion {
+				return internal.ErrVersionConflict{AccountID: id, ExpectedVersion: *expectedVersion}
+			}
+
+			var since []internal.Transaction
+			if start := account.InterestAccrualStart(); start != nil {
+				since, err = tx.Transactions().Find(ctx, internal.TransactionQuery{AccountID: id, From: start})
+				if err != nil {
+					return fmt.Errorf("finding transactions: %w", err)
+				}
+			}
+
+			postings, err := account.AccrueInterest(through, s.dayCount, since)
+			if err != nil {
+				return err
+			}
+
+			for _, posting := range postings {
+				transaction := internal.NewInterestTransaction(uuid.NewString(), *account, posting)
+
+				entry, err := internal.NewTransactionEntry(uuid.NewString(), transaction)
+				if err != nil {
+					return err
+				}
+
+				if err := s.ledger.Post(ctx, tx, entry); err != nil {
+					return err
+				}
+
+				if err := tx.Transactions().Save(ctx, transaction); err != nil {
+					return fmt.Errorf("saving transaction: %w", err)
+				}
+
+				transactions = append(transactions, transaction)
+			}
+
+			// Posting the interest changed the balance and the version of
+			// the account.
+			posted, err := tx.Accounts().Get(ctx, id)
+			if err != nil {
+				return err
+			}
+			account.Balance, account.Version = posted.Balance, posted.Version
+
+			if err := change(account); err != nil {
+				return err
+			}
+
+			if err := tx.Accounts().UpdateInterest(ctx, id, account.Version, account.Type, account.AccountInterest); err != nil {
+				return fmt.Errorf("updating interest of account %q: %w", id, err)
+			}
+			account.Version++
+
+			return nil
+		})
+	}
+
+	// A conflict on a conditional update means the client's precondition no
+	// longer holds, so it's reported instead of retried.
+	if expectedVersion != nil {
+		err = save()
+	} else {
+		err = retryOnConflict(ctx, save)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return account, transactions, nil
+}
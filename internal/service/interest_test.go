@@ -0,0 +1,102 @@
+package service_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v3"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fxrates"
+	"github.com/jyisus/bank-server/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterestService(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo        = b.accounts
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledger              = service.NewLedger(logger, accountsRepo, b.ledger)
+			accountsService     = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			transactionsService = service.NewTransactionService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, nil, internal.SystemClock{})
+			clock               = &testClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+			interestService     = service.NewInterestService(logger, accountsRepo, b.unitOfWork, ledger, clock, internal.DayCountActual365)
+			ctx                 = context.Background()
+		)
+
+		savings, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("1000.00"))
+		require.NoError(t, err)
+
+		checking, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("1000.00"))
+		require.NoError(t, err)
+
+		_, err = interestService.SetInterestTerms(ctx, checking.ID, internal.AccountChecking, internal.MustParseInterestRate("0.02"))
+		require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+
+		// Every day earns 0.10 on 1000.00.
+		account, err := interestService.SetInterestTerms(ctx, savings.ID, internal.AccountSavings, internal.MustParseInterestRate("0.0365"))
+		require.NoError(t, err)
+		assert.Equal(t, internal.AccountSavings, account.Type)
+
+		accrueAt := func(t *testing.T, now, asOf time.Time, expectedAccounts int, expectedBalance string) *service.InterestAccrual {
+			t.Helper()
+
+			clock.now = now
+			accrual, err := interestService.AccrueInterest(ctx, asOf)
+			require.NoError(t, err)
+			assert.Equal(t, expectedAccounts, accrual.Accounts)
+
+			repoAccount, err := accountsRepo.Get(ctx, savings.ID)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(expectedBalance), repoAccount.Balance)
+			assert.NoError(t, ledger.Verify(ctx, savings.ID))
+
+			return accrual
+		}
+
+		// Yesterday's interest was accrued with the previous terms.
+		accrual := accrueAt(t, clock.now, time.Time{}, 0, "1000.00")
+		assert.Equal(t, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), accrual.AsOf)
+
+		accrual = accrueAt(t, time.Date(2024, 1, 20, 3, 0, 0, 0, time.UTC), time.Time{}, 1, "1000.00")
+		assert.Empty(t, accrual.Transactions)
+
+		accrual = accrueAt(t, time.Date(2024, 2, 2, 3, 0, 0, 0, time.UTC), time.Time{}, 1, "1003.10")
+		require.Len(t, accrual.Transactions, 1)
+		assert.Equal(t, internal.TransactionType(internal.TxInterest), accrual.Transactions[0].Type)
+		assert.Equal(t, time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC), accrual.Transactions[0].Timestamp)
+
+		// Running the accrual again, or for an earlier day, does nothing.
+		accrueAt(t, clock.now, time.Time{}, 0, "1003.10")
+		accrueAt(t, clock.now, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 0, "1003.10")
+
+		_, err = interestService.AccrueInterest(ctx, clock.now)
+		require.ErrorAs(t, err, &internal.ErrInvalidValue{}, "days that aren't over can't be accrued")
+
+		transactions, err := transactionsService.RetrieveAccountTransactions(ctx, savings.ID)
+		require.NoError(t, err)
+		assert.Contains(t, transactions, accrual.Transactions[0])
+
+		// The interest earned before becoming a checking account is still
+		// posted at the end of the month.
+		account, err = interestService.SetInterestTerms(ctx, savings.ID, internal.AccountChecking, internal.InterestRate{})
+		require.NoError(t, err)
+		assert.Zero(t, internal.MustParseMoney("0.10031").Cmp(account.AccruedInterest), account.AccruedInterest.String())
+
+		accrueAt(t, time.Date(2024, 3, 5, 3, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 1, "1003.20")
+
+		repoAccount, err := accountsRepo.Get(ctx, savings.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.AccountChecking, repoAccount.Type)
+		assert.Zero(t, internal.MustParseMoney("0.00031").Cmp(repoAccount.AccruedInterest), repoAccount.AccruedInterest.String())
+		assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), *repoAccount.InterestAccruedThrough)
+
+		repoAccount, err = accountsRepo.Get(ctx, checking.ID)
+		require.NoError(t, err)
+		assert.Nil(t, repoAccount.InterestAccruedThrough, "accounts without interest terms must be skipped")
+	})
+}
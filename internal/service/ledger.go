@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/keylock"
+)
+
+// Ledger is the posting engine shared by every operation that moves money. It
+// books balanced journal entries and applies them to the account balances.
+type Ledger struct {
+	logger             *slog.Logger
+	accountsRepository internal.AccountsRepository
+	ledgerRepository   internal.LedgerRepository
+	// locks makes concurrent operations on the same accounts wait for each
+	// other instead of failing with version conflicts.
+	locks *keylock.Locks
+}
+
+func NewLedger(
+	logger *slog.Logger,
+	accountsRepository internal.AccountsRepository,
+	ledgerRepository internal.LedgerRepository,
+) *Ledger {
+	return &Ledger{
+		logger:             logger,
+		accountsRepository: accountsRepository,
+		ledgerRepository:   ledgerRepository,
+		locks:              keylock.New(),
+	}
+}
+
+// OpenAccount stores a new account and books its initial balance, as of the
+// account's opening time, within the given transaction.
+func (l *Ledger) OpenAccount(ctx context.Context, tx internal.Tx, account internal.Account) error {
+	if account.OpenedAt == nil {
+		return fmt.Errorf("opening account %q: missing opening time", account.ID)
+	}
+
+	entry, err := internal.NewOpeningEntry(uuid.NewString(), account, *account.OpenedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Accounts().Create(ctx, account); err != nil {
+		return err
+	}
+
+	if err := tx.Ledger().Append(ctx, entry); err != nil {
+		return fmt.Errorf("appending opening entry: %w", err)
+	}
+
+	return nil
+}
+
+// Lock serializes the operations on the given accounts: it blocks until no
+// other operation of the services sharing this ledger holds any of them. The
+// returned function releases the accounts.
+func (l *Ledger) Lock(ctx context.Context, accountIDs ...string) (func(), error) {
+	return l.locks.Lock(ctx, accountIDs...)
+}
+
+// Post validates the entry, applies its postings to the customer accounts and
+// stores it within the given transaction.
+func (l *Ledger) Post(ctx context.Context, tx internal.Tx, entry internal.JournalEntry) error {
+	return l.post(ctx, tx, entry, movePosting)
+}
+
+// Adjust posts an entry that corrects the balances of the customer accounts,
+// which goes through whatever their status and available balance.
+func (l *Ledger) Adjust(ctx context.Context, tx internal.Tx, entry internal.JournalEntry) error {
+	return l.post(ctx, tx, entry, func(account *internal.Account, posting internal.Posting) error {
+		return account.Adjust(posting.Amount, posting.Currency)
+	})
+}
+
+func (l *Ledger) post(
+	ctx context.Context,
+	tx internal.Tx,
+	entry internal.JournalEntry,
+	change func(*internal.Account, internal.Posting) error,
+) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	accounts, err := l.apply(ctx, tx.Accounts(), entry, change)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Ledger().Append(ctx, entry); err != nil {
+		return fmt.Errorf("appending journal entry: %w", err)
+	}
+
+	for _, account := range accounts {
+		if err := tx.Accounts().UpdateBalance(ctx, account.ID, account.Version, account.Balance); err != nil {
+			return fmt.Errorf("updating balance of account %q: %w", account.ID, err)
+		}
+	}
+
+	l.logger.Debug("Journal entry posted", "ID", entry.ID, "reference", entry.Reference, "postings", len(entry.Postings))
+
+	return nil
+}
+
+// Balance derives the balance of an account from its postings.
+func (l *Ledger) Balance(ctx context.Context, accountID string, currency internal.Currency) (internal.Money, error) {
+	entries, err := l.ledgerRepository.FindAllByAccount(ctx, accountID)
+	if err != nil {
+		return internal.Money{}, err
+	}
+
+	return internal.BalanceOf(entries, accountID, currency)
+}
+
+// OpeningBalance returns the initial balance booked for the account when it
+// was opened.
+func (l *Ledger) OpeningBalance(ctx context.Context, account internal.Account) (internal.Money, error) {
+	entries, err := l.ledgerRepository.FindAllByAccount(ctx, account.ID)
+	if err != nil {
+		return internal.Money{}, err
+	}
+
+	return internal.OpeningBalance(entries, account.ID, account.Currency)
+}
+
+// Verify checks that the stored balance of the account matches its postings.
+func (l *Ledger) Verify(ctx context.Context, accountID string) error {
+	account, err := l.accountsRepository.Get(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	ledgerBalance, err := l.Balance(ctx, accountID, account.Currency)
+	if err != nil {
+		return err
+	}
+
+	if ledgerBalance.Cmp(account.Balance) != 0 {
+		return internal.ErrBalanceMismatch{
+			AccountID:     accountID,
+			Balance:       account.Balance,
+			LedgerBalance: ledgerBalance,
+		}
+	}
+
+	return nil
+}
+
+// apply returns the customer accounts of the entry with their new balances,
+// changed posting by posting.
+func (l *Ledger) apply(
+	ctx context.Context,
+	accountsRepository internal.AccountsRepository,
+	entry internal.JournalEntry,
+	change func(*internal.Account, internal.Posting) error,
+) ([]*internal.Account, error) {
+	var accounts []*internal.Account
+	byID := make(map[string]*internal.Account)
+
+	for _, posting := range entry.Postings {
+		if internal.IsSystemAccount(posting.AccountID) {
+			continue
+		}
+
+		account, ok := byID[posting.AccountID]
+		if !ok {
+			var err error
+			account, err = accountsRepository.Get(ctx, posting.AccountID)
+			if err != nil {
+				return nil, err
+			}
+
+			byID[account.ID] = account
+			accounts = append(accounts, account)
+		}
+
+		if err := change(account, posting); err != nil {
+			return nil, err
+		}
+	}
+
+	return accounts, nil
+}
+
+// movePosting deposits positive postings into the account and withdraws
+// negative ones from it. Zero postings move nothing and are rejected.
+func movePosting(account *internal.Account, posting internal.Posting) error {
+	if posting.Amount.IsZero() {
+		return internal.ErrInvalidValue{Msg: fmt.Sprintf("the posting to account %q moves no money", account.ID)}
+	}
+
+	if posting.Amount.IsNegative() {
+		return account.Withdraw(posting.Amount.Neg(), posting.Currency)
+	}
+
+	return account.Deposit(posting.Amount, posting.Currency)
+}
@@ -0,0 +1,131 @@
+package service_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fxrates"
+	"github.com/jyisus/bank-server/internal/memrepo"
+	"github.com/jyisus/bank-server/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedger_BalancesMatchPostings(t *testing.T) {
+	var (
+		logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+		accountsRepo        = memrepo.NewAccountsRepository()
+		transactionsRepo    = memrepo.NewTransactionsRepository()
+		ledgerRepo          = memrepo.NewLedgerRepository()
+		unitOfWork          = memrepo.NewUnitOfWork(accountsRepo, transactionsRepo, ledgerRepo, memrepo.NewHoldsRepository())
+		ledger              = service.NewLedger(logger, accountsRepo, ledgerRepo)
+		accountsService     = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+		transactionsService = service.NewTransactionService(logger, accountsRepo, transactionsRepo, unitOfWork, ledger, nil, internal.SystemClock{})
+		ctx                 = context.Background()
+	)
+
+	source, err := accountsService.CreateAccount(ctx, "Source", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+
+	destination, err := accountsService.CreateAccount(ctx, "Destination", "EUR", internal.MustParseMoney("0"))
+	require.NoError(t, err)
+
+	_, _, err = transactionsService.SaveTransaction(ctx, source.ID, internal.TxDeposit, internal.MustParseMoney("20.30"), "")
+	require.NoError(t, err)
+
+	_, _, err = transactionsService.SaveTransaction(ctx, source.ID, internal.TxWithdrawal, internal.MustParseMoney("5"), "")
+	require.NoError(t, err)
+
+	_, err = accountsService.Transfer(ctx, source.ID, destination.ID, internal.MustParseMoney("50"), "")
+	require.NoError(t, err)
+
+	require.NoError(t, ledger.Verify(ctx, source.ID))
+	require.NoError(t, ledger.Verify(ctx, destination.ID))
+
+	sourceBalance, err := ledger.Balance(ctx, source.ID, "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("65.30"), sourceBalance)
+
+	cashIn, err := ledger.Balance(ctx, internal.SystemAccountCashIn, "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("-120.30"), cashIn)
+
+	cashOut, err := ledger.Balance(ctx, internal.SystemAccountCashOut, "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("5.00"), cashOut)
+}
+
+func TestLedger_Post_InsufficientBalance(t *testing.T) {
+	var (
+		logger       = slog.New(slog.NewTextHandler(os.Stdout, nil))
+		accountsRepo = memrepo.NewAccountsRepository()
+		ledgerRepo   = memrepo.NewLedgerRepository()
+		unitOfWork   = memrepo.NewUnitOfWork(accountsRepo, memrepo.NewTransactionsRepository(), ledgerRepo, memrepo.NewHoldsRepository())
+		ledger       = service.NewLedger(logger, accountsRepo, ledgerRepo)
+		ctx          = context.Background()
+		openedAt     = time.Now().UTC()
+
+		account = internal.Account{
+			ID:       uuid.NewString(),
+			Currency: "EUR",
+			Balance:  internal.MustParseMoney("10.00"),
+			OpenedAt: &openedAt,
+		}
+	)
+
+	require.NoError(t, internal.RunInTx(ctx, unitOfWork, func(tx internal.Tx) error {
+		return ledger.OpenAccount(ctx, tx, account)
+	}))
+
+	entry, err := internal.NewJournalEntry(
+		uuid.NewString(),
+		"withdrawal",
+		time.Now(),
+		internal.Posting{AccountID: account.ID, Amount: internal.MustParseMoney("-20"), Currency: "EUR"},
+		internal.Posting{AccountID: internal.SystemAccountCashOut, Amount: internal.MustParseMoney("20"), Currency: "EUR"},
+	)
+	require.NoError(t, err)
+
+	err = internal.RunInTx(ctx, unitOfWork, func(tx internal.Tx) error {
+		return ledger.Post(ctx, tx, entry)
+	})
+	require.ErrorAs(t, err, &internal.ErrInsufficientBalance{})
+
+	entries, err := ledgerRepo.FindAllByAccount(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	require.NoError(t, ledger.Verify(ctx, account.ID))
+}
+
+func TestLedger_Verify_Mismatch(t *testing.T) {
+	var (
+		logger       = slog.New(slog.NewTextHandler(os.Stdout, nil))
+		accountsRepo = memrepo.NewAccountsRepository()
+		ledgerRepo   = memrepo.NewLedgerRepository()
+		unitOfWork   = memrepo.NewUnitOfWork(accountsRepo, memrepo.NewTransactionsRepository(), ledgerRepo, memrepo.NewHoldsRepository())
+		ledger       = service.NewLedger(logger, accountsRepo, ledgerRepo)
+		ctx          = context.Background()
+		openedAt     = time.Now().UTC()
+
+		account = internal.Account{
+			ID:       uuid.NewString(),
+			Currency: "EUR",
+			Balance:  internal.MustParseMoney("10.00"),
+			OpenedAt: &openedAt,
+		}
+	)
+
+	require.NoError(t, internal.RunInTx(ctx, unitOfWork, func(tx internal.Tx) error {
+		return ledger.OpenAccount(ctx, tx, account)
+	}))
+	require.NoError(t, accountsRepo.UpdateBalance(ctx, account.ID, 0, internal.MustParseMoney("15.00")))
+
+	err := ledger.Verify(ctx, account.ID)
+	require.ErrorAs(t, err, &internal.ErrBalanceMismatch{})
+}
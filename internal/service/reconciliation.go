@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+)
+
+// ReconciliationService checks that the balances of the accounts are what
+// their history adds up to, and can correct the ones that aren't.
+type ReconciliationService struct {
+	logger                 *slog.Logger
+	accountsRepository     internal.AccountsRepository
+	transactionsRepository internal.TransactionsRepository
+	unitOfWork             internal.UnitOfWork
+	ledger                 *Ledger
+	clock                  internal.Clock
+	// reconciledDay is the day the daily reconciliation last ran, so it only
+	// runs once a day.
+	reconciledDay *atomic.Pointer[time.Time]
+}
+
+func NewReconciliationService(
+	logger *slog.Logger,
+	accountsRepository internal.AccountsRepository,
+	transactionsRepository internal.TransactionsRepository,
+	unitOfWork internal.UnitOfWork,
+	ledger *Ledger,
+	clock internal.Clock,
+) *ReconciliationService {
+	return &ReconciliationService{
+		logger:                 logger,
+		accountsRepository:     accountsRepository,
+		transactionsRepository: transactionsRepository,
+		unitOfWork:             unitOfWork,
+		ledger:                 ledger,
+		clock:                  clock,
+		reconciledDay:          &atomic.Pointer[time.Time]{},
+	}
+}
+
+// Reconciliation is the outcome of reconciling the balances of the accounts
+// with their history. Its ID is the reference of the adjustment entries made.
+type Reconciliation struct {
+	ID string    `json:"id"`
+	At time.Time `json:"at"`
+	// Accounts is how many accounts were checked.
+	Accounts      int                    `json:"accounts"`
+	Discrepancies []internal.Discrepancy `json:"discrepancies"`
+	// Adjusted tells whether the discrepancies were corrected.
+	Adjusted bool `json:"adjusted"`
+}
+
+// Reconcile recomputes the balance of every account from its opening balance
+// and its transactions, and reports the accounts whose stored balance doesn't
+// match. When adjust is set, each of their balances is corrected to the
+// recomputed one by an adjustment transaction, booked against the suspense
+// account so the difference stays in the ledger until it's explained.
+func (s ReconciliationService) Reconcile(ctx context.Context, adjust bool) (*Reconciliation, error) {
+	accounts, err := s.accountsRepository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reconciliation := &Reconciliation{
+		ID:            uuid.NewString(),
+		At:            s.clock.Now(),
+		Discrepancies: []internal.Discrepancy{},
+		Adjusted:      adjust,
+	}
+	for _, account := range accounts {
+		discrepancy, err := s.reconcileAccount(ctx, reconciliation, account.ID, adjust)
+		if err != nil {
+			return reconciliation, fmt.Errorf("reconciling account %q: %w", account.ID, err)
+		}
+
+		reconciliation.Accounts++
+		if discrepancy != nil {
+			reconciliation.Discrepancies = append(reconciliation.Discrepancies, *discrepancy)
+		}
+	}
+
+	s.logger.Info(
+		"Accounts reconciled",
+		"ID", reconciliation.ID,
+		"accounts", reconciliation.Accounts,
+		"discrepancies", len(reconciliation.Discrepancies),
+		"adjusted", adjust,
+	)
+
+	return reconciliation, nil
+}
+
+// ReconcileDaily reconciles the accounts without correcting them, only on the
+// first run of each day. It returns nil on the other runs.
+func (s ReconciliationService) ReconcileDaily(ctx context.Context) (*Reconciliation, error) {
+	day := internal.Date(s.clock.Now())
+	if reconciled := s.reconciledDay.Load(); reconciled != nil && reconciled.Equal(day) {
+		return nil, nil
+	}
+
+	reconciliation, err := s.Reconcile(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	s.reconciledDay.Store(&day)
+
+	return reconciliation, nil
+}
+
+// reconcileAccount returns the discrepancy of the account, corrected when
+// adjust is set, or nil when its balance matches its history.
+func (s ReconciliationService) reconcileAccount(
+	ctx context.Context,
+	reconciliation *Reconciliation,
+	accountID string,
+	adjust bool,
+) (*internal.Discrepancy, error) {
+	unlock, err := s.ledger.Lock(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	account, err := s.accountsRepository.Get(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	opening, err := s.ledger.OpeningBalance(ctx, *account)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.transactionsRepository.FindAllByAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	discrepancy, err := internal.Reconcile(*account, opening, transactions)
+	if err != nil || discrepancy == nil {
+		return nil, err
+	}
+
+	s.logger.Error(
+		"Balance doesn't match the history",
+		"account", accountID,
+		"balance", discrepancy.Balance,
+		"recomputed", discrepancy.Recomputed,
+	)
+
+	if !adjust {
+		return discrepancy, nil
+	}
+
+	entry, err := internal.NewAdjustmentEntry(uuid.NewString(), reconciliation.ID, *discrepancy, reconciliation.At)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := internal.NewAdjustmentTransaction(uuid.NewString(), *discrepancy, reconciliation.At)
+
+	err = retryOnConflict(ctx, func() error {
+		return internal.RunInTx(ctx, s.unitOfWork, func(tx internal.Tx) error {
+			if err := s.ledger.Adjust(ctx, tx, entry); err != nil {
+				return err
+			}
+
+			if err := tx.Transactions().Save(ctx, transaction); err != nil {
+				return fmt.Errorf("saving adjustment: %w", err)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("adjusting balance: %w", err)
+	}
+
+	discrepancy.AdjustmentID = entry.ID
+
+	return discrepancy, nil
+}
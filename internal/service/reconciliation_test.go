@@ -0,0 +1,213 @@
+package service_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v3"
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fxrates"
+	"github.com/jyisus/bank-server/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciliationService(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo          = b.accounts
+			logger                = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledger                = service.NewLedger(logger, accountsRepo, b.ledger)
+			accountsService       = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			transactionsService   = service.NewTransactionService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, nil, internal.SystemClock{})
+			clock                 = &testClock{now: time.Date(2024, 11, 1, 10, 0, 0, 0, time.UTC)}
+			reconciliationService = service.NewReconciliationService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, clock)
+			ctx                   = context.Background()
+		)
+
+		reconciled, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		ahead, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("50.00"))
+		require.NoError(t, err)
+
+		behind, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("20.00"))
+		require.NoError(t, err)
+
+		_, err = accountsService.Transfer(ctx, reconciled.ID, ahead.ID, internal.MustParseMoney("30.00"), "")
+		require.NoError(t, err)
+
+		_, _, err = transactionsService.SaveTransaction(ctx, behind.ID, internal.TxWithdrawal, internal.MustParseMoney("5.00"), "")
+		require.NoError(t, err)
+
+		// A deposit into ahead was posted without being recorded in its
+		// history, and the account was frozen since, while a deposit into
+		// behind was recorded without being posted.
+		unrecorded, err := internal.NewTransaction(uuid.NewString(), ahead.ID, internal.TxDeposit, internal.MustParseMoney("120.00"), "EUR", clock.now)
+		require.NoError(t, err)
+
+		entry, err := internal.NewTransactionEntry(uuid.NewString(), unrecorded)
+		require.NoError(t, err)
+
+		require.NoError(t, internal.RunInTx(ctx, b.unitOfWork, func(tx internal.Tx) error {
+			return ledger.Post(ctx, tx, entry)
+		}))
+
+		_, err = accountsService.FreezeAccount(ctx, ahead.ID)
+		require.NoError(t, err)
+
+		require.NoError(t, b.transactions.Save(ctx, internal.Transaction{
+			ID:        uuid.NewString(),
+			AccountID: behind.ID,
+			Type:      internal.TxDeposit,
+			Amount:    internal.MustParseMoney("10.00"),
+			Currency:  "EUR",
+			Timestamp: clock.now,
+		}))
+
+		expected := map[string]struct {
+			balance    string
+			recomputed string
+			difference string
+		}{
+			ahead.ID:  {balance: "200.00", recomputed: "80.00", difference: "120.00"},
+			behind.ID: {balance: "15.00", recomputed: "25.00", difference: "-10.00"},
+		}
+
+		assertDiscrepancies := func(t *testing.T, reconciliation *service.Reconciliation) {
+			t.Helper()
+
+			assert.Equal(t, 3, reconciliation.Accounts)
+			assert.Equal(t, clock.now, reconciliation.At)
+			require.Len(t, reconciliation.Discrepancies, len(expected))
+
+			for _, discrepancy := range reconciliation.Discrepancies {
+				tc, ok := expected[discrepancy.AccountID]
+				require.True(t, ok, "unexpected discrepancy of account %q", discrepancy.AccountID)
+				assert.Equal(t, internal.Currency("EUR"), discrepancy.Currency)
+				assert.Zero(t, internal.MustParseMoney(tc.balance).Cmp(discrepancy.Balance), "balance %s", discrepancy.Balance)
+				assert.Zero(t, internal.MustParseMoney(tc.recomputed).Cmp(discrepancy.Recomputed), "recomputed %s", discrepancy.Recomputed)
+				assert.Zero(t, internal.MustParseMoney(tc.difference).Cmp(discrepancy.Difference), "difference %s", discrepancy.Difference)
+			}
+		}
+
+		t.Run("Report", func(t *testing.T) {
+			reconciliation, err := reconciliationService.Reconcile(ctx, false)
+			require.NoError(t, err)
+			assertDiscrepancies(t, reconciliation)
+
+			for _, discrepancy := range reconciliation.Discrepancies {
+				assert.Empty(t, discrepancy.AdjustmentID)
+			}
+
+			repoAccount, err := accountsRepo.Get(ctx, ahead.ID)
+			require.NoError(t, err)
+			assert.Zero(t, internal.MustParseMoney("200.00").Cmp(repoAccount.Balance))
+		})
+
+		t.Run("Daily", func(t *testing.T) {
+			reconciliation, err := reconciliationService.ReconcileDaily(ctx)
+			require.NoError(t, err)
+			require.NotNil(t, reconciliation)
+			assertDiscrepancies(t, reconciliation)
+
+			clock.now = clock.now.Add(time.Hour)
+			reconciliation, err = reconciliationService.ReconcileDaily(ctx)
+			require.NoError(t, err)
+			assert.Nil(t, reconciliation)
+		})
+
+		t.Run("Adjust", func(t *testing.T) {
+			reconciliation, err := reconciliationService.Reconcile(ctx, true)
+			require.NoError(t, err)
+			assertDiscrepancies(t, reconciliation)
+
+			for _, discrepancy := range reconciliation.Discrepancies {
+				assert.NotEmpty(t, discrepancy.AdjustmentID)
+
+				repoAccount, err := accountsRepo.Get(ctx, discrepancy.AccountID)
+				require.NoError(t, err)
+				assert.Zero(t, discrepancy.Recomputed.Cmp(repoAccount.Balance), "balance %s", repoAccount.Balance)
+				assert.NoError(t, ledger.Verify(ctx, discrepancy.AccountID))
+			}
+
+			suspense, err := ledger.Balance(ctx, internal.SystemAccountSuspense, "EUR")
+			require.NoError(t, err)
+			assert.Zero(t, internal.MustParseMoney("110.00").Cmp(suspense), "suspense %s", suspense)
+
+			reconciliation, err = reconciliationService.Reconcile(ctx, false)
+			require.NoError(t, err)
+			assert.Equal(t, 3, reconciliation.Accounts)
+			assert.Empty(t, reconciliation.Discrepancies)
+		})
+	})
+}
+
+func TestReconciliationService_AdjustedHistory(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo          = b.accounts
+			logger                = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledger                = service.NewLedger(logger, accountsRepo, b.ledger)
+			clock                 = &testClock{now: time.Date(2024, 11, 1, 10, 0, 0, 0, time.UTC)}
+			accountsService       = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, nil, clock)
+			balanceService        = service.NewBalanceService(logger, accountsRepo, b.transactions, b.balanceSnapshots, ledger, clock)
+			reconciliationService = service.NewReconciliationService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, clock)
+			ctx                   = context.Background()
+		)
+
+		account, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		// A deposit was posted without being recorded in the history before
+		// the first snapshot of the account, which holds it.
+		unrecorded, err := internal.NewTransaction(uuid.NewString(), account.ID, internal.TxDeposit, internal.MustParseMoney("20.00"), "EUR", clock.now)
+		require.NoError(t, err)
+
+		entry, err := internal.NewTransactionEntry(uuid.NewString(), unrecorded)
+		require.NoError(t, err)
+
+		require.NoError(t, internal.RunInTx(ctx, b.unitOfWork, func(tx internal.Tx) error {
+			return ledger.Post(ctx, tx, entry)
+		}))
+
+		clock.now = time.Date(2024, 11, 2, 2, 0, 0, 0, time.UTC)
+		taken, err := balanceService.TakeBalanceSnapshots(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, taken)
+
+		reconciliation, err := reconciliationService.Reconcile(ctx, true)
+		require.NoError(t, err)
+		require.Len(t, reconciliation.Discrepancies, 1)
+
+		transactions, err := b.transactions.FindAllByAccount(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, transactions, 1, "the adjustment must be in the history")
+		assert.Equal(t, internal.TransactionType(internal.TxAdjustmentOut), transactions[0].Type)
+		assert.Zero(t, internal.MustParseMoney("20.00").Cmp(transactions[0].Amount), transactions[0].Amount.String())
+
+		// The next snapshot replays the adjustment from the previous one.
+		clock.now = time.Date(2024, 11, 3, 2, 0, 0, 0, time.UTC)
+		taken, err = balanceService.TakeBalanceSnapshots(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, taken)
+
+		for at, expected := range map[time.Time]string{
+			time.Date(2024, 11, 2, 1, 0, 0, 0, time.UTC): "120.00",
+			time.Date(2024, 11, 2, 3, 0, 0, 0, time.UTC): "100.00",
+			clock.now: "100.00",
+		} {
+			balance, err := balanceService.BalanceAt(ctx, account.ID, at)
+			require.NoError(t, err, at)
+			assert.Zero(t, internal.MustParseMoney(expected).Cmp(balance.Balance), "balance at %s: %s", at, balance.Balance)
+		}
+
+		reconciliation, err = reconciliationService.Reconcile(ctx, false)
+		require.NoError(t, err)
+		assert.Empty(t, reconciliation.Discrepancies, "adjustments must not be taken as part of the history")
+	})
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+// maxConflictAttempts bounds how many times an operation runs when other
+// operations keep changing its accounts first.
+const maxConflictAttempts = 5
+
+// retryOnConflict runs fn again, after a short random wait, while it fails
+// with ErrVersionConflict: another operation updated one of its accounts
+// since it read them, so running it again works on the new balances.
+func retryOnConflict(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := range maxConflictAttempts {
+		err = fn()
+		if !errors.As(err, &internal.ErrVersionConflict{}) {
+			return err
+		}
+
+		wait := time.Duration(rand.Int64N(int64(time.Millisecond << attempt)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/keylock"
+)
+
+// ScheduledTransferService manages the standing orders of the accounts and
+// runs them when they're due. Time comes from its clock, so tests can move it
+// at will.
+type ScheduledTransferService struct {
+	logger                       *slog.Logger
+	accountsRepository           internal.AccountsRepository
+	scheduledTransfersRepository internal.ScheduledTransfersRepository
+	accountsService              *AccountService
+	clock                        internal.Clock
+	// scheduleLocks keeps a scheduled transfer from being run and changed at once.
+	scheduleLocks *keylock.Locks
+}
+
+func NewScheduledTransferService(
+	logger *slog.Logger,
+	accountsRepository internal.AccountsRepository,
+	scheduledTransfersRepository internal.ScheduledTransfersRepository,
+	accountsService *AccountService,
+	clock internal.Clock,
+) *ScheduledTransferService {
+	return &ScheduledTransferService{
+		logger:                       logger,
+		accountsRepository:           accountsRepository,
+		scheduledTransfersRepository: scheduledTransfersRepository,
+		accountsService:              accountsService,
+		clock:                        clock,
+		scheduleLocks:                keylock.New(),
+	}
+}
+
+// CreateScheduledTransfer schedules the plan from the account. An empty
+// currency means the account's currency.
+func (s ScheduledTransferService) CreateScheduledTransfer(
+	ctx context.Context,
+	accountID string,
+	plan internal.TransferPlan,
+) (*internal.ScheduledTransfer, error) {
+	plan, err := s.checkPlan(ctx, accountID, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledTransfer, err := internal.NewScheduledTransfer(uuid.NewString(), accountID, plan, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduledTransfersRepository.Create(ctx, scheduledTransfer); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info(
+		"Scheduled transfer created",
+		"ID", scheduledTransfer.ID,
+		"source", scheduledTransfer.SourceAccountID,
+		"destination", scheduledTransfer.DestinationAccountID,
+		"schedule", scheduledTransfer.Schedule,
+		"next_run_at", scheduledTransfer.NextRunAt,
+	)
+
+	return &scheduledTransfer, nil
+}
+
+// ListScheduledTransfers returns the scheduled transfers of the account,
+// finished ones included, in creation order.
+func (s ScheduledTransferService) ListScheduledTransfers(
+	ctx context.Context,
+	accountID string,
+) ([]internal.ScheduledTransfer, error) {
+	if _, err := s.accountsRepository.Get(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	return s.scheduledTransfersRepository.FindAllByAccount(ctx, accountID)
+}
+
+func (s ScheduledTransferService) GetScheduledTransfer(
+	ctx context.Context,
+	accountID,
+	id string,
+) (*internal.ScheduledTransfer, error) {
+	scheduledTransfer, err := s.scheduledTransfersRepository.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scheduled transfers are only reachable from their source account.
+	if scheduledTransfer.SourceAccountID != accountID {
+		return nil, internal.ErrScheduledTransferNotFound{ScheduledTransferID: id}
+	}
+
+	return &scheduledTransfer, nil
+}
+
+// UpdateScheduledTransfer replaces the plan of an active scheduled transfer.
+func (s ScheduledTransferService) UpdateScheduledTransfer(
+	ctx context.Context,
+	accountID,
+	id string,
+	plan internal.TransferPlan,
+) (*internal.ScheduledTransfer, error) {
+	plan, err := s.checkPlan(ctx, accountID, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.changeScheduledTransfer(ctx, accountID, id, func(scheduledTransfer *internal.ScheduledTransfer) error {
+		return scheduledTransfer.Replan(plan, s.clock.Now())
+	})
+}
+
+// CancelScheduledTransfer stops an active scheduled transfer for good.
+func (s ScheduledTransferService) CancelScheduledTransfer(
+	ctx context.Context,
+	accountID,
+	id string,
+) (*internal.ScheduledTransfer, error) {
+	return s.changeScheduledTransfer(ctx, accountID, id, (*internal.ScheduledTransfer).Cancel)
+}
+
+// RunDueTransfers makes the transfers of the scheduled transfers due now, and
+// returns how many were made. Failed transfers are recorded in their scheduled
+// transfer to be retried later, so they don't stop the others.
+func (s ScheduledTransferService) RunDueTransfers(ctx context.Context) (int, error) {
+	now := s.clock.Now()
+	scheduledTransfers, err := s.scheduledTransfersRepository.FindDue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	made := 0
+	for _, scheduledTransfer := range scheduledTransfers {
+		transferred, err := s.run(ctx, scheduledTransfer.ID, now)
+		if err != nil {
+			return made, fmt.Errorf("running scheduled transfer %q: %w", scheduledTransfer.ID, err)
+		}
+
+		if transferred {
+			made++
+		}
+	}
+
+	if len(scheduledTransfers) > 0 {
+		s.logger.Info("Scheduled transfers run", "due", len(scheduledTransfers), "made", made, "as_of", now)
+	}
+
+	return made, nil
+}
+
+// run makes the transfer of the scheduled transfer if it's still due, and
+// records its outcome. It reports whether the transfer was made.
+func (s ScheduledTransferService) run(ctx context.Context, id string, now time.Time) (bool, error) {
+	unlock, err := s.scheduleLocks.Lock(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	// It may have been run, changed or cancelled since it was found.
+	scheduledTransfer, err := s.scheduledTransfersRepository.Get(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	if !scheduledTransfer.IsDue(now) {
+		return false, nil
+	}
+
+	// The transfer and the record of the run are stored apart, so the
+	// transfer of each occurrence has a fixed ID: if the run wasn't recorded,
+	// the next one finds the transfer made instead of making it again.
+	transferID := occurrenceTransferID(scheduledTransfer)
+	_, _, err = s.accountsService.transferOnce(
+		ctx,
+		transferID,
+		scheduledTransfer.SourceAccountID,
+		scheduledTransfer.DestinationAccountID,
+		scheduledTransfer.Amount,
+		scheduledTransfer.Currency,
+	)
+
+	switch {
+	case err == nil:
+		scheduledTransfer.RecordSuccess(now, transferID)
+	case ctx.Err() != nil:
+		// The transfer was interrupted rather than refused, so it's left
+		// due for the next run.
+		return false, ctx.Err()
+	default:
+		s.logger.Warn("Scheduled transfer failed", "ID", scheduledTransfer.ID, "attempts", scheduledTransfer.Attempts+1, "error", err)
+		scheduledTransfer.RecordFailure(now, err)
+	}
+
+	if err := s.scheduledTransfersRepository.Update(ctx, scheduledTransfer); err != nil {
+		return false, fmt.Errorf("recording scheduled transfer run: %w", err)
+	}
+
+	return err == nil, nil
+}
+
+// occurrenceTransferID returns the ID of the transfer of the current
+// occurrence of the scheduled transfer, the same on every attempt.
+func occurrenceTransferID(scheduledTransfer internal.ScheduledTransfer) string {
+	occurrence := scheduledTransfer.ID + ":" + scheduledTransfer.NextRunAt.UTC().Format(time.RFC3339)
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("scheduled-transfer:"+occurrence)).String()
+}
+
+// checkPlan checks that the accounts of the plan exist, and that the source
+// one can send its currency, which defaults to it.
+func (s ScheduledTransferService) checkPlan(
+	ctx context.Context,
+	accountID string,
+	plan internal.TransferPlan,
+) (internal.TransferPlan, error) {
+	account, err := s.accountsRepository.Get(ctx, accountID)
+	if err != nil {
+		return internal.TransferPlan{}, fmt.Errorf("getting source account: %w", err)
+	}
+
+	if plan.Currency == "" {
+		plan.Currency = account.Currency
+	}
+
+	if plan.Currency != account.Currency {
+		return internal.TransferPlan{}, internal.ErrCurrencyMismatch{Expected: account.Currency, Actual: plan.Currency}
+	}
+
+	if plan.DestinationAccountID != "" && plan.DestinationAccountID != accountID {
+		if _, err := s.accountsRepository.Get(ctx, plan.DestinationAccountID); err != nil {
+			return internal.TransferPlan{}, fmt.Errorf("getting destination account: %w", err)
+		}
+	}
+
+	return plan, nil
+}
+
+// changeScheduledTransfer applies fn to the scheduled transfer of the account
+// while it isn't being run, and stores it.
+func (s ScheduledTransferService) changeScheduledTransfer(
+	ctx context.Context,
+	accountID,
+	id string,
+	fn func(scheduledTransfer *internal.ScheduledTransfer) error,
+) (*internal.ScheduledTransfer, error) {
+	unlock, err := s.scheduleLocks.Lock(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	scheduledTransfer, err := s.GetScheduledTransfer(ctx, accountID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fn(scheduledTransfer); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduledTransfersRepository.Update(ctx, *scheduledTransfer); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Scheduled transfer changed", "ID", scheduledTransfer.ID, "status", scheduledTransfer.Status)
+
+	return scheduledTransfer, nil
+}
@@ -0,0 +1,314 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v3"
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fxrates"
+	"github.com/jyisus/bank-server/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClock is a clock the tests move at will.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func TestScheduledTransferService(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo        = b.accounts
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledger              = service.NewLedger(logger, accountsRepo, b.ledger)
+			accountsService     = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			transactionsService = service.NewTransactionService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, nil, internal.SystemClock{})
+			clock               = &testClock{now: time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)}
+			schedulesService    = service.NewScheduledTransferService(logger, accountsRepo, b.scheduledTransfers, accountsService, clock)
+			ctx                 = context.Background()
+		)
+
+		source, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		destination, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("0.00"))
+		require.NoError(t, err)
+
+		runAt := func(t *testing.T, now time.Time, expectedMade int, expectedBalance string) {
+			t.Helper()
+
+			clock.now = now
+			made, err := schedulesService.RunDueTransfers(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, expectedMade, made)
+
+			repoAccount, err := accountsRepo.Get(ctx, source.ID)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(expectedBalance), repoAccount.Balance)
+			assert.NoError(t, ledger.Verify(ctx, source.ID))
+		}
+
+		monthly, err := schedulesService.CreateScheduledTransfer(ctx, source.ID, internal.TransferPlan{
+			DestinationAccountID: destination.ID,
+			Amount:               internal.MustParseMoney("40"),
+			Schedule:             "0 9 1 * *",
+			MaxOccurrences:       3,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, internal.Currency("EUR"), monthly.Currency)
+
+		oneOff, err := schedulesService.CreateScheduledTransfer(ctx, source.ID, internal.TransferPlan{
+			DestinationAccountID: destination.ID,
+			Amount:               internal.MustParseMoney("10"),
+			StartAt:              time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC),
+		})
+		require.NoError(t, err)
+
+		cancelled, err := schedulesService.CreateScheduledTransfer(ctx, source.ID, internal.TransferPlan{
+			DestinationAccountID: destination.ID,
+			Amount:               internal.MustParseMoney("1"),
+			Schedule:             "@daily",
+		})
+		require.NoError(t, err)
+
+		_, err = schedulesService.CancelScheduledTransfer(ctx, source.ID, cancelled.ID)
+		require.NoError(t, err)
+
+		runAt(t, clock.now, 0, "100.00")
+		runAt(t, time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC), 1, "60.00")
+		runAt(t, time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC), 1, "50.00")
+
+		oneOff, err = schedulesService.GetScheduledTransfer(ctx, source.ID, oneOff.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.ScheduledTransferCompleted, oneOff.Status)
+		assert.NotEmpty(t, oneOff.LastTransferID)
+
+		transfer, err := transactionsService.RetrieveTransfer(ctx, oneOff.LastTransferID)
+		require.NoError(t, err)
+		assert.Len(t, transfer, 2)
+
+		runAt(t, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), 1, "10.00")
+
+		// Without funds the occurrence is retried later.
+		runAt(t, time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC), 0, "10.00")
+
+		monthly, err = schedulesService.GetScheduledTransfer(ctx, source.ID, monthly.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, monthly.Attempts)
+		assert.Contains(t, monthly.LastError, "insufficient")
+		require.NotNil(t, monthly.RetryAt)
+
+		runAt(t, monthly.RetryAt.Add(-time.Second), 0, "10.00")
+
+		_, _, err = transactionsService.SaveTransaction(ctx, source.ID, internal.TxDeposit, internal.MustParseMoney("50"), "")
+		require.NoError(t, err)
+
+		runAt(t, *monthly.RetryAt, 1, "20.00")
+
+		monthly, err = schedulesService.GetScheduledTransfer(ctx, source.ID, monthly.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.ScheduledTransferCompleted, monthly.Status)
+		assert.Equal(t, 3, monthly.Occurrences)
+		assert.Empty(t, monthly.LastError)
+
+		runAt(t, time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC), 0, "20.00")
+
+		// One-off transfers fail after the last attempt.
+		failed, err := schedulesService.CreateScheduledTransfer(ctx, source.ID, internal.TransferPlan{
+			DestinationAccountID: destination.ID,
+			Amount:               internal.MustParseMoney("1000"),
+			StartAt:              clock.now.Add(time.Hour),
+		})
+		require.NoError(t, err)
+
+		for range internal.ScheduledTransferMaxAttempts {
+			failed, err = schedulesService.GetScheduledTransfer(ctx, source.ID, failed.ID)
+			require.NoError(t, err)
+			runAt(t, failed.DueAt(), 0, "20.00")
+		}
+
+		failed, err = schedulesService.GetScheduledTransfer(ctx, source.ID, failed.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.ScheduledTransferFailed, failed.Status)
+		assert.Equal(t, 1, failed.Failures)
+
+		scheduledTransfers, err := schedulesService.ListScheduledTransfers(ctx, source.ID)
+		require.NoError(t, err)
+		require.Len(t, scheduledTransfers, 4)
+		for _, scheduledTransfer := range scheduledTransfers {
+			if scheduledTransfer.ID == cancelled.ID {
+				assert.Equal(t, internal.ScheduledTransferCancelled, scheduledTransfer.Status)
+				assert.Zero(t, scheduledTransfer.Occurrences)
+			}
+		}
+
+		_, err = schedulesService.UpdateScheduledTransfer(ctx, source.ID, failed.ID, failed.TransferPlan)
+		require.ErrorAs(t, err, &internal.ErrScheduledTransferNotActive{})
+
+		_, err = schedulesService.GetScheduledTransfer(ctx, destination.ID, monthly.ID)
+		require.ErrorAs(t, err, &internal.ErrScheduledTransferNotFound{})
+
+		_, err = schedulesService.CreateScheduledTransfer(ctx, source.ID, internal.TransferPlan{
+			DestinationAccountID: uuid.NewString(),
+			Amount:               internal.MustParseMoney("1"),
+			Schedule:             "@daily",
+		})
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+	})
+}
+
+// failingUpdates fails the next updates of the scheduled transfers, as a
+// crash right after the transfer of a run would.
+type failingUpdates struct {
+	internal.ScheduledTransfersRepository
+	failures int
+}
+
+func (r *failingUpdates) Update(ctx context.Context, scheduledTransfer internal.ScheduledTransfer) error {
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("storage unavailable")
+	}
+
+	return r.ScheduledTransfersRepository.Update(ctx, scheduledTransfer)
+}
+
+func TestScheduledTransferService_UnrecordedRun(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo        = b.accounts
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledger              = service.NewLedger(logger, accountsRepo, b.ledger)
+			accountsService     = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			transactionsService = service.NewTransactionService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, nil, internal.SystemClock{})
+			clock               = &testClock{now: time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)}
+			scheduledTransfers  = &failingUpdates{ScheduledTransfersRepository: b.scheduledTransfers}
+			schedulesService    = service.NewScheduledTransferService(logger, accountsRepo, scheduledTransfers, accountsService, clock)
+			ctx                 = context.Background()
+		)
+
+		source, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		destination, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("0.00"))
+		require.NoError(t, err)
+
+		monthly, err := schedulesService.CreateScheduledTransfer(ctx, source.ID, internal.TransferPlan{
+			DestinationAccountID: destination.ID,
+			Amount:               internal.MustParseMoney("40"),
+			Schedule:             "0 9 1 * *",
+		})
+		require.NoError(t, err)
+
+		clock.now = time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+		scheduledTransfers.failures = 1
+		_, err = schedulesService.RunDueTransfers(ctx)
+		require.Error(t, err)
+
+		// The occurrence is still due, but its transfer was already made.
+		made, err := schedulesService.RunDueTransfers(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, made)
+
+		repoSource, err := accountsRepo.Get(ctx, source.ID)
+		require.NoError(t, err)
+		assert.Equal(t, internal.MustParseMoney("60.00"), repoSource.Balance, "the occurrence must be transferred once")
+
+		monthly, err = schedulesService.GetScheduledTransfer(ctx, source.ID, monthly.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, monthly.Occurrences)
+		assert.Equal(t, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), monthly.NextRunAt)
+
+		transfer, err := transactionsService.RetrieveTransfer(ctx, monthly.LastTransferID)
+		require.NoError(t, err)
+		assert.Len(t, transfer, 2)
+	})
+}
+
+// hiddenTransfers hides the transactions of the transfers from the checks made
+// within its transactions, as when another process makes the same transfer
+// right after the check.
+type hiddenTransfers struct {
+	internal.UnitOfWork
+}
+
+func (u hiddenTransfers) Begin(ctx context.Context) (internal.Tx, error) {
+	tx, err := u.UnitOfWork.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return hiddenTransfersTx{Tx: tx}, nil
+}
+
+type hiddenTransfersTx struct {
+	internal.Tx
+}
+
+func (tx hiddenTransfersTx) Transactions() internal.TransactionsRepository {
+	return hiddenTransfersRepository{TransactionsRepository: tx.Tx.Transactions()}
+}
+
+type hiddenTransfersRepository struct {
+	internal.TransactionsRepository
+}
+
+func (hiddenTransfersRepository) FindAllByTransfer(context.Context, string) ([]internal.Transaction, error) {
+	return nil, nil
+}
+
+func TestScheduledTransferService_ConcurrentRun(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo       = b.accounts
+			logger             = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledger             = service.NewLedger(logger, accountsRepo, b.ledger)
+			accountsService    = service.NewAccountService(logger, accountsRepo, hiddenTransfers{UnitOfWork: b.unitOfWork}, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			clock              = &testClock{now: time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)}
+			scheduledTransfers = &failingUpdates{ScheduledTransfersRepository: b.scheduledTransfers}
+			schedulesService   = service.NewScheduledTransferService(logger, accountsRepo, scheduledTransfers, accountsService, clock)
+			ctx                = context.Background()
+		)
+
+		source, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		destination, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("0.00"))
+		require.NoError(t, err)
+
+		_, err = schedulesService.CreateScheduledTransfer(ctx, source.ID, internal.TransferPlan{
+			DestinationAccountID: destination.ID,
+			Amount:               internal.MustParseMoney("40"),
+			Schedule:             "0 9 1 * *",
+		})
+		require.NoError(t, err)
+
+		clock.now = time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+		scheduledTransfers.failures = 1
+		_, err = schedulesService.RunDueTransfers(ctx)
+		require.Error(t, err)
+
+		// The storage refuses the second copy of the transfer the check missed.
+		made, err := schedulesService.RunDueTransfers(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, made)
+
+		for id, expected := range map[string]string{source.ID: "60.00", destination.ID: "40.00"} {
+			repoAccount, err := accountsRepo.Get(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, internal.MustParseMoney(expected), repoAccount.Balance, "the occurrence must be transferred once")
+			assert.NoError(t, ledger.Verify(ctx, id))
+		}
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,74 +16,166 @@ type TransactionService struct {
 	logger                 *slog.Logger
 	accountsRepository     internal.AccountsRepository
 	transactionsRepository internal.TransactionsRepository
+	unitOfWork             internal.UnitOfWork
+	ledger                 *Ledger
+	fees                   internal.FeeSchedules
+	clock                  internal.Clock
 }
 
 func NewTransactionService(
 	logger *slog.Logger,
 	accountsRepository internal.AccountsRepository,
 	transactionsRepository internal.TransactionsRepository,
+	unitOfWork internal.UnitOfWork,
+	ledger *Ledger,
+	fees internal.FeeSchedules,
+	clock internal.Clock,
 ) *TransactionService {
 	return &TransactionService{
 		logger:                 logger,
 		accountsRepository:     accountsRepository,
 		transactionsRepository: transactionsRepository,
+		unitOfWork:             unitOfWork,
+		ledger:                 ledger,
+		fees:                   fees,
+		clock:                  clock,
 	}
 }
 
+// SaveTransaction deposits or withdraws the amount. The withdrawal fee is
+// charged to the account as a transaction of its own, linked to the
+// withdrawal. It returns the transaction and the account as stored after it.
 func (s TransactionService) SaveTransaction(
 	ctx context.Context,
 	accountID,
 	txType string,
-	amount float32,
-) (*internal.Transaction, error) {
+	amount internal.Money,
+	currency internal.Currency,
+) (*internal.Transaction, *internal.Account, error) {
+	return s.saveTransaction(ctx, accountID, nil, txType, amount, currency)
+}
+
+// SaveTransactionIfVersion saves the transaction only if the account is still
+// at the given version, which lets clients make sure the balance didn't
+// change since they read it. Otherwise it returns ErrVersionConflict.
+func (s TransactionService) SaveTransactionIfVersion(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	txType string,
+	amount internal.Money,
+	currency internal.Currency,
+) (*internal.Transaction, *internal.Account, error) {
+	return s.saveTransaction(ctx, accountID, &expectedVersion, txType, amount, currency)
+}
+
+func (s TransactionService) saveTransaction(
+	ctx context.Context,
+	accountID string,
+	expectedVersion *int64,
+	txType string,
+	amount internal.Money,
+	currency internal.Currency,
+) (*internal.Transaction, *internal.Account, error) {
 	txID := uuid.NewString()
 
+	if _, err := internal.NewTransactionType(txType); err != nil {
+		return nil, nil, err
+	}
+
+	account, err := s.accountsRepository.Get(ctx, accountID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting transaction's account: %w", err)
+	}
+
+	// Transactions without an explicit currency use the account's one
+	if currency == "" {
+		currency = account.Currency
+	}
+
 	transaction, err := internal.NewTransaction(
 		txID,
 		accountID,
 		txType,
 		amount,
-		time.Now(),
+		currency,
+		s.clock.Now(),
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	account, err := s.accountsRepository.Get(ctx, accountID)
+	entry, err := internal.NewTransactionEntry(uuid.NewString(), transaction)
 	if err != nil {
-		return nil, fmt.Errorf("getting transaction's account: %w", err)
+		return nil, nil, err
 	}
 
-	switch transaction.Type {
-	case internal.TxDeposit:
-		account.Deposit(transaction.Amount)
-	case internal.TxWithdrawal:
-		if err := account.Withdraw(transaction.Amount); err != nil {
-			return nil, err
+	var fee *internal.Transaction
+	if transaction.Type == internal.TxWithdrawal {
+		amount, err := s.fees.WithdrawalFee(*account, transaction.Amount)
+		if err != nil {
+			return nil, nil, err
 		}
+
+		if amount.IsPositive() {
+			feeTransaction := internal.NewFeeTransaction(uuid.NewString(), *account, amount, transaction.ID, transaction.Timestamp)
+			fee = &feeTransaction
+		}
+	}
+
+	var saved *internal.Account
+	save := func() error {
+		return internal.RunInTx(ctx, s.unitOfWork, func(tx internal.Tx) error {
+			if expectedVersion != nil {
+				account, err := tx.Accounts().Get(ctx, accountID)
+				if err != nil {
+					return err
+				}
+
+				if account.Version != *expectedVersion {
+					return internal.ErrVersionConflict{AccountID: accountID, ExpectedVersion: *expectedVersion}
+				}
+			}
+
+			if err := s.ledger.Post(ctx, tx, entry); err != nil {
+				return err
+			}
+
+			if err := tx.Transactions().Save(ctx, transaction); err != nil {
+				return fmt.Errorf("saving transaction: %w", err)
+			}
+
+			if fee != nil {
+				if err := chargeFee(ctx, tx, s.ledger, *fee); err != nil {
+					return err
+				}
+			}
+
+			saved, err = tx.Accounts().Get(ctx, accountID)
+
+			return err
+		})
 	}
 
-	// if txResult < 0 {
-	// 	http.Error(w, "Invalid transaction: not enough money", http.StatusForbidden)
-	// 	return
-	// }
-	newTransaction := internal.Transaction{
-		ID:        txID,
-		AccountID: accountID,
-		Type:      internal.TransactionType(transaction.Type),
-		Amount:    transaction.Amount,
-		Timestamp: time.Now(),
+	unlock, err := s.ledger.Lock(ctx, accountID)
+	if err != nil {
+		return nil, nil, err
 	}
+	defer unlock()
 
-	if err := s.accountsRepository.UpdateBalance(ctx, accountID, account.Balance); err != nil {
-		return nil, fmt.Errorf("updating account balance: %w", err)
+	// A conflict on a conditional save means the client's precondition no
+	// longer holds, so it's reported instead of retried.
+	if expectedVersion != nil {
+		err = save()
+	} else {
+		err = retryOnConflict(ctx, save)
 	}
 
-	if err := s.transactionsRepository.Save(ctx, newTransaction); err != nil {
-		return nil, fmt.Errorf("saving transaction: %w", err)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return &newTransaction, nil
+	return &transaction, saved, nil
 }
 
 func (s TransactionService) RetrieveAccountTransactions(
@@ -99,3 +193,303 @@ func (s TransactionService) RetrieveAccountTransactions(
 
 	return transactions, nil
 }
+
+// ListAccountTransactions returns a page of the account's transactions
+// selected by the query, of DefaultTransactionsLimit transactions when it has
+// no limit.
+func (s TransactionService) ListAccountTransactions(
+	ctx context.Context,
+	query internal.TransactionQuery,
+) (*internal.TransactionPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	if query.Limit == 0 {
+		query.Limit = internal.DefaultTransactionsLimit
+	}
+
+	if _, err := s.accountsRepository.Get(ctx, query.AccountID); err != nil {
+		return nil, err
+	}
+
+	// The extra transaction tells whether there's a next page.
+	limit := query.Limit
+	query.Limit++
+
+	transactions, err := s.transactionsRepository.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &internal.TransactionPage{Transactions: transactions}
+	if len(transactions) > limit {
+		page.Transactions = transactions[:limit]
+		page.NextCursor = internal.CursorOf(transactions[limit-1]).String()
+	}
+
+	return page, nil
+}
+
+// GenerateStatement returns the statement of the account over the days from
+// from to to, both included. The account is locked meanwhile, so its balance
+// and its transactions are read in step.
+func (s TransactionService) GenerateStatement(
+	ctx context.Context,
+	accountID string,
+	from,
+	to time.Time,
+) (*internal.Statement, error) {
+	start, end, err := internal.StatementPeriod(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.ledger.Lock(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var statement internal.Statement
+	if err := internal.RunInTx(ctx, s.unitOfWork, func(tx internal.Tx) error {
+		account, err := tx.Accounts().Get(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		transactions, err := tx.Transactions().Find(ctx, internal.TransactionQuery{AccountID: accountID, From: &start, To: &end})
+		if err != nil {
+			return err
+		}
+
+		since, err := tx.Transactions().Find(ctx, internal.TransactionQuery{AccountID: accountID, From: &end})
+		if err != nil {
+			return err
+		}
+
+		statement, err = internal.NewStatement(*account, start, end.AddDate(0, 0, -1), transactions, since)
+
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	s.logger.Debug(
+		"Statement generated",
+		"account", accountID,
+		"from", statement.From.Format(time.DateOnly),
+		"to", statement.To.Format(time.DateOnly),
+		"transactions", len(statement.Lines),
+	)
+
+	return &statement, nil
+}
+
+// RetrieveTransfer returns both transactions of a transfer, the outgoing one first.
+func (s TransactionService) RetrieveTransfer(ctx context.Context, transferID string) ([]internal.Transaction, error) {
+	transactions, err := s.transactionsRepository.FindAllByTransfer(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(transactions) == 0 {
+		return nil, internal.ErrTransferNotFound{TransferID: transferID}
+	}
+
+	slices.SortFunc(transactions, func(a, b internal.Transaction) int {
+		if a.Type == b.Type {
+			return strings.Compare(a.ID, b.ID)
+		}
+
+		if a.Type == internal.TxTransferOut {
+			return -1
+		}
+
+		return 1
+	})
+
+	return transactions, nil
+}
+
+// ReverseTransaction gives back the amount of the transaction, or all that
+// remains of it after previous reversals when amount is nil, and returns the
+// compensating transactions. Transfers are reversed on both sides, with the
+// amount in the currency of the given transaction.
+func (s TransactionService) ReverseTransaction(
+	ctx context.Context,
+	id string,
+	amount *internal.Money,
+) ([]internal.Transaction, error) {
+	original, err := s.transactionsRepository.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	accountIDs := []string{original.AccountID}
+	if original.CounterpartyAccountID != "" {
+		accountIDs = append(accountIDs, original.CounterpartyAccountID)
+	}
+
+	unlock, err := s.ledger.Lock(ctx, accountIDs...)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	now := s.clock.Now()
+
+	var reversals []internal.Transaction
+	if err := retryOnConflict(ctx, func() error {
+		return internal.RunInTx(ctx, s.unitOfWork, func(tx internal.Tx) error {
+			var (
+				entry internal.JournalEntry
+				err   error
+			)
+
+			// The previous reversals are read within the transaction, so
+			// concurrent ones conflict on the accounts' versions.
+			if original.TransferID != "" {
+				reversals, entry, err = s.newTransferReversal(ctx, tx, original, amount, now)
+			} else {
+				reversals, entry, err = s.newReversal(ctx, tx, original, amount, now)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if err := s.ledger.Post(ctx, tx, entry); err != nil {
+				return err
+			}
+
+			for _, reversal := range reversals {
+				if err := tx.Transactions().Save(ctx, reversal); err != nil {
+					return fmt.Errorf("saving reversal: %w", err)
+				}
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, reversal := range reversals {
+		s.logger.Info(
+			"Transaction reversed",
+			"ID", reversal.ReversalOf,
+			"reversal", reversal.ID,
+			"account", reversal.AccountID,
+			"amount", reversal.Amount,
+		)
+	}
+
+	return reversals, nil
+}
+
+func (s TransactionService) newReversal(
+	ctx context.Context,
+	tx internal.Tx,
+	original internal.Transaction,
+	amount *internal.Money,
+	now time.Time,
+) ([]internal.Transaction, internal.JournalEntry, error) {
+	reversed, err := reversedAmount(ctx, tx, original)
+	if err != nil {
+		return nil, internal.JournalEntry{}, err
+	}
+
+	value, err := internal.ReversalAmount(original, reversed, amount)
+	if err != nil {
+		return nil, internal.JournalEntry{}, err
+	}
+
+	reversal, err := internal.NewReversal(uuid.NewString(), original, value, now)
+	if err != nil {
+		return nil, internal.JournalEntry{}, err
+	}
+
+	entry, err := internal.NewTransactionEntry(uuid.NewString(), reversal)
+	if err != nil {
+		return nil, internal.JournalEntry{}, err
+	}
+
+	return []internal.Transaction{reversal}, entry, nil
+}
+
+func (s TransactionService) newTransferReversal(
+	ctx context.Context,
+	tx internal.Tx,
+	original internal.Transaction,
+	amount *internal.Money,
+	now time.Time,
+) ([]internal.Transaction, internal.JournalEntry, error) {
+	transactions, err := tx.Transactions().FindAllByTransfer(ctx, original.TransferID)
+	if err != nil {
+		return nil, internal.JournalEntry{}, err
+	}
+
+	var out, in internal.Transaction
+	for _, transaction := range transactions {
+		switch transaction.Type {
+		case internal.TxTransferOut:
+			out = transaction
+		case internal.TxTransferIn:
+			in = transaction
+		}
+	}
+
+	if out.ID == "" || in.ID == "" {
+		return nil, internal.JournalEntry{}, fmt.Errorf("transfer %q doesn't have both transactions", original.TransferID)
+	}
+
+	outReversed, err := reversedAmount(ctx, tx, out)
+	if err != nil {
+		return nil, internal.JournalEntry{}, err
+	}
+
+	inReversed, err := reversedAmount(ctx, tx, in)
+	if err != nil {
+		return nil, internal.JournalEntry{}, err
+	}
+
+	var outAmount, inAmount internal.Money
+	if original.ID == out.ID {
+		outAmount, inAmount, err = internal.TransferReversalAmounts(out, in, outReversed, inReversed, amount)
+	} else {
+		inAmount, outAmount, err = internal.TransferReversalAmounts(in, out, inReversed, outReversed, amount)
+	}
+
+	if err != nil {
+		return nil, internal.JournalEntry{}, err
+	}
+
+	transfer, reversalOut, reversalIn := internal.NewTransferReversal(
+		uuid.NewString(),
+		uuid.NewString(),
+		uuid.NewString(),
+		out,
+		in,
+		outAmount,
+		inAmount,
+		now,
+	)
+
+	entry, err := internal.NewTransferEntry(uuid.NewString(), transfer)
+	if err != nil {
+		return nil, internal.JournalEntry{}, err
+	}
+
+	return []internal.Transaction{reversalOut, reversalIn}, entry, nil
+}
+
+// reversedAmount adds up what the reversals of the transaction gave back.
+func reversedAmount(ctx context.Context, tx internal.Tx, original internal.Transaction) (internal.Money, error) {
+	transactions, err := tx.Transactions().FindAllByAccount(ctx, original.AccountID)
+	if err != nil {
+		return internal.Money{}, fmt.Errorf("getting reversals of transaction %q: %w", original.ID, err)
+	}
+
+	return internal.Reversed(original, transactions)
+}
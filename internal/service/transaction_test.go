@@ -4,13 +4,16 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
 	"bou.ke/monkey"
+	"github.com/bxcodec/faker/v3"
 	"github.com/google/uuid"
 	"github.com/jyisus/bank-server/internal"
-	"github.com/jyisus/bank-server/internal/memrepo"
+	"github.com/jyisus/bank-server/internal/fxrates"
 	"github.com/jyisus/bank-server/internal/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,33 +27,43 @@ func TestTransactionsService_SaveTransaction(t *testing.T) {
 	testCases := map[string]struct {
 		account       *internal.Account
 		txType        string
-		amount        float32
+		amount        internal.Money
+		currency      internal.Currency
 		expectedError error
 	}{
 		"Deposit transaction successfully created": {
 			account:       fakeAccount(t),
 			txType:        internal.TxDeposit,
-			amount:        100,
+			amount:        internal.MustParseMoney("100"),
 			expectedError: nil,
 		},
 		"Withdrawal transaction successfully created": {
 			account: &internal.Account{
-				ID:      uuid.NewString(),
-				Balance: 100,
+				ID:       uuid.NewString(),
+				Currency: internal.DefaultCurrency,
+				Balance:  internal.MustParseMoney("100.00"),
 			},
 			txType:        internal.TxWithdrawal,
-			amount:        50,
+			amount:        internal.MustParseMoney("50"),
 			expectedError: nil,
 		},
 		"Withdrawal transaction insufficient balance": {
 			account: &internal.Account{
-				ID:      uuid.NewString(),
-				Balance: 100,
+				ID:       uuid.NewString(),
+				Currency: internal.DefaultCurrency,
+				Balance:  internal.MustParseMoney("100.00"),
 			},
 			txType:        internal.TxWithdrawal,
-			amount:        2000,
+			amount:        internal.MustParseMoney("2000"),
 			expectedError: &internal.ErrInsufficientBalance{},
 		},
+		"Currency mismatch": {
+			account:       fakeAccount(t),
+			txType:        internal.TxDeposit,
+			amount:        internal.MustParseMoney("100"),
+			currency:      "USD",
+			expectedError: &internal.ErrCurrencyMismatch{},
+		},
 		"Account doesn't exist": {
 			account:       nil,
 			txType:        internal.TxDeposit,
@@ -65,44 +78,53 @@ func TestTransactionsService_SaveTransaction(t *testing.T) {
 
 	for testName, tc := range testCases {
 		t.Run(testName, func(t *testing.T) {
-			var (
-				logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
-				accountsRepo        = memrepo.NewAccountsRepository()
-				transactionsRepo    = memrepo.NewTransactionsRepository()
-				transactionsService = service.NewTransactionService(logger, accountsRepo, transactionsRepo)
-				ctx                 = context.Background()
-			)
-
-			accountID := ""
-			if tc.account != nil {
-				accountID = tc.account.ID
-				require.NoError(t, accountsRepo.Create(ctx, *tc.account))
-			}
+			forEachBackend(t, func(t *testing.T, b backend) {
+				var (
+					logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+					accountsRepo        = b.accounts
+					transactionsRepo    = b.transactions
+					ledgerRepo          = b.ledger
+					unitOfWork          = b.unitOfWork
+					ledger              = service.NewLedger(logger, accountsRepo, ledgerRepo)
+					transactionsService = service.NewTransactionService(logger, accountsRepo, transactionsRepo, unitOfWork, ledger, nil, internal.SystemClock{})
+					ctx                 = context.Background()
+				)
 
-			createdTx, err := transactionsService.SaveTransaction(ctx, accountID, tc.txType, tc.amount)
-			if err != nil {
-				assert.ErrorAs(t, err, tc.expectedError)
-				return
-			}
+				accountID := ""
+				if tc.account != nil {
+					accountID = tc.account.ID
+					require.NoError(t, accountsRepo.Create(ctx, *tc.account))
+				}
 
-			require.NoError(t, err)
+				createdTx, _, err := transactionsService.SaveTransaction(ctx, accountID, tc.txType, tc.amount, tc.currency)
+				if err != nil {
+					assert.ErrorAs(t, err, tc.expectedError)
+					return
+				}
 
-			repoTransaction, err := transactionsRepo.Get(ctx, createdTx.ID)
-			require.NoError(t, err)
+				require.NoError(t, err)
 
-			assert.Equal(t, *createdTx, repoTransaction)
+				repoTransaction, err := transactionsRepo.Get(ctx, createdTx.ID)
+				require.NoError(t, err)
 
-			account, err := accountsRepo.Get(ctx, tc.account.ID)
-			require.NoError(t, err)
+				assert.Equal(t, *createdTx, repoTransaction)
 
-			if tc.txType == internal.TxDeposit {
-				assert.Equal(t, tc.account.Balance+tc.amount, account.Balance)
-				return
-			}
+				account, err := accountsRepo.Get(ctx, tc.account.ID)
+				require.NoError(t, err)
 
-			if tc.txType == internal.TxWithdrawal {
-				assert.Equal(t, tc.account.Balance-tc.amount, account.Balance)
-			}
+				if tc.txType == internal.TxDeposit {
+					expected, err := tc.account.Balance.Add(tc.amount)
+					require.NoError(t, err)
+					assert.Equal(t, expected, account.Balance)
+					return
+				}
+
+				if tc.txType == internal.TxWithdrawal {
+					expected, err := tc.account.Balance.Sub(tc.amount)
+					require.NoError(t, err)
+					assert.Equal(t, expected, account.Balance)
+				}
+			})
 		})
 	}
 }
@@ -143,32 +165,384 @@ func TestTransactionsService_RetrieveAccountTransactions(t *testing.T) {
 
 	for testName, tc := range testCases {
 		t.Run(testName, func(t *testing.T) {
-			var (
-				logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
-				accountsRepo        = memrepo.NewAccountsRepository()
-				transactionsRepo    = memrepo.NewTransactionsRepository()
-				transactionsService = service.NewTransactionService(logger, accountsRepo, transactionsRepo)
-				ctx                 = context.Background()
-			)
-
-			accountID := ""
-			if tc.account != nil {
-				accountID = tc.account.ID
-				require.NoError(t, accountsRepo.Create(ctx, *tc.account))
-			}
+			forEachBackend(t, func(t *testing.T, b backend) {
+				var (
+					logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+					accountsRepo        = b.accounts
+					transactionsRepo    = b.transactions
+					ledgerRepo          = b.ledger
+					unitOfWork          = b.unitOfWork
+					ledger              = service.NewLedger(logger, accountsRepo, ledgerRepo)
+					transactionsService = service.NewTransactionService(logger, accountsRepo, transactionsRepo, unitOfWork, ledger, nil, internal.SystemClock{})
+					ctx                 = context.Background()
+				)
+
+				accountID := ""
+				if tc.account != nil {
+					accountID = tc.account.ID
+					require.NoError(t, accountsRepo.Create(ctx, *tc.account))
+				}
+
+				for _, tx := range tc.allTransactions {
+					require.NoError(t, transactionsRepo.Save(ctx, tx))
+				}
+
+				transactions, err := transactionsService.RetrieveAccountTransactions(ctx, accountID)
+				if err != nil {
+					assert.ErrorAs(t, err, tc.expectedError)
+					return
+				}
+				require.NoError(t, err)
+
+				assert.ElementsMatch(t, tc.expectedTransactions, transactions)
+			})
+		})
+	}
+}
+
+func TestTransactionsService_ListAccountTransactions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			accountsRepo        = b.accounts
+			ledger              = service.NewLedger(logger, accountsRepo, b.ledger)
+			transactionsService = service.NewTransactionService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, nil, internal.SystemClock{})
+			account             = fakeAccount(t)
+			timestamp           = time.Date(2024, 11, 24, 3, 26, 51, 0, time.UTC)
+			ctx                 = context.Background()
+		)
+
+		require.NoError(t, accountsRepo.Create(ctx, *account))
 
-			for _, tx := range tc.allTransactions {
-				require.NoError(t, transactionsRepo.Save(ctx, tx))
+		transactions := make([]internal.Transaction, 0, 5)
+		for i := range 5 {
+			transaction := internal.Transaction{
+				ID:        uuid.NewString(),
+				AccountID: account.ID,
+				Type:      internal.TxDeposit,
+				Amount:    internal.MustParseMoney("10.00"),
+				Currency:  account.Currency,
+				Timestamp: timestamp.Add(time.Duration(i) * time.Minute),
 			}
+			require.NoError(t, b.transactions.Save(ctx, transaction))
+			transactions = append(transactions, transaction)
+		}
 
-			transactions, err := transactionsService.RetrieveAccountTransactions(ctx, accountID)
-			if err != nil {
-				assert.ErrorAs(t, err, tc.expectedError)
-				return
+		query := internal.TransactionQuery{AccountID: account.ID, Order: internal.SortDescending, Limit: 2}
+
+		var walked []internal.Transaction
+		for pages := 1; ; pages++ {
+			page, err := transactionsService.ListAccountTransactions(ctx, query)
+			require.NoError(t, err)
+			walked = append(walked, page.Transactions...)
+
+			if page.NextCursor == "" {
+				assert.Equal(t, 3, pages)
+				break
 			}
+
+			cursor, err := internal.ParseTransactionCursor(page.NextCursor)
 			require.NoError(t, err)
+			query.After = &cursor
+		}
 
-			assert.ElementsMatch(t, tc.expectedTransactions, transactions)
-		})
-	}
+		slices.Reverse(transactions)
+		assert.Equal(t, transactions, walked)
+
+		page, err := transactionsService.ListAccountTransactions(ctx, internal.TransactionQuery{AccountID: account.ID})
+		require.NoError(t, err)
+		assert.Len(t, page.Transactions, 5, "without limit the default page holds them all")
+		assert.Empty(t, page.NextCursor)
+
+		_, err = transactionsService.ListAccountTransactions(ctx, internal.TransactionQuery{AccountID: uuid.NewString()})
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+
+		_, err = transactionsService.ListAccountTransactions(ctx, internal.TransactionQuery{AccountID: account.ID, Limit: internal.MaxTransactionsLimit + 1})
+		require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+	})
+}
+
+func TestTransactionsService_GenerateStatement(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			accountsRepo        = b.accounts
+			ledger              = service.NewLedger(logger, accountsRepo, b.ledger)
+			transactionsService = service.NewTransactionService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, nil, internal.SystemClock{})
+			account             = fakeAccount(t)
+			from                = time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+			to                  = time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC)
+			ctx                 = context.Background()
+		)
+
+		// The balance is what the transactions left, the last one of them
+		// after the statement.
+		account.Balance = internal.MustParseMoney("35.00")
+		require.NoError(t, accountsRepo.Create(ctx, *account))
+
+		transactions := []internal.Transaction{
+			{Type: internal.TxDeposit, Amount: internal.MustParseMoney("50.00"), Timestamp: from.Add(-time.Minute)},
+			{Type: internal.TxWithdrawal, Amount: internal.MustParseMoney("10.00"), Timestamp: from.AddDate(0, 0, 4)},
+			{Type: internal.TxDeposit, Amount: internal.MustParseMoney("2.50"), Timestamp: to.Add(23 * time.Hour)},
+			{Type: internal.TxFee, Amount: internal.MustParseMoney("7.50"), Timestamp: to.AddDate(0, 0, 1)},
+		}
+		for i := range transactions {
+			transactions[i].ID = uuid.NewString()
+			transactions[i].AccountID = account.ID
+			transactions[i].Currency = account.Currency
+			require.NoError(t, b.transactions.Save(ctx, transactions[i]))
+		}
+
+		statement, err := transactionsService.GenerateStatement(ctx, account.ID, from, to)
+		require.NoError(t, err)
+		assert.Equal(t, account.ID, statement.AccountID)
+		assert.Equal(t, from, statement.From)
+		assert.Equal(t, to, statement.To)
+		assert.Zero(t, statement.OpeningBalance.Cmp(internal.MustParseMoney("50.00")))
+		assert.Zero(t, statement.ClosingBalance.Cmp(internal.MustParseMoney("42.50")))
+
+		require.Len(t, statement.Lines, 2)
+		assert.Equal(t, transactions[1].ID, statement.Lines[0].ID)
+		assert.Zero(t, statement.Lines[0].Balance.Cmp(internal.MustParseMoney("40.00")))
+		assert.Equal(t, transactions[2].ID, statement.Lines[1].ID)
+		assert.Zero(t, statement.Lines[1].Balance.Cmp(internal.MustParseMoney("42.50")))
+
+		statement, err = transactionsService.GenerateStatement(ctx, account.ID, to.AddDate(0, 0, 1), to.AddDate(0, 0, 1))
+		require.NoError(t, err)
+		assert.Zero(t, statement.ClosingBalance.Cmp(account.Balance), "the statement up to today closes with the balance")
+
+		_, err = transactionsService.GenerateStatement(ctx, uuid.NewString(), from, to)
+		require.ErrorAs(t, err, &internal.ErrAccountNotFound{})
+
+		_, err = transactionsService.GenerateStatement(ctx, account.ID, to, from)
+		require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+	})
+}
+
+func TestTransactionsService_RetrieveTransfer(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			accountsRepo        = b.accounts
+			transactionsRepo    = b.transactions
+			ledgerRepo          = b.ledger
+			unitOfWork          = b.unitOfWork
+			ledger              = service.NewLedger(logger, accountsRepo, ledgerRepo)
+			accountsService     = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+			transactionsService = service.NewTransactionService(logger, accountsRepo, transactionsRepo, unitOfWork, ledger, nil, internal.SystemClock{})
+			ctx                 = context.Background()
+
+			sourceAccount = internal.Account{
+				ID:       uuid.NewString(),
+				Currency: internal.DefaultCurrency,
+				Balance:  internal.MustParseMoney("100.00"),
+			}
+			destinationAccount = internal.Account{
+				ID:       uuid.NewString(),
+				Currency: internal.DefaultCurrency,
+				Balance:  internal.MustParseMoney("0.00"),
+			}
+		)
+
+		require.NoError(t, accountsRepo.Create(ctx, sourceAccount))
+		require.NoError(t, accountsRepo.Create(ctx, destinationAccount))
+
+		transfer, err := accountsService.Transfer(ctx, sourceAccount.ID, destinationAccount.ID, internal.MustParseMoney("30"), "")
+		require.NoError(t, err)
+
+		transactions, err := transactionsService.RetrieveTransfer(ctx, transfer.ID)
+		require.NoError(t, err)
+		require.Len(t, transactions, 2)
+
+		out, in := transactions[0], transactions[1]
+		assert.Equal(t, internal.TransactionType(internal.TxTransferOut), out.Type)
+		assert.Equal(t, sourceAccount.ID, out.AccountID)
+		assert.Equal(t, destinationAccount.ID, out.CounterpartyAccountID)
+		assert.Equal(t, internal.MustParseMoney("30.00"), out.Amount)
+		assert.Equal(t, internal.TransactionType(internal.TxTransferIn), in.Type)
+		assert.Equal(t, destinationAccount.ID, in.AccountID)
+		assert.Equal(t, sourceAccount.ID, in.CounterpartyAccountID)
+		assert.Equal(t, internal.MustParseMoney("30.00"), in.Amount)
+		assert.Equal(t, transfer.ID, out.TransferID)
+		assert.Equal(t, transfer.ID, in.TransferID)
+
+		sourceTransactions, err := transactionsService.RetrieveAccountTransactions(ctx, sourceAccount.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []internal.Transaction{out}, sourceTransactions)
+
+		_, err = transactionsService.RetrieveTransfer(ctx, uuid.NewString())
+		require.ErrorAs(t, err, &internal.ErrTransferNotFound{})
+	})
+}
+
+func TestTransactionsService_SaveTransactionIfVersion(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			accountsRepo        = b.accounts
+			transactionsRepo    = b.transactions
+			ledgerRepo          = b.ledger
+			unitOfWork          = b.unitOfWork
+			ledger              = service.NewLedger(logger, accountsRepo, ledgerRepo)
+			transactionsService = service.NewTransactionService(logger, accountsRepo, transactionsRepo, unitOfWork, ledger, nil, internal.SystemClock{})
+			ctx                 = context.Background()
+
+			account = fakeAccount(t)
+		)
+
+		require.NoError(t, accountsRepo.Create(ctx, *account))
+
+		_, _, err := transactionsService.SaveTransactionIfVersion(ctx, account.ID, 0, internal.TxDeposit, internal.MustParseMoney("10"), "")
+		require.NoError(t, err)
+
+		_, _, err = transactionsService.SaveTransactionIfVersion(ctx, account.ID, 0, internal.TxDeposit, internal.MustParseMoney("10"), "")
+		require.ErrorAs(t, err, &internal.ErrVersionConflict{})
+
+		repoAccount, err := accountsRepo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		expected, err := account.Balance.Add(internal.MustParseMoney("10"))
+		require.NoError(t, err)
+		assert.Equal(t, expected, repoAccount.Balance)
+		assert.Equal(t, int64(1), repoAccount.Version)
+
+		transactions, err := transactionsService.RetrieveAccountTransactions(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Len(t, transactions, 1)
+	})
+}
+
+func TestTransactionsService_ConcurrentDeposits(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+			accountsRepo        = b.accounts
+			transactionsRepo    = b.transactions
+			ledgerRepo          = b.ledger
+			unitOfWork          = b.unitOfWork
+			ledger              = service.NewLedger(logger, accountsRepo, ledgerRepo)
+			transactionsService = service.NewTransactionService(logger, accountsRepo, transactionsRepo, unitOfWork, ledger, nil, internal.SystemClock{})
+			ctx                 = context.Background()
+			deposits            = 20
+			wg                  sync.WaitGroup
+
+			account = fakeAccount(t)
+		)
+
+		require.NoError(t, accountsRepo.Create(ctx, *account))
+
+		for range deposits {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, err := transactionsService.SaveTransaction(ctx, account.ID, internal.TxDeposit, internal.MustParseMoney("1"), "")
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		repoAccount, err := accountsRepo.Get(ctx, account.ID)
+		require.NoError(t, err)
+		expected, err := account.Balance.Add(internal.MustParseMoney("20"))
+		require.NoError(t, err)
+		assert.Equal(t, expected, repoAccount.Balance, "no deposit may be lost")
+		assert.Equal(t, int64(deposits), repoAccount.Version)
+	})
+}
+
+func TestTransactionsService_ReverseTransaction(t *testing.T) {
+	fxRates := fxrates.NewStaticProvider(internal.ExchangeRate{
+		From:      "EUR",
+		To:        "USD",
+		Rate:      internal.MustParseRate("1.0834"),
+		Timestamp: time.Date(2024, 11, 24, 0, 0, 0, 0, time.UTC),
+	})
+
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var (
+			accountsRepo        = b.accounts
+			logger              = slog.New(slog.NewTextHandler(os.Stdout, nil))
+			ledger              = service.NewLedger(logger, accountsRepo, b.ledger)
+			accountsService     = service.NewAccountService(logger, accountsRepo, b.unitOfWork, fxRates, ledger, nil, internal.SystemClock{})
+			transactionsService = service.NewTransactionService(logger, accountsRepo, b.transactions, b.unitOfWork, ledger, nil, internal.SystemClock{})
+			ctx                 = context.Background()
+		)
+
+		source, err := accountsService.CreateAccount(ctx, faker.Name(), "EUR", internal.MustParseMoney("100.00"))
+		require.NoError(t, err)
+
+		destination, err := accountsService.CreateAccount(ctx, faker.Name(), "USD", internal.MustParseMoney("0.00"))
+		require.NoError(t, err)
+
+		assertBalances := func(t *testing.T, sourceBalance, destinationBalance string) {
+			t.Helper()
+
+			for accountID, balance := range map[string]string{source.ID: sourceBalance, destination.ID: destinationBalance} {
+				repoAccount, err := accountsRepo.Get(ctx, accountID)
+				require.NoError(t, err)
+				assert.Equal(t, internal.MustParseMoney(balance), repoAccount.Balance)
+				assert.NoError(t, ledger.Verify(ctx, accountID))
+			}
+		}
+
+		deposit, _, err := transactionsService.SaveTransaction(ctx, source.ID, internal.TxDeposit, internal.MustParseMoney("50"), "")
+		require.NoError(t, err)
+
+		amount := internal.MustParseMoney("20")
+		reversals, err := transactionsService.ReverseTransaction(ctx, deposit.ID, &amount)
+		require.NoError(t, err)
+		require.Len(t, reversals, 1)
+		assert.Equal(t, internal.TransactionType(internal.TxWithdrawal), reversals[0].Type)
+		assert.Equal(t, deposit.ID, reversals[0].ReversalOf)
+		assertBalances(t, "130.00", "0.00")
+
+		amount = internal.MustParseMoney("30.01")
+		_, err = transactionsService.ReverseTransaction(ctx, deposit.ID, &amount)
+		require.ErrorAs(t, err, &internal.ErrReversalExceeded{})
+
+		reversals, err = transactionsService.ReverseTransaction(ctx, deposit.ID, nil)
+		require.NoError(t, err)
+		assert.Equal(t, internal.MustParseMoney("30.00"), reversals[0].Amount)
+		assertBalances(t, "100.00", "0.00")
+
+		_, err = transactionsService.ReverseTransaction(ctx, deposit.ID, nil)
+		require.ErrorAs(t, err, &internal.ErrReversalExceeded{})
+
+		_, err = transactionsService.ReverseTransaction(ctx, reversals[0].ID, nil)
+		require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+
+		_, err = accountsService.Transfer(ctx, source.ID, destination.ID, internal.MustParseMoney("100"), "")
+		require.NoError(t, err)
+		assertBalances(t, "0.00", "108.34")
+
+		transactions, err := transactionsService.RetrieveAccountTransactions(ctx, destination.ID)
+		require.NoError(t, err)
+		require.Len(t, transactions, 1)
+		in := transactions[0]
+
+		// The amount is in the currency of the given side of the transfer.
+		amount = internal.MustParseMoney("10.83")
+		reversals, err = transactionsService.ReverseTransaction(ctx, in.ID, &amount)
+		require.NoError(t, err)
+		require.Len(t, reversals, 2)
+		assert.Equal(t, internal.TransactionType(internal.TxTransferOut), reversals[0].Type)
+		assert.Equal(t, in.ID, reversals[0].ReversalOf)
+		assert.Equal(t, internal.MustParseMoney("10.83"), reversals[0].Amount)
+		assert.Equal(t, internal.TransactionType(internal.TxTransferIn), reversals[1].Type)
+		assert.Equal(t, internal.MustParseMoney("10.00"), reversals[1].Amount)
+		assertBalances(t, "10.00", "97.51")
+
+		reversals, err = transactionsService.ReverseTransaction(ctx, in.ID, nil)
+		require.NoError(t, err)
+		assert.Equal(t, internal.MustParseMoney("97.51"), reversals[0].Amount)
+		assert.Equal(t, internal.MustParseMoney("90.00"), reversals[1].Amount)
+		assertBalances(t, "100.00", "0.00")
+
+		_, err = transactionsService.ReverseTransaction(ctx, in.CounterpartyAccountID, nil)
+		require.ErrorAs(t, err, &internal.ErrTransactionNotFound{})
+
+		transfer, err := transactionsService.RetrieveTransfer(ctx, in.TransferID)
+		require.NoError(t, err)
+		_, err = transactionsService.ReverseTransaction(ctx, transfer[0].ID, nil)
+		require.ErrorAs(t, err, &internal.ErrReversalExceeded{})
+	})
 }
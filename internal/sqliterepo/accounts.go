@@ -0,0 +1,323 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+const accountColumns = `id, owner, currency, balance, status, overdraft_limit, held, version, type,
+	interest_rate, accrued_interest, interest_accrued_through, opened_at`
+
+type AccountsRepository struct {
+	db querier
+}
+
+var _ internal.AccountsRepository = (*AccountsRepository)(nil)
+
+func NewAccountsRepository(db *sql.DB) *AccountsRepository {
+	return &AccountsRepository{db: db}
+}
+
+func (ar *AccountsRepository) Create(ctx context.Context, account internal.Account) error {
+	_, err := ar.db.ExecContext(ctx,
+		`INSERT INTO accounts (`+accountColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		account.ID, string(account.Owner), string(account.Currency), account.Balance.String(), string(account.Status),
+		account.OverdraftLimit.String(), account.Held.String(), account.Version, string(account.Type),
+		account.InterestRate.String(), account.AccruedInterest.String(), nullTime(account.InterestAccruedThrough),
+		nullTime(account.OpenedAt),
+	)
+	if isUniqueViolation(err) {
+		return internal.ErrAccountAlreadyExists
+	}
+
+	if err != nil {
+		return fmt.Errorf("inserting account: %w", err)
+	}
+
+	return nil
+}
+
+func (ar *AccountsRepository) Get(ctx context.Context, id string) (*internal.Account, error) {
+	account, err := scanAccount(ar.db.QueryRowContext(ctx,
+		`SELECT `+accountColumns+` FROM accounts WHERE id = ?`, id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, internal.ErrAccountNotFound{AccountID: id}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (ar *AccountsRepository) List(ctx context.Context) ([]internal.Account, error) {
+	return ar.find(ctx, `SELECT `+accountColumns+` FROM accounts ORDER BY seq`)
+}
+
+func (ar *AccountsRepository) Find(ctx context.Context, query internal.AccountQuery) ([]internal.Account, error) {
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "?"
+	}
+
+	conditions := []string{"TRUE"}
+
+	if len(query.Statuses) > 0 {
+		placeholders := make([]string, len(query.Statuses))
+		for i, status := range query.Statuses {
+			placeholders[i] = arg(string(status))
+		}
+		conditions = append(conditions, "status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	if query.After != "" {
+		conditions = append(conditions, "seq > (SELECT seq FROM accounts WHERE id = "+arg(query.After)+")")
+	}
+
+	statement := `SELECT ` + accountColumns + ` FROM accounts WHERE ` + strings.Join(conditions, " AND ") + ` ORDER BY seq`
+
+	// Balances are stored as text, which SQLite only compares as floating
+	// point numbers, and LIKE only ignores the case of ASCII letters, so
+	// both are filtered exactly here, and the limit along with them.
+	if query.OwnerPrefix != "" || query.MinBalance != nil || query.MaxBalance != nil {
+		return ar.findMatching(ctx, query.Matches, query.Limit, statement, args...)
+	}
+
+	if query.Limit > 0 {
+		statement += ` LIMIT ` + arg(query.Limit)
+	}
+
+	return ar.find(ctx, statement, args...)
+}
+
+func (ar *AccountsRepository) find(ctx context.Context, query string, args ...any) ([]internal.Account, error) {
+	return ar.findMatching(ctx, nil, 0, query, args...)
+}
+
+// findMatching returns the accounts of the query that matches selects, all of
+// them when it's nil, up to the limit unless it's 0.
+func (ar *AccountsRepository) findMatching(
+	ctx context.Context,
+	matches func(internal.Account) bool,
+	limit int,
+	query string,
+	args ...any,
+) ([]internal.Account, error) {
+	rows, err := ar.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := make([]internal.Account, 0)
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		if matches != nil && !matches(account) {
+			continue
+		}
+
+		accounts = append(accounts, account)
+		if len(accounts) == limit {
+			break
+		}
+	}
+
+	return accounts, rows.Err()
+}
+
+func (ar *AccountsRepository) UpdateBalance(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	newBalance internal.Money,
+) error {
+	return ar.update(ctx, accountID, expectedVersion, []string{"balance"}, newBalance.String())
+}
+
+func (ar *AccountsRepository) UpdateStatus(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	status internal.AccountStatus,
+) error {
+	return ar.update(ctx, accountID, expectedVersion, []string{"status"}, string(status))
+}
+
+func (ar *AccountsRepository) UpdateOverdraftLimit(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	limit internal.Money,
+) error {
+	return ar.update(ctx, accountID, expectedVersion, []string{"overdraft_limit"}, limit.String())
+}
+
+func (ar *AccountsRepository) UpdateHeld(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	held internal.Money,
+) error {
+	return ar.update(ctx, accountID, expectedVersion, []string{"held"}, held.String())
+}
+
+func (ar *AccountsRepository) UpdateInterest(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	accountType internal.AccountType,
+	interest internal.AccountInterest,
+) error {
+	return ar.update(ctx, accountID, expectedVersion,
+		[]string{"type", "interest_rate", "accrued_interest", "interest_accrued_through"},
+		string(accountType), interest.InterestRate.String(), interest.AccruedInterest.String(), nullTime(interest.InterestAccruedThrough),
+	)
+}
+
+// update sets the columns of the account to the values, given in the same
+// order, as long as it's at the expected version.
+func (ar *AccountsRepository) update(
+	ctx context.Context,
+	accountID string,
+	expectedVersion int64,
+	columns []string,
+	values ...any,
+) error {
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		assignments[i] = column + " = ?"
+	}
+
+	result, err := ar.db.ExecContext(ctx,
+		`UPDATE accounts SET `+strings.Join(assignments, ", ")+`, version = version + 1 WHERE id = ? AND version = ?`,
+		append(values, accountID, expectedVersion)...,
+	)
+	if err != nil {
+		return fmt.Errorf("updating %s: %w", strings.Join(columns, ", "), err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("updating %s: %w", strings.Join(columns, ", "), err)
+	}
+
+	if updated == 0 {
+		// Either the account doesn't exist or it's at another version.
+		if _, err := ar.Get(ctx, accountID); err != nil {
+			return err
+		}
+
+		return internal.ErrVersionConflict{AccountID: accountID, ExpectedVersion: expectedVersion}
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAccount(row scanner) (internal.Account, error) {
+	var (
+		account  internal.Account
+		owner    string
+		currency string
+		balance  string
+		status   string
+		limit    string
+		held     string
+		rate     string
+		accrued  string
+		through  sql.NullString
+		openedAt sql.NullString
+	)
+
+	if err := row.Scan(
+		&account.ID,
+		&owner,
+		&currency,
+		&balance,
+		&status,
+		&limit,
+		&held,
+		&account.Version,
+		&account.Type,
+		&rate,
+		&accrued,
+		&through,
+		&openedAt,
+	); err != nil {
+		return internal.Account{}, err
+	}
+
+	account.Owner = internal.Name(owner)
+	account.Currency = internal.Currency(currency)
+	account.Status = internal.AccountStatus(status)
+
+	var err error
+	account.Balance, err = parseAmount(balance, account.Currency)
+	if err != nil {
+		return internal.Account{}, err
+	}
+
+	account.OverdraftLimit, err = parseAmount(limit, account.Currency)
+	if err != nil {
+		return internal.Account{}, err
+	}
+
+	account.Held, err = parseAmount(held, account.Currency)
+	if err != nil {
+		return internal.Account{}, err
+	}
+
+	account.InterestRate, err = internal.ParseInterestRate(rate)
+	if err != nil {
+		return internal.Account{}, fmt.Errorf("parsing stored interest rate: %w", err)
+	}
+
+	// The accrued interest keeps more decimals than the currency.
+	account.AccruedInterest, err = internal.ParseMoney(accrued)
+	if err != nil {
+		return internal.Account{}, fmt.Errorf("parsing stored amount: %w", err)
+	}
+
+	if through.Valid {
+		accruedThrough, err := parseTime(through.String)
+		if err != nil {
+			return internal.Account{}, err
+		}
+		account.InterestAccruedThrough = &accruedThrough
+	}
+
+	if openedAt.Valid {
+		opened, err := parseTime(openedAt.String)
+		if err != nil {
+			return internal.Account{}, err
+		}
+		account.OpenedAt = &opened
+	}
+
+	return account, nil
+}
+
+// parseAmount reads an amount column expressed in the currency's minor units.
+func parseAmount(value string, currency internal.Currency) (internal.Money, error) {
+	amount, err := internal.ParseMoney(value)
+	if err != nil {
+		return internal.Money{}, fmt.Errorf("parsing stored amount: %w", err)
+	}
+
+	return amount.Round(currency)
+}
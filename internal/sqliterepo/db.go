@@ -0,0 +1,170 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"sort"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// timestampLayout has a fixed width so stored timestamps sort chronologically.
+const timestampLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// querier is satisfied by both *sql.DB and *sql.Tx so repositories can run
+// inside and outside transactions.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Open opens the database file, enabling WAL mode, and applies the pending
+// migrations. Write transactions take the database lock when they begin, so
+// concurrent transfers are serialized instead of failing on lock upgrades.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "busy_timeout(10000)")
+	params.Add("_pragma", "foreign_keys(ON)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Add("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	if err := Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Migrate applies, in order, the embedded migrations that haven't been applied yet.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return fmt.Errorf("listing migrations: %w", err)
+	}
+	sort.Strings(names)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning migrations: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    TEXT PRIMARY KEY,
+			applied_at TEXT NOT NULL
+		)`,
+	); err != nil {
+		return fmt.Errorf("creating migrations table: %w", err)
+	}
+
+	for _, name := range names {
+		var applied bool
+		if err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)`, name,
+		).Scan(&applied); err != nil {
+			return fmt.Errorf("checking migration %s: %w", name, err)
+		}
+
+		if applied {
+			continue
+		}
+
+		migration, err := migrations.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("reading migration %s: %w", name, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, string(migration)); err != nil {
+			return fmt.Errorf("applying migration %s: %w", name, err)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+			name, formatTime(time.Now()),
+		); err != nil {
+			return fmt.Errorf("recording migration %s: %w", name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing migrations: %w", err)
+	}
+
+	return nil
+}
+
+// inTx runs fn in a database transaction unless q is already one.
+func inTx(ctx context.Context, q querier, fn func(q querier) error) error {
+	db, ok := q.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// isTransferLegViolation reports whether the error comes from saving a second
+// transaction of a transfer with the same type on the same account, the only
+// unique index of the transactions besides their primary key, whose
+// violations have a code of their own.
+func isTransferLegViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+func parseTime(s string) (time.Time, error) {
+	t, err := time.Parse(timestampLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing stored timestamp: %w", err)
+	}
+
+	return t.UTC(), nil
+}
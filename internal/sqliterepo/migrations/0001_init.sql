@@ -0,0 +1,45 @@
+-- Amounts are stored as TEXT decimals to avoid SQLite's floating point
+-- NUMERIC affinity, and timestamps as fixed width UTC TEXT so they sort.
+CREATE TABLE accounts (
+    seq      INTEGER PRIMARY KEY AUTOINCREMENT,
+    id       TEXT NOT NULL UNIQUE,
+    owner    TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    balance  TEXT NOT NULL
+);
+
+CREATE TABLE transactions (
+    id                      TEXT PRIMARY KEY,
+    account_id              TEXT NOT NULL,
+    type                    TEXT NOT NULL,
+    amount                  TEXT NOT NULL,
+    currency                TEXT NOT NULL,
+    timestamp               TEXT NOT NULL,
+    transfer_id             TEXT,
+    counterparty_account_id TEXT,
+    rate_from               TEXT,
+    rate_to                 TEXT,
+    rate                    TEXT,
+    rate_timestamp          TEXT
+);
+
+CREATE INDEX transactions_account_id_idx ON transactions (account_id, timestamp);
+CREATE INDEX transactions_transfer_id_idx ON transactions (transfer_id);
+
+CREATE TABLE journal_entries (
+    seq       INTEGER PRIMARY KEY AUTOINCREMENT,
+    id        TEXT NOT NULL UNIQUE,
+    reference TEXT NOT NULL,
+    timestamp TEXT NOT NULL
+);
+
+CREATE TABLE postings (
+    entry_id   TEXT NOT NULL REFERENCES journal_entries (id),
+    position   INTEGER NOT NULL,
+    account_id TEXT NOT NULL,
+    amount     TEXT NOT NULL,
+    currency   TEXT NOT NULL,
+    PRIMARY KEY (entry_id, position)
+);
+
+CREATE INDEX postings_account_id_idx ON postings (account_id);
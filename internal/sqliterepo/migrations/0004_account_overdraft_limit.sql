@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN overdraft_limit TEXT NOT NULL DEFAULT '0';
@@ -0,0 +1,15 @@
+ALTER TABLE accounts ADD COLUMN held TEXT NOT NULL DEFAULT '0';
+
+CREATE TABLE holds (
+    id              TEXT PRIMARY KEY,
+    account_id      TEXT NOT NULL,
+    amount          TEXT NOT NULL,
+    currency        TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    created_at      TEXT NOT NULL,
+    expires_at      TEXT NOT NULL,
+    captured_amount TEXT,
+    transaction_id  TEXT
+);
+
+CREATE INDEX holds_expires_at_idx ON holds (status, expires_at);
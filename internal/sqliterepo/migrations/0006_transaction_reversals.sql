@@ -0,0 +1 @@
+ALTER TABLE transactions ADD COLUMN reversal_of TEXT;
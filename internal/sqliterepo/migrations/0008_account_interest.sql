@@ -0,0 +1,4 @@
+ALTER TABLE accounts ADD COLUMN type TEXT NOT NULL DEFAULT 'checking';
+ALTER TABLE accounts ADD COLUMN interest_rate TEXT NOT NULL DEFAULT '0';
+ALTER TABLE accounts ADD COLUMN accrued_interest TEXT NOT NULL DEFAULT '0';
+ALTER TABLE accounts ADD COLUMN interest_accrued_through TEXT;
@@ -0,0 +1,7 @@
+CREATE TABLE balance_snapshots (
+    account_id TEXT NOT NULL,
+    at         TEXT NOT NULL,
+    balance    TEXT NOT NULL,
+    currency   TEXT NOT NULL,
+    PRIMARY KEY (account_id, at)
+);
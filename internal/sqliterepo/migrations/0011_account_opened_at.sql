@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN opened_at TEXT;
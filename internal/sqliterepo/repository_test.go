@@ -0,0 +1,160 @@
+package sqliterepo_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fxrates"
+	"github.com/jyisus/bank-server/internal/repotest"
+	"github.com/jyisus/bank-server/internal/service"
+	"github.com/jyisus/bank-server/internal/sqliterepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDB returns a migrated and empty database only used by the test.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sqliterepo.Open(context.Background(), filepath.Join(t.TempDir(), "bank.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestOpen_WALMode(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "bank.db")
+		ctx  = context.Background()
+	)
+
+	db, err := sqliterepo.Open(ctx, path)
+	require.NoError(t, err)
+
+	var journalMode string
+	require.NoError(t, db.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journalMode))
+	assert.Equal(t, "wal", journalMode)
+	require.NoError(t, db.Close())
+
+	// Opening an existing database doesn't apply the migrations again.
+	db, err = sqliterepo.Open(ctx, path)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
+
+func TestAccountsRepository(t *testing.T) {
+	repotest.TestAccountsRepository(t, func(t *testing.T) internal.AccountsRepository {
+		return sqliterepo.NewAccountsRepository(newTestDB(t))
+	})
+}
+
+func TestTransactionsRepository(t *testing.T) {
+	repotest.TestTransactionsRepository(t, func(t *testing.T) internal.TransactionsRepository {
+		return sqliterepo.NewTransactionsRepository(newTestDB(t))
+	})
+}
+
+func TestHoldsRepository(t *testing.T) {
+	repotest.TestHoldsRepository(t, func(t *testing.T) internal.HoldsRepository {
+		return sqliterepo.NewHoldsRepository(newTestDB(t))
+	})
+}
+
+func TestScheduledTransfersRepository(t *testing.T) {
+	repotest.TestScheduledTransfersRepository(t, func(t *testing.T) internal.ScheduledTransfersRepository {
+		return sqliterepo.NewScheduledTransfersRepository(newTestDB(t))
+	})
+}
+
+func TestBalanceSnapshotsRepository(t *testing.T) {
+	repotest.TestBalanceSnapshotsRepository(t, func(t *testing.T) internal.BalanceSnapshotsRepository {
+		return sqliterepo.NewBalanceSnapshotsRepository(newTestDB(t))
+	})
+}
+
+func TestUnitOfWork_Rollback(t *testing.T) {
+	var (
+		db           = newTestDB(t)
+		accountsRepo = sqliterepo.NewAccountsRepository(db)
+		ledgerRepo   = sqliterepo.NewLedgerRepository(db)
+		unitOfWork   = sqliterepo.NewUnitOfWork(db)
+		ctx          = context.Background()
+		errFailure   = errors.New("failure")
+
+		account = internal.Account{ID: uuid.NewString(), Owner: "Test", Currency: "EUR", Balance: internal.MustParseMoney("10.00")}
+	)
+
+	require.NoError(t, accountsRepo.Create(ctx, account))
+
+	err := internal.RunInTx(ctx, unitOfWork, func(tx internal.Tx) error {
+		if err := tx.Accounts().UpdateBalance(ctx, account.ID, 0, internal.MustParseMoney("1.00")); err != nil {
+			return err
+		}
+
+		entry := internal.JournalEntry{ID: uuid.NewString(), Timestamp: time.Now(), Postings: []internal.Posting{
+			{AccountID: account.ID, Amount: internal.MustParseMoney("-9.00"), Currency: "EUR"},
+		}}
+		if err := tx.Ledger().Append(ctx, entry); err != nil {
+			return err
+		}
+
+		return errFailure
+	})
+	require.ErrorIs(t, err, errFailure)
+
+	repoAccount, err := accountsRepo.Get(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, account.Balance, repoAccount.Balance)
+
+	entries, err := ledgerRepo.FindAllByAccount(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestServices_ConcurrentTransfers(t *testing.T) {
+	var (
+		db              = newTestDB(t)
+		logger          = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		accountsRepo    = sqliterepo.NewAccountsRepository(db)
+		ledgerRepo      = sqliterepo.NewLedgerRepository(db)
+		unitOfWork      = sqliterepo.NewUnitOfWork(db)
+		ledger          = service.NewLedger(logger, accountsRepo, ledgerRepo)
+		accountsService = service.NewAccountService(logger, accountsRepo, unitOfWork, fxrates.NewStaticProvider(), ledger, nil, internal.SystemClock{})
+		ctx             = context.Background()
+		transfers       = 20
+		wg              sync.WaitGroup
+	)
+
+	source, err := accountsService.CreateAccount(ctx, "Source", "EUR", internal.MustParseMoney("100"))
+	require.NoError(t, err)
+
+	destination, err := accountsService.CreateAccount(ctx, "Destination", "EUR", internal.MustParseMoney("0"))
+	require.NoError(t, err)
+
+	for range transfers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := accountsService.Transfer(ctx, source.ID, destination.ID, internal.MustParseMoney("1"), "")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	repoSource, err := accountsRepo.Get(ctx, source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.MustParseMoney("80.00"), repoSource.Balance)
+
+	require.NoError(t, ledger.Verify(ctx, source.ID))
+	require.NoError(t, ledger.Verify(ctx, destination.ID))
+}
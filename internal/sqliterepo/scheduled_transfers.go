@@ -0,0 +1,238 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+const scheduledTransferColumns = `id, source_account_id, destination_account_id, amount, currency,
+	schedule, start_at, end_at, max_occurrences, status, next_run_at, retry_at, occurrences,
+	failures, attempts, last_run_at, last_transfer_id, last_error, created_at`
+
+type ScheduledTransfersRepository struct {
+	db querier
+}
+
+var _ internal.ScheduledTransfersRepository = (*ScheduledTransfersRepository)(nil)
+
+func NewScheduledTransfersRepository(db *sql.DB) *ScheduledTransfersRepository {
+	return &ScheduledTransfersRepository{db: db}
+}
+
+func (sr *ScheduledTransfersRepository) Create(ctx context.Context, st internal.ScheduledTransfer) error {
+	_, err := sr.db.ExecContext(ctx,
+		`INSERT INTO scheduled_transfers (`+scheduledTransferColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		st.ID,
+		st.SourceAccountID,
+		st.DestinationAccountID,
+		st.Amount.String(),
+		string(st.Currency),
+		nullString(st.Schedule),
+		formatTime(st.StartAt),
+		nullTime(st.EndAt),
+		st.MaxOccurrences,
+		string(st.Status),
+		formatTime(st.NextRunAt),
+		nullTime(st.RetryAt),
+		st.Occurrences,
+		st.Failures,
+		st.Attempts,
+		nullTime(st.LastRunAt),
+		nullString(st.LastTransferID),
+		nullString(st.LastError),
+		formatTime(st.CreatedAt),
+	)
+	if isUniqueViolation(err) {
+		return errors.New("scheduled transfer with given ID already exists")
+	}
+
+	if err != nil {
+		return fmt.Errorf("inserting scheduled transfer: %w", err)
+	}
+
+	return nil
+}
+
+func (sr *ScheduledTransfersRepository) Get(ctx context.Context, id string) (internal.ScheduledTransfer, error) {
+	st, err := scanScheduledTransfer(sr.db.QueryRowContext(ctx,
+		`SELECT `+scheduledTransferColumns+` FROM scheduled_transfers WHERE id = ?`, id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return internal.ScheduledTransfer{}, internal.ErrScheduledTransferNotFound{ScheduledTransferID: id}
+	}
+
+	return st, err
+}
+
+func (sr *ScheduledTransfersRepository) Update(ctx context.Context, st internal.ScheduledTransfer) error {
+	result, err := sr.db.ExecContext(ctx,
+		`UPDATE scheduled_transfers SET destination_account_id = ?, amount = ?, currency = ?,
+			schedule = ?, start_at = ?, end_at = ?, max_occurrences = ?, status = ?, next_run_at = ?,
+			retry_at = ?, occurrences = ?, failures = ?, attempts = ?, last_run_at = ?,
+			last_transfer_id = ?, last_error = ?
+		WHERE id = ?`,
+		st.DestinationAccountID,
+		st.Amount.String(),
+		string(st.Currency),
+		nullString(st.Schedule),
+		formatTime(st.StartAt),
+		nullTime(st.EndAt),
+		st.MaxOccurrences,
+		string(st.Status),
+		formatTime(st.NextRunAt),
+		nullTime(st.RetryAt),
+		st.Occurrences,
+		st.Failures,
+		st.Attempts,
+		nullTime(st.LastRunAt),
+		nullString(st.LastTransferID),
+		nullString(st.LastError),
+		st.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("updating scheduled transfer: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("updating scheduled transfer: %w", err)
+	}
+
+	if updated == 0 {
+		return internal.ErrScheduledTransferNotFound{ScheduledTransferID: st.ID}
+	}
+
+	return nil
+}
+
+func (sr *ScheduledTransfersRepository) FindAllByAccount(ctx context.Context, accountID string) ([]internal.ScheduledTransfer, error) {
+	return sr.find(ctx,
+		`SELECT `+scheduledTransferColumns+` FROM scheduled_transfers
+		WHERE source_account_id = ? ORDER BY created_at, id`,
+		accountID,
+	)
+}
+
+func (sr *ScheduledTransfersRepository) FindDue(ctx context.Context, asOf time.Time) ([]internal.ScheduledTransfer, error) {
+	return sr.find(ctx,
+		`SELECT `+scheduledTransferColumns+` FROM scheduled_transfers
+		WHERE status = ? AND COALESCE(retry_at, next_run_at) <= ?
+		ORDER BY COALESCE(retry_at, next_run_at), id`,
+		string(internal.ScheduledTransferActive), formatTime(asOf),
+	)
+}
+
+func (sr *ScheduledTransfersRepository) find(ctx context.Context, query string, args ...any) ([]internal.ScheduledTransfer, error) {
+	rows, err := sr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying scheduled transfers: %w", err)
+	}
+	defer rows.Close()
+
+	scheduledTransfers := make([]internal.ScheduledTransfer, 0)
+	for rows.Next() {
+		st, err := scanScheduledTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		scheduledTransfers = append(scheduledTransfers, st)
+	}
+
+	return scheduledTransfers, rows.Err()
+}
+
+func scanScheduledTransfer(row scanner) (internal.ScheduledTransfer, error) {
+	var (
+		st                                internal.ScheduledTransfer
+		amount, currency, status          string
+		startAt, nextRunAt, createdAt     string
+		schedule, lastTransferID, lastErr sql.NullString
+		endAt, retryAt, lastRunAt         sql.NullString
+	)
+
+	if err := row.Scan(
+		&st.ID,
+		&st.SourceAccountID,
+		&st.DestinationAccountID,
+		&amount,
+		&currency,
+		&schedule,
+		&startAt,
+		&endAt,
+		&st.MaxOccurrences,
+		&status,
+		&nextRunAt,
+		&retryAt,
+		&st.Occurrences,
+		&st.Failures,
+		&st.Attempts,
+		&lastRunAt,
+		&lastTransferID,
+		&lastErr,
+		&createdAt,
+	); err != nil {
+		return internal.ScheduledTransfer{}, err
+	}
+
+	st.Currency = internal.Currency(currency)
+	st.Status = internal.ScheduledTransferStatus(status)
+	st.Schedule = schedule.String
+	st.LastTransferID = lastTransferID.String
+	st.LastError = lastErr.String
+
+	var err error
+	st.Amount, err = parseAmount(amount, st.Currency)
+	if err != nil {
+		return internal.ScheduledTransfer{}, err
+	}
+
+	for _, field := range []struct {
+		value  string
+		parsed *time.Time
+	}{
+		{startAt, &st.StartAt},
+		{nextRunAt, &st.NextRunAt},
+		{createdAt, &st.CreatedAt},
+	} {
+		*field.parsed, err = parseTime(field.value)
+		if err != nil {
+			return internal.ScheduledTransfer{}, err
+		}
+	}
+
+	for _, field := range []struct {
+		value  sql.NullString
+		parsed **time.Time
+	}{
+		{endAt, &st.EndAt},
+		{retryAt, &st.RetryAt},
+		{lastRunAt, &st.LastRunAt},
+	} {
+		if !field.value.Valid {
+			continue
+		}
+
+		t, err := parseTime(field.value.String)
+		if err != nil {
+			return internal.ScheduledTransfer{}, err
+		}
+		*field.parsed = &t
+	}
+
+	return st, nil
+}
+
+func nullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: formatTime(*t), Valid: true}
+}
@@ -0,0 +1,261 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+const transactionColumns = `id, account_id, type, amount, currency, timestamp,
+	transfer_id, counterparty_account_id, rate_from, rate_to, rate, rate_timestamp, reversal_of, fee_for`
+
+type TransactionsRepository struct {
+	db querier
+}
+
+var _ internal.TransactionsRepository = (*TransactionsRepository)(nil)
+
+func NewTransactionsRepository(db *sql.DB) *TransactionsRepository {
+	return &TransactionsRepository{db: db}
+}
+
+func (tr *TransactionsRepository) Save(ctx context.Context, transaction internal.Transaction) error {
+	var rateFrom, rateTo, rate sql.NullString
+	var rateTimestamp sql.NullString
+	if er := transaction.ExchangeRate; er != nil {
+		rateFrom = sql.NullString{String: string(er.From), Valid: true}
+		rateTo = sql.NullString{String: string(er.To), Valid: true}
+		rate = sql.NullString{String: er.Rate.String(), Valid: true}
+		rateTimestamp = sql.NullString{String: formatTime(er.Timestamp), Valid: true}
+	}
+
+	_, err := tr.db.ExecContext(ctx,
+		`INSERT INTO transactions (`+transactionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		transaction.ID,
+		transaction.AccountID,
+		string(transaction.Type),
+		transaction.Amount.String(),
+		string(transaction.Currency),
+		formatTime(transaction.Timestamp),
+		nullString(transaction.TransferID),
+		nullString(transaction.CounterpartyAccountID),
+		rateFrom,
+		rateTo,
+		rate,
+		rateTimestamp,
+		nullString(transaction.ReversalOf),
+		nullString(transaction.FeeFor),
+	)
+	if isTransferLegViolation(err) {
+		return internal.ErrTransferExists{TransferID: transaction.TransferID}
+	}
+
+	if isUniqueViolation(err) {
+		return errors.New("transaction with given ID already exists")
+	}
+
+	if err != nil {
+		return fmt.Errorf("inserting transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (tr *TransactionsRepository) Get(ctx context.Context, id string) (internal.Transaction, error) {
+	transaction, err := scanTransaction(tr.db.QueryRowContext(ctx,
+		`SELECT `+transactionColumns+` FROM transactions WHERE id = ?`, id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return internal.Transaction{}, internal.ErrTransactionNotFound{TransactionID: id}
+	}
+
+	return transaction, err
+}
+
+func (tr *TransactionsRepository) FindAllByAccount(ctx context.Context, accountID string) ([]internal.Transaction, error) {
+	return tr.find(ctx,
+		`SELECT `+transactionColumns+` FROM transactions WHERE account_id = ? ORDER BY timestamp, id`,
+		accountID,
+	)
+}
+
+// Find pushes the query's filters, order and limit down to the database,
+// resuming after the cursor with a keyset condition. Amounts are stored as
+// text, which SQLite only compares as floating point numbers, so the amount
+// filters, and the limit along with them, are applied exactly here instead.
+func (tr *TransactionsRepository) Find(ctx context.Context, query internal.TransactionQuery) ([]internal.Transaction, error) {
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "?"
+	}
+
+	conditions := []string{"account_id = " + arg(query.AccountID)}
+
+	if len(query.Types) > 0 {
+		placeholders := make([]string, len(query.Types))
+		for i, txType := range query.Types {
+			placeholders[i] = arg(string(txType))
+		}
+		conditions = append(conditions, "type IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	if query.From != nil {
+		conditions = append(conditions, "timestamp >= "+arg(formatTime(*query.From)))
+	}
+
+	if query.To != nil {
+		conditions = append(conditions, "timestamp < "+arg(formatTime(*query.To)))
+	}
+
+	order, after := "ASC", ">"
+	if query.Order == internal.SortDescending {
+		order, after = "DESC", "<"
+	}
+
+	if query.After != nil {
+		conditions = append(conditions, fmt.Sprintf(
+			"(timestamp %[1]s %[2]s OR (timestamp = %[3]s AND id %[1]s %[4]s))",
+			after,
+			arg(formatTime(query.After.Timestamp)),
+			arg(formatTime(query.After.Timestamp)),
+			arg(query.After.ID),
+		))
+	}
+
+	statement := `SELECT ` + transactionColumns + ` FROM transactions WHERE ` + strings.Join(conditions, " AND ") +
+		` ORDER BY timestamp ` + order + `, id ` + order
+
+	if query.MinAmount != nil || query.MaxAmount != nil {
+		return tr.findMatching(ctx, query.Matches, query.Limit, statement, args...)
+	}
+
+	if query.Limit > 0 {
+		statement += ` LIMIT ` + arg(query.Limit)
+	}
+
+	return tr.find(ctx, statement, args...)
+}
+
+func (tr *TransactionsRepository) FindAllByTransfer(ctx context.Context, transferID string) ([]internal.Transaction, error) {
+	return tr.find(ctx,
+		`SELECT `+transactionColumns+` FROM transactions WHERE transfer_id = ? ORDER BY id`,
+		transferID,
+	)
+}
+
+func (tr *TransactionsRepository) find(ctx context.Context, query string, args ...any) ([]internal.Transaction, error) {
+	return tr.findMatching(ctx, nil, 0, query, args...)
+}
+
+// findMatching returns the transactions of the query that matches selects, all
+// of them when it's nil, up to the limit unless it's 0.
+func (tr *TransactionsRepository) findMatching(
+	ctx context.Context,
+	matches func(internal.Transaction) bool,
+	limit int,
+	query string,
+	args ...any,
+) ([]internal.Transaction, error) {
+	rows, err := tr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := make([]internal.Transaction, 0)
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		if matches != nil && !matches(transaction) {
+			continue
+		}
+
+		transactions = append(transactions, transaction)
+		if len(transactions) == limit {
+			break
+		}
+	}
+
+	return transactions, rows.Err()
+}
+
+func scanTransaction(row scanner) (internal.Transaction, error) {
+	var (
+		transaction                                           internal.Transaction
+		txType, amount, currency, timestamp                   string
+		transferID, counterpartyID, rateFrom, rateTo, rateStr sql.NullString
+		reversalOf, feeFor                                    sql.NullString
+		rateTimestamp                                         sql.NullString
+	)
+
+	if err := row.Scan(
+		&transaction.ID,
+		&transaction.AccountID,
+		&txType,
+		&amount,
+		&currency,
+		&timestamp,
+		&transferID,
+		&counterpartyID,
+		&rateFrom,
+		&rateTo,
+		&rateStr,
+		&rateTimestamp,
+		&reversalOf,
+		&feeFor,
+	); err != nil {
+		return internal.Transaction{}, err
+	}
+
+	transaction.Type = internal.TransactionType(txType)
+	transaction.Currency = internal.Currency(currency)
+	transaction.TransferID = transferID.String
+	transaction.CounterpartyAccountID = counterpartyID.String
+	transaction.ReversalOf = reversalOf.String
+	transaction.FeeFor = feeFor.String
+
+	var err error
+	transaction.Timestamp, err = parseTime(timestamp)
+	if err != nil {
+		return internal.Transaction{}, err
+	}
+
+	transaction.Amount, err = parseAmount(amount, transaction.Currency)
+	if err != nil {
+		return internal.Transaction{}, err
+	}
+
+	if rateStr.Valid {
+		rate, err := internal.ParseRate(rateStr.String)
+		if err != nil {
+			return internal.Transaction{}, fmt.Errorf("parsing stored rate: %w", err)
+		}
+
+		rateTime, err := parseTime(rateTimestamp.String)
+		if err != nil {
+			return internal.Transaction{}, err
+		}
+
+		transaction.ExchangeRate = &internal.ExchangeRate{
+			From:      internal.Currency(rateFrom.String),
+			To:        internal.Currency(rateTo.String),
+			Rate:      rate,
+			Timestamp: rateTime,
+		}
+	}
+
+	return transaction, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,68 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+// UnitOfWork runs transactions on the database. Transactions begin with
+// BEGIN IMMEDIATE, so they are serialized by SQLite's write lock.
+type UnitOfWork struct {
+	db *sql.DB
+}
+
+var _ internal.UnitOfWork = (*UnitOfWork)(nil)
+
+func NewUnitOfWork(db *sql.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+func (u *UnitOfWork) Begin(ctx context.Context) (internal.Tx, error) {
+	sqlTx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tx{sqlTx: sqlTx}, nil
+}
+
+type tx struct {
+	sqlTx *sql.Tx
+}
+
+func (t *tx) Accounts() internal.AccountsRepository {
+	return &AccountsRepository{db: t.sqlTx}
+}
+
+func (t *tx) Transactions() internal.TransactionsRepository {
+	return &TransactionsRepository{db: t.sqlTx}
+}
+
+func (t *tx) Ledger() internal.LedgerRepository {
+	return &LedgerRepository{db: t.sqlTx}
+}
+
+func (t *tx) Holds() internal.HoldsRepository {
+	return &HoldsRepository{db: t.sqlTx}
+}
+
+func (t *tx) Commit(_ context.Context) error {
+	if err := t.sqlTx.Commit(); errors.Is(err, sql.ErrTxDone) {
+		return internal.ErrTxDone
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *tx) Rollback(_ context.Context) error {
+	if err := t.sqlTx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+// Statement is the history of an account over the days from From to To, both
+// included: the balance at the start of the first day, the transactions in
+// chronological order with the balance after each of them, and the balance at
+// the end of the last day.
+type Statement struct {
+	AccountID      string          `json:"account_id"`
+	Owner          Name            `json:"owner"`
+	Currency       Currency        `json:"currency"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance Money           `json:"opening_balance"`
+	ClosingBalance Money           `json:"closing_balance"`
+	Lines          []StatementLine `json:"transactions"`
+}
+
+// StatementLine is a transaction of a statement with the balance of the
+// account right after it.
+type StatementLine struct {
+	Transaction
+	Balance Money `json:"balance"`
+}
+
+// StatementPeriod returns the start and the end, excluded, of the days from
+// from to to, checking they're in order.
+func StatementPeriod(from, to time.Time) (time.Time, time.Time, error) {
+	from, to = Date(from), Date(to)
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, ErrInvalidValue{Msg: fmt.Sprintf(
+			"the statement can't end on %s, before it starts on %s",
+			to.Format(time.DateOnly),
+			from.Format(time.DateOnly),
+		)}
+	}
+
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// NewStatement returns the statement of the account over the days from from
+// to to given its transactions within them, and those made since, in
+// chronological order. The balances are worked out back from the current one,
+// as the initial balance of accounts isn't a transaction, so the closing
+// balance plus the changes made since always adds up to the account's
+// balance.
+func NewStatement(account Account, from, to time.Time, transactions, since []Transaction) (Statement, error) {
+	start, end, err := StatementPeriod(from, to)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	closing := account.Balance
+	for _, transaction := range since {
+		if transaction.Timestamp.Before(end) {
+			return Statement{}, fmt.Errorf("transaction %q was made within the statement, not since", transaction.ID)
+		}
+
+		if closing, err = closing.Sub(transaction.BalanceChange()); err != nil {
+			return Statement{}, err
+		}
+	}
+
+	opening := closing
+	for _, transaction := range transactions {
+		if transaction.Timestamp.Before(start) || !transaction.Timestamp.Before(end) {
+			return Statement{}, fmt.Errorf("transaction %q was made out of the statement", transaction.ID)
+		}
+
+		if opening, err = opening.Sub(transaction.BalanceChange()); err != nil {
+			return Statement{}, err
+		}
+	}
+
+	statement := Statement{
+		AccountID:      account.ID,
+		Owner:          account.Owner,
+		Currency:       account.Currency,
+		From:           start,
+		To:             end.AddDate(0, 0, -1),
+		OpeningBalance: opening,
+		ClosingBalance: closing,
+		Lines:          make([]StatementLine, 0, len(transactions)),
+	}
+
+	balance := opening
+	for _, transaction := range transactions {
+		if balance, err = balance.Add(transaction.BalanceChange()); err != nil {
+			return Statement{}, err
+		}
+
+		statement.Lines = append(statement.Lines, StatementLine{Transaction: transaction, Balance: balance})
+	}
+
+	return statement, nil
+}
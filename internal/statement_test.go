@@ -0,0 +1,73 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStatement(t *testing.T) {
+	var (
+		from    = time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+		to      = time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC)
+		account = internal.Account{ID: uuid.NewString(), Currency: "EUR", Balance: internal.MustParseMoney("75.00")}
+	)
+
+	newTransaction := func(txType string, amount string, timestamp time.Time) internal.Transaction {
+		return internal.Transaction{
+			ID:        uuid.NewString(),
+			AccountID: account.ID,
+			Type:      internal.TransactionType(txType),
+			Amount:    internal.MustParseMoney(amount),
+			Currency:  "EUR",
+			Timestamp: timestamp,
+		}
+	}
+
+	transactions := []internal.Transaction{
+		newTransaction(internal.TxDeposit, "50.00", from),
+		newTransaction(internal.TxTransferOut, "20.00", from.AddDate(0, 0, 10)),
+		newTransaction(internal.TxFee, "1.00", from.AddDate(0, 0, 10)),
+		newTransaction(internal.TxInterest, "0.50", to.Add(23*time.Hour+59*time.Minute)),
+	}
+	since := []internal.Transaction{
+		newTransaction(internal.TxTransferIn, "30.00", to.AddDate(0, 0, 1)),
+		newTransaction(internal.TxWithdrawal, "5.00", to.AddDate(0, 0, 2)),
+	}
+
+	statement, err := internal.NewStatement(account, from.Add(15*time.Hour), to, transactions, since)
+	require.NoError(t, err)
+	assert.Equal(t, from, statement.From)
+	assert.Equal(t, to, statement.To)
+	assert.Equal(t, internal.MustParseMoney("50.00"), statement.ClosingBalance, "75.00 - 30.00 + 5.00")
+	assert.Equal(t, internal.MustParseMoney("20.50"), statement.OpeningBalance, "50.00 - 50.00 + 20.00 + 1.00 - 0.50")
+
+	require.Len(t, statement.Lines, 4)
+	for i, expected := range []string{"70.50", "50.50", "49.50", "50.00"} {
+		assert.Equal(t, transactions[i], statement.Lines[i].Transaction)
+		assert.Equal(t, internal.MustParseMoney(expected), statement.Lines[i].Balance)
+	}
+
+	t.Run("Inverted period", func(t *testing.T) {
+		_, err := internal.NewStatement(account, to, from, nil, nil)
+		require.ErrorAs(t, err, &internal.ErrInvalidValue{})
+	})
+
+	t.Run("Single day", func(t *testing.T) {
+		statement, err := internal.NewStatement(account, to, to, transactions[3:], since)
+		require.NoError(t, err)
+		assert.Equal(t, internal.MustParseMoney("49.50"), statement.OpeningBalance)
+	})
+
+	t.Run("Transactions out of the period", func(t *testing.T) {
+		_, err := internal.NewStatement(account, from, to, since, nil)
+		require.Error(t, err)
+
+		_, err = internal.NewStatement(account, from, to, nil, transactions)
+		require.Error(t, err)
+	})
+}
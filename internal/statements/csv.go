@@ -0,0 +1,53 @@
+// Package statements renders account statements as files customers can
+// download.
+package statements
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+// WriteCSV writes the statement as CSV, one row per transaction with its
+// signed amount and the running balance, between an opening_balance row and a
+// closing_balance one.
+func WriteCSV(w io.Writer, statement internal.Statement) error {
+	writer := csv.NewWriter(w)
+
+	rows := [][]string{
+		{"timestamp", "transaction_id", "type", "amount", "currency", "balance"},
+		{formatTimestamp(statement.From), "", "opening_balance", "", string(statement.Currency), statement.OpeningBalance.String()},
+	}
+
+	for _, line := range statement.Lines {
+		rows = append(rows, []string{
+			formatTimestamp(line.Timestamp),
+			line.ID,
+			string(line.Type),
+			line.BalanceChange().String(),
+			string(line.Currency),
+			line.Balance.String(),
+		})
+	}
+
+	rows = append(rows, []string{
+		formatTimestamp(statement.To.AddDate(0, 0, 1)),
+		"",
+		"closing_balance",
+		"",
+		string(statement.Currency),
+		statement.ClosingBalance.String(),
+	})
+
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
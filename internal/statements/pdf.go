@@ -0,0 +1,134 @@
+package statements
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+)
+
+// The pages are A4 in points, written in a monospaced font so the columns of
+// the transactions line up.
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	pageMargin   = 50
+	fontSize     = 8
+	lineHeight   = 11
+	linesPerPage = (pageHeight - 2*pageMargin) / lineHeight
+	lineFormat   = "%-16s  %-12s  %-36s  %12s  %12s"
+)
+
+// WritePDF writes the statement as a PDF document with as many pages as its
+// transactions need.
+func WritePDF(w io.Writer, statement internal.Statement) error {
+	lines := []string{
+		"Statement of account " + statement.AccountID,
+		"Owner: " + string(statement.Owner),
+		fmt.Sprintf("Period: %s to %s", statement.From.Format(time.DateOnly), statement.To.Format(time.DateOnly)),
+		"",
+		fmt.Sprintf("Opening balance: %s %s", statement.OpeningBalance, statement.Currency),
+		"",
+		fmt.Sprintf(lineFormat, "Date", "Type", "Transaction", "Amount", "Balance"),
+	}
+
+	for _, line := range statement.Lines {
+		lines = append(lines, fmt.Sprintf(
+			lineFormat,
+			line.Timestamp.UTC().Format("2006-01-02 15:04"),
+			line.Type,
+			line.ID,
+			line.BalanceChange(),
+			line.Balance,
+		))
+	}
+
+	lines = append(lines, "", fmt.Sprintf("Closing balance: %s %s", statement.ClosingBalance, statement.Currency))
+
+	var pages [][]string
+	for len(lines) > linesPerPage {
+		pages = append(pages, lines[:linesPerPage])
+		lines = lines[linesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	_, err := w.Write(newPDF(pages))
+
+	return err
+}
+
+// newPDF returns a document with a page for each group of lines. Objects 1, 2
+// and 3 are the catalog, the page tree and the font, and each page is followed
+// by its content stream.
+func newPDF(pages [][]string) []byte {
+	var (
+		doc     bytes.Buffer
+		offsets []int
+	)
+
+	object := func(body string) {
+		offsets = append(offsets, doc.Len())
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	doc.WriteString("%PDF-1.4\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, lines := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, lineHeight, pageMargin, pageHeight-pageMargin)
+		for _, line := range lines {
+			fmt.Fprintf(&content, "(%s) '\n", escapePDFText(line))
+		}
+		content.WriteString("ET")
+
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth,
+			pageHeight,
+			5+2*i,
+		))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return doc.Bytes()
+}
+
+// escapePDFText escapes the text for a PDF string. Characters out of Latin-1
+// can't be shown with the standard fonts, so they're replaced.
+func escapePDFText(s string) string {
+	var escaped strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			escaped.WriteRune('\\')
+			escaped.WriteRune(r)
+		case r >= ' ' && r < 0x7f:
+			escaped.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&escaped, "\\%03o", r)
+		default:
+			escaped.WriteRune('?')
+		}
+	}
+
+	return escaped.String()
+}
@@ -0,0 +1,118 @@
+package statements_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/statements"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, statements.WriteCSV(&buf, newStatement(t, 2)))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"timestamp", "transaction_id", "type", "amount", "currency", "balance"},
+		{"2024-11-01T00:00:00Z", "", "opening_balance", "", "EUR", "100.00"},
+		{"2024-11-02T10:00:00Z", "transaction-0", "deposit", "10.00", "EUR", "110.00"},
+		{"2024-11-02T10:01:00Z", "transaction-1", "withdrawal", "-10.00", "EUR", "100.00"},
+		{"2024-12-01T00:00:00Z", "", "closing_balance", "", "EUR", "100.00"},
+	}, rows)
+}
+
+func TestWritePDF(t *testing.T) {
+	testCases := map[string]struct {
+		transactions  int
+		expectedPages int
+	}{
+		"Empty":      {transactions: 0, expectedPages: 1},
+		"One page":   {transactions: 10, expectedPages: 1},
+		"Many pages": {transactions: 150, expectedPages: 3},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			statement := newStatement(t, tc.transactions)
+			statement.Owner = "Zoë (Müller)"
+
+			var buf bytes.Buffer
+			require.NoError(t, statements.WritePDF(&buf, statement))
+			pdf := buf.String()
+
+			assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4\n"))
+			assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+			assert.Contains(t, pdf, fmt.Sprintf("/Count %d", tc.expectedPages))
+			assert.Contains(t, pdf, `(Owner: Zo\353 \(M\374ller\)) '`)
+			assert.Contains(t, pdf, "(Closing balance: 100.00 EUR) '")
+			assertXref(t, pdf, 3+2*tc.expectedPages)
+		})
+	}
+}
+
+// assertXref checks that the cross-reference table points to every object.
+func assertXref(t *testing.T, pdf string, objects int) {
+	t.Helper()
+
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(pdf)
+	require.NotNil(t, match)
+	start, err := strconv.Atoi(match[1])
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(pdf[start:], fmt.Sprintf("xref\n0 %d\n", objects+1)))
+
+	entries := strings.Split(pdf[start:], "\n")[3 : 3+objects]
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(entry[:10])
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(pdf[offset:], fmt.Sprintf("%d 0 obj\n", i+1)), "object %d", i+1)
+	}
+}
+
+// newStatement returns a November statement opening and closing with 100.00,
+// with deposits and withdrawals of 10.00 in turns.
+func newStatement(t *testing.T, transactions int) internal.Statement {
+	t.Helper()
+
+	account := internal.Account{ID: "account", Owner: "Test User", Currency: "EUR", Balance: internal.MustParseMoney("100.00")}
+	if transactions%2 == 1 {
+		account.Balance = internal.MustParseMoney("110.00")
+	}
+
+	var history []internal.Transaction
+	for i := range transactions {
+		txType := internal.TransactionType(internal.TxDeposit)
+		if i%2 == 1 {
+			txType = internal.TxWithdrawal
+		}
+
+		history = append(history, internal.Transaction{
+			ID:        fmt.Sprintf("transaction-%d", i),
+			AccountID: account.ID,
+			Type:      txType,
+			Amount:    internal.MustParseMoney("10.00"),
+			Currency:  "EUR",
+			Timestamp: time.Date(2024, 11, 2, 10, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	statement, err := internal.NewStatement(
+		account,
+		time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC),
+		history,
+		nil,
+	)
+	require.NoError(t, err)
+
+	return statement
+}
@@ -10,6 +10,21 @@ type TransactionType string
 const (
 	TxDeposit    = "deposit"
 	TxWithdrawal = "withdrawal"
+
+	// Transfer transactions are only created by transfers, never directly
+	TxTransferOut = "transfer_out"
+	TxTransferIn  = "transfer_in"
+
+	// Interest transactions are only created when posting interest
+	TxInterest = "interest"
+
+	// Fee transactions are only created when charging fees
+	TxFee = "fee"
+
+	// Adjustment transactions are only created when reconciling balances, to
+	// correct them up or down to what their history adds up to
+	TxAdjustmentIn  = "adjustment_in"
+	TxAdjustmentOut = "adjustment_out"
 )
 
 func NewTransactionType(txType string) (TransactionType, error) {
@@ -25,21 +40,72 @@ type Transaction struct {
 	ID        string          `json:"id"`
 	AccountID string          `json:"accountId"`
 	Type      TransactionType `json:"type"`
-	Amount    float32         `json:"amount"`
+	Amount    Money           `json:"amount"`
+	Currency  Currency        `json:"currency"`
 	Timestamp time.Time       `json:"timestamp"`
+
+	// Only set for transfer transactions
+	TransferID            string        `json:"transferId,omitempty"`
+	CounterpartyAccountID string        `json:"counterpartyAccountId,omitempty"`
+	ExchangeRate          *ExchangeRate `json:"exchangeRate,omitempty"`
+
+	// Only set for reversals, which have the opposite type of the
+	// transaction they reverse
+	ReversalOf string `json:"reversalOf,omitempty"`
+
+	// Only set for fees charged for another transaction
+	FeeFor string `json:"feeFor,omitempty"`
 }
 
-func NewTransaction(id, accountID, txType string, amount float32, timestamp time.Time) (Transaction, error) {
+func NewTransaction(
+	id,
+	accountID,
+	txType string,
+	amount Money,
+	currency Currency,
+	timestamp time.Time,
+) (Transaction, error) {
 	transactionType, err := NewTransactionType(txType)
 	if err != nil {
 		return Transaction{}, err
 	}
 
+	if !amount.IsPositive() {
+		return Transaction{}, ErrInvalidValue{Msg: "the amount should be greater than 0"}
+	}
+
+	if _, err := NewCurrency(string(currency)); err != nil {
+		return Transaction{}, err
+	}
+
+	amount, err = amount.In(currency)
+	if err != nil {
+		return Transaction{}, err
+	}
+
 	return Transaction{
 		ID:        id,
 		AccountID: accountID,
 		Type:      transactionType,
 		Amount:    amount,
+		Currency:  currency,
 		Timestamp: timestamp,
 	}, nil
 }
+
+// BalanceChange returns how much the transaction changes the balance of its
+// account.
+func (t Transaction) BalanceChange() Money {
+	switch t.Type {
+	case TxWithdrawal, TxTransferOut, TxFee, TxAdjustmentOut:
+		return t.Amount.Neg()
+	}
+
+	return t.Amount
+}
+
+// IsAdjustment reports whether the transaction corrected the balance of its
+// account when reconciling it.
+func (t Transaction) IsAdjustment() bool {
+	return t.Type == TxAdjustmentIn || t.Type == TxAdjustmentOut
+}
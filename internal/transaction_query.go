@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTransactionsLimit is the size of the pages of transactions when
+	// none is given.
+	DefaultTransactionsLimit = 100
+	MaxTransactionsLimit     = 1000
+)
+
+// SortOrder is the order of a listing by timestamp.
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+func NewSortOrder(order string) (SortOrder, error) {
+	switch SortOrder(order) {
+	case SortAscending, SortDescending:
+		return SortOrder(order), nil
+	}
+
+	return "", ErrInvalidValue{Msg: fmt.Sprintf("invalid sort order %q, it should be asc or desc", order)}
+}
+
+// ParseTransactionType returns the type of any transaction, unlike
+// NewTransactionType that only accepts the types clients can create.
+func ParseTransactionType(txType string) (TransactionType, error) {
+	switch txType {
+	case TxDeposit, TxWithdrawal, TxTransferOut, TxTransferIn, TxInterest, TxFee, TxAdjustmentIn, TxAdjustmentOut:
+		return TransactionType(txType), nil
+	}
+
+	return "", ErrInvalidValue{Msg: fmt.Sprintf("invalid transaction type %q", txType)}
+}
+
+// TransactionCursor is the position of a transaction in the history of its
+// account. Pages resume right after it.
+type TransactionCursor struct {
+	Timestamp time.Time
+	ID        string
+}
+
+func CursorOf(transaction Transaction) TransactionCursor {
+	return TransactionCursor{Timestamp: transaction.Timestamp, ID: transaction.ID}
+}
+
+// String encodes the cursor as an opaque token.
+func (c TransactionCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Timestamp.UTC().Format(time.RFC3339Nano) + " " + c.ID))
+}
+
+func ParseTransactionCursor(s string) (TransactionCursor, error) {
+	invalid := ErrInvalidValue{Msg: fmt.Sprintf("invalid cursor %q", s)}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return TransactionCursor{}, invalid
+	}
+
+	timestamp, id, ok := strings.Cut(string(decoded), " ")
+	if !ok || id == "" {
+		return TransactionCursor{}, invalid
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return TransactionCursor{}, invalid
+	}
+
+	return TransactionCursor{Timestamp: t, ID: id}, nil
+}
+
+// TransactionQuery selects the transactions of an account. Every filter left
+// empty matches all of them. Transactions are ordered by timestamp, then by ID
+// to break ties.
+type TransactionQuery struct {
+	AccountID string
+	Types     []TransactionType
+	// MinAmount and MaxAmount bound the amount, both included.
+	MinAmount *Money
+	MaxAmount *Money
+	// From and To bound the timestamp, From included and To excluded.
+	From  *time.Time
+	To    *time.Time
+	Order SortOrder
+	// After selects the transactions coming after the cursor in the order.
+	After *TransactionCursor
+	// Limit is the maximum number of transactions returned, with 0 meaning
+	// all of them.
+	Limit int
+}
+
+func (q TransactionQuery) Validate() error {
+	if q.Order != "" {
+		if _, err := NewSortOrder(string(q.Order)); err != nil {
+			return err
+		}
+	}
+
+	for _, txType := range q.Types {
+		if _, err := ParseTransactionType(string(txType)); err != nil {
+			return err
+		}
+	}
+
+	if q.MinAmount != nil && q.MaxAmount != nil && q.MinAmount.Cmp(*q.MaxAmount) > 0 {
+		return ErrInvalidValue{Msg: "the minimum amount is above the maximum"}
+	}
+
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return ErrInvalidValue{Msg: "the start of the date range should be before its end"}
+	}
+
+	if q.Limit < 0 || q.Limit > MaxTransactionsLimit {
+		return ErrInvalidValue{Msg: fmt.Sprintf("the limit can't be negative or above %d", MaxTransactionsLimit)}
+	}
+
+	return nil
+}
+
+// Matches reports whether the transaction is selected by the query, leaving
+// aside the limit.
+func (q TransactionQuery) Matches(transaction Transaction) bool {
+	switch {
+	case transaction.AccountID != q.AccountID,
+		len(q.Types) > 0 && !slices.Contains(q.Types, transaction.Type),
+		q.MinAmount != nil && transaction.Amount.Cmp(*q.MinAmount) < 0,
+		q.MaxAmount != nil && transaction.Amount.Cmp(*q.MaxAmount) > 0,
+		q.From != nil && transaction.Timestamp.Before(*q.From),
+		q.To != nil && !transaction.Timestamp.Before(*q.To):
+		return false
+	case q.After != nil:
+		return q.Compare(CursorOf(transaction), *q.After) > 0
+	default:
+		return true
+	}
+}
+
+// Compare orders the positions of two transactions as the query does.
+func (q TransactionQuery) Compare(a, b TransactionCursor) int {
+	c := cmp.Or(a.Timestamp.Compare(b.Timestamp), strings.Compare(a.ID, b.ID))
+	if q.Order == SortDescending {
+		return -c
+	}
+
+	return c
+}
+
+// TransactionPage is a page of the transactions selected by a query.
+// NextCursor is empty on the last page.
+type TransactionPage struct {
+	Transactions []Transaction
+	NextCursor   string
+}
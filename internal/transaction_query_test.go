@@ -0,0 +1,59 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyisus/bank-server/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionCursor(t *testing.T) {
+	cursor := internal.TransactionCursor{Timestamp: time.Date(2024, 11, 24, 3, 26, 51, 835490418, time.UTC), ID: uuid.NewString()}
+
+	parsed, err := internal.ParseTransactionCursor(cursor.String())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, parsed)
+
+	for _, invalid := range []string{"", "not base64!", "bm8tc3BhY2U", "MjAyNC0xMS0yNCA"} {
+		_, err := internal.ParseTransactionCursor(invalid)
+		assert.ErrorAs(t, err, &internal.ErrInvalidValue{}, invalid)
+	}
+}
+
+func TestTransactionQuery_Validate(t *testing.T) {
+	var (
+		ten   = internal.MustParseMoney("10")
+		five  = internal.MustParseMoney("5")
+		start = time.Date(2024, 11, 24, 0, 0, 0, 0, time.UTC)
+		end   = start.Add(24 * time.Hour)
+	)
+
+	testCases := map[string]struct {
+		query         internal.TransactionQuery
+		expectedError error
+	}{
+		"Empty":               {query: internal.TransactionQuery{}},
+		"Every filter":        {query: internal.TransactionQuery{Types: []internal.TransactionType{internal.TxFee}, MinAmount: &five, MaxAmount: &ten, From: &start, To: &end, Order: internal.SortDescending, Limit: 10}},
+		"Unknown type":        {query: internal.TransactionQuery{Types: []internal.TransactionType{"bonus"}}, expectedError: &internal.ErrInvalidValue{}},
+		"Inverted amounts":    {query: internal.TransactionQuery{MinAmount: &ten, MaxAmount: &five}, expectedError: &internal.ErrInvalidValue{}},
+		"Inverted dates":      {query: internal.TransactionQuery{From: &end, To: &start}, expectedError: &internal.ErrInvalidValue{}},
+		"Empty date range":    {query: internal.TransactionQuery{From: &start, To: &start}, expectedError: &internal.ErrInvalidValue{}},
+		"Unknown order":       {query: internal.TransactionQuery{Order: "random"}, expectedError: &internal.ErrInvalidValue{}},
+		"Limit above maximum": {query: internal.TransactionQuery{Limit: internal.MaxTransactionsLimit + 1}, expectedError: &internal.ErrInvalidValue{}},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			err := tc.query.Validate()
+			if tc.expectedError != nil {
+				require.ErrorAs(t, err, tc.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
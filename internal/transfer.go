@@ -0,0 +1,50 @@
+package internal
+
+import "time"
+
+// Transfer records a movement of money between two accounts together with the
+// conversion applied, so cross-currency transfers can be audited.
+type Transfer struct {
+	ID                   string    `json:"id"`
+	SourceAccountID      string    `json:"from_account_id"`
+	DestinationAccountID string    `json:"to_account_id"`
+	Timestamp            time.Time `json:"timestamp"`
+	Quote
+}
+
+// NewTransferTransactions returns the transactions recorded on the source and
+// destination accounts of the transfer.
+func NewTransferTransactions(outID, inID string, transfer Transfer) (Transaction, Transaction) {
+	rate := &ExchangeRate{
+		From:      transfer.SourceCurrency,
+		To:        transfer.DestinationCurrency,
+		Rate:      transfer.Rate,
+		Timestamp: transfer.RateTimestamp,
+	}
+
+	out := Transaction{
+		ID:                    outID,
+		AccountID:             transfer.SourceAccountID,
+		Type:                  TxTransferOut,
+		Amount:                transfer.SourceAmount,
+		Currency:              transfer.SourceCurrency,
+		Timestamp:             transfer.Timestamp,
+		TransferID:            transfer.ID,
+		CounterpartyAccountID: transfer.DestinationAccountID,
+		ExchangeRate:          rate,
+	}
+
+	in := Transaction{
+		ID:                    inID,
+		AccountID:             transfer.DestinationAccountID,
+		Type:                  TxTransferIn,
+		Amount:                transfer.DestinationAmount,
+		Currency:              transfer.DestinationCurrency,
+		Timestamp:             transfer.Timestamp,
+		TransferID:            transfer.ID,
+		CounterpartyAccountID: transfer.SourceAccountID,
+		ExchangeRate:          rate,
+	}
+
+	return out, in
+}
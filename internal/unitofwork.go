@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+)
+
+// Tx gives access to repositories whose changes are only visible to others
+// once committed. Rollback after Commit is a no-op, so it can always be deferred.
+type Tx interface {
+	Accounts() AccountsRepository
+	Transactions() TransactionsRepository
+	Ledger() LedgerRepository
+	Holds() HoldsRepository
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+type UnitOfWork interface {
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// RunInTx runs fn inside a transaction, committing it when fn succeeds and
+// rolling it back otherwise.
+func RunInTx(ctx context.Context, uow UnitOfWork, fn func(tx Tx) error) error {
+	tx, err := uow.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/jyisus/bank-server/internal"
+	"github.com/jyisus/bank-server/internal/fees"
+	"github.com/jyisus/bank-server/internal/fxrates"
 	"github.com/jyisus/bank-server/internal/memrepo"
+	"github.com/jyisus/bank-server/internal/pgrepo"
 	"github.com/jyisus/bank-server/internal/server"
 	"github.com/jyisus/bank-server/internal/service"
+	"github.com/jyisus/bank-server/internal/sqliterepo"
 )
 
 var _port = "8080"
@@ -17,15 +27,133 @@ var _port = "8080"
 func run() error {
 	logHandler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
 	logger := slog.New(logHandler)
-	accountsRepo := memrepo.NewAccountsRepository()
-	transactionsRepo := memrepo.NewTransactionsRepository()
 
-	accountsService := service.NewAccountService(logger, accountsRepo)
-	transactionsService := service.NewTransactionService(logger, accountsRepo, transactionsRepo)
+	repos, err := newRepositories(context.Background())
+	if err != nil {
+		return err
+	}
+	defer repos.close()
+
+	fxRates, err := newFXRateProvider()
+	if err != nil {
+		return err
+	}
+
+	dayCount, err := interestDayCount()
+	if err != nil {
+		return err
+	}
+
+	feeSchedules, err := newFeeSchedules()
+	if err != nil {
+		return err
+	}
+
+	ledger := service.NewLedger(logger, repos.accounts, repos.ledger)
+	accountsService := service.NewAccountService(logger, repos.accounts, repos.unitOfWork, fxRates, ledger, feeSchedules, internal.SystemClock{})
+	transactionsService := service.NewTransactionService(
+		logger,
+		repos.accounts,
+		repos.transactions,
+		repos.unitOfWork,
+		ledger,
+		feeSchedules,
+		internal.SystemClock{},
+	)
+	holdsService := service.NewHoldService(logger, repos.accounts, repos.holds, repos.unitOfWork, ledger, internal.SystemClock{})
+	scheduledTransfersService := service.NewScheduledTransferService(
+		logger,
+		repos.accounts,
+		repos.scheduledTransfers,
+		accountsService,
+		internal.SystemClock{},
+	)
+	interestService := service.NewInterestService(
+		logger,
+		repos.accounts,
+		repos.unitOfWork,
+		ledger,
+		internal.SystemClock{},
+		dayCount,
+	)
+	feeService := service.NewFeeService(
+		logger,
+		repos.accounts,
+		repos.unitOfWork,
+		ledger,
+		feeSchedules,
+		internal.SystemClock{},
+	)
+	balanceService := service.NewBalanceService(
+		logger,
+		repos.accounts,
+		repos.transactions,
+		repos.balanceSnapshots,
+		ledger,
+		internal.SystemClock{},
+	)
+	reconciliationService := service.NewReconciliationService(
+		logger,
+		repos.accounts,
+		repos.transactions,
+		repos.unitOfWork,
+		ledger,
+		internal.SystemClock{},
+	)
 
-	s := server.New(accountsService, transactionsService)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go everyMinute(ctx, func(ctx context.Context, now time.Time) {
+		if _, err := holdsService.ExpireHolds(ctx, now); err != nil {
+			logger.Error("Expiring holds", "error", err)
+		}
+	})
+	go everyMinute(ctx, func(ctx context.Context, _ time.Time) {
+		if _, err := scheduledTransfersService.RunDueTransfers(ctx); err != nil {
+			logger.Error("Running scheduled transfers", "error", err)
+		}
+	})
+	go everyMinute(ctx, func(ctx context.Context, _ time.Time) {
+		// Only the first run of each day has interest to accrue.
+		if _, err := interestService.AccrueInterest(ctx, time.Time{}); err != nil {
+			logger.Error("Accruing interest", "error", err)
+		}
+	})
+	go everyMinute(ctx, func(ctx context.Context, _ time.Time) {
+		// Only the first run of each month has maintenance fees to charge.
+		if _, err := feeService.ChargeMaintenanceFees(ctx); err != nil {
+			logger.Error("Charging maintenance fees", "error", err)
+		}
+	})
+	go everyMinute(ctx, func(ctx context.Context, _ time.Time) {
+		// Only the first run of each day has snapshots to take.
+		if _, err := balanceService.TakeBalanceSnapshots(ctx); err != nil {
+			logger.Error("Taking balance snapshots", "error", err)
+		}
+	})
+	go everyMinute(ctx, func(ctx context.Context, _ time.Time) {
+		// Only the first run of each day reconciles the balances, without
+		// correcting them.
+		if _, err := reconciliationService.ReconcileDaily(ctx); err != nil {
+			logger.Error("Reconciling balances", "error", err)
+		}
+	})
 
-	logger.Info("Server running", "port", _port)
+	// The admin endpoints are only enabled when ADMIN_TOKEN is set.
+	s := server.New(
+		accountsService,
+		transactionsService,
+		holdsService,
+		scheduledTransfersService,
+		interestService,
+		balanceService,
+		reconciliationService,
+		memrepo.NewIdempotencyStore(),
+		internal.SystemClock{},
+		os.Getenv("ADMIN_TOKEN"),
+	)
+
+	logger.Info("Server running", "port", _port, "storage", repos.storage)
 
 	httpServer := &http.Server{
 		Addr:    net.JoinHostPort("localhost", _port),
@@ -35,8 +163,241 @@ func run() error {
 	return httpServer.ListenAndServe()
 }
 
+// reconcile runs the reconcile subcommand: it reconciles the balances of the
+// accounts in the storage with their history and prints the outcome as JSON.
+// It fails when there are discrepancies left, that is unless -adjust is given
+// to correct them.
+func reconcile(args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	adjust := flags.Bool("adjust", false, "correct the balances that don't match their history")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// The output is the reconciliation, so the logs go to stderr.
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	// The memory storage is only kept in its log, so without one there's
+	// nothing to reconcile.
+	if storage := os.Getenv("STORAGE"); (storage == "" || storage == "memory") && os.Getenv("WAL_DIR") == "" {
+		return errors.New("reconciling the memory storage needs its WAL_DIR")
+	}
+
+	repos, err := newRepositories(context.Background())
+	if err != nil {
+		return err
+	}
+	defer repos.close()
+
+	ledger := service.NewLedger(logger, repos.accounts, repos.ledger)
+	reconciliationService := service.NewReconciliationService(
+		logger,
+		repos.accounts,
+		repos.transactions,
+		repos.unitOfWork,
+		ledger,
+		internal.SystemClock{},
+	)
+
+	reconciliation, err := reconciliationService.Reconcile(context.Background(), *adjust)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(reconciliation); err != nil {
+		return err
+	}
+
+	if discrepancies := len(reconciliation.Discrepancies); discrepancies > 0 && !*adjust {
+		return fmt.Errorf("%d accounts don't match their history", discrepancies)
+	}
+
+	return nil
+}
+
+// everyMinute runs the background job every minute until the context is
+// done.
+func everyMinute(ctx context.Context, job func(ctx context.Context, now time.Time)) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			job(ctx, now)
+		}
+	}
+}
+
+type repositories struct {
+	storage            string
+	accounts           internal.AccountsRepository
+	transactions       internal.TransactionsRepository
+	ledger             internal.LedgerRepository
+	holds              internal.HoldsRepository
+	scheduledTransfers internal.ScheduledTransfersRepository
+	balanceSnapshots   internal.BalanceSnapshotsRepository
+	unitOfWork         internal.UnitOfWork
+	close              func()
+}
+
+// newRepositories selects the storage backend with the STORAGE environment
+// variable: "memory" (default), "postgres", which connects to DATABASE_URL, or
+// "sqlite", which stores the data in the file pointed by SQLITE_PATH. The
+// memory backend keeps a write-ahead log in WAL_DIR when it's set.
+func newRepositories(ctx context.Context) (repositories, error) {
+	switch storage := os.Getenv("STORAGE"); storage {
+	case "", "memory":
+		accountsRepo := memrepo.NewAccountsRepository()
+		transactionsRepo := memrepo.NewTransactionsRepository()
+		ledgerRepo := memrepo.NewLedgerRepository()
+		holdsRepo := memrepo.NewHoldsRepository()
+		scheduledTransfersRepo := memrepo.NewScheduledTransfersRepository()
+		balanceSnapshotsRepo := memrepo.NewBalanceSnapshotsRepository()
+
+		repos := repositories{
+			storage:            "memory",
+			accounts:           accountsRepo,
+			transactions:       transactionsRepo,
+			ledger:             ledgerRepo,
+			holds:              holdsRepo,
+			scheduledTransfers: scheduledTransfersRepo,
+			balanceSnapshots:   balanceSnapshotsRepo,
+			unitOfWork:         memrepo.NewUnitOfWork(accountsRepo, transactionsRepo, ledgerRepo, holdsRepo),
+			close:              func() {},
+		}
+
+		dir := os.Getenv("WAL_DIR")
+		if dir == "" {
+			return repos, nil
+		}
+
+		options, err := walOptions()
+		if err != nil {
+			return repositories{}, err
+		}
+
+		wal, err := memrepo.OpenWAL(dir, options, accountsRepo, transactionsRepo, ledgerRepo, holdsRepo, scheduledTransfersRepo, balanceSnapshotsRepo)
+		if err != nil {
+			return repositories{}, err
+		}
+		repos.close = func() { wal.Close() }
+
+		return repos, nil
+	case "postgres":
+		db, err := pgrepo.Open(ctx, os.Getenv("DATABASE_URL"))
+		if err != nil {
+			return repositories{}, err
+		}
+
+		return repositories{
+			storage:            storage,
+			accounts:           pgrepo.NewAccountsRepository(db),
+			transactions:       pgrepo.NewTransactionsRepository(db),
+			ledger:             pgrepo.NewLedgerRepository(db),
+			holds:              pgrepo.NewHoldsRepository(db),
+			scheduledTransfers: pgrepo.NewScheduledTransfersRepository(db),
+			balanceSnapshots:   pgrepo.NewBalanceSnapshotsRepository(db),
+			unitOfWork:         pgrepo.NewUnitOfWork(db),
+			close:              func() { db.Close() },
+		}, nil
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "bank.db"
+		}
+
+		db, err := sqliterepo.Open(ctx, path)
+		if err != nil {
+			return repositories{}, err
+		}
+
+		return repositories{
+			storage:            storage,
+			accounts:           sqliterepo.NewAccountsRepository(db),
+			transactions:       sqliterepo.NewTransactionsRepository(db),
+			ledger:             sqliterepo.NewLedgerRepository(db),
+			holds:              sqliterepo.NewHoldsRepository(db),
+			scheduledTransfers: sqliterepo.NewScheduledTransfersRepository(db),
+			balanceSnapshots:   sqliterepo.NewBalanceSnapshotsRepository(db),
+			unitOfWork:         sqliterepo.NewUnitOfWork(db),
+			close:              func() { db.Close() },
+		}, nil
+	default:
+		return repositories{}, fmt.Errorf("unknown storage %q", storage)
+	}
+}
+
+// walOptions reads the sync policy of the write-ahead log from WAL_SYNC:
+// "always" (default), "never" or a duration between syncs such as "100ms".
+func walOptions() (memrepo.WALOptions, error) {
+	options := memrepo.WALOptions{Sync: memrepo.SyncAlways, SnapshotEvery: 10000}
+
+	switch policy := os.Getenv("WAL_SYNC"); policy {
+	case "", "always":
+	case "never":
+		options.Sync = memrepo.SyncNever
+	default:
+		interval, err := time.ParseDuration(policy)
+		if err != nil {
+			return memrepo.WALOptions{}, fmt.Errorf("invalid WAL_SYNC %q", policy)
+		}
+
+		options.Sync = memrepo.SyncInterval
+		options.SyncInterval = interval
+	}
+
+	return options, nil
+}
+
+// interestDayCount reads the day count convention used to accrue interest
+// from INTEREST_DAY_COUNT: "ACT/365" (default), "ACT/360" or "ACT/ACT".
+func interestDayCount() (internal.DayCount, error) {
+	convention := os.Getenv("INTEREST_DAY_COUNT")
+	if convention == "" {
+		return internal.DayCountActual365, nil
+	}
+
+	return internal.ParseDayCount(convention)
+}
+
+// newFXRateProvider loads the exchange rates from the file pointed by the
+// FX_RATES_FILE environment variable. Without it only same-currency transfers
+// are possible.
+func newFXRateProvider() (internal.FXRateProvider, error) {
+	path := os.Getenv("FX_RATES_FILE")
+	if path == "" {
+		return fxrates.NewStaticProvider(), nil
+	}
+
+	return fxrates.LoadFile(path)
+}
+
+// newFeeSchedules loads the fees charged to each account type from the file
+// pointed by the FEES_FILE environment variable. Without it no fees are
+// charged.
+func newFeeSchedules() (internal.FeeSchedules, error) {
+	path := os.Getenv("FEES_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	return fees.LoadFile(path)
+}
+
 func main() {
-	if err := run(); err != nil {
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		err = reconcile(os.Args[2:])
+	} else {
+		err = run()
+	}
+
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err)
 		os.Exit(1)
 	}